@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/backfill"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/database"
+)
+
+// backfill repairs rows written before a schema change.
+//
+//	go run ./cmd/backfill -list
+//	go run ./cmd/backfill -dry-run backfill-image-metadata
+//	go run ./cmd/backfill all
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would change without writing")
+	batchSize := flag.Int("batch-size", 500, "rows per batch")
+	list := flag.Bool("list", false, "list available tasks and exit")
+	flag.Parse()
+
+	if *list || flag.NArg() == 0 {
+		fmt.Println("Available backfill tasks:")
+		for _, task := range backfill.Tasks() {
+			fmt.Printf("  %-28s %s\n", task.Name, task.Description)
+		}
+		fmt.Println("\nUsage: backfill [-dry-run] [-batch-size N] <task>... | all")
+		return
+	}
+
+	if *batchSize < 1 {
+		log.Fatalf("-batch-size must be at least 1")
+	}
+
+	// Resolve tasks before touching the database so typos fail fast
+	var tasks []backfill.Task
+	if flag.NArg() == 1 && flag.Arg(0) == "all" {
+		tasks = backfill.Tasks()
+	} else {
+		for _, name := range flag.Args() {
+			task, ok := backfill.Lookup(name)
+			if !ok {
+				log.Fatalf("Unknown backfill task %q (use -list to see available tasks)", name)
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	if os.Getenv("APP_ENV") == "" {
+		os.Setenv("APP_ENV", "development")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	opts := backfill.Options{
+		DryRun:          *dryRun,
+		BatchSize:       *batchSize,
+		UploadsPath:     cfg.UploadsPath,
+		DefaultCurrency: cfg.DefaultCurrency,
+	}
+
+	for _, task := range tasks {
+		if _, err := backfill.Run(db.DB, task, opts); err != nil {
+			log.Fatalf("Backfill failed: %v", err)
+		}
+	}
+}
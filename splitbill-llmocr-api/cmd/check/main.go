@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+)
+
+// defaultMaxAmount matches the EXTRACTION_MAX_AMOUNT default
+const defaultMaxAmount = 99999999
+
+// check runs offline checks of n8n extraction callbacks, with no database or server.
+//
+//	go run ./cmd/check contract payload.json
+//	go run ./cmd/check contract -currency IDR payload.json
+//
+// The recorded fixtures are verified by go test ./internal/server.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "contract":
+		checkContract(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("  check contract [-currency CODE] [-max-amount N] <payload.json>  parse a callback body as /process-data would")
+	os.Exit(2)
+}
+
+// checkContract parses one callback body and prints what the API would store
+func checkContract(args []string) {
+	flags := flag.NewFlagSet("contract", flag.ExitOnError)
+	code := flags.String("currency", "USD", "currency of the bill the payload is for")
+	maxAmount := flags.Int64("max-amount", defaultMaxAmount, "largest amount accepted, as EXTRACTION_MAX_AMOUNT")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+
+	body, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to read payload: %v", err)
+	}
+
+	shape, data, err := services.ParseExtractionCallback(body, currency.Normalize(*code), *maxAmount)
+	if err != nil {
+		fmt.Printf("REJECTED: %v\n", err)
+		os.Exit(1)
+	}
+
+	output, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode result: %v", err)
+	}
+	fmt.Printf("ACCEPTED as %s\n%s\n", shape, output)
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net/http"
 	"os"
-	"time"
 
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/database"
-	"github.com/Aebroyx/splitbill-llmocr-api/internal/handlers"
-	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/secrets"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/server"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -123,6 +124,12 @@ func main() {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
+	// Load the keyring used for encrypted columns
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	if err != nil {
+		log.Fatalf("Invalid encryption keys: %v", err)
+	}
+
 	// Initialize database
 	log.Println("Initializing database connection...")
 	db, err := database.NewConnection(cfg)
@@ -130,140 +137,50 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Initialize services
-	log.Println("Initializing services...")
-	userService := services.NewUserService(db.DB, cfg)
-	billService := services.NewBillService(db.DB)
-
-	// Initialize handlers
-	log.Println("Initializing handlers...")
-	authHandler := handlers.NewAuthHandler(userService)
-	billHandler := handlers.NewBillHandler(billService)
-
-	// Initialize router
-	router := gin.New() // Use gin.New() instead of gin.Default() to avoid default middleware
-
-	// Add logger middleware
-	router.Use(gin.Logger())
-
-	// Add CORS middleware
-	router.Use(func(c *gin.Context) {
-		// Log incoming request
-		log.Printf("Incoming request: %s %s", c.Request.Method, c.Request.URL.Path)
-
-		// Get allowed origins from config
-		allowedOrigins := cfg.CORSAllowedOrigins
-		var allowedOrigin string
-
-		// Handle multiple origins
-		if len(allowedOrigins) == 0 {
-			if cfg.Environment == "production" {
-				allowedOrigin = "*" // Allow all origins in production if not specified
-			} else {
-				allowedOrigin = "http://localhost:3001" // fallback for development
-			}
-		} else if len(allowedOrigins) == 1 {
-			allowedOrigin = allowedOrigins[0]
-		} else {
-			// Multiple origins - check if request origin is in the allowed list
-			requestOrigin := c.Request.Header.Get("Origin")
-			for _, origin := range allowedOrigins {
-				if origin == requestOrigin {
-					allowedOrigin = requestOrigin
-					break
-				}
-			}
-			// If no match found, don't set Access-Control-Allow-Origin header
-		}
-
-		// Set CORS headers (only if we have a valid origin)
-		if allowedOrigin != "" {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		}
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-
-		// Handle preflight
-		if c.Request.Method == "OPTIONS" {
-			log.Printf("Handling OPTIONS request for: %s", c.Request.URL.Path)
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	// Health check endpoint for keep-alive and monitoring
-	router.GET("/health", func(c *gin.Context) {
-		// Check database connectivity
-		if err := db.HealthCheck(); err != nil {
-			log.Printf("Health check failed - database connectivity issue: %v", err)
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"status":      "unhealthy",
-				"error":       "database connectivity failed",
-				"timestamp":   time.Now().UTC().Format(time.RFC3339),
-				"environment": os.Getenv("APP_ENV"),
-			})
-			return
-		}
-
-		c.JSON(http.StatusOK, gin.H{
-			"status":      "healthy",
-			"timestamp":   time.Now().UTC().Format(time.RFC3339),
-			"environment": os.Getenv("APP_ENV"),
-			"database":    "connected",
-		})
-	})
-
-	// Serve static files (for uploaded images)
-	uploadsPath := os.Getenv("UPLOADS_PATH")
-	if uploadsPath == "" {
-		uploadsPath = "./uploads"
+	// Refuse to start when encrypted data exists but cannot be decrypted
+	if err := secrets.CheckKeyAvailable(db.DB, keyring); err != nil {
+		log.Fatalf("Encryption key check failed: %v", err)
 	}
-	router.Static("/uploads", uploadsPath)
-
-	// All API routes
-	api := router.Group("/api")
-	{
-		// Public routes
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-		}
 
-		bills := api.Group("/bills")
-		{
-			bills.POST("/", billHandler.CreateBill)
-			bills.GET("/:id", billHandler.GetBill)
-			bills.PUT("/:id", billHandler.UpdateBill)
-			bills.GET("/:id/status", billHandler.GetBillStatus)
-			bills.POST("/:id/image", billHandler.UploadBillImage)
-			bills.GET("/:id/summary", billHandler.GetBillSummary)
-			bills.GET("/:id/participants", billHandler.GetParticipants)
-			bills.POST("/:id/participants", billHandler.AddParticipant)
-			bills.DELETE("/:id/participants/:participantId", billHandler.DeleteParticipant)
-			bills.GET("/:id/item-assignments", billHandler.GetItemAssignments)
-			bills.POST("/:id/assign-items", billHandler.AssignItemToParticipant)
-			bills.DELETE("/:id/assign-items", billHandler.DeleteItemAssignment)
-			bills.POST("/:id/process-data", billHandler.ProcessExtractedData)
-		}
-
-		// Items routes
-		items := api.Group("/items")
-		{
-			items.PUT("/:id", billHandler.UpdateItem)
-		}
+	// Watch the connection and rebuild it automatically if the database drops us
+	go db.Supervise(context.Background(), cfg.DBPingInterval, cfg.DBPingFailureThreshold)
 
-		// Protected routes (with auth middleware)
-		protected := api.Group("")
-		protected.Use(middleware.Auth(cfg.JWTSecret, db.DB))
-		{
-			protected.GET("/me", authHandler.GetMe)
-			protected.POST("/auth/logout", authHandler.Logout)
-		}
+	// Initialize services
+	log.Println("Initializing services...")
+	userCache := cache.NewUserCache(cfg.AuthCacheTTL, cfg.AuthCacheMaxEntries)
+	userService := services.NewUserService(db.DB, cfg, userCache)
+	uploadStorage := storage.NewLocal(cfg.UploadsPath)
+	if err := uploadStorage.Probe(); err != nil {
+		log.Printf("Warning: uploads directory %s is not writable: %v", cfg.UploadsPath, err)
+	}
+	go uploadStorage.Watch(context.Background(), cfg.StorageProbeInterval)
+
+	billViews := cache.NewBillViewCache(cfg.ViewCacheTTL, cfg.ViewCacheMaxEntries)
+	billService := services.NewBillService(db.DB, cfg, uploadStorage, keyring, billViews)
+
+	// Send uploaded images to n8n with at most N8N_MAX_CONCURRENCY in flight
+	billService.StartExtractionWorkers(context.Background())
+
+	// Fail bills that n8n accepted but never called back for
+	billService.StartStuckBillSweeper(context.Background(), cfg.StuckBillSweepInterval, cfg.StuckBillTimeout)
+	billService.StartAbandonedBillSweeper(context.Background(), cfg.AbandonedBillSweepInterval)
+	billService.StartDeletedRowPurger(context.Background(), cfg.DeletedRowPurgeInterval)
+
+	// Forget editors whose heartbeats stopped
+	billService.StartPresenceSweeper(context.Background())
+
+	// Build the router; every route must have a declared permission
+	router, err := server.NewRouter(server.Deps{
+		Config:      cfg,
+		Database:    db,
+		DB:          db.DB,
+		UserCache:   userCache,
+		Storage:     uploadStorage,
+		UserService: userService,
+		BillService: billService,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build router: %v", err)
 	}
 
 	// COMMENTED OUT: Using external cron job for keep-alive instead
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/database"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/secrets"
+)
+
+// reencrypt rewrites every encrypted column value with the active key.
+// Run it after adding a new key to ENCRYPTION_KEYS and pointing
+// ENCRYPTION_ACTIVE_KEY at it; the old key can be removed once it finishes.
+func main() {
+	if os.Getenv("APP_ENV") == "" {
+		os.Setenv("APP_ENV", "development")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+	if err != nil {
+		log.Fatalf("Invalid encryption keys: %v", err)
+	}
+	if keyring == nil {
+		log.Fatalf("ENCRYPTION_KEYS is not set, nothing to re-encrypt with")
+	}
+
+	db, err := database.NewConnection(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	rotated, err := secrets.RotateColumns(db.DB, keyring)
+	for col, count := range rotated {
+		log.Printf("Re-encrypted %d values in %s.%s", count, col.Table, col.Column)
+	}
+	if err != nil {
+		log.Fatalf("Re-encryption stopped: %v", err)
+	}
+
+	log.Printf("Re-encryption finished with key %q", cfg.EncryptionActiveKey)
+}
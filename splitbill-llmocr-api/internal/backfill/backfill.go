@@ -0,0 +1,74 @@
+package backfill
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Options controls how a task runs
+type Options struct {
+	// DryRun reports what would change without writing anything
+	DryRun bool
+	// BatchSize is how many rows are loaded and written per step
+	BatchSize int
+	// UploadsPath is the directory uploaded bill images are stored in
+	UploadsPath string
+	// DefaultCurrency is assumed for bills that don't store a currency
+	DefaultCurrency string
+}
+
+// Task repairs rows written before a schema change. Tasks must be idempotent:
+// running one twice leaves the data the same as running it once.
+type Task struct {
+	Name        string
+	Description string
+	// Run returns how many rows were (or, in a dry run, would be) updated
+	Run func(db *gorm.DB, opts Options) (int, error)
+}
+
+// registry holds every known task by name
+var registry = map[string]Task{}
+
+// Register adds a task. Tasks register themselves from init in this package.
+func Register(task Task) {
+	if _, exists := registry[task.Name]; exists {
+		panic(fmt.Sprintf("backfill task %q registered twice", task.Name))
+	}
+	registry[task.Name] = task
+}
+
+// Lookup returns the task with the given name
+func Lookup(name string) (Task, bool) {
+	task, ok := registry[name]
+	return task, ok
+}
+
+// Tasks returns every registered task sorted by name
+func Tasks() []Task {
+	tasks := make([]Task, 0, len(registry))
+	for _, task := range registry {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks
+}
+
+// Run executes a single task with progress logging
+func Run(db *gorm.DB, task Task, opts Options) (int, error) {
+	mode, outcome := "applying", "updated"
+	if opts.DryRun {
+		mode, outcome = "dry run", "would change"
+	}
+	log.Printf("[%s] starting (%s, batch size %d)", task.Name, mode, opts.BatchSize)
+
+	updated, err := task.Run(db, opts)
+	if err != nil {
+		return updated, fmt.Errorf("%s: %w", task.Name, err)
+	}
+
+	log.Printf("[%s] done, %d rows %s", task.Name, updated, outcome)
+	return updated, nil
+}
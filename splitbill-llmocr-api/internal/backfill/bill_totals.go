@@ -0,0 +1,80 @@
+package backfill
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Task{
+		Name:        "recompute-bill-totals",
+		Description: "Recompute item_subtotal and grand_total and report bills whose stored totals drifted",
+		Run:         recomputeBillTotals,
+	})
+}
+
+// recomputeBillTotals recomputes the stored totals of every bill and rewrites the ones that
+// differ. With -dry-run it is a consistency check: each drifting bill is logged and nothing
+// is written.
+func recomputeBillTotals(db *gorm.DB, opts Options) (int, error) {
+	var total int64
+	if err := db.Model(&models.Bills{}).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to count bills: %w", err)
+	}
+	log.Printf("[recompute-bill-totals] checking %d bills", total)
+
+	drifted, checked := 0, 0
+	var lastID string
+	for {
+		var bills []models.Bills
+		query := db.Preload("Items").Preload("Participants").Order("id").Limit(opts.BatchSize)
+		if lastID != "" {
+			query = query.Where("id > ?", lastID)
+		}
+		if err := query.Find(&bills).Error; err != nil {
+			return drifted, fmt.Errorf("failed to load bills: %w", err)
+		}
+		if len(bills) == 0 {
+			break
+		}
+
+		for _, bill := range bills {
+			checked++
+			subtotal, grandTotal := services.BillTotals(&bill, bill.Items, opts.DefaultCurrency)
+			code := bill.Currency
+			if code == "" {
+				code = opts.DefaultCurrency
+			}
+			if sameAmount(subtotal, bill.ItemSubtotal, code) && sameAmount(grandTotal, bill.GrandTotal, code) {
+				continue
+			}
+
+			log.Printf("[recompute-bill-totals] bill %s drifted: item_subtotal %.2f -> %.2f, grand_total %.2f -> %.2f",
+				bill.ID, bill.ItemSubtotal, subtotal, bill.GrandTotal, grandTotal)
+			if !opts.DryRun {
+				if err := db.Model(&models.Bills{}).Where("id = ?", bill.ID).UpdateColumns(map[string]interface{}{
+					"item_subtotal": subtotal,
+					"grand_total":   grandTotal,
+				}).Error; err != nil {
+					return drifted, fmt.Errorf("failed to update bill %s: %w", bill.ID, err)
+				}
+			}
+			drifted++
+		}
+		lastID = bills[len(bills)-1].ID.String()
+
+		log.Printf("[recompute-bill-totals] checked %d/%d bills, %d drifted", checked, total, drifted)
+	}
+
+	return drifted, nil
+}
+
+// sameAmount compares two amounts in minor units of code, the precision they are stored at
+func sameAmount(a, b float64, code string) bool {
+	return currency.ToMinor(a, code) == currency.ToMinor(b, code)
+}
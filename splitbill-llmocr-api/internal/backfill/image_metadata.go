@@ -0,0 +1,115 @@
+package backfill
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Task{
+		Name:        "backfill-image-metadata",
+		Description: "Link stored uploads to bills missing image_path/image_hash using the bill_<id>_<filename> convention",
+		Run:         backfillImageMetadata,
+	})
+}
+
+// storedImage is the newest upload found on disk for a bill
+type storedImage struct {
+	path    string
+	modTime int64
+}
+
+// backfillImageMetadata fills image_path and image_hash for bills uploaded before
+// those columns existed. Bills that already have an image_path are left alone.
+func backfillImageMetadata(db *gorm.DB, opts Options) (int, error) {
+	entries, err := os.ReadDir(opts.UploadsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read uploads directory: %w", err)
+	}
+
+	// Keep the newest file per bill, matching what the last upload would have stored
+	images := make(map[uuid.UUID]storedImage)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		billID, ok := parseUploadName(entry.Name())
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if current, seen := images[billID]; !seen || info.ModTime().UnixNano() > current.modTime {
+			images[billID] = storedImage{
+				path:    filepath.Join(opts.UploadsPath, entry.Name()),
+				modTime: info.ModTime().UnixNano(),
+			}
+		}
+	}
+
+	billIDs := make([]uuid.UUID, 0, len(images))
+	for billID := range images {
+		billIDs = append(billIDs, billID)
+	}
+	log.Printf("[backfill-image-metadata] %d bills have files in %s", len(billIDs), opts.UploadsPath)
+
+	updated := 0
+	for start := 0; start < len(billIDs); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(billIDs))
+
+		var bills []models.Bills
+		if err := db.Select("id").
+			Where("id IN ? AND (image_path = '' OR image_path IS NULL)", billIDs[start:end]).
+			Find(&bills).Error; err != nil {
+			return updated, fmt.Errorf("failed to load bills: %w", err)
+		}
+
+		for _, bill := range bills {
+			image := images[bill.ID]
+			data, err := os.ReadFile(image.path)
+			if err != nil {
+				log.Printf("[backfill-image-metadata] skipping bill %s: %v", bill.ID, err)
+				continue
+			}
+			hash := sha256.Sum256(data)
+
+			if !opts.DryRun {
+				if err := db.Model(&models.Bills{}).Where("id = ?", bill.ID).Updates(map[string]interface{}{
+					"image_path": image.path,
+					"image_hash": hex.EncodeToString(hash[:]),
+				}).Error; err != nil {
+					return updated, fmt.Errorf("failed to update bill %s: %w", bill.ID, err)
+				}
+			}
+			updated++
+		}
+
+		log.Printf("[backfill-image-metadata] processed %d/%d bills", end, len(billIDs))
+	}
+
+	return updated, nil
+}
+
+// parseUploadName extracts the bill ID from a "bill_<uuid>_<filename>" upload name
+func parseUploadName(name string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(name, "bill_")
+	if !ok || len(rest) < 38 || rest[36] != '_' {
+		return uuid.Nil, false
+	}
+	billID, err := uuid.Parse(rest[:36])
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return billID, true
+}
@@ -0,0 +1,64 @@
+package backfill
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/secrets"
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(Task{
+		Name:        "backfill-share-tokens",
+		Description: "Give bills created before share links existed a share_token",
+		Run:         backfillShareTokens,
+	})
+}
+
+// backfillShareTokens generates a share token for every bill that has none
+func backfillShareTokens(db *gorm.DB, opts Options) (int, error) {
+	var total int64
+	if err := db.Model(&models.Bills{}).Where("share_token IS NULL").Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to count bills: %w", err)
+	}
+	log.Printf("[backfill-share-tokens] %d bills have no share token", total)
+
+	updated := 0
+	var lastID string
+	for {
+		// Page by ID so a dry run, which changes nothing, still makes progress
+		var bills []models.Bills
+		query := db.Select("id").Where("share_token IS NULL").Order("id").Limit(opts.BatchSize)
+		if lastID != "" {
+			query = query.Where("id > ?", lastID)
+		}
+		if err := query.Find(&bills).Error; err != nil {
+			return updated, fmt.Errorf("failed to load bills: %w", err)
+		}
+		if len(bills) == 0 {
+			break
+		}
+
+		for _, bill := range bills {
+			token, err := secrets.NewToken()
+			if err != nil {
+				return updated, err
+			}
+			if !opts.DryRun {
+				if err := db.Model(&models.Bills{}).
+					Where("id = ? AND share_token IS NULL", bill.ID).
+					Update("share_token", token).Error; err != nil {
+					return updated, fmt.Errorf("failed to update bill %s: %w", bill.ID, err)
+				}
+			}
+			updated++
+		}
+		lastID = bills[len(bills)-1].ID.String()
+
+		log.Printf("[backfill-share-tokens] processed %d/%d bills", updated, total)
+	}
+
+	return updated, nil
+}
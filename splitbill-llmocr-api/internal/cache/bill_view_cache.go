@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// BillViewCache caches the views of a bill that clients poll, its summary and its item
+// assignments, keyed by bill ID. Writers take a Generation before computing a view and
+// hand it to Set; a view computed before the bill's last Invalidate is dropped.
+type BillViewCache interface {
+	Enabled() bool
+	Generation() uint64
+	Stale(billID uuid.UUID, generation uint64) bool
+	Summary(billID uuid.UUID) (*models.BillSummary, bool)
+	SetSummary(billID uuid.UUID, generation uint64, summary *models.BillSummary)
+	Assignments(billID uuid.UUID) ([]models.ItemAssignments, bool)
+	SetAssignments(billID uuid.UUID, generation uint64, assignments []models.ItemAssignments)
+	Invalidate(billID uuid.UUID)
+}
+
+type billViewEntry struct {
+	summary        *models.BillSummary
+	assignments    []models.ItemAssignments
+	hasAssignments bool
+	// invalidated is the generation of the bill's last Invalidate
+	invalidated uint64
+	expiresAt   time.Time
+}
+
+// MemoryBillViewCache is an in-memory, size-bounded BillViewCache with a fixed TTL
+type MemoryBillViewCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[uuid.UUID]*billViewEntry
+	generation uint64
+	// floor is the newest invalidation forgotten by eviction; views computed before it
+	// may predate a change that is no longer on record, so they are never stored
+	floor uint64
+}
+
+// NewBillViewCache creates an in-memory bill view cache. A non-positive ttl or maxEntries
+// disables caching.
+func NewBillViewCache(ttl time.Duration, maxEntries int) *MemoryBillViewCache {
+	return &MemoryBillViewCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[uuid.UUID]*billViewEntry),
+	}
+}
+
+// Enabled reports whether views are stored at all
+func (c *MemoryBillViewCache) Enabled() bool {
+	return c.ttl > 0 && c.maxEntries > 0
+}
+
+// Generation returns the current generation, to be passed to Set once the view is computed
+func (c *MemoryBillViewCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Stale reports whether a bill has been invalidated since generation, so a view computed
+// from then would not be stored
+func (c *MemoryBillViewCache) Stale(billID uuid.UUID, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation < c.floor {
+		return true
+	}
+	entry, ok := c.entries[billID]
+	return ok && generation < entry.invalidated
+}
+
+// Summary returns the cached summary of a bill if present and not expired
+func (c *MemoryBillViewCache) Summary(billID uuid.UUID) (*models.BillSummary, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.live(billID, time.Now())
+	if !ok || entry.summary == nil {
+		return nil, false
+	}
+	return entry.summary, true
+}
+
+// SetSummary stores a bill's summary computed at generation
+func (c *MemoryBillViewCache) SetSummary(billID uuid.UUID, generation uint64, summary *models.BillSummary) {
+	c.set(billID, generation, func(entry *billViewEntry) {
+		entry.summary = summary
+	})
+}
+
+// Assignments returns the cached item assignments of a bill if present and not expired
+func (c *MemoryBillViewCache) Assignments(billID uuid.UUID) ([]models.ItemAssignments, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.live(billID, time.Now())
+	if !ok || !entry.hasAssignments {
+		return nil, false
+	}
+	return entry.assignments, true
+}
+
+// SetAssignments stores a bill's item assignments computed at generation
+func (c *MemoryBillViewCache) SetAssignments(billID uuid.UUID, generation uint64, assignments []models.ItemAssignments) {
+	c.set(billID, generation, func(entry *billViewEntry) {
+		entry.assignments = assignments
+		entry.hasAssignments = true
+	})
+}
+
+// Invalidate drops a bill's views and keeps views computed before now from being stored
+func (c *MemoryBillViewCache) Invalidate(billID uuid.UUID) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.generation++
+	if _, exists := c.entries[billID]; !exists && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[billID] = &billViewEntry{invalidated: c.generation, expiresAt: now.Add(c.ttl)}
+}
+
+// set applies store to a bill's entry unless the view is older than its last invalidation
+func (c *MemoryBillViewCache) set(billID uuid.UUID, generation uint64, store func(*billViewEntry)) {
+	if !c.Enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation < c.floor {
+		return
+	}
+	now := time.Now()
+	entry, ok := c.live(billID, now)
+	if ok && generation < entry.invalidated {
+		return
+	}
+	if !ok {
+		if len(c.entries) >= c.maxEntries {
+			c.evict(now)
+			if generation < c.floor {
+				return
+			}
+		}
+		entry = &billViewEntry{expiresAt: now.Add(c.ttl)}
+		c.entries[billID] = entry
+	}
+	store(entry)
+}
+
+// live returns a bill's entry, forgetting it once expired. Callers must hold the lock.
+func (c *MemoryBillViewCache) live(billID uuid.UUID, now time.Time) (*billViewEntry, bool) {
+	entry, ok := c.entries[billID]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(entry.expiresAt) {
+		c.forget(billID, entry)
+		return nil, false
+	}
+	return entry, true
+}
+
+// evict removes expired entries, falling back to the entry closest to expiry.
+// Callers must hold the lock.
+func (c *MemoryBillViewCache) evict(now time.Time) {
+	var oldestID uuid.UUID
+	var oldest *billViewEntry
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.forget(id, entry)
+			continue
+		}
+		if oldest == nil || entry.expiresAt.Before(oldest.expiresAt) {
+			oldestID, oldest = id, entry
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldest != nil {
+		c.forget(oldestID, oldest)
+	}
+}
+
+// forget removes an entry, remembering its invalidation in the floor. Callers must hold
+// the lock.
+func (c *MemoryBillViewCache) forget(billID uuid.UUID, entry *billViewEntry) {
+	if entry.invalidated > c.floor {
+		c.floor = entry.invalidated
+	}
+	delete(c.entries, billID)
+}
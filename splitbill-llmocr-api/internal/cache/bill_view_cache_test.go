@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+func TestBillViewCacheServesUntilInvalidated(t *testing.T) {
+	c := NewBillViewCache(time.Minute, 10)
+	billID, other := uuid.New(), uuid.New()
+
+	generation := c.Generation()
+	c.SetSummary(billID, generation, &models.BillSummary{BillID: billID, TotalBill: 15})
+	c.SetAssignments(billID, generation, []models.ItemAssignments{{ItemID: 1}})
+	c.SetSummary(other, generation, &models.BillSummary{BillID: other})
+
+	if summary, ok := c.Summary(billID); !ok || summary.TotalBill != 15 {
+		t.Fatalf("Summary right after Set = %+v, %v, want the cached summary", summary, ok)
+	}
+	if assignments, ok := c.Assignments(billID); !ok || len(assignments) != 1 {
+		t.Fatalf("Assignments right after Set = %v, %v, want the cached list", assignments, ok)
+	}
+
+	c.Invalidate(billID)
+	if _, ok := c.Summary(billID); ok {
+		t.Error("summary still served after Invalidate")
+	}
+	if _, ok := c.Assignments(billID); ok {
+		t.Error("assignments still served after Invalidate")
+	}
+	if _, ok := c.Summary(other); !ok {
+		t.Error("invalidating one bill dropped another")
+	}
+}
+
+func TestBillViewCacheDropsStaleViews(t *testing.T) {
+	c := NewBillViewCache(time.Minute, 10)
+	billID := uuid.New()
+
+	// A view computed while the bill changed describes the bill before the change
+	before := c.Generation()
+	c.Invalidate(billID)
+	if !c.Stale(billID, before) {
+		t.Error("view from before the invalidation not reported stale")
+	}
+	c.SetSummary(billID, before, &models.BillSummary{TotalBill: 15})
+	if _, ok := c.Summary(billID); ok {
+		t.Error("stale summary was stored")
+	}
+
+	after := c.Generation()
+	if c.Stale(billID, after) {
+		t.Error("view from after the invalidation reported stale")
+	}
+	c.SetSummary(billID, after, &models.BillSummary{TotalBill: 20})
+	if summary, ok := c.Summary(billID); !ok || summary.TotalBill != 20 {
+		t.Errorf("Summary = %+v, %v, want the fresh summary", summary, ok)
+	}
+}
+
+func TestBillViewCacheExpiresAfterTTL(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	c := NewBillViewCache(ttl, 10)
+	billID := uuid.New()
+
+	c.SetSummary(billID, c.Generation(), &models.BillSummary{})
+	time.Sleep(ttl + 10*time.Millisecond)
+	if _, ok := c.Summary(billID); ok {
+		t.Error("summary still served after its TTL")
+	}
+}
+
+func TestBillViewCacheBounded(t *testing.T) {
+	c := NewBillViewCache(time.Minute, 2)
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range ids {
+		c.SetSummary(id, c.Generation(), &models.BillSummary{BillID: id})
+		// Keep the expiry times apart so the oldest entry is well defined
+		time.Sleep(time.Millisecond)
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("cache holds %d entries, want at most 2", len(c.entries))
+	}
+	if _, ok := c.Summary(ids[0]); ok {
+		t.Error("the entry closest to expiry was not evicted")
+	}
+}
+
+func TestBillViewCacheDisabled(t *testing.T) {
+	c := NewBillViewCache(0, 10)
+	billID := uuid.New()
+
+	c.SetSummary(billID, c.Generation(), &models.BillSummary{})
+	if c.Enabled() {
+		t.Error("cache with no TTL reports enabled")
+	}
+	if _, ok := c.Summary(billID); ok {
+		t.Error("disabled cache served a summary")
+	}
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+// UserCache caches the user fields the auth middleware needs, keyed by user ID
+type UserCache interface {
+	Get(userID uint) (models.RegisterResponse, bool)
+	Set(user models.RegisterResponse)
+	Invalidate(userID uint)
+}
+
+type userCacheEntry struct {
+	user      models.RegisterResponse
+	expiresAt time.Time
+}
+
+// MemoryUserCache is an in-memory, size-bounded UserCache with a fixed TTL
+type MemoryUserCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[uint]userCacheEntry
+}
+
+// NewUserCache creates an in-memory user cache. A non-positive ttl or maxEntries disables caching.
+func NewUserCache(ttl time.Duration, maxEntries int) *MemoryUserCache {
+	return &MemoryUserCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[uint]userCacheEntry),
+	}
+}
+
+// Get returns the cached user if present and not expired
+func (c *MemoryUserCache) Get(userID uint) (models.RegisterResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok {
+		return models.RegisterResponse{}, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(c.entries, userID)
+		return models.RegisterResponse{}, false
+	}
+	return entry.user, true
+}
+
+// Set stores a user, evicting expired entries (and then the oldest) when the cache is full
+func (c *MemoryUserCache) Set(user models.RegisterResponse) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, exists := c.entries[user.ID]; !exists && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[user.ID] = userCacheEntry{user: user, expiresAt: now.Add(c.ttl)}
+}
+
+// Invalidate drops a user so the next request reloads them from the database
+func (c *MemoryUserCache) Invalidate(userID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+// evict removes expired entries, falling back to the entry closest to expiry.
+// Callers must hold the lock.
+func (c *MemoryUserCache) evict(now time.Time) {
+	var oldestID uint
+	var oldestExpiry time.Time
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+			continue
+		}
+		if oldestExpiry.IsZero() || entry.expiresAt.Before(oldestExpiry) {
+			oldestID, oldestExpiry = id, entry.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries && !oldestExpiry.IsZero() {
+		delete(c.entries, oldestID)
+	}
+}
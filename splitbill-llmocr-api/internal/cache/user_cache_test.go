@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+func TestUserCacheExpiresAfterTTL(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	c := NewUserCache(ttl, 10)
+	c.Set(models.RegisterResponse{ID: 1, Role: "admin"})
+
+	if user, ok := c.Get(1); !ok || user.Role != "admin" {
+		t.Fatalf("Get right after Set = %+v, %v, want the cached user", user, ok)
+	}
+	time.Sleep(ttl + 10*time.Millisecond)
+	if _, ok := c.Get(1); ok {
+		t.Error("entry still served after its TTL")
+	}
+}
+
+func TestUserCacheInvalidate(t *testing.T) {
+	c := NewUserCache(time.Minute, 10)
+	c.Set(models.RegisterResponse{ID: 1})
+	c.Set(models.RegisterResponse{ID: 2})
+
+	c.Invalidate(1)
+	if _, ok := c.Get(1); ok {
+		t.Error("invalidated user still cached")
+	}
+	if _, ok := c.Get(2); !ok {
+		t.Error("invalidating one user dropped another")
+	}
+}
+
+func TestUserCacheBounded(t *testing.T) {
+	c := NewUserCache(time.Minute, 2)
+	for id := uint(1); id <= 3; id++ {
+		c.Set(models.RegisterResponse{ID: id})
+		// Keep the expiry times apart so the oldest entry is well defined
+		time.Sleep(time.Millisecond)
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("cache holds %d entries, want at most 2", len(c.entries))
+	}
+	if _, ok := c.Get(1); ok {
+		t.Error("the entry closest to expiry was not evicted")
+	}
+}
+
+func TestUserCacheDisabled(t *testing.T) {
+	c := NewUserCache(0, 10)
+	c.Set(models.RegisterResponse{ID: 1})
+	if _, ok := c.Get(1); ok {
+		t.Error("a cache with no TTL stored a user")
+	}
+}
@@ -4,12 +4,62 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/itemname"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/origin"
 	"github.com/joho/godotenv"
 )
 
+// Optional features that can be switched off with FEATURES_DISABLED
+const (
+	FeatureAuth       = "auth"
+	FeaturePayments   = "payments"
+	FeatureExtraction = "extraction"
+)
+
+// Body formats for the request that sends an image to n8n (N8N_PAYLOAD_FORMAT)
+const (
+	N8nPayloadMultipart = "multipart"
+	N8nPayloadJSON      = "json"
+)
+
+// What creating a bill that repeats a recent one does (DUPLICATE_BILL_MODE)
+const (
+	DuplicateBillReturn = "return"
+	DuplicateBillReject = "reject"
+)
+
+// knownFeatures lists every name accepted in FEATURES_DISABLED
+var knownFeatures = []string{FeatureAuth, FeaturePayments, FeatureExtraction}
+
+// Features records which optional features are turned off for this deployment
+type Features struct {
+	Disabled []string
+}
+
+// Enabled reports whether the named feature is switched on
+func (f Features) Enabled(name string) bool {
+	for _, disabled := range f.Disabled {
+		if disabled == name {
+			return false
+		}
+	}
+	return true
+}
+
+// Map returns the on/off state of every known feature
+func (f Features) Map() map[string]bool {
+	states := make(map[string]bool, len(knownFeatures))
+	for _, name := range knownFeatures {
+		states[name] = f.Enabled(name)
+	}
+	return states
+}
+
 type Config struct {
 	// Server config
 	Environment string
@@ -23,14 +73,119 @@ type Config struct {
 	DBPassword  string
 	DBName      string
 	DBSSLMode   string
-	DatabaseURL string
+	DatabaseURL string `redact:"true"`
+
+	// Database supervisor config
+	DBPingInterval         time.Duration
+	DBPingFailureThreshold int
 
 	// JWT config
 	JWTSecret string
 	JWTExpiry time.Duration
 
-	// CORS config
+	// Lifetime of the read-only tokens admins use to impersonate a user
+	ImpersonationTTL time.Duration
+
+	// Auth user cache config
+	AuthCacheTTL        time.Duration
+	AuthCacheMaxEntries int
+
+	// Bill summary and assignments cache config
+	ViewCacheTTL        time.Duration
+	ViewCacheMaxEntries int
+
+	// Upload storage config
+	UploadsPath          string
+	StorageRequired      bool
+	StorageProbeInterval time.Duration
+
+	// Extraction queue config
+	ExtractionConcurrency int
+	ExtractionQueueSize   int
+
+	// How the image is sent to n8n, and the largest JSON body (base64 grows images by a third)
+	N8nPayloadFormat   string
+	N8nJSONMaxBodySize int
+
+	// Largest body accepted on the extraction callback (/process-data)
+	ExtractionCallbackMaxBodySize int
+
+	// Shared secret n8n must send in X-Callback-Secret with every extraction callback
+	N8nCallbackSecret string
+
+	// Largest amount, in major units, an extracted price, tax, tip or total may carry
+	ExtractionMaxAmount int64
+
+	// Frontend share page revalidation webhook (disabled when the URL is empty)
+	RevalidateURL         string
+	RevalidateSecret      string
+	RevalidateDebounce    time.Duration
+	RevalidateMaxAttempts int
+
+	// Per-bill callback URLs notified when extraction finishes. Callbacks are refused
+	// without a signing secret; private network hosts only when explicitly allowed.
+	CallbackSigningSecret     string
+	CallbackAllowPrivateHosts bool
+
+	// Also send the deprecated name-keyed participant_shares_by_name map in bill summaries
+	SummaryLegacyShares bool
+
+	// Bills left in processing longer than StuckBillTimeout are marked failed (0 disables)
+	StuckBillTimeout       time.Duration
+	StuckBillSweepInterval time.Duration
+
+	// Empty bills untouched for AbandonedBillAge are soft-deleted (0 disables)
+	AbandonedBillAge           time.Duration
+	AbandonedBillSweepInterval time.Duration
+
+	// Creating a bill that repeats one made within DuplicateBillWindow by the same owner or
+	// address returns or rejects it, as DuplicateBillMode says (0 disables)
+	DuplicateBillWindow time.Duration
+	DuplicateBillMode   string
+
+	// Deleted items and participants can be restored for DeletedRowRestoreWindow and are
+	// purged for good after DeletedRowRetention (0 keeps them)
+	DeletedRowRestoreWindow time.Duration
+	DeletedRowRetention     time.Duration
+	DeletedRowPurgeInterval time.Duration
+
+	// How long an editing heartbeat keeps someone listed as editing a bill
+	EditingPresenceTTL time.Duration
+
+	// How many participants can add themselves through one share link per window
+	SharedJoinLimit  int
+	SharedJoinWindow time.Duration
+
+	// Item name normalization applied to extracted items
+	ItemNameSteps     []string
+	ItemNameUnits     []string
+	ItemNameMaxLength int
+
+	// ISO 4217 currency for new bills that don't name one
+	DefaultCurrency string
+
+	// Tip and tax percentages suggested on new bills when neither the request nor the
+	// owner's preferences give one (nil suggests nothing)
+	DefaultTipPercent *float64
+	DefaultTaxPercent *float64
+
+	// Encryption config
+	EncryptionKeys      string
+	EncryptionActiveKey string `redact:"false"`
+
+	// CORS config: exact origins, wildcard patterns such as https://*-myteam.vercel.app,
+	// and local origins on any port for development
 	CORSAllowedOrigins []string
+	CORSOriginPatterns []string
+	CORSAllowLocalhost bool
+
+	// Feature flags
+	Features Features
+
+	// Maintenance window announced on GET /api/status
+	MaintenanceMessage  string
+	MaintenanceStartsAt *time.Time
+	MaintenanceEndsAt   *time.Time
 
 	// Logging
 	LogLevel string
@@ -55,6 +210,192 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid JWT_EXPIRY format: %v", err)
 	}
 
+	impersonationTTL, err := time.ParseDuration(getEnv("IMPERSONATION_TTL", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMPERSONATION_TTL format: %v", err)
+	}
+
+	// Parse auth cache settings
+	authCacheTTL, err := time.ParseDuration(getEnv("AUTH_CACHE_TTL", "60s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTH_CACHE_TTL format: %v", err)
+	}
+
+	authCacheMaxEntries, err := getEnvInt("AUTH_CACHE_MAX_ENTRIES", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse bill view cache settings
+	viewCacheTTL, err := time.ParseDuration(getEnv("VIEW_CACHE_TTL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VIEW_CACHE_TTL format: %v", err)
+	}
+
+	viewCacheMaxEntries, err := getEnvInt("VIEW_CACHE_MAX_ENTRIES", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse database supervisor settings
+	dbPingInterval, err := time.ParseDuration(getEnv("DB_PING_INTERVAL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_PING_INTERVAL format: %v", err)
+	}
+
+	dbPingFailureThreshold, err := getEnvInt("DB_PING_FAILURE_THRESHOLD", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse upload storage settings
+	storageProbeInterval, err := time.ParseDuration(getEnv("STORAGE_PROBE_INTERVAL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_PROBE_INTERVAL format: %v", err)
+	}
+
+	storageRequired, err := strconv.ParseBool(getEnv("STORAGE_REQUIRED", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORAGE_REQUIRED: must be true or false")
+	}
+
+	// Parse extraction queue settings
+	extractionConcurrency, err := getEnvInt("N8N_MAX_CONCURRENCY", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	extractionQueueSize, err := getEnvInt("EXTRACTION_QUEUE_SIZE", 20)
+	if err != nil {
+		return nil, err
+	}
+
+	extractionMaxAmount, err := getEnvInt("EXTRACTION_MAX_AMOUNT", 99999999)
+	if err != nil {
+		return nil, err
+	}
+
+	n8nJSONMaxBodySize, err := getEnvInt("N8N_JSON_MAX_BODY_BYTES", 20<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	extractionCallbackMaxBodySize, err := getEnvInt("EXTRACTION_CALLBACK_MAX_BODY_BYTES", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse frontend revalidation settings
+	revalidateDebounce, err := time.ParseDuration(getEnv("FRONTEND_REVALIDATE_DEBOUNCE", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FRONTEND_REVALIDATE_DEBOUNCE format: %v", err)
+	}
+
+	revalidateMaxAttempts, err := getEnvInt("FRONTEND_REVALIDATE_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	callbackAllowPrivateHosts, err := strconv.ParseBool(getEnv("CALLBACK_ALLOW_PRIVATE_HOSTS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CALLBACK_ALLOW_PRIVATE_HOSTS: must be true or false")
+	}
+
+	corsAllowLocalhost, err := strconv.ParseBool(getEnv("CORS_ALLOW_LOCALHOST", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_ALLOW_LOCALHOST: must be true or false")
+	}
+
+	summaryLegacyShares, err := strconv.ParseBool(getEnv("SUMMARY_LEGACY_PARTICIPANT_SHARES", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SUMMARY_LEGACY_PARTICIPANT_SHARES: must be true or false")
+	}
+
+	// Parse stuck bill sweeper settings
+	stuckBillTimeout, err := time.ParseDuration(getEnv("STUCK_BILL_TIMEOUT", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid STUCK_BILL_TIMEOUT format: %v", err)
+	}
+
+	stuckBillSweepInterval, err := time.ParseDuration(getEnv("STUCK_BILL_SWEEP_INTERVAL", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid STUCK_BILL_SWEEP_INTERVAL format: %v", err)
+	}
+
+	// Parse abandoned bill cleanup settings
+	abandonedBillAge, err := time.ParseDuration(getEnv("ABANDONED_BILL_AGE", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABANDONED_BILL_AGE format: %v", err)
+	}
+
+	abandonedBillSweepInterval, err := time.ParseDuration(getEnv("ABANDONED_BILL_SWEEP_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABANDONED_BILL_SWEEP_INTERVAL format: %v", err)
+	}
+
+	// Parse deleted item and participant settings
+	deletedRowRestoreWindow, err := time.ParseDuration(getEnv("DELETED_ROW_RESTORE_WINDOW", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DELETED_ROW_RESTORE_WINDOW format: %v", err)
+	}
+
+	deletedRowRetention, err := time.ParseDuration(getEnv("DELETED_ROW_RETENTION", "720h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DELETED_ROW_RETENTION format: %v", err)
+	}
+
+	deletedRowPurgeInterval, err := time.ParseDuration(getEnv("DELETED_ROW_PURGE_INTERVAL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DELETED_ROW_PURGE_INTERVAL format: %v", err)
+	}
+
+	duplicateBillWindow, err := time.ParseDuration(getEnv("DUPLICATE_BILL_WINDOW", "2m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DUPLICATE_BILL_WINDOW format: %v", err)
+	}
+
+	editingPresenceTTL, err := time.ParseDuration(getEnv("EDITING_PRESENCE_TTL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EDITING_PRESENCE_TTL format: %v", err)
+	}
+
+	sharedJoinLimit, err := getEnvInt("SHARED_JOIN_LIMIT", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedJoinWindow, err := time.ParseDuration(getEnv("SHARED_JOIN_WINDOW", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHARED_JOIN_WINDOW format: %v", err)
+	}
+
+	// Parse item name normalization settings
+	itemNameMaxLength, err := getEnvInt("ITEM_NAME_MAX_LENGTH", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse bill defaults
+	defaultTipPercent, err := getEnvFloat("DEFAULT_TIP_PERCENT")
+	if err != nil {
+		return nil, err
+	}
+	defaultTaxPercent, err := getEnvFloat("DEFAULT_TAX_PERCENT")
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse maintenance window
+	maintenanceStartsAt, err := getEnvTime("MAINTENANCE_STARTS_AT")
+	if err != nil {
+		return nil, err
+	}
+
+	maintenanceEndsAt, err := getEnvTime("MAINTENANCE_ENDS_AT")
+	if err != nil {
+		return nil, err
+	}
+
 	environment := getEnv("APP_ENV", "development")
 	fmt.Printf("Environment detected: %s\n", environment)
 
@@ -133,12 +474,104 @@ func Load() (*Config, error) {
 		DBSSLMode:   dbSSLMode,
 		DatabaseURL: databaseURL,
 
+		// Database supervisor config
+		DBPingInterval:         dbPingInterval,
+		DBPingFailureThreshold: dbPingFailureThreshold,
+
 		// JWT config
 		JWTSecret: getEnv("JWT_SECRET", ""),
 		JWTExpiry: jwtExpiry,
 
+		// Impersonation
+		ImpersonationTTL: impersonationTTL,
+
+		// Auth user cache config
+		AuthCacheTTL:        authCacheTTL,
+		AuthCacheMaxEntries: authCacheMaxEntries,
+
+		// Bill view cache config
+		ViewCacheTTL:        viewCacheTTL,
+		ViewCacheMaxEntries: viewCacheMaxEntries,
+
+		// Upload storage config
+		UploadsPath:          getEnv("UPLOADS_PATH", "./uploads"),
+		StorageRequired:      storageRequired,
+		StorageProbeInterval: storageProbeInterval,
+
+		// Extraction queue config
+		ExtractionConcurrency: extractionConcurrency,
+		ExtractionQueueSize:   extractionQueueSize,
+		ExtractionMaxAmount:   int64(extractionMaxAmount),
+		N8nPayloadFormat:      strings.ToLower(getEnv("N8N_PAYLOAD_FORMAT", N8nPayloadMultipart)),
+		N8nJSONMaxBodySize:    n8nJSONMaxBodySize,
+		N8nCallbackSecret:     getEnv("N8N_CALLBACK_SECRET", ""),
+		ExtractionCallbackMaxBodySize: extractionCallbackMaxBodySize,
+
+		// Frontend revalidation
+		RevalidateURL:         getEnv("FRONTEND_REVALIDATE_URL", ""),
+		RevalidateSecret:      getEnv("FRONTEND_REVALIDATE_SECRET", ""),
+		RevalidateDebounce:    revalidateDebounce,
+		RevalidateMaxAttempts: revalidateMaxAttempts,
+
+		// Bill callbacks
+		CallbackSigningSecret:     getEnv("CALLBACK_SIGNING_SECRET", ""),
+		CallbackAllowPrivateHosts: callbackAllowPrivateHosts,
+
+		// Bill summaries
+		SummaryLegacyShares: summaryLegacyShares,
+
+		// Stuck bill sweeper
+		StuckBillTimeout:       stuckBillTimeout,
+		StuckBillSweepInterval: stuckBillSweepInterval,
+
+		// Abandoned bill cleanup
+		AbandonedBillAge:           abandonedBillAge,
+		AbandonedBillSweepInterval: abandonedBillSweepInterval,
+
+		// Duplicate bill detection
+		DuplicateBillWindow: duplicateBillWindow,
+		DuplicateBillMode:   strings.ToLower(getEnv("DUPLICATE_BILL_MODE", DuplicateBillReturn)),
+
+		// Deleted items and participants
+		DeletedRowRestoreWindow: deletedRowRestoreWindow,
+		DeletedRowRetention:     deletedRowRetention,
+		DeletedRowPurgeInterval: deletedRowPurgeInterval,
+
+		// Editing presence
+		EditingPresenceTTL: editingPresenceTTL,
+
+		// Joining through share links
+		SharedJoinLimit:  sharedJoinLimit,
+		SharedJoinWindow: sharedJoinWindow,
+
+		// Item name normalization
+		ItemNameSteps:     parseCommaSeparated(strings.ToLower(getEnv("ITEM_NAME_STEPS", itemname.StepCollapseWhitespace))),
+		ItemNameUnits:     parseCommaSeparated(getEnv("ITEM_NAME_UNITS", "")),
+		ItemNameMaxLength: itemNameMaxLength,
+
+		// Currency
+		DefaultCurrency: currency.Normalize(getEnv("DEFAULT_CURRENCY", "USD")),
+
+		// Bill defaults
+		DefaultTipPercent: defaultTipPercent,
+		DefaultTaxPercent: defaultTaxPercent,
+
+		// Encryption config
+		EncryptionKeys:      getEnv("ENCRYPTION_KEYS", ""),
+		EncryptionActiveKey: getEnv("ENCRYPTION_ACTIVE_KEY", ""),
+
 		// CORS config
 		CORSAllowedOrigins: parseCommaSeparated(getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3001")),
+		CORSOriginPatterns: parseCommaSeparated(getEnv("CORS_ORIGIN_PATTERNS", "")),
+		CORSAllowLocalhost: corsAllowLocalhost,
+
+		// Feature flags
+		Features: Features{Disabled: parseCommaSeparated(strings.ToLower(getEnv("FEATURES_DISABLED", "")))},
+
+		// Maintenance window
+		MaintenanceMessage:  getEnv("MAINTENANCE_MESSAGE", ""),
+		MaintenanceStartsAt: maintenanceStartsAt,
+		MaintenanceEndsAt:   maintenanceEndsAt,
 
 		// Logging
 		LogLevel: getEnv("LOG_LEVEL", "debug"),
@@ -153,32 +586,229 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt gets an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: must be an integer", key)
+	}
+	return parsed, nil
+}
+
+// getEnvFloat gets an optional decimal number from an environment variable
+func getEnvFloat(key string) (*float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: must be a number", key)
+	}
+	return &parsed, nil
+}
+
+// getEnvTime gets an optional RFC 3339 timestamp from an environment variable
+func getEnvTime(key string) (*time.Time, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: must be an RFC 3339 timestamp", key)
+	}
+	return &parsed, nil
+}
+
 // parseCommaSeparated parses a comma-separated string into a slice of strings
 func parseCommaSeparated(input string) []string {
 	if input == "" {
 		return []string{}
 	}
-	
+
 	// Split by comma and trim whitespace from each item
 	parts := strings.Split(input, ",")
 	result := make([]string, 0, len(parts))
-	
+
 	for _, part := range parts {
 		trimmed := strings.TrimSpace(part)
 		if trimmed != "" {
 			result = append(result, trimmed)
 		}
 	}
-	
+
 	return result
 }
 
+// maxImpersonationTTL caps impersonation sessions so a leaked support token is short-lived
+const maxImpersonationTTL = 30 * time.Minute
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.JWTSecret == "" {
 		return fmt.Errorf("JWT_SECRET is required")
 	}
 
+	if c.ImpersonationTTL <= 0 || c.ImpersonationTTL > maxImpersonationTTL {
+		return fmt.Errorf("IMPERSONATION_TTL must be positive and at most %s", maxImpersonationTTL)
+	}
+
+	if c.DBPingInterval <= 0 {
+		return fmt.Errorf("DB_PING_INTERVAL must be positive")
+	}
+
+	if c.DBPingFailureThreshold < 1 {
+		return fmt.Errorf("DB_PING_FAILURE_THRESHOLD must be at least 1")
+	}
+
+	if c.StorageProbeInterval <= 0 {
+		return fmt.Errorf("STORAGE_PROBE_INTERVAL must be positive")
+	}
+
+	if c.ExtractionConcurrency < 1 {
+		return fmt.Errorf("N8N_MAX_CONCURRENCY must be at least 1")
+	}
+
+	if c.ExtractionQueueSize < 1 {
+		return fmt.Errorf("EXTRACTION_QUEUE_SIZE must be at least 1")
+	}
+
+	if c.ExtractionMaxAmount < 1 {
+		return fmt.Errorf("EXTRACTION_MAX_AMOUNT must be at least 1")
+	}
+
+	if c.N8nPayloadFormat != N8nPayloadMultipart && c.N8nPayloadFormat != N8nPayloadJSON {
+		return fmt.Errorf("N8N_PAYLOAD_FORMAT must be %s or %s", N8nPayloadMultipart, N8nPayloadJSON)
+	}
+
+	if c.N8nJSONMaxBodySize < 1 {
+		return fmt.Errorf("N8N_JSON_MAX_BODY_BYTES must be at least 1")
+	}
+
+	if c.ExtractionCallbackMaxBodySize < 1 {
+		return fmt.Errorf("EXTRACTION_CALLBACK_MAX_BODY_BYTES must be at least 1")
+	}
+
+	if c.Features.Enabled(FeatureExtraction) && c.N8nCallbackSecret == "" {
+		return fmt.Errorf("N8N_CALLBACK_SECRET is required while extraction is enabled")
+	}
+
+	if c.RevalidateURL != "" {
+		if c.RevalidateSecret == "" {
+			return fmt.Errorf("FRONTEND_REVALIDATE_SECRET is required when FRONTEND_REVALIDATE_URL is set")
+		}
+		if c.RevalidateDebounce <= 0 {
+			return fmt.Errorf("FRONTEND_REVALIDATE_DEBOUNCE must be positive")
+		}
+		if c.RevalidateMaxAttempts < 1 {
+			return fmt.Errorf("FRONTEND_REVALIDATE_MAX_ATTEMPTS must be at least 1")
+		}
+	}
+
+	if c.StuckBillTimeout < 0 {
+		return fmt.Errorf("STUCK_BILL_TIMEOUT must not be negative")
+	}
+
+	if c.StuckBillTimeout > 0 && c.StuckBillSweepInterval <= 0 {
+		return fmt.Errorf("STUCK_BILL_SWEEP_INTERVAL must be positive")
+	}
+
+	if c.AbandonedBillAge < 0 {
+		return fmt.Errorf("ABANDONED_BILL_AGE must not be negative")
+	}
+
+	if c.AbandonedBillAge > 0 && c.AbandonedBillSweepInterval <= 0 {
+		return fmt.Errorf("ABANDONED_BILL_SWEEP_INTERVAL must be positive")
+	}
+
+	if c.DuplicateBillWindow < 0 {
+		return fmt.Errorf("DUPLICATE_BILL_WINDOW must not be negative")
+	}
+
+	if c.DuplicateBillMode != DuplicateBillReturn && c.DuplicateBillMode != DuplicateBillReject {
+		return fmt.Errorf("DUPLICATE_BILL_MODE must be %s or %s", DuplicateBillReturn, DuplicateBillReject)
+	}
+
+	if c.DeletedRowRestoreWindow <= 0 {
+		return fmt.Errorf("DELETED_ROW_RESTORE_WINDOW must be positive")
+	}
+
+	if c.DeletedRowRetention < 0 {
+		return fmt.Errorf("DELETED_ROW_RETENTION must not be negative")
+	}
+
+	// Rows must be kept at least as long as they can be restored
+	if c.DeletedRowRetention > 0 && c.DeletedRowRetention < c.DeletedRowRestoreWindow {
+		return fmt.Errorf("DELETED_ROW_RETENTION must be at least DELETED_ROW_RESTORE_WINDOW")
+	}
+
+	if c.DeletedRowRetention > 0 && c.DeletedRowPurgeInterval <= 0 {
+		return fmt.Errorf("DELETED_ROW_PURGE_INTERVAL must be positive")
+	}
+
+	if c.EditingPresenceTTL <= 0 {
+		return fmt.Errorf("EDITING_PRESENCE_TTL must be positive")
+	}
+
+	if c.SharedJoinLimit < 0 {
+		return fmt.Errorf("SHARED_JOIN_LIMIT must not be negative")
+	}
+
+	if c.SharedJoinWindow <= 0 {
+		return fmt.Errorf("SHARED_JOIN_WINDOW must be positive")
+	}
+
+	for _, step := range c.ItemNameSteps {
+		if !itemname.IsStep(step) {
+			return fmt.Errorf("ITEM_NAME_STEPS contains unknown step %q (known: %s)", step, strings.Join(itemname.KnownSteps, ", "))
+		}
+	}
+
+	if c.ItemNameMaxLength < 0 {
+		return fmt.Errorf("ITEM_NAME_MAX_LENGTH must not be negative")
+	}
+
+	if !currency.Known(c.DefaultCurrency) {
+		return fmt.Errorf("DEFAULT_CURRENCY %q is not supported (accepted: %s)", c.DefaultCurrency, strings.Join(currency.Codes(), ", "))
+	}
+
+	if c.DefaultTipPercent != nil && (*c.DefaultTipPercent < 0 || *c.DefaultTipPercent > 100) {
+		return fmt.Errorf("DEFAULT_TIP_PERCENT must be between 0 and 100")
+	}
+
+	if c.DefaultTaxPercent != nil && (*c.DefaultTaxPercent < 0 || *c.DefaultTaxPercent > 100) {
+		return fmt.Errorf("DEFAULT_TAX_PERCENT must be between 0 and 100")
+	}
+
+	if c.MaintenanceStartsAt != nil && c.MaintenanceEndsAt != nil && !c.MaintenanceEndsAt.After(*c.MaintenanceStartsAt) {
+		return fmt.Errorf("MAINTENANCE_ENDS_AT must be after MAINTENANCE_STARTS_AT")
+	}
+
+	knownStates := c.Features.Map()
+	for _, name := range c.Features.Disabled {
+		if _, known := knownStates[name]; !known {
+			return fmt.Errorf("FEATURES_DISABLED contains unknown feature %q (known: %s)", name, strings.Join(knownFeatures, ", "))
+		}
+	}
+
+	for _, pattern := range c.CORSOriginPatterns {
+		if _, err := origin.CompilePattern(pattern); err != nil {
+			return fmt.Errorf("invalid CORS_ORIGIN_PATTERNS: %v", err)
+		}
+	}
+
+	if c.CORSAllowLocalhost && c.Environment == "production" {
+		return fmt.Errorf("CORS_ALLOW_LOCALHOST is for development and cannot be enabled in production")
+	}
+
 	// For production, DATABASE_URL is required and must be valid
 	if c.Environment == "production" {
 		if c.DatabaseURL == "" {
@@ -239,6 +869,21 @@ func (c *Config) GetDSN() string {
 	)
 }
 
+// MaintenanceActive reports whether now falls inside the configured maintenance window.
+// A message without a start time counts as already started; without an end it never ends.
+func (c *Config) MaintenanceActive(now time.Time) bool {
+	if c.MaintenanceMessage == "" {
+		return false
+	}
+	if c.MaintenanceStartsAt != nil && now.Before(*c.MaintenanceStartsAt) {
+		return false
+	}
+	if c.MaintenanceEndsAt != nil && !now.Before(*c.MaintenanceEndsAt) {
+		return false
+	}
+	return true
+}
+
 // GetServerAddr returns the server address
 func (c *Config) GetServerAddr() string {
 	return fmt.Sprintf("%s:%s", c.ServerHost, c.ServerPort)
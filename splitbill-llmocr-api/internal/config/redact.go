@@ -0,0 +1,88 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// secretNameParts mark a field as secret by name. A field whose name contains one of them is
+// redacted unless it is tagged redact:"false"; any other field can opt in with redact:"true".
+// Redacting by name means a new secret field is hidden even if nobody remembers to tag it.
+var secretNameParts = []string{"secret", "password", "key", "token"}
+
+// Redacted returns every configuration field by name, for operators checking what a
+// deployment runs with. Secret fields are replaced by a fingerprint (see Fingerprint), so
+// two deployments can be compared without showing the value. Derived values are added
+// under their own names: the effective database host, the storage backend and the state
+// of every feature.
+func (c *Config) Redacted() map[string]interface{} {
+	out := make(map[string]interface{})
+
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldValue := value.Field(i).Interface()
+		if isSecretField(field) {
+			out[field.Name] = Fingerprint(fmt.Sprint(fieldValue))
+			continue
+		}
+		// Durations read better as "30s" than as nanoseconds
+		if duration, ok := fieldValue.(time.Duration); ok {
+			fieldValue = duration.String()
+		}
+		out[field.Name] = fieldValue
+	}
+
+	out["EffectiveDatabaseHost"] = c.databaseHost()
+	out["StorageBackend"] = "local"
+	out["FeatureStates"] = c.Features.Map()
+	return out
+}
+
+// isSecretField reports whether a configuration field must be redacted
+func isSecretField(field reflect.StructField) bool {
+	switch field.Tag.Get("redact") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	name := strings.ToLower(field.Name)
+	for _, part := range secretNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprint identifies a secret without revealing it: the first 12 hex characters of its
+// SHA-256, or "" when it is not set
+func Fingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// databaseHost returns the host and port GetDSN connects to, without credentials
+func (c *Config) databaseHost() string {
+	if c.Environment == "production" && c.DatabaseURL != "" {
+		parsed, err := url.Parse(c.DatabaseURL)
+		if err != nil {
+			return ""
+		}
+		return parsed.Host
+	}
+	return net.JoinHostPort(c.DBHost, c.DBPort)
+}
@@ -0,0 +1,5 @@
+package config
+
+// Version is the API version reported by GET /api/status. Set it at build time with
+// -ldflags "-X github.com/Aebroyx/splitbill-llmocr-api/internal/config.Version=1.2.3".
+var Version = "dev"
@@ -0,0 +1,70 @@
+package currency
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// decimals maps the accepted ISO 4217 codes to how many decimal places their amounts use
+var decimals = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 0,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"MYR": 2,
+	"NZD": 2,
+	"PHP": 2,
+	"SGD": 2,
+	"THB": 2,
+	"TWD": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// Normalize upper-cases and trims a currency code
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Known reports whether code is an accepted currency code
+func Known(code string) bool {
+	_, ok := decimals[code]
+	return ok
+}
+
+// Codes returns every accepted currency code in alphabetical order
+func Codes() []string {
+	codes := make([]string, 0, len(decimals))
+	for code := range decimals {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Decimals returns the number of decimal places used by code, 2 for unknown codes
+func Decimals(code string) int {
+	if places, ok := decimals[code]; ok {
+		return places
+	}
+	return 2
+}
+
+// ToMinor converts an amount to whole minor units of code, such as cents for USD or
+// whole rupiah for IDR
+func ToMinor(amount float64, code string) int64 {
+	return int64(math.Round(amount * math.Pow10(Decimals(code))))
+}
+
+// FromMinor converts whole minor units of code back to an amount
+func FromMinor(units int64, code string) float64 {
+	return float64(units) / math.Pow10(Decimals(code))
+}
@@ -0,0 +1,59 @@
+package currency
+
+import "testing"
+
+func TestNormalizeAndKnown(t *testing.T) {
+	if got := Normalize(" idr "); got != "IDR" {
+		t.Errorf("Normalize(\" idr \") = %q, want IDR", got)
+	}
+	if !Known("USD") {
+		t.Error("USD should be known")
+	}
+	if Known("usd") {
+		t.Error("codes are only known once normalized")
+	}
+	if Known("XXX") {
+		t.Error("XXX should not be known")
+	}
+}
+
+func TestCodesAreSorted(t *testing.T) {
+	codes := Codes()
+	if len(codes) != len(decimals) {
+		t.Fatalf("got %d codes, want %d", len(codes), len(decimals))
+	}
+	for i := 1; i < len(codes); i++ {
+		if codes[i-1] >= codes[i] {
+			t.Fatalf("codes out of order: %q before %q", codes[i-1], codes[i])
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		code   string
+		amount float64
+		minor  int64
+	}{
+		{"USD", 12.34, 1234},
+		{"USD", 0.1 + 0.2, 30},
+		{"USD", 19.995, 2000},
+		{"IDR", 25000, 25000},
+		{"IDR", 12500.6, 12501},
+		{"JPY", 980, 980},
+		// Unknown codes use two decimals
+		{"XXX", 1.5, 150},
+	}
+	for _, tt := range tests {
+		if got := ToMinor(tt.amount, tt.code); got != tt.minor {
+			t.Errorf("ToMinor(%v, %s) = %d, want %d", tt.amount, tt.code, got, tt.minor)
+		}
+	}
+
+	if got := FromMinor(1234, "USD"); got != 12.34 {
+		t.Errorf("FromMinor(1234, USD) = %v, want 12.34", got)
+	}
+	if got := FromMinor(25000, "IDR"); got != 25000 {
+		t.Errorf("FromMinor(25000, IDR) = %v, want 25000", got)
+	}
+}
@@ -1,9 +1,10 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
-	"time"
+	"sync/atomic"
 
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
@@ -14,6 +15,11 @@ import (
 
 type DB struct {
 	*gorm.DB
+
+	pool      *reconnectingPool
+	dsn       string
+	open      func(dsn string) (*sql.DB, error)
+	available atomic.Bool
 }
 
 func NewConnection(cfg *config.Config) (*DB, error) {
@@ -50,36 +56,39 @@ func NewConnection(cfg *config.Config) (*DB, error) {
 		log.Printf("Connecting to database: %s:%s/%s", cfg.DBHost, cfg.DBPort, cfg.DBName)
 	}
 
-	// Open database connection
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: gormLogger,
-	})
+	// Open the connection pool behind a swappable wrapper so the supervisor can rebuild it
+	sqlDB, err := openSQLDB(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
+	pool := newReconnectingPool(sqlDB)
 
-	// Configure connection pool settings to prevent connection timeouts
-	sqlDB, err := db.DB()
+	// Open database connection
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{
+		Logger: gormLogger,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get underlying sql.DB: %v", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	// Set connection pool parameters
-	sqlDB.SetMaxOpenConns(25)                  // Maximum number of open connections
-	sqlDB.SetMaxIdleConns(5)                   // Maximum number of idle connections
-	sqlDB.SetConnMaxLifetime(15 * time.Minute) // Maximum lifetime of a connection (15 minutes)
-	sqlDB.SetConnMaxIdleTime(8 * time.Minute)  // Maximum idle time for a connection (8 minutes)
-
 	log.Printf("Successfully connected to database with connection pool configured")
 
 	// Auto-migrate models
 	log.Printf("Running database migrations...")
-	if err := db.AutoMigrate(&models.Users{}, &models.Bills{}, &models.Items{}, &models.Participants{}, &models.ItemAssignments{}); err != nil {
+	if err := Migrate(db); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %v", err)
 	}
 	log.Printf("Database migrations completed successfully")
 
-	return &DB{db}, nil
+	conn := &DB{DB: db, pool: pool, dsn: dsn, open: openSQLDB}
+	conn.available.Store(true)
+	return conn, nil
+}
+
+// Migrate creates or updates the tables of every model
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&models.Users{}, &models.Bills{}, &models.Items{}, &models.Participants{}, &models.ItemAssignments{}, &models.AttentionDismissals{}, &models.BillPayers{}, &models.BillEvents{}, &models.AuditLogs{}, &models.WebhookDeliveries{}, &models.UserPreferences{}, &models.ExtractionTimings{})
 }
 
 // HealthCheck performs a database health check by pinging the database
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+)
+
+// TestNewConnectionLogsNoSecrets connects to a closed port and checks that neither the log
+// lines nor the returned error carry the database password
+func TestNewConnectionLogsNoSecrets(t *testing.T) {
+	const password = "db-pa55word-s3cr3t"
+	tests := []struct {
+		name string
+		cfg  *config.Config
+	}{
+		{name: "development", cfg: &config.Config{
+			Environment: "development", DBHost: "127.0.0.1", DBPort: "1", DBUser: "splitbill",
+			DBPassword: password, DBName: "splitbill", DBSSLMode: "disable",
+		}},
+		{name: "production", cfg: &config.Config{
+			Environment: "production",
+			DatabaseURL: "postgresql://splitbill:" + password + "@127.0.0.1:1/splitbill?sslmode=disable",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			previous := log.Writer()
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(previous) })
+
+			if _, err := NewConnection(tt.cfg); err == nil {
+				t.Fatal("connected to a closed port")
+			} else if strings.Contains(err.Error(), password) {
+				t.Errorf("error shows the database password: %v", err)
+			}
+			if strings.Contains(logs.String(), password) {
+				t.Errorf("logs show the database password:\n%s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// reconnectingPool is a gorm.ConnPool that delegates to a *sql.DB which can be
+// swapped at runtime, so a wedged pool can be rebuilt without restarting the process
+// or handing services a new *gorm.DB.
+type reconnectingPool struct {
+	current atomic.Pointer[sql.DB]
+}
+
+// newReconnectingPool wraps an initial *sql.DB
+func newReconnectingPool(sqlDB *sql.DB) *reconnectingPool {
+	pool := &reconnectingPool{}
+	pool.current.Store(sqlDB)
+	return pool
+}
+
+// swap installs a new *sql.DB and returns the previous one
+func (p *reconnectingPool) swap(sqlDB *sql.DB) *sql.DB {
+	return p.current.Swap(sqlDB)
+}
+
+func (p *reconnectingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.current.Load().PrepareContext(ctx, query)
+}
+
+func (p *reconnectingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.current.Load().ExecContext(ctx, query, args...)
+}
+
+func (p *reconnectingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.current.Load().QueryContext(ctx, query, args...)
+}
+
+func (p *reconnectingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.current.Load().QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx lets GORM start transactions on the current pool
+func (p *reconnectingPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.current.Load().BeginTx(ctx, opts)
+}
+
+// GetDBConn lets gorm.DB.DB() return the current *sql.DB
+func (p *reconnectingPool) GetDBConn() (*sql.DB, error) {
+	return p.current.Load(), nil
+}
+
+// Ping is used by gorm.Open to verify the initial connection
+func (p *reconnectingPool) Ping() error {
+	return p.current.Load().Ping()
+}
+
+// openSQLDB opens a pgx-backed *sql.DB with the pool settings used across the app
+func openSQLDB(dsn string) (*sql.DB, error) {
+	pgxConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database DSN: %v", err)
+	}
+
+	sqlDB := stdlib.OpenDB(*pgxConfig)
+
+	// Set connection pool parameters
+	sqlDB.SetMaxOpenConns(25)                  // Maximum number of open connections
+	sqlDB.SetMaxIdleConns(5)                   // Maximum number of idle connections
+	sqlDB.SetConnMaxLifetime(15 * time.Minute) // Maximum lifetime of a connection (15 minutes)
+	sqlDB.SetConnMaxIdleTime(8 * time.Minute)  // Maximum idle time for a connection (8 minutes)
+
+	return sqlDB, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	reconnectInitialBackoff = time.Second
+	reconnectMaxBackoff     = 30 * time.Second
+	pingTimeout             = 5 * time.Second
+)
+
+// IsAvailable reports whether the database is currently considered reachable
+func (d *DB) IsAvailable() bool {
+	return d.available.Load()
+}
+
+// Supervise pings the database every interval. After failureThreshold consecutive
+// failed pings the database is marked unavailable and the connection pool is rebuilt
+// with exponential backoff until it recovers. It blocks until ctx is cancelled.
+func (d *DB) Supervise(ctx context.Context, interval time.Duration, failureThreshold int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := d.ping(ctx)
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		log.Printf("Database supervisor: ping failed (%d/%d): %v", failures, failureThreshold, err)
+		if failures < failureThreshold {
+			continue
+		}
+
+		d.available.Store(false)
+		log.Printf("Database supervisor: marking database unavailable, rebuilding connection pool")
+		d.reconnect(ctx)
+		failures = 0
+	}
+}
+
+// reconnect opens a fresh connection pool, retrying with backoff until a ping succeeds,
+// then swaps it in and closes the old pool
+func (d *DB) reconnect(ctx context.Context) {
+	backoff := reconnectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		sqlDB, err := d.open(d.dsn)
+		if err == nil {
+			pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+			err = sqlDB.PingContext(pingCtx)
+			cancel()
+			if err != nil {
+				sqlDB.Close()
+			}
+		}
+
+		if err == nil {
+			old := d.pool.swap(sqlDB)
+			if closeErr := old.Close(); closeErr != nil {
+				log.Printf("Database supervisor: failed to close old connection pool: %v", closeErr)
+			}
+			d.available.Store(true)
+			log.Printf("Database supervisor: connection restored after %d attempt(s)", attempt)
+			return
+		}
+
+		log.Printf("Database supervisor: reconnect attempt %d failed: %v (retrying in %s)", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+}
+
+// ping checks the current pool with a bounded timeout
+func (d *DB) ping(ctx context.Context) error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(pingCtx)
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeServer is a database/sql connector standing in for Postgres. While it is down every
+// new connection and every ping on an existing one fails, like a database in maintenance.
+type fakeServer struct {
+	down atomic.Bool
+}
+
+func (s *fakeServer) Connect(context.Context) (driver.Conn, error) {
+	if s.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeConn{server: s}, nil
+}
+
+func (s *fakeServer) Driver() driver.Driver {
+	return fakeDriver{server: s}
+}
+
+type fakeDriver struct {
+	server *fakeServer
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return d.server.Connect(context.Background())
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	if c.server.down.Load() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+// newSupervisedDB returns a DB whose pools all connect to server
+func newSupervisedDB(t *testing.T, server *fakeServer) *DB {
+	t.Helper()
+
+	open := func(string) (*sql.DB, error) {
+		return sql.OpenDB(server), nil
+	}
+	sqlDB, _ := open("")
+	pool := newReconnectingPool(sqlDB)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	conn := &DB{DB: db, pool: pool, dsn: "fake", open: open}
+	conn.available.Store(true)
+	return conn
+}
+
+// waitFor polls cond until it holds or the timeout runs out
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSuperviseDetectsOutageAndRecovers(t *testing.T) {
+	server := &fakeServer{}
+	db := newSupervisedDB(t, server)
+	original := db.pool.current.Load()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		db.Supervise(ctx, 10*time.Millisecond, 3)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// A healthy database stays available
+	time.Sleep(50 * time.Millisecond)
+	if !db.IsAvailable() {
+		t.Fatal("database marked unavailable while pings succeed")
+	}
+
+	server.down.Store(true)
+	waitFor(t, 2*time.Second, "the outage to be detected", func() bool { return !db.IsAvailable() })
+	if err := db.HealthCheck(); err == nil {
+		t.Error("HealthCheck succeeded during the outage")
+	}
+
+	// Reconnect attempts back off while the server is down, then the pool is rebuilt
+	server.down.Store(false)
+	waitFor(t, 3*reconnectInitialBackoff, "the connection to be restored", db.IsAvailable)
+	if db.pool.current.Load() == original {
+		t.Error("connection pool was not rebuilt")
+	}
+	if err := original.Ping(); err == nil {
+		t.Error("old connection pool is still open")
+	}
+	if err := db.HealthCheck(); err != nil {
+		t.Errorf("HealthCheck after recovery: %v", err)
+	}
+}
+
+func TestSuperviseToleratesBlips(t *testing.T) {
+	server := &fakeServer{}
+	db := newSupervisedDB(t, server)
+	original := db.pool.current.Load()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		db.Supervise(ctx, 20*time.Millisecond, 1000)
+		close(done)
+	}()
+
+	// Failed pings below the threshold don't take the database offline
+	server.down.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	server.down.Store(false)
+	cancel()
+	<-done
+
+	if !db.IsAvailable() {
+		t.Error("database marked unavailable below the failure threshold")
+	}
+	if db.pool.current.Load() != original {
+		t.Error("connection pool was rebuilt below the failure threshold")
+	}
+}
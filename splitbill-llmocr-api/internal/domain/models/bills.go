@@ -1,148 +1,886 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// Bills represents the bills table
+// Bills represents the bills table.
+// PricesIncludeTax and PricesIncludeService mark receipts whose item prices already
+// contain the printed tax or service charge (the tip amount). UserID is the
+// account that created the bill and stays nil for bills created by guests. ShareToken
+// grants read-only access through /api/shared/:token. CallbackURL is stored encrypted
+// and is notified when extraction completes or fails. CreatorIP is the address a guest
+// created the bill from, kept to recognize repeated submits.
 type Bills struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name      string         `json:"name" gorm:"size:255"`
-	Status    string         `json:"status" gorm:"size:20;not null;default:'active'"`
-	TaxAmount float64        `json:"tax_amount" gorm:"type:numeric(10,2);default:0.00"`
-	TipAmount float64        `json:"tip_amount" gorm:"type:numeric(10,2);default:0.00"`
-	CreatedAt time.Time      `json:"created_at" gorm:"not null;default:now()"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                   uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name                 string         `json:"name" gorm:"size:255"`
+	UserID               *uint          `json:"user_id" gorm:"index"`
+	ShareToken           *string        `json:"share_token" gorm:"size:64;uniqueIndex"`
+	Status               string         `json:"status" gorm:"size:20;not null;default:'active';index:idx_bills_status_updated_at,priority:1"`
+	TaxAmount            float64        `json:"tax_amount" gorm:"type:numeric(10,2);default:0.00"`
+	TipAmount            float64        `json:"tip_amount" gorm:"type:numeric(10,2);default:0.00"`
+	ServiceChargeAmount  float64        `json:"service_charge_amount" gorm:"type:numeric(10,2);not null;default:0.00"`
+	DiscountAmount       float64        `json:"discount_amount" gorm:"type:numeric(10,2);not null;default:0.00"`
+	Currency             string         `json:"currency" gorm:"size:3;not null;default:''"`
+	ItemSubtotal         float64        `json:"item_subtotal" gorm:"type:numeric(10,2);not null;default:0.00"`
+	GrandTotal           float64        `json:"grand_total" gorm:"type:numeric(10,2);not null;default:0.00"`
+	RoundingMode         string         `json:"rounding_mode" gorm:"size:20;not null;default:'largest_remainder'"`
+	SplitMode            string         `json:"split_mode" gorm:"size:20;not null;default:'itemized'"`
+	PayerParticipantID   *uint          `json:"payer_participant_id"`
+	PricesIncludeTax     bool           `json:"prices_include_tax" gorm:"not null;default:false"`
+	PricesIncludeService bool           `json:"prices_include_service" gorm:"not null;default:false"`
+	Notes                string         `json:"notes" gorm:"type:text;not null;default:''"`
+	Metadata             Metadata       `json:"metadata" gorm:"type:jsonb;not null;default:'{}'"`
+	SuggestedTipPercent  *float64       `json:"suggested_tip_percent" gorm:"type:numeric(5,2)"`
+	SuggestedTaxPercent  *float64       `json:"suggested_tax_percent" gorm:"type:numeric(5,2)"`
+	ImagePath            string         `json:"-" gorm:"size:512"`
+	ImageHash            string         `json:"-" gorm:"size:64;index"`
+	CallbackURL          string         `json:"-" gorm:"type:text;not null;default:''"`
+	CreatorIP            string         `json:"-" gorm:"size:45;not null;default:''"`
+	CreatedAt            time.Time      `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt            time.Time      `json:"updated_at" gorm:"index:idx_bills_status_updated_at,priority:2"`
+	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Items        []Items        `json:"items,omitempty" gorm:"foreignKey:BillID"`
 	Participants []Participants `json:"participants,omitempty" gorm:"foreignKey:BillID"`
+	Payers       []BillPayers   `json:"payers,omitempty" gorm:"foreignKey:BillID"`
 }
 
-// Items represents the items table
+// Version returns the bill's row version, derived from updated_at at the microsecond
+// precision the database stores
+func (b *Bills) Version() int64 {
+	return b.UpdatedAt.UnixMicro()
+}
+
+// Items represents the items table.
+// RawName keeps the name exactly as extraction returned it, before normalization.
+// Quantity may be fractional, such as 0.46 for kilograms of salmon, to three decimals.
+// DiscountAmount is a promotion on that one line, taken off price times quantity.
+// Category is a free-form label such as "drinks"; nil when the item has none.
+// NeedsReview marks an extracted item whose price may be a line total rather than a unit
+// price; it is cleared once its price or quantity is edited.
+// Source is "manual" or "ocr". Extracted items start unconfirmed, as a draft of the
+// receipt, and the bill can't be finalized until every item is confirmed.
+// Deleted items are soft-deleted so a mistaken delete can be restored for a while.
 type Items struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	BillID    uuid.UUID `json:"bill_id" gorm:"type:uuid;not null"`
-	Name      string    `json:"name" gorm:"size:255;not null"`
-	Price     float64   `json:"price" gorm:"type:numeric(10,2);not null"`
-	Quantity  int       `json:"quantity" gorm:"not null;default:1"`
-	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID             uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	BillID         uuid.UUID      `json:"bill_id" gorm:"type:uuid;not null"`
+	Name           string         `json:"name" gorm:"size:255;not null"`
+	RawName        string         `json:"raw_name" gorm:"type:text;not null;default:''"`
+	Price          float64        `json:"price" gorm:"type:numeric(10,2);not null"`
+	Quantity       float64        `json:"quantity" gorm:"type:numeric(12,3);not null;default:1"`
+	DiscountAmount float64        `json:"discount_amount" gorm:"type:numeric(10,2);not null;default:0"`
+	Category       *string        `json:"category" gorm:"size:64"`
+	NeedsReview    bool           `json:"needs_review" gorm:"not null;default:false"`
+	Source         string         `json:"source" gorm:"size:20;not null;default:'manual'"`
+	Confirmed      bool           `json:"confirmed" gorm:"not null;default:true"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Bill            Bills             `json:"bill,omitempty" gorm:"foreignKey:BillID"`
 	ItemAssignments []ItemAssignments `json:"item_assignments,omitempty" gorm:"foreignKey:ItemID"`
 }
 
-// Participants represents the participants table
+// Version returns the item's row version, derived from updated_at at the microsecond
+// precision the database stores
+func (i *Items) Version() int64 {
+	return i.UpdatedAt.UnixMicro()
+}
+
+// Participants represents the participants table. Notes and Tags are for the bill's
+// editors, such as dietary needs or how someone pays, and are left out of share links.
 type Participants struct {
-	ID                 uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	BillID             uuid.UUID `json:"bill_id" gorm:"type:uuid;not null"`
-	Name               string    `json:"name" gorm:"size:255;not null"`
-	PaymentStatus      string    `json:"payment_status" gorm:"size:20;not null;default:'unpaid'"`
-	ShareOfCommonCosts float64   `json:"share_of_common_costs" gorm:"type:numeric(10,2);default:0.00"`
-	CreatedAt          time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt          time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID                 uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	BillID             uuid.UUID      `json:"bill_id" gorm:"type:uuid;not null"`
+	Name               string         `json:"name" gorm:"size:255;not null"`
+	UserID             *uint          `json:"user_id" gorm:"index"`
+	Email              string         `json:"email" gorm:"size:255;not null;default:''"`
+	ClaimedAt          *time.Time     `json:"claimed_at"`
+	PaymentStatus      string         `json:"payment_status" gorm:"size:20;not null;default:'unpaid'"`
+	AmountPaid         float64        `json:"amount_paid" gorm:"type:numeric(10,2);not null;default:0"`
+	PaidAt             *time.Time     `json:"paid_at"`
+	ShareOfCommonCosts float64        `json:"share_of_common_costs" gorm:"type:numeric(10,2);default:0.00"`
+	ManualAmount       *float64       `json:"manual_amount" gorm:"type:numeric(10,2)"`
+	Notes              string         `json:"notes" gorm:"type:text;not null;default:''"`
+	Tags               Tags           `json:"tags" gorm:"type:jsonb;not null;default:'[]'"`
+	Color              string         `json:"color" gorm:"size:7;not null;default:''"`
+	Position           int            `json:"position" gorm:"not null;default:0;index"`
+	CreatedAt          time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt          time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Bill            Bills             `json:"bill,omitempty" gorm:"foreignKey:BillID"`
 	ItemAssignments []ItemAssignments `json:"item_assignments,omitempty" gorm:"foreignKey:ParticipantID"`
 }
 
-// ItemAssignments represents the item_assignments table (join table)
+// Version returns the participant's row version, derived from updated_at at the
+// microsecond precision the database stores
+func (p *Participants) Version() int64 {
+	return p.UpdatedAt.UnixMicro()
+}
+
+// FormatVersion renders a row version the way ETag and If-Match headers carry it
+func FormatVersion(version int64) string {
+	return strconv.FormatInt(version, 36)
+}
+
+// ItemAssignments represents the item_assignments table (join table). Assignments are
+// soft-deleted with their item, so restoring the item brings them back; deleting a
+// participant removes theirs for good.
 type ItemAssignments struct {
-	ItemID        uint      `json:"item_id" gorm:"primaryKey"`
-	ParticipantID uint      `json:"participant_id" gorm:"primaryKey"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ItemID        uint           `json:"item_id" gorm:"primaryKey"`
+	ParticipantID uint           `json:"participant_id" gorm:"primaryKey"`
+	Weight        int            `json:"weight" gorm:"not null;default:1"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Item        Items        `json:"item,omitempty" gorm:"foreignKey:ItemID"`
 	Participant Participants `json:"participant,omitempty" gorm:"foreignKey:ParticipantID"`
 }
 
+// MaxBillNotesLength is the most characters a bill's notes may hold once trimmed
+const MaxBillNotesLength = 2000
+
 // BillRequest represents the request payload for creating/updating a bill
 type BillRequest struct {
-	Name      string  `json:"name" validate:"required,max=255"`
-	TaxAmount float64 `json:"tax_amount" validate:"gte=0"`
-	TipAmount float64 `json:"tip_amount" validate:"gte=0"`
+	Name        string   `json:"name" validate:"required,max=255"`
+	Currency    string   `json:"currency"`
+	TaxAmount   *float64 `json:"tax_amount" validate:"omitnil,gte=0"`
+	TipAmount   *float64 `json:"tip_amount" validate:"omitnil,gte=0"`
+	Notes       string   `json:"notes"`
+	Metadata    Metadata `json:"metadata" validate:"metadata"`
+	CallbackURL string   `json:"callback_url" validate:"omitempty,max=2048"`
 }
 
-// BillResponse represents the response payload for a bill
+// BillUpdateRequest represents the request payload for partially updating a bill
+type BillUpdateRequest struct {
+	Name                 *string   `json:"name" validate:"omitnil,min=1,max=255"`
+	Currency             *string   `json:"currency"`
+	TaxAmount            *float64  `json:"tax_amount" validate:"omitnil,gte=0"`
+	TipAmount            *float64  `json:"tip_amount" validate:"omitnil,gte=0"`
+	ServiceChargeAmount  *float64  `json:"service_charge_amount" validate:"omitnil,gte=0"`
+	DiscountAmount       *float64  `json:"discount_amount"`
+	RoundingMode         *string   `json:"rounding_mode" validate:"omitnil,oneof=payer_absorbs largest_remainder"`
+	PayerParticipantID   *uint     `json:"payer_participant_id" validate:"omitnil,gt=0"`
+	PricesIncludeTax     *bool     `json:"prices_include_tax"`
+	PricesIncludeService *bool     `json:"prices_include_service"`
+	Notes                *string   `json:"notes"`
+	Metadata             *Metadata `json:"metadata" validate:"omitnil,metadata"`
+	CallbackURL          *string   `json:"callback_url" validate:"omitnil,max=2048"`
+}
+
+// BillResponse represents the response payload for a bill. Defaulted names the fields a
+// new bill took from defaults and where each came from, "preferences" or "config"; it is
+// only sent when the bill is created. DuplicateOf is only sent when creating returned an
+// existing bill instead.
 type BillResponse struct {
-	ID           uuid.UUID             `json:"id"`
-	Name         string                `json:"name"`
-	Status       string                `json:"status"`
-	TaxAmount    float64               `json:"tax_amount"`
-	TipAmount    float64               `json:"tip_amount"`
-	CreatedAt    time.Time             `json:"created_at"`
-	Items        []ItemResponse        `json:"items,omitempty"`
-	Participants []ParticipantResponse `json:"participants,omitempty"`
+	ID                   uuid.UUID             `json:"id"`
+	Name                 string                `json:"name"`
+	UserID               *uint                 `json:"user_id"`
+	ShareToken           *string               `json:"share_token"`
+	Status               string                `json:"status"`
+	Currency             string                `json:"currency"`
+	TaxAmount            float64               `json:"tax_amount"`
+	TipAmount            float64               `json:"tip_amount"`
+	ServiceChargeAmount  float64               `json:"service_charge_amount"`
+	DiscountAmount       float64               `json:"discount_amount"`
+	RoundingMode         string                `json:"rounding_mode"`
+	SplitMode            string                `json:"split_mode"`
+	PayerParticipantID   *uint                 `json:"payer_participant_id"`
+	PricesIncludeTax     bool                  `json:"prices_include_tax"`
+	PricesIncludeService bool                  `json:"prices_include_service"`
+	Notes                string                `json:"notes"`
+	Metadata             Metadata              `json:"metadata"`
+	HasCallbackURL       bool                  `json:"has_callback_url"`
+	SuggestedTipPercent  *float64              `json:"suggested_tip_percent"`
+	SuggestedTaxPercent  *float64              `json:"suggested_tax_percent"`
+	Defaulted            map[string]string     `json:"defaulted,omitempty"`
+	DuplicateOf          *uuid.UUID            `json:"duplicate_of,omitempty"`
+	Version              string                `json:"version"`
+	CreatedAt            time.Time             `json:"created_at"`
+	Items                []ItemResponse        `json:"items,omitempty"`
+	Participants         []ParticipantResponse `json:"participants,omitempty"`
+}
+
+// SharedClaimRequest represents the request payload for claiming a participant through a
+// share link; Name must repeat the participant's name as a confirmation
+type SharedClaimRequest struct {
+	Name  string `json:"name" validate:"required,max=255"`
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+}
+
+// SharedJoinRequest represents the request payload for adding oneself to a bill through a
+// share link
+type SharedJoinRequest struct {
+	Name  string `json:"name" validate:"required,max=255"`
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+}
+
+// SharedBillResponse is what a share link shows: the bill and its summary
+type SharedBillResponse struct {
+	Bill    BillResponse `json:"bill"`
+	Summary BillSummary  `json:"summary"`
+}
+
+// BillListQuery represents the query parameters for listing bills.
+// Archived bills are left out unless IncludeArchived is set or Status asks for them.
+// Q matches names case-insensitively, the created_* bounds take RFC3339 timestamps, and
+// Sort defaults to created_at, newest first.
+type BillListQuery struct {
+	Page            int        `form:"page" json:"page" validate:"omitempty,gte=1"`
+	PageSize        int        `form:"page_size" json:"page_size" validate:"omitempty,gte=1,lte=100"`
+	Status          string     `form:"status" json:"status" validate:"omitempty,oneof=active queued processing completed failed archived"`
+	IncludeArchived bool       `form:"include_archived" json:"include_archived"`
+	Q               string     `form:"q" json:"q" validate:"max=255"`
+	CreatedAfter    *time.Time `form:"created_after" json:"created_after"`
+	CreatedBefore   *time.Time `form:"created_before" json:"created_before"`
+	Sort            string     `form:"sort" json:"sort" validate:"omitempty,oneof=created_at name"`
+	Order           string     `form:"order" json:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+// BillListItem is the lightweight shape of a bill in list responses
+type BillListItem struct {
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	Status           string    `json:"status"`
+	TaxAmount        float64   `json:"tax_amount"`
+	TipAmount        float64   `json:"tip_amount"`
+	ItemSubtotal     float64   `json:"item_subtotal"`
+	GrandTotal       float64   `json:"grand_total"`
+	ItemCount        int64     `json:"item_count"`
+	ParticipantCount int64     `json:"participant_count"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// BillListResponse represents one page of bills
+type BillListResponse struct {
+	Bills    []BillListItem `json:"bills"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// AdminBillResponse is the read-only support view of a bill, including soft-deleted ones,
+// with the stage timings of its latest extractions
+type AdminBillResponse struct {
+	BillResponse
+	DeletedAt         *time.Time          `json:"deleted_at"`
+	ItemAssignments   []ItemAssignments   `json:"item_assignments"`
+	ExtractionTimings []ExtractionTimings `json:"extraction_timings"`
 }
 
 // ItemRequest represents the request payload for creating/updating an item
 type ItemRequest struct {
-	Name     string  `json:"name" validate:"required,max=255"`
-	Price    float64 `json:"price" validate:"required,gt=0"`
-	Quantity int     `json:"quantity" validate:"required,gt=0"`
+	Name           string  `json:"name" validate:"required,max=255"`
+	Price          float64 `json:"price" validate:"gt=0"`
+	Quantity       float64 `json:"quantity" validate:"gte=0.001"`
+	DiscountAmount float64 `json:"discount_amount" validate:"gte=0"`
+	Category       *string `json:"category" validate:"omitnil,max=64"`
+}
+
+// ItemBatchRequest is several items added by hand in one call. It is sent as a bare JSON array.
+type ItemBatchRequest struct {
+	Items []ItemRequest `json:"items" validate:"required,min=1,max=100,dive"`
+}
+
+// ItemUpdateRequest represents the request payload for partially updating an item
+type ItemUpdateRequest struct {
+	Name           *string  `json:"name" validate:"omitnil,min=1,max=255"`
+	Price          *float64 `json:"price" validate:"omitnil,gt=0"`
+	Quantity       *float64 `json:"quantity" validate:"omitnil,gte=0.001"`
+	DiscountAmount *float64 `json:"discount_amount" validate:"omitnil,gte=0"`
+	// Category set to "" clears it
+	Category *string `json:"category" validate:"omitnil,max=64"`
+}
+
+// ItemPatchRequest is one item of a bulk update, by ID; only the fields sent are changed
+type ItemPatchRequest struct {
+	ID             uint     `json:"id" validate:"required,gt=0"`
+	Name           *string  `json:"name" validate:"omitnil,min=1,max=255"`
+	Price          *float64 `json:"price" validate:"omitnil,gt=0"`
+	Quantity       *float64 `json:"quantity" validate:"omitnil,gte=0.001"`
+	DiscountAmount *float64 `json:"discount_amount" validate:"omitnil,gte=0"`
+	// Category set to "" clears it
+	Category *string `json:"category" validate:"omitnil,max=64"`
+}
+
+// ItemPatchBatchRequest is a bulk item update, each item at most once. It is sent as a bare
+// JSON array.
+type ItemPatchBatchRequest struct {
+	Items []ItemPatchRequest `json:"items" validate:"required,min=1,max=100,unique=ID,dive"`
+}
+
+// ItemSplitPart is one line of an item split. Price is per unit, like an item's.
+type ItemSplitPart struct {
+	Quantity float64 `json:"quantity" validate:"gte=0.001"`
+	Price    float64 `json:"price" validate:"gt=0"`
+}
+
+// ItemSplitRequest splits an item into Parts lines of near-equal quantity, or into the
+// lines in Rows; exactly one of them is set. It is sent as {"parts": 2} or as a bare array
+// of rows.
+type ItemSplitRequest struct {
+	Parts int             `json:"parts" validate:"omitempty,gte=2,lte=100"`
+	Rows  []ItemSplitPart `json:"rows" validate:"omitempty,min=2,max=100,dive"`
+}
+
+// ItemConfirmRequest confirms the listed items of a bill, or with All every item on it
+type ItemConfirmRequest struct {
+	ItemIDs []uint `json:"item_ids" validate:"max=500,unique,dive,gt=0"`
+	All     bool   `json:"all"`
+}
+
+// ItemConfirmResponse is how many items a confirmation changed and how many of the bill's
+// items are still unconfirmed
+type ItemConfirmResponse struct {
+	Confirmed   int64 `json:"confirmed"`
+	Unconfirmed int64 `json:"unconfirmed"`
+}
+
+// ParticipantMergeRequest merges the source participant into the target, for two rows
+// that turn out to be the same person
+type ParticipantMergeRequest struct {
+	SourceParticipantID uint `json:"source_participant_id" validate:"required,gt=0"`
+	TargetParticipantID uint `json:"target_participant_id" validate:"required,gt=0"`
+}
+
+// ItemMergeRequest merges items of a bill into the first one listed
+type ItemMergeRequest struct {
+	ItemIDs []uint `json:"item_ids" validate:"required,min=2,max=100,unique,dive,gt=0"`
+}
+
+// DuplicateItemGroup is items of a bill with the same name and price, in ID order
+type DuplicateItemGroup struct {
+	Name  string         `json:"name"`
+	Price float64        `json:"price"`
+	Items []ItemResponse `json:"items"`
+}
+
+// ItemListQuery pages through a bill's items in ID order; Unassigned keeps only the items
+// nobody is assigned to
+type ItemListQuery struct {
+	Unassigned bool `form:"unassigned" json:"unassigned"`
+	Page       int  `form:"page" json:"page" validate:"omitempty,gte=1"`
+	PageSize   int  `form:"page_size" json:"page_size" validate:"omitempty,gte=1,lte=500"`
+}
+
+// ItemListEntry is an item with the participants assigned to it
+type ItemListEntry struct {
+	ItemResponse
+	AssignedParticipantIDs []uint `json:"assigned_participant_ids"`
+	IsUnassigned           bool   `json:"is_unassigned"`
+}
+
+// ItemListResponse is one page of a bill's items
+type ItemListResponse struct {
+	Items    []ItemListEntry `json:"items"`
+	Total    int64           `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
 }
 
 // ItemResponse represents the response payload for an item
 type ItemResponse struct {
-	ID        uint      `json:"id"`
-	BillID    uuid.UUID `json:"bill_id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	Quantity  int       `json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             uint      `json:"id"`
+	BillID         uuid.UUID `json:"bill_id"`
+	Name           string    `json:"name"`
+	RawName        string    `json:"raw_name"`
+	Price          float64   `json:"price"`
+	Quantity       float64   `json:"quantity"`
+	DiscountAmount float64   `json:"discount_amount"`
+	Category       *string   `json:"category"`
+	NeedsReview    bool      `json:"needs_review"`
+	Source         string    `json:"source"`
+	Confirmed      bool      `json:"confirmed"`
+	Version        string    `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
-// ParticipantRequest represents the request payload for creating/updating a participant
+// ParticipantRequest represents the request payload for creating a participant
 type ParticipantRequest struct {
 	Name               string  `json:"name" validate:"required,max=255"`
 	ShareOfCommonCosts float64 `json:"share_of_common_costs" validate:"gte=0"`
+	Notes              string  `json:"notes" validate:"max=500"`
+	Tags               Tags    `json:"tags" validate:"max=10,dive,required,max=32"`
+}
+
+// ParticipantUpdateRequest represents the request payload for partially updating a
+// participant. Tags replaces the whole list; an empty array clears it.
+type ParticipantUpdateRequest struct {
+	Name               *string  `json:"name" validate:"omitnil,min=1,max=255"`
+	ShareOfCommonCosts *float64 `json:"share_of_common_costs" validate:"omitnil,gte=0"`
+	PaymentStatus      *string  `json:"payment_status" validate:"omitnil,oneof=unpaid paid partial"`
+	AmountPaid         *float64 `json:"amount_paid" validate:"omitnil,gte=0"`
+	Notes              *string  `json:"notes" validate:"omitnil,max=500"`
+	Tags               Tags     `json:"tags" validate:"max=10,dive,required,max=32"`
+	Color              *string  `json:"color" validate:"omitnil,rgbcolor"`
+}
+
+// ParticipantListQuery filters a bill's participants. Q matches the name, notes or tags;
+// Tag matches one tag exactly, ignoring case.
+type ParticipantListQuery struct {
+	Q   string `form:"q" json:"q" validate:"max=255"`
+	Tag string `form:"tag" json:"tag" validate:"max=32"`
 }
 
 // ParticipantResponse represents the response payload for a participant
 type ParticipantResponse struct {
-	ID                 uint      `json:"id"`
-	BillID             uuid.UUID `json:"bill_id"`
-	Name               string    `json:"name"`
-	PaymentStatus      string    `json:"payment_status"`
-	ShareOfCommonCosts float64   `json:"share_of_common_costs"`
-	CreatedAt          time.Time `json:"created_at"`
+	ID                 uint       `json:"id"`
+	BillID             uuid.UUID  `json:"bill_id"`
+	Name               string     `json:"name"`
+	UserID             *uint      `json:"user_id"`
+	Email              string     `json:"email,omitempty"`
+	ClaimedAt          *time.Time `json:"claimed_at"`
+	PaymentStatus      string     `json:"payment_status"`
+	AmountPaid         float64    `json:"amount_paid"`
+	PaidAt             *time.Time `json:"paid_at"`
+	ShareOfCommonCosts float64    `json:"share_of_common_costs"`
+	ManualAmount       *float64   `json:"manual_amount"`
+	Notes              string     `json:"notes,omitempty"`
+	Tags               Tags       `json:"tags,omitempty"`
+	Color              string     `json:"color"`
+	Position           int        `json:"position"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// ReorderParticipantsRequest represents the full new display order of a bill's participants
+type ReorderParticipantsRequest struct {
+	ParticipantIDs []uint `json:"participant_ids" validate:"required,min=1,dive,gt=0"`
+}
+
+// ParticipantPaymentRequest represents the request payload for updating a participant's payment status
+type ParticipantPaymentRequest struct {
+	PaymentStatus string   `json:"payment_status" validate:"required,oneof=unpaid paid partial"`
+	AmountPaid    *float64 `json:"amount_paid" validate:"omitnil,gte=0"`
+}
+
+// MarkAllPaidRequest represents the request payload for marking every participant of a
+// bill paid, leaving out the participants in Except
+type MarkAllPaidRequest struct {
+	Except []uint `json:"except" validate:"max=100,dive,gt=0"`
+}
+
+// RecordPaymentRequest represents the request payload for recording what a participant has
+// paid back
+type RecordPaymentRequest struct {
+	Status     string   `json:"status" validate:"required,oneof=unpaid paid partial"`
+	AmountPaid *float64 `json:"amount_paid" validate:"omitnil,gte=0"`
+}
+
+// BulkDeleteParticipantsRequest represents the request payload for deleting several participants at once
+type BulkDeleteParticipantsRequest struct {
+	ParticipantIDs []uint `json:"participant_ids" validate:"required,min=1,max=100,dive,gt=0"`
+	AllOrNothing   bool   `json:"all_or_nothing"`
+}
+
+// BulkDeleteError describes why a single participant in a bulk request was not deleted
+type BulkDeleteError struct {
+	ParticipantID uint   `json:"participant_id"`
+	Error         string `json:"error"`
+}
+
+// BulkDeleteParticipantsResponse represents the outcome of a bulk participant deletion
+type BulkDeleteParticipantsResponse struct {
+	DeletedParticipants int64             `json:"deleted_participants"`
+	DeletedAssignments  int64             `json:"deleted_assignments"`
+	Errors              []BulkDeleteError `json:"errors"`
 }
 
 // ItemAssignmentRequest represents the request payload for assigning items to participants
 type ItemAssignmentRequest struct {
 	ItemID        uint `json:"item_id" validate:"required"`
 	ParticipantID uint `json:"participant_id" validate:"required"`
+	Weight        int  `json:"weight" validate:"omitempty,min=1,max=100"`
 }
 
 // BillSummary represents a summary of bill calculations
 type BillSummary struct {
-	BillID            uuid.UUID          `json:"bill_id"`
-	TotalItems        float64            `json:"total_items"`
-	TaxAmount         float64            `json:"tax_amount"`
-	TipAmount         float64            `json:"tip_amount"`
-	TotalBill         float64            `json:"total_bill"`
-	ParticipantShares map[string]float64 `json:"participant_shares"`
+	BillID               uuid.UUID          `json:"bill_id"`
+	Currency             string             `json:"currency"`
+	TotalItems           float64            `json:"total_items"`
+	TaxAmount            float64            `json:"tax_amount"`
+	TipAmount            float64            `json:"tip_amount"`
+	ServiceChargeAmount  float64            `json:"service_charge_amount"`
+	DiscountAmount       float64            `json:"discount_amount"`
+	TotalBill            float64            `json:"total_bill"`
+	PricesIncludeTax     bool               `json:"prices_include_tax"`
+	PricesIncludeService bool               `json:"prices_include_service"`
+	ParticipantShares    []ParticipantShare `json:"participant_shares"`
+	// Deprecated: ParticipantSharesByName is the old name-keyed shares map, sent only while
+	// SUMMARY_LEGACY_PARTICIPANT_SHARES is on. Participants with the same name collide.
+	ParticipantSharesByName map[string]float64   `json:"participant_shares_by_name,omitempty"`
+	SplitMode               string               `json:"split_mode"`
+	RoundingMode            string               `json:"rounding_mode"`
+	ResidualCents           int64                `json:"residual_cents"`
+	AbsorbedBy              []RoundingAbsorption `json:"absorbed_by"`
+	Payers                  []BillPayerResponse  `json:"payers"`
+	Settlements             []Settlement         `json:"settlements"`
+	// TotalCollected is what participants have recorded paying back; TotalOutstanding is
+	// what is still owed on their shares
+	TotalCollected   float64 `json:"total_collected"`
+	TotalOutstanding float64 `json:"total_outstanding"`
+	// HasUnconfirmedItems warns that extracted items not yet confirmed are counted in
+	// the totals, which may still change while they are corrected
+	HasUnconfirmedItems bool  `json:"has_unconfirmed_items"`
+	UnconfirmedItems    int64 `json:"unconfirmed_items"`
+	// CategoryTotals is sent with group_by=category: item totals after item discounts by
+	// lowercased category, with items without one under "uncategorized"
+	CategoryTotals map[string]float64 `json:"category_totals,omitempty"`
+}
+
+// BillSummaryQuery represents the query parameters of the bill summary
+type BillSummaryQuery struct {
+	GroupBy string `form:"group_by" json:"group_by" validate:"omitempty,oneof=category"`
+}
+
+// ParticipantShare is one participant's part of the bill, in participant order. Owed is
+// Split plus CommonCosts; Split already includes any RoundingCents they absorbed.
+type ParticipantShare struct {
+	ParticipantID uint    `json:"participant_id"`
+	Name          string  `json:"name"`
+	PaymentStatus string  `json:"payment_status"`
+	Split         float64 `json:"split"`
+	CommonCosts   float64 `json:"common_costs"`
+	RoundingCents int64   `json:"rounding_cents"`
+	Owed          float64 `json:"owed"`
+	Paid          float64 `json:"paid"`
 }
 
-// ExtractedItemData represents the structure of extracted item data from LLM
+// RoundingAbsorption records the leftover cents a participant took on when the total
+// didn't divide evenly
+type RoundingAbsorption struct {
+	ParticipantID uint   `json:"participant_id"`
+	Name          string `json:"name"`
+	Cents         int64  `json:"cents"`
+}
+
+// EditingHeartbeatRequest identifies a guest who has a bill's editing screen open.
+// Logged-in users are identified by their session instead.
+type EditingHeartbeatRequest struct {
+	ParticipantID *uint `json:"participant_id" validate:"omitnil,gt=0"`
+}
+
+// BillEditor is someone who recently sent an editing heartbeat for a bill
+type BillEditor struct {
+	UserID        *uint     `json:"user_id,omitempty"`
+	ParticipantID *uint     `json:"participant_id,omitempty"`
+	Name          string    `json:"name"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ExtractedItemData represents the structure of extracted item data from LLM. Rows
+// without a positive price and quantity are listed in Skipped instead of Items.
 type ExtractedItemData struct {
-	Items []ExtractedItem `json:"items"`
-	Tax   float64         `json:"tax"`
-	Tip   float64         `json:"tip"`
-	Total float64         `json:"total"`
+	Items                []ExtractedItem        `json:"items"`
+	Skipped              []SkippedExtractedItem `json:"skipped,omitempty"`
+	Tax                  float64                `json:"tax"`
+	Tip                  float64                `json:"tip"`
+	Total                float64                `json:"total"`
+	PricesIncludeTax     bool                   `json:"prices_include_tax"`
+	PricesIncludeService bool                   `json:"prices_include_service"`
+}
+
+// SkippedExtractedItem is an extracted row that was not stored, with the reason why.
+// Index is the row's position in the extracted items.
+type SkippedExtractedItem struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
 }
 
-// ExtractedItem represents a single item extracted from the bill
+// ExtractedItem represents a single item extracted from the bill. LineTotal is the unit
+// price times quantity as the receipt prints it, before DiscountAmount. NeedsReview is set
+// when its prices could not be told apart from the receipt.
 type ExtractedItem struct {
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity int     `json:"quantity"`
+	Name           string  `json:"name"`
+	UnitPrice      float64 `json:"unit_price"`
+	Quantity       float64 `json:"quantity"`
+	LineTotal      float64 `json:"line_total"`
+	DiscountAmount float64 `json:"discount_amount"`
+	Category       string  `json:"category,omitempty"`
+	NeedsReview    bool    `json:"needs_review,omitempty"`
+}
+
+// ExtractedItemPreview shows how an extracted item would be stored, for dry runs
+type ExtractedItemPreview struct {
+	RawName        string  `json:"raw_name"`
+	Name           string  `json:"name"`
+	Price          float64 `json:"price"`
+	Quantity       float64 `json:"quantity"`
+	LineTotal      float64 `json:"line_total"`
+	DiscountAmount float64 `json:"discount_amount"`
+	Category       string  `json:"category,omitempty"`
+	NeedsReview    bool    `json:"needs_review"`
+}
+
+// AttentionDismissals represents the attention_dismissals table. A bill stays out of its
+// owner's attention list while its current reasons still match Fingerprint.
+type AttentionDismissals struct {
+	UserID      uint      `json:"user_id" gorm:"primaryKey"`
+	BillID      uuid.UUID `json:"bill_id" gorm:"type:uuid;primaryKey"`
+	Fingerprint string    `json:"fingerprint" gorm:"type:text;not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// AttentionReason is one reason a bill needs its owner's attention
+type AttentionReason struct {
+	Code  string `json:"code"`
+	Count int64  `json:"count"`
+}
+
+// AttentionItem is a bill that needs attention, with why
+type AttentionItem struct {
+	BillID    uuid.UUID         `json:"bill_id"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Reasons   []AttentionReason `json:"reasons"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Participation is a bill on which the caller claimed a participant, with their share
+type Participation struct {
+	BillID        uuid.UUID `json:"bill_id"`
+	BillName      string    `json:"bill_name"`
+	BillStatus    string    `json:"bill_status"`
+	Currency      string    `json:"currency"`
+	ParticipantID uint      `json:"participant_id"`
+	Name          string    `json:"name"`
+	Owed          float64   `json:"owed"`
+	PaymentStatus string    `json:"payment_status"`
+	AmountPaid    float64   `json:"amount_paid"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ParticipationsResponse lists the bills the caller takes part in, newest first
+type ParticipationsResponse struct {
+	Participations []Participation `json:"participations"`
+	Total          int             `json:"total"`
+}
+
+// AttentionResponse lists the caller's bills that need attention, most urgent first
+type AttentionResponse struct {
+	Bills []AttentionItem `json:"bills"`
+	Total int             `json:"total"`
+}
+
+// BillPayers represents the bill_payers table: who paid the merchant and how much, for
+// bills that were split across more than one card
+type BillPayers struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	BillID        uuid.UUID `json:"bill_id" gorm:"type:uuid;not null;uniqueIndex:idx_bill_payers_participant"`
+	ParticipantID uint      `json:"participant_id" gorm:"not null;uniqueIndex:idx_bill_payers_participant"`
+	AmountPaid    float64   `json:"amount_paid" gorm:"type:numeric(10,2);not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// BillPayerRequest is one payer and what they paid the merchant
+type BillPayerRequest struct {
+	ParticipantID uint    `json:"participant_id" validate:"required,gt=0"`
+	AmountPaid    float64 `json:"amount_paid" validate:"gt=0"`
+}
+
+// SetBillPayersRequest replaces a bill's payers. An empty list goes back to the single
+// payer_participant_id.
+type SetBillPayersRequest struct {
+	Payers []BillPayerRequest `json:"payers" validate:"max=100,dive"`
+}
+
+// ManualShareRequest is what one participant owes on a manual bill
+type ManualShareRequest struct {
+	ParticipantID uint    `json:"participant_id" validate:"required,gt=0"`
+	Amount        float64 `json:"amount" validate:"gte=0"`
+}
+
+// SetManualSharesRequest sets what every participant owes, switching the bill to the
+// manual split mode. Participants left out owe nothing.
+type SetManualSharesRequest struct {
+	Shares []ManualShareRequest `json:"shares" validate:"required,min=1,max=100,dive"`
+}
+
+// BillPayerResponse is a participant who paid the merchant, and how much
+type BillPayerResponse struct {
+	ParticipantID uint    `json:"participant_id"`
+	Name          string  `json:"name"`
+	AmountPaid    float64 `json:"amount_paid"`
+}
+
+// Settlement is a transfer one participant owes another once the bill is settled
+type Settlement struct {
+	FromParticipantID uint    `json:"from_participant_id"`
+	FromName          string  `json:"from_name"`
+	ToParticipantID   uint    `json:"to_participant_id"`
+	ToName            string  `json:"to_name"`
+	Amount            float64 `json:"amount"`
+}
+
+// MatchedItemAssignment is an item of the bill that matched an item of the source bill,
+// with the participants it was assigned to as a result
+type MatchedItemAssignment struct {
+	ItemID         uint     `json:"item_id"`
+	SourceItemID   uint     `json:"source_item_id"`
+	Name           string   `json:"name"`
+	ParticipantIDs []uint   `json:"participant_ids"`
+	Participants   []string `json:"participants"`
+}
+
+// UnmatchedItem is an item of the bill with no counterpart on the source bill
+type UnmatchedItem struct {
+	ItemID uint   `json:"item_id"`
+	Name   string `json:"name"`
+}
+
+// ApplyAssignmentsReport describes what copying assignments from an earlier bill did.
+// UnmatchedParticipants are names assigned on the source bill that aren't on this bill.
+type ApplyAssignmentsReport struct {
+	SourceBillID          uuid.UUID               `json:"source_bill_id"`
+	Matched               []MatchedItemAssignment `json:"matched"`
+	UnmatchedItems        []UnmatchedItem         `json:"unmatched_items"`
+	UnmatchedParticipants []string                `json:"unmatched_participants"`
+	AssignmentsCreated    int64                   `json:"assignments_created"`
+}
+
+// BillEvents represents the bill_events table, the activity log of a bill. Actor is
+// "user:<id>" for logged-in users, "guest" for anonymous callers and "system" or
+// "extraction" for changes the server makes on its own.
+type BillEvents struct {
+	ID        uint         `json:"id" gorm:"primaryKey;autoIncrement"`
+	BillID    uuid.UUID    `json:"bill_id" gorm:"type:uuid;not null;index:idx_bill_events_bill_created"`
+	Actor     string       `json:"actor" gorm:"size:64;not null"`
+	Action    string       `json:"action" gorm:"size:50;not null"`
+	Payload   EventPayload `json:"payload" gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt time.Time    `json:"created_at" gorm:"not null;default:now();index:idx_bill_events_bill_created"`
+}
+
+// BillEventListQuery represents the query parameters for listing a bill's events
+type BillEventListQuery struct {
+	Page     int `form:"page" json:"page" validate:"omitempty,gte=1"`
+	PageSize int `form:"page_size" json:"page_size" validate:"omitempty,gte=1,lte=100"`
+}
+
+// BillEventListResponse represents one page of a bill's events
+type BillEventListResponse struct {
+	Events   []BillEvents `json:"events"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
+
+// WebhookDeliveries represents the webhook_deliveries table, one row per attempt to call an
+// outgoing webhook. StatusCode is zero when no response came back.
+type WebhookDeliveries struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	BillID     uuid.UUID `json:"bill_id" gorm:"type:uuid;not null;index"`
+	Target     string    `json:"target" gorm:"not null;size:50"`
+	Attempt    int       `json:"attempt" gorm:"not null"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty" gorm:"size:500"`
+	Delivered  bool      `json:"delivered" gorm:"not null;default:false"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// ExtractionTimings represents the extraction_timings table, one row per image sent through
+// extraction. Outcome is processing until the callback arrives, then completed or failed;
+// FailedStage names the stage a failed attempt stopped in.
+type ExtractionTimings struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	BillID      uuid.UUID    `json:"bill_id" gorm:"type:uuid;not null;index"`
+	Stages      StageTimings `json:"stages_ms" gorm:"type:jsonb;not null;default:'{}'"`
+	Outcome     string       `json:"outcome" gorm:"size:20;not null"`
+	FailedStage string       `json:"failed_stage,omitempty" gorm:"size:32;not null;default:''"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// ExtractionStatsQuery selects the extractions started within Since, a duration such as "24h"
+type ExtractionStatsQuery struct {
+	Since string `form:"since" json:"since"`
+}
+
+// ExtractionStageStats are the percentiles of one stage's duration, in milliseconds
+type ExtractionStageStats struct {
+	Stage string `json:"stage"`
+	Count int    `json:"count"`
+	P50Ms int64  `json:"p50_ms"`
+	P90Ms int64  `json:"p90_ms"`
+	P99Ms int64  `json:"p99_ms"`
+	MaxMs int64  `json:"max_ms"`
+}
+
+// ExtractionStatsResponse breaks recent extractions down by stage, in pipeline order
+type ExtractionStatsResponse struct {
+	Since    time.Time              `json:"since"`
+	Attempts int                    `json:"attempts"`
+	Outcomes map[string]int         `json:"outcomes"`
+	Stages   []ExtractionStageStats `json:"stages"`
+}
+
+// WebhookDeliveryListQuery represents the query parameters for listing webhook deliveries
+type WebhookDeliveryListQuery struct {
+	BillID   string `form:"bill_id" json:"bill_id" validate:"omitempty,uuid"`
+	Page     int    `form:"page" json:"page" validate:"omitempty,gte=1"`
+	PageSize int    `form:"page_size" json:"page_size" validate:"omitempty,gte=1,lte=100"`
+}
+
+// AdminBillListQuery selects bills that have sat in one status for at least OlderThan,
+// a duration such as "10m"
+type AdminBillListQuery struct {
+	Status    string `form:"status" json:"status" validate:"required,oneof=active queued processing completed failed archived"`
+	OlderThan string `form:"older_than" json:"older_than"`
+	Limit     int    `form:"limit" json:"limit" validate:"omitempty,gte=1,lte=500"`
+}
+
+// AdminBillListItem is a bill in the admin status list
+type AdminBillListItem struct {
+	ID        uuid.UUID `json:"id"`
+	Status    string    `json:"status"`
+	HasImage  bool      `json:"has_image"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AdminBillListResponse lists bills by status, the longest unchanged first
+type AdminBillListResponse struct {
+	Bills []AdminBillListItem `json:"bills"`
+	Count int                 `json:"count"`
+}
+
+// AbandonedBill is an empty bill the abandoned bill cleanup deletes
+type AbandonedBill struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	UserID    *uint     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AbandonedBillsResponse lists the bills the next cleanup would delete, those untouched
+// since before Cutoff first
+type AbandonedBillsResponse struct {
+	Bills  []AbandonedBill `json:"bills"`
+	Count  int             `json:"count"`
+	Cutoff time.Time       `json:"cutoff"`
+}
+
+// ForceStatusRequest is the status an admin moves a bill to, bypassing the usual transitions
+type ForceStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=active completed failed"`
+}
+
+// WebhookDeliveryListResponse represents one page of webhook deliveries
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveries `json:"deliveries"`
+	Total      int64               `json:"total"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"page_size"`
 }
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxMetadataBytes caps the JSON-encoded size of a bill's metadata
+const MaxMetadataBytes = 4096
+
+// Metadata holds integrator-defined key/value strings. It is stored as JSONB and
+// never interpreted by the application.
+type Metadata map[string]string
+
+// Value implements driver.Valuer so Metadata can be written to a jsonb column
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so Metadata can be read from a jsonb column
+func (m *Metadata) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = Metadata{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Metadata", value)
+	}
+	return json.Unmarshal(data, m)
+}
+
+// Size returns the JSON-encoded size of the metadata in bytes
+func (m Metadata) Size() int {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// Tags is a short list of free-form labels, such as "vegetarian" on a participant. It is
+// stored as a JSONB array.
+type Tags []string
+
+// Value implements driver.Valuer so Tags can be written to a jsonb column
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so Tags can be read from a jsonb column
+func (t *Tags) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = Tags{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", value)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// EventPayload holds the details of a bill event. It is stored as JSONB.
+type EventPayload map[string]interface{}
+
+// Value implements driver.Valuer so EventPayload can be written to a jsonb column
+func (p EventPayload) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so EventPayload can be read from a jsonb column
+func (p *EventPayload) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = EventPayload{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into EventPayload", value)
+	}
+	return json.Unmarshal(data, p)
+}
+
+// StageTimings holds how long each extraction stage took, in milliseconds, by stage name.
+// It is stored as a JSONB object.
+type StageTimings map[string]int64
+
+// Value implements driver.Valuer so StageTimings can be written to a jsonb column
+func (t StageTimings) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner so StageTimings can be read from a jsonb column
+func (t *StageTimings) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = StageTimings{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StageTimings", value)
+	}
+	return json.Unmarshal(data, t)
+}
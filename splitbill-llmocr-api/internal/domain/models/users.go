@@ -18,6 +18,30 @@ type Users struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 	IsDeleted bool           `json:"is_deleted" gorm:"default:false"`
+	// TokenVersion is signed into every token; bumping it revokes all of them at once
+	TokenVersion int `json:"-" gorm:"not null;default:0"`
+}
+
+// UserPreferences holds a user's defaults for the bills they create. Nil percentages and
+// empty strings mean no preference. CleanupEmptyBills lets the abandoned bill cleanup
+// delete the user's own empty bills, which it otherwise leaves alone.
+type UserPreferences struct {
+	UserID            uint      `json:"-" gorm:"primaryKey"`
+	DefaultTipPercent *float64  `json:"default_tip_percent" gorm:"type:numeric(5,2)"`
+	DefaultTaxPercent *float64  `json:"default_tax_percent" gorm:"type:numeric(5,2)"`
+	DefaultCurrency   string    `json:"default_currency" gorm:"size:3;not null;default:''"`
+	Locale            string    `json:"locale" gorm:"size:35;not null;default:''"`
+	CleanupEmptyBills bool      `json:"cleanup_empty_bills" gorm:"not null;default:false"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// UserPreferencesRequest replaces a user's preferences; omitted fields are cleared
+type UserPreferencesRequest struct {
+	DefaultTipPercent *float64 `json:"default_tip_percent" validate:"omitnil,gte=0,lte=100"`
+	DefaultTaxPercent *float64 `json:"default_tax_percent" validate:"omitnil,gte=0,lte=100"`
+	DefaultCurrency   string   `json:"default_currency"`
+	Locale            string   `json:"locale" validate:"omitempty,max=35,bcp47_language_tag"`
+	CleanupEmptyBills bool     `json:"cleanup_empty_bills"`
 }
 
 // RegisterRequest represents the registration request payload
@@ -28,13 +52,15 @@ type RegisterRequest struct {
 	Name     string `json:"name" validate:"required,max=100"`
 }
 
-// RegisterResponse represents the registration response payload
+// RegisterResponse represents the registration response payload. The auth middleware also
+// caches it per user, with the TokenVersion tokens must carry.
 type RegisterResponse struct {
-	ID       uint   `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Name     string `json:"name"`
-	Role     string `json:"role"`
+	ID           uint   `json:"id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	Role         string `json:"role"`
+	TokenVersion int    `json:"-"`
 }
 
 // LoginRequest represents the login request payload
@@ -57,11 +83,34 @@ type LoginResponse struct {
 	Token TokenResponse    `json:"token"`
 }
 
-// Claims represents the JWT claims
+// Claims represents the JWT claims. ImpersonatorID is set on read-only support tokens
+// and names the admin acting as UserID.
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
+	UserID         uint   `json:"user_id"`
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	ImpersonatorID uint   `json:"impersonator_id,omitempty"`
+	TokenVersion   int    `json:"token_version"`
 	jwt.RegisteredClaims
 }
+
+// ImpersonationResponse carries a read-only token that acts as another user
+type ImpersonationResponse struct {
+	User      RegisterResponse `json:"user"`
+	Token     string           `json:"token"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// AuditLogs represents the audit_logs table. ActorID is who really acted; SubjectID is
+// the user they acted as, which differs only while impersonating.
+type AuditLogs struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ActorID   uint      `json:"actor_id" gorm:"not null;index"`
+	SubjectID uint      `json:"subject_id" gorm:"not null;index"`
+	Action    string    `json:"action" gorm:"not null;size:50"`
+	Method    string    `json:"method" gorm:"size:10"`
+	Path      string    `json:"path" gorm:"size:2048"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
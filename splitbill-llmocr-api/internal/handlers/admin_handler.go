@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/handlers/internal/bind"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	billService *services.BillService
+	userService *services.UserService
+	config      *config.Config
+}
+
+func NewAdminHandler(billService *services.BillService, userService *services.UserService, config *config.Config) *AdminHandler {
+	return &AdminHandler{
+		billService: billService,
+		userService: userService,
+		config:      config,
+	}
+}
+
+// GetConfig handles showing the configuration the server runs with, secrets redacted
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.config.Redacted())
+}
+
+// GetBill handles the read-only support view of a bill, including soft-deleted bills
+func (h *AdminHandler) GetBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	bill, err := h.billService.GetBillForAdmin(billID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// ListBills handles listing bills that have sat in one status for a while, such as bills
+// stuck in processing
+func (h *AdminHandler) ListBills(c *gin.Context) {
+	var query models.AdminBillListQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = 100
+	}
+
+	var olderThan time.Duration
+	if query.OlderThan != "" {
+		parsed, err := time.ParseDuration(query.OlderThan)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a non-negative duration such as 10m"})
+			return
+		}
+		olderThan = parsed
+	}
+
+	bills, err := h.billService.ListBillsByStatus(services.BillStatus(query.Status), olderThan, time.Now(), query.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list bills: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, bills)
+}
+
+// GetExtractionStats handles the per-stage latency percentiles of recent extractions.
+// since defaults to 24h.
+func (h *AdminHandler) GetExtractionStats(c *gin.Context) {
+	var query models.ExtractionStatsQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+
+	since := 24 * time.Hour
+	if query.Since != "" {
+		parsed, err := time.ParseDuration(query.Since)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a positive duration such as 24h"})
+			return
+		}
+		since = parsed
+	}
+
+	stats, err := h.billService.ExtractionStats(time.Now().Add(-since))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to compute extraction stats: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ListAbandonedBills handles previewing which empty bills the abandoned bill cleanup would
+// delete, without deleting them. older_than overrides the configured age.
+func (h *AdminHandler) ListAbandonedBills(c *gin.Context) {
+	age := h.billService.AbandonedBillAge()
+	if olderThan := c.Query("older_than"); olderThan != "" {
+		parsed, err := time.ParseDuration(olderThan)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a positive duration such as 168h"})
+			return
+		}
+		age = parsed
+	}
+	if age <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Abandoned bill cleanup is disabled; pass older_than to preview"})
+		return
+	}
+
+	bills, err := h.billService.FindAbandonedBills(time.Now(), age)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list abandoned bills: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, bills)
+}
+
+// ForceBillStatus handles moving a bill to a status outside the usual transitions
+func (h *AdminHandler) ForceBillStatus(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ForceStatusRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	admin := currentUser(c)
+	if admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	bill, err := h.billService.ForceBillStatus(billID, services.BillStatus(req.Status), services.AdminActor(admin.ID))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update bill status: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// Impersonate handles issuing a read-only token that lets support see what a user sees
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	userID, ok := bind.UintParam(c, "userId")
+	if !ok {
+		return
+	}
+
+	admin := currentUser(c)
+	if admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	response, err := h.userService.Impersonate(admin.ID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else if errors.Is(err, services.ErrCannotImpersonate) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admins and disabled users cannot be impersonated"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to impersonate user: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListWebhookDeliveries handles listing outgoing webhook attempts, optionally for one bill
+func (h *AdminHandler) ListWebhookDeliveries(c *gin.Context) {
+	var query models.WebhookDeliveryListQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	var billID *uuid.UUID
+	if query.BillID != "" {
+		parsed := uuid.MustParse(query.BillID)
+		billID = &parsed
+	}
+
+	deliveries, err := h.billService.ListWebhookDeliveries(billID, query.Page, query.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list webhook deliveries: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
@@ -1,25 +1,24 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/handlers/internal/bind"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type AuthHandler struct {
 	userService *services.UserService
-	validate    *validator.Validate
 }
 
 func NewAuthHandler(userService *services.UserService) *AuthHandler {
 	return &AuthHandler{
 		userService: userService,
-		validate:    validator.New(),
 	}
 }
 
@@ -27,14 +26,7 @@ func NewAuthHandler(userService *services.UserService) *AuthHandler {
 func (h *AuthHandler) Register(c *gin.Context) {
 	// Using Gin's context
 	var req models.RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
-
-	// Validate request
-	if err := h.validate.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+	if !bind.JSON(c, &req) {
 		return
 	}
 
@@ -58,14 +50,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
-
-	// Validate request
-	if err := h.validate.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed: " + err.Error()})
+	if !bind.JSON(c, &req) {
 		return
 	}
 
@@ -110,6 +95,42 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
+	// Drop the cached user so the next authenticated request re-reads it
+	if user, exists := c.Get("user"); exists {
+		if userResponse, ok := user.(models.RegisterResponse); ok {
+			h.userService.InvalidateUser(userResponse.ID)
+		}
+	}
+
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll handles signing the caller out on every device by revoking all of their tokens
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := h.userService.RevokeSessions(*userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to sign out everywhere: %v", err)})
+		return
+	}
+
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out on every device",
+	})
+}
+
+// clearAuthCookies removes the access and refresh token cookies
+func clearAuthCookies(c *gin.Context) {
 	// Clear access token cookie by setting it to expire immediately
 	c.SetCookie(
 		"access_token",
@@ -131,10 +152,6 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		false, // secure (set to false for development)
 		true,  // httpOnly
 	)
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Logged out successfully",
-	})
 }
 
 func (h *AuthHandler) GetMe(c *gin.Context) {
@@ -146,3 +163,49 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// GetPreferences handles reading the authenticated user's bill defaults
+func (h *AuthHandler) GetPreferences(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	prefs, err := h.userService.GetPreferences(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to load preferences: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences handles replacing the authenticated user's bill defaults
+func (h *AuthHandler) UpdatePreferences(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UserPreferencesRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+	if req.DefaultCurrency != "" {
+		code, ok := bindCurrency(c, req.DefaultCurrency)
+		if !ok {
+			return
+		}
+		req.DefaultCurrency = code
+	}
+
+	prefs, err := h.userService.UpdatePreferences(user.ID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save preferences: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
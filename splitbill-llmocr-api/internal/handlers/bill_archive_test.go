@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestArchivedBillRejectsPayments(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.PATCH("/api/bills/:id/participants/:participantId/payment", handler.UpdateParticipantPayment)
+	router.POST("/api/bills/:id/participants/:participantId/payment", handler.RecordParticipantPayment)
+
+	s := handler.billService
+	bill, err := s.CreateBill(&models.BillRequest{Name: "Ramen"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ana"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	if _, err := s.ArchiveBill(bill.ID, "test"); err != nil {
+		t.Fatalf("failed to archive bill: %v", err)
+	}
+
+	path := "/api/bills/" + bill.ID.String() + "/participants/" + strconv.FormatUint(uint64(participant.ID), 10) + "/payment"
+	ifMatch := map[string]string{"If-Match": versionETag(participant.Version())}
+	if w := performJSON(t, router, http.MethodPatch, path, map[string]string{"payment_status": "paid"}, ifMatch); w.Code != http.StatusConflict {
+		t.Errorf("PATCH on an archived bill: status %d, want 409: %s", w.Code, w.Body)
+	}
+	if w := performJSON(t, router, http.MethodPost, path, map[string]string{"status": "paid"}, ifMatch); w.Code != http.StatusConflict {
+		t.Errorf("POST on an archived bill: status %d, want 409: %s", w.Code, w.Body)
+	}
+
+	var stored models.Participants
+	if err := db.First(&stored, participant.ID).Error; err != nil {
+		t.Fatalf("failed to load participant: %v", err)
+	}
+	if stored.PaymentStatus != "unpaid" || stored.Version() != participant.Version() {
+		t.Errorf("participant changed on an archived bill: status %s", stored.PaymentStatus)
+	}
+
+	// Once unarchived the same payment goes through
+	if _, err := s.UnarchiveBill(bill.ID, "test"); err != nil {
+		t.Fatalf("failed to unarchive bill: %v", err)
+	}
+	if w := performJSON(t, router, http.MethodPatch, path, map[string]string{"payment_status": "paid"}, ifMatch); w.Code != http.StatusOK {
+		t.Errorf("PATCH on an unarchived bill: status %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateBillDuplicateResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		query  string
+		status int
+	}{
+		{name: "return mode", mode: config.DuplicateBillReturn, status: http.StatusOK},
+		{name: "reject mode", mode: config.DuplicateBillReject, status: http.StatusConflict},
+		{name: "forced", mode: config.DuplicateBillReject, query: "?force=true", status: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestBillHandler(t, func(cfg *config.Config) {
+				cfg.DuplicateBillWindow = 2 * time.Minute
+				cfg.DuplicateBillMode = tt.mode
+			})
+			router := gin.New()
+			router.POST("/api/bills/", handler.CreateBill)
+
+			body := map[string]string{"name": "Team dinner"}
+			first := performJSON(t, router, http.MethodPost, "/api/bills/", body, nil)
+			if first.Code != http.StatusCreated {
+				t.Fatalf("first create: status %d: %s", first.Code, first.Body)
+			}
+			var created struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(first.Body.Bytes(), &created); err != nil {
+				t.Fatalf("failed to decode bill: %v", err)
+			}
+
+			repeat := performJSON(t, router, http.MethodPost, "/api/bills/"+tt.query, body, nil)
+			if repeat.Code != tt.status {
+				t.Fatalf("repeat create: status %d, want %d: %s", repeat.Code, tt.status, repeat.Body)
+			}
+			var response struct {
+				ID          string `json:"id"`
+				DuplicateOf string `json:"duplicate_of"`
+			}
+			if err := json.Unmarshal(repeat.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			switch tt.status {
+			case http.StatusCreated:
+				if response.DuplicateOf != "" || response.ID == created.ID {
+					t.Errorf("forced create returned %+v, want a new bill", response)
+				}
+			default:
+				if response.DuplicateOf != created.ID {
+					t.Errorf("duplicate_of = %q, want %q", response.DuplicateOf, created.ID)
+				}
+			}
+		})
+	}
+}
@@ -1,19 +1,21 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
-	"strings"
+	"time"
 
 	"errors"
 
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/handlers/internal/bind"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type BillHandler struct {
@@ -24,617 +26,1899 @@ func NewBillHandler(billService *services.BillService) *BillHandler {
 	return &BillHandler{billService: billService}
 }
 
-// CreateBill handles bill creation
+// requireBill writes a 404 and returns false when the bill does not exist or was deleted
+func (h *BillHandler) requireBill(c *gin.Context, billID uuid.UUID) bool {
+	if err := h.billService.BillExists(billID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find bill: %v", err)})
+		}
+		return false
+	}
+	return true
+}
+
+// requireEditableBill writes a 404 for missing bills and a 409 for archived ones, and
+// returns false in both cases. Who may change a bill is checked by middleware.Authorize.
+func (h *BillHandler) requireEditableBill(c *gin.Context, billID uuid.UUID) bool {
+	if err := h.billService.EnsureBillEditable(billID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrArchived) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Bill is archived; unarchive it before making changes"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find bill: %v", err)})
+		}
+		return false
+	}
+	return true
+}
+
+// CreateBill handles bill creation. ?force=true creates the bill even when it repeats one
+// created moments ago.
 func (h *BillHandler) CreateBill(c *gin.Context) {
 	var req models.BillRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+	if !bind.LocalizeNumbers(c) || !bind.JSON(c, &req) {
+		return
+	}
+	if req.Currency != "" {
+		code, ok := bindCurrency(c, req.Currency)
+		if !ok {
+			return
+		}
+		req.Currency = code
+	}
+
+	bill, err := h.billService.CreateBill(&req, currentUserID(c), c.ClientIP(), c.Query("force") == "true")
+	if err != nil {
+		var duplicateErr *services.DuplicateBillError
+		if errors.As(err, &duplicateErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":        "A bill with this name was just created; send force=true to create another",
+				"duplicate_of": duplicateErr.BillID,
+			})
+		} else if !writeBillInputError(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create bill: %v", err)})
+		}
+		return
+	}
+
+	// A repeat of a bill created moments ago returns that bill instead
+	if bill.DuplicateOf != nil {
+		c.JSON(http.StatusOK, bill)
+		return
+	}
+	c.JSON(http.StatusCreated, bill)
+}
+
+// ListBills handles listing the authenticated user's bills page by page, optionally
+// filtered by status. Archived bills are only listed on request.
+func (h *BillHandler) ListBills(c *gin.Context) {
+	h.ListMyBills(c)
+}
+
+// ListMyBills handles listing the bills created by the authenticated user
+func (h *BillHandler) ListMyBills(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	h.listBills(c, *userID)
+}
+
+// GetAttention handles listing the authenticated user's bills that need attention.
+// The list drives a badge count, so clients may cache it for 30 seconds.
+func (h *BillHandler) GetAttention(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	attention, err := h.billService.Attention(*userID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find bills needing attention: %v", err)})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=30")
+	c.JSON(http.StatusOK, attention)
+}
+
+// DismissAttention handles hiding a bill from the user's attention list until its reasons change
+func (h *BillHandler) DismissAttention(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID, ok := bind.UUIDParam(c, "billId")
+	if !ok {
+		return
+	}
+
+	if err := h.billService.DismissAttention(*userID, billID, time.Now()); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill does not need attention"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to dismiss bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bill dismissed"})
+}
+
+// listBills binds the list query and writes one page of the bills owned by userID
+func (h *BillHandler) listBills(c *gin.Context, userID uint) {
+	var query models.BillListQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+	if query.CreatedAfter != nil && query.CreatedBefore != nil && !query.CreatedBefore.After(*query.CreatedAfter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "created_before must be after created_after"})
+		return
+	}
+
+	bills, err := h.billService.ListBills(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list bills: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, bills)
+}
+
+// bindCurrency normalizes a currency code from a request body, answering 400 with the
+// accepted codes when it is not one of them
+func bindCurrency(c *gin.Context, code string) (string, bool) {
+	code = currency.Normalize(code)
+	if !currency.Known(code) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    fmt.Sprintf("Unsupported currency %q", code),
+			"accepted": currency.Codes(),
+		})
+		return "", false
+	}
+	return code, true
+}
+
+// writeBillInputError answers 400 for a callback_url or notes the service refused and
+// reports whether it wrote a response
+func writeBillInputError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrInvalidCallbackURL) || errors.Is(err, services.ErrCallbacksUnavailable) ||
+		errors.Is(err, services.ErrNotesTooLong) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+// currentUser returns the user the auth middleware stored in the context, or nil for
+// anonymous requests
+func currentUser(c *gin.Context) *models.RegisterResponse {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	userResponse, ok := user.(models.RegisterResponse)
+	if !ok {
+		return nil
+	}
+	return &userResponse
+}
+
+// currentUserID returns the ID of the logged-in user, or nil for anonymous requests
+func currentUserID(c *gin.Context) *uint {
+	if user := currentUser(c); user != nil {
+		return &user.ID
+	}
+	return nil
+}
+
+// isBillEditor reports whether the caller may change the bill, and so may see what only
+// its editors see, such as participant notes and tags
+func (h *BillHandler) isBillEditor(c *gin.Context, billID uuid.UUID) bool {
+	return h.billService.AuthorizeBillChange(billID, currentUserID(c)) == nil
+}
+
+// redactParticipant clears the fields of a participant only the bill's editors may read,
+// on a stored participant or one in a bill response
+func redactParticipant[P *models.Participants | *models.ParticipantResponse](participant P) {
+	switch p := any(participant).(type) {
+	case *models.Participants:
+		p.Notes, p.Tags, p.Email = "", nil, ""
+	case *models.ParticipantResponse:
+		p.Notes, p.Tags, p.Email = "", nil, ""
+	}
+}
+
+// redactBill clears what only the bill's editors may read: the share token, which grants
+// access to the bill on its own, and the participants' private fields
+func redactBill(bill *models.BillResponse) {
+	bill.ShareToken = nil
+	for i := range bill.Participants {
+		redactParticipant(&bill.Participants[i])
+	}
+}
+
+// GetBill handles retrieving a bill by ID
+func (h *BillHandler) GetBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	// Answer pollers that already have this version without loading the bill. Editors
+	// see more than other readers, so they get a different ETag.
+	version, err := h.billService.BillVersion(billID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
+		return
+	}
+	editor := h.isBillEditor(c, billID)
+	if notModified(c, weakETag(version, editor)) {
+		return
+	}
+
+	bill, err := h.billService.GetBill(billID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
+		return
+	}
+	if !editor {
+		redactBill(bill)
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// GetSharedBill handles read-only access to a bill and its summary through a share link
+func (h *BillHandler) GetSharedBill(c *gin.Context) {
+	shared, err := h.billService.GetSharedBill(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find shared bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, shared)
+}
+
+// ClaimSharedParticipant handles someone holding a share link saying which participant
+// they are
+func (h *BillHandler) ClaimSharedParticipant(c *gin.Context) {
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	var req models.SharedClaimRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	participant, err := h.billService.ClaimSharedParticipant(c.Param("token"), participantID, req.Name, req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this shared bill"})
+		case errors.Is(err, services.ErrAlreadyClaimed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Participant is already claimed"})
+		case errors.Is(err, services.ErrNameMismatch):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Name does not match the participant"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to claim participant: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// JoinSharedBill handles someone missing from a shared bill adding themselves
+func (h *BillHandler) JoinSharedBill(c *gin.Context) {
+	var req models.SharedJoinRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	participant, err := h.billService.JoinSharedBill(c.Param("token"), req.Name, req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared bill not found"})
+		case errors.Is(err, services.ErrArchived):
+			c.JSON(http.StatusConflict, gin.H{"error": "Bill is archived"})
+		case errors.Is(err, services.ErrInvalidUpdate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrJoinLimited):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many people joined through this link; try again later"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to join bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, participant)
+}
+
+// RegenerateShareToken handles replacing a bill's share token, which invalidates old links
+func (h *BillHandler) RegenerateShareToken(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.billService.RegenerateShareToken(billID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to regenerate share token: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"share_token": token})
+}
+
+// DeleteBill handles deleting a bill together with its items, participants and assignments
+func (h *BillHandler) DeleteBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.billService.DeleteBill(billID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			fmt.Printf("Database error deleting bill: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bill deleted successfully"})
+}
+
+// ArchiveBill handles archiving a bill so it drops out of the default bill list
+func (h *BillHandler) ArchiveBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	bill, err := h.billService.ArchiveBill(billID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrInvalidStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Bills cannot be archived while their image is being processed"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to archive bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// UnarchiveBill handles restoring an archived bill
+func (h *BillHandler) UnarchiveBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	bill, err := h.billService.UnarchiveBill(billID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrInvalidStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Bill is not archived"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to unarchive bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// ReopenBill handles moving a completed bill back to active so it can be edited again
+func (h *BillHandler) ReopenBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	bill, err := h.billService.ReopenBill(billID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		var transitionErr *services.TransitionError
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only completed bills can be reopened; this bill is %s", transitionErr.From)})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reopen bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// FinalizeBill handles marking an active bill completed once every item is confirmed and
+// assigned
+func (h *BillHandler) FinalizeBill(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	bill, err := h.billService.FinalizeBill(billID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		var transitionErr *services.TransitionError
+		var unconfirmedErr *services.UnconfirmedItemsError
+		var unassignedErr *services.UnassignedItemsError
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.As(err, &unconfirmedErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":                "Every extracted item must be confirmed before the bill can be finalized",
+				"unconfirmed_item_ids": unconfirmedErr.ItemIDs,
+			})
+		} else if errors.As(err, &unassignedErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":               "Every item must be assigned before the bill can be finalized",
+				"unassigned_item_ids": unassignedErr.ItemIDs,
+			})
+		} else if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Only active bills can be finalized; this bill is %s", transitionErr.From)})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to finalize bill: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// UploadBillImage handles image upload for a bill
+func (h *BillHandler) UploadBillImage(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	timer := services.NewStageTimer()
+	timer.Start(services.StageValidation)
+
+	// Get the uploaded file
+	file, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		return
+	}
+
+	// Validate file type
+	if !isValidImageType(file.Filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only JPG, PNG, and JPEG are allowed"})
+		return
+	}
+
+	// Validate file size (max 10MB)
+	if file.Size > 10*1024*1024 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large. Maximum size is 10MB"})
 		return
 	}
 
-	bill, err := h.billService.CreateBill(&req)
+	// ?force=true re-runs extraction even for an image that was already processed
+	force := c.Query("force") == "true"
+
+	bill, duplicate, err := h.billService.UploadBillImage(billID, file, force, timer)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create bill: %v", err)})
+		if errors.Is(err, services.ErrQueueFull) {
+			// The service already restored the previous status
+			c.Header("Retry-After", "30")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Too many bills are being processed right now. Please try uploading again shortly.",
+				"code":  "EXTRACTION_QUEUE_FULL",
+			})
+		} else if errors.Is(err, storage.ErrUnavailable) {
+			// Nothing changed on the bill, so there is no status to revert
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Image storage is temporarily unavailable. Please try uploading again later.",
+				"code":  "STORAGE_UNAVAILABLE",
+			})
+		} else if errors.Is(err, services.ErrInvalidStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Cannot upload an image now: %v", err)})
+		} else {
+			// Failures happen before the bill is queued, so its status is unchanged
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload image: %v", err)})
+		}
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Duplicate image — using existing extraction",
+			"bill":      bill,
+			"status":    bill.Status,
+			"duplicate": true,
+		})
+		return
+	}
+
+	response := gin.H{
+		"message": "Image uploaded successfully and queued for processing",
+		"bill":    bill,
+		"status":  bill.Status,
+	}
+	if position, queued := h.billService.QueuePosition(billID); queued {
+		response["queue_position"] = position
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// GetBillSummary handles retrieving bill summary
+func (h *BillHandler) GetBillSummary(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var query models.BillSummaryQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+
+	summary, err := h.billService.GetBillSummary(billID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
+		return
+	}
+
+	if query.GroupBy == "category" {
+		summary.CategoryTotals, err = h.billService.CategoryTotals(billID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to total categories: %v", err)})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// AddParticipant handles adding a participant to a bill
+func (h *BillHandler) AddParticipant(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("Adding participant to bill: %s\n", billID)
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	var req models.ParticipantRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	fmt.Printf("Participant request: %+v\n", req)
+
+	participant, err := h.billService.AddParticipant(billID, &req, services.UserActor(currentUserID(c)))
+	if err != nil {
+		fmt.Printf("Database error: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add participant: %v", err)})
+		return
+	}
+
+	fmt.Printf("Participant created successfully with ID: %d\n", participant.ID)
+	c.JSON(http.StatusCreated, participant)
+}
+
+// GetParticipants handles fetching all participants for a bill
+func (h *BillHandler) GetParticipants(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("Fetching participants for bill: %s\n", billID)
+
+	var query models.ParticipantListQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+
+	if !h.requireBill(c, billID) {
+		return
+	}
+
+	participants, err := h.billService.ListParticipants(billID, &query)
+	if err != nil {
+		fmt.Printf("Database error fetching participants: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch participants: %v", err)})
+		return
+	}
+
+	if !h.isBillEditor(c, billID) {
+		for i := range participants {
+			redactParticipant(&participants[i])
+		}
+	}
+
+	fmt.Printf("Found %d participants for bill %s\n", len(participants), billID)
+	c.JSON(http.StatusOK, participants)
+}
+
+// UpdateParticipant handles changing a participant's name, share of common costs, notes
+// or tags
+func (h *BillHandler) UpdateParticipant(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	var req models.ParticipantUpdateRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	participant, err := h.billService.UpdateParticipant(billID, participantID, &req, services.UserActor(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoChanges):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		case errors.Is(err, services.ErrInvalidUpdate), errors.Is(err, services.ErrInvalidPayment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update participant: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// ReorderParticipants handles setting the display order of a bill's participants
+func (h *BillHandler) ReorderParticipants(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReorderParticipantsRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	participants, err := h.billService.ReorderParticipants(billID, req.ParticipantIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOrder) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "participant_ids must list every participant of this bill exactly once"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to reorder participants: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participants)
+}
+
+// SetBillPayers handles replacing who paid the merchant, for bills split across several cards
+func (h *BillHandler) SetBillPayers(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetBillPayersRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	payers, err := h.billService.SetBillPayers(billID, req.Payers, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrInvalidPayers) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to set payers: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payers": payers})
+}
+
+// SetManualShares handles setting what each participant owes directly, for groups that
+// split a bill by agreed amounts instead of by item
+func (h *BillHandler) SetManualShares(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetManualSharesRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	summary, err := h.billService.SetManualShares(billID, req.Shares, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrInvalidManualShares) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to set manual shares: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ClearManualShares handles switching a bill back to the itemized split. The manual
+// amounts are lost, so ?confirm=true is required.
+func (h *BillHandler) ClearManualShares(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	summary, err := h.billService.ClearManualShares(billID, c.Query("confirm") == "true", services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrConfirmationRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pass confirm=true to discard the manual shares"})
+		} else if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to clear manual shares: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ApplyPreviousAssignments handles copying item assignments from an earlier bill named by
+// ?source_bill_id, for groups that split the same things the same way every time
+func (h *BillHandler) ApplyPreviousAssignments(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	sourceID, ok := bind.UUIDQuery(c, "source_bill_id")
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	report, err := h.billService.ApplyPreviousAssignments(billID, sourceID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrSameBill) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source_bill_id must be a different bill"})
+		} else if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to apply previous assignments: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListBillEvents handles listing a bill's activity log page by page, newest first
+func (h *BillHandler) ListBillEvents(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var query models.BillEventListQuery
+	if !bind.Query(c, &query) {
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	if !h.requireBill(c, billID) {
+		return
+	}
+
+	events, err := h.billService.ListBillEvents(billID, query.Page, query.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list bill events: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// EditingHeartbeat handles recording that the caller has the bill's editing screen open.
+// Logged-in users are identified by their session, guests by participant_id.
+func (h *BillHandler) EditingHeartbeat(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireBill(c, billID) {
+		return
+	}
+
+	// Logged-in users may send an empty body
+	var req models.EditingHeartbeatRequest
+	if c.Request.ContentLength != 0 && !bind.JSON(c, &req) {
+		return
+	}
+
+	user := currentUser(c)
+	if user == nil && req.ParticipantID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "participant_id is required when not logged in"})
+		return
+	}
+
+	editors, err := h.billService.RecordEditingHeartbeat(billID, user, req.ParticipantID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		} else if errors.Is(err, services.ErrPresenceFull) {
+			c.Header("Retry-After", "30")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many people are editing bills right now"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to record heartbeat: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"editors": editors})
+}
+
+// GetEditors handles listing who currently has the bill's editing screen open
+func (h *BillHandler) GetEditors(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireBill(c, billID) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"editors": h.billService.BillEditors(billID)})
+}
+
+// GetParticipant handles fetching a single participant, returning its version as an ETag
+func (h *BillHandler) GetParticipant(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	participant, err := h.billService.GetParticipant(billID, participantID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch participant: %v", err)})
+		}
+		return
+	}
+
+	if !h.isBillEditor(c, billID) {
+		redactParticipant(participant)
+	}
+
+	c.Header("ETag", versionETag(participant.Version()))
+	c.JSON(http.StatusOK, participant)
+}
+
+// UpdateParticipantPayment handles toggling a participant's payment status.
+// The request must carry an If-Match header so stale clients can't overwrite newer changes.
+func (h *BillHandler) UpdateParticipantPayment(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req models.ParticipantPaymentRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	h.writeParticipantPayment(c, billID, participantID, req.PaymentStatus, req.AmountPaid, expectedVersion)
+}
+
+// RecordParticipantPayment handles recording a participant's payment status with the
+// amount they paid back. Like UpdateParticipantPayment it requires If-Match, so two
+// people settling up at once can't silently overwrite each other.
+func (h *BillHandler) RecordParticipantPayment(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	expectedVersion, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req models.RecordPaymentRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	h.writeParticipantPayment(c, billID, participantID, req.Status, req.AmountPaid, expectedVersion)
+}
+
+// MarkAllPaid handles marking every participant of a bill paid in one call
+func (h *BillHandler) MarkAllPaid(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MarkAllPaidRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	participants, err := h.billService.MarkAllPaid(billID, req.Except, services.UserActor(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoParticipants):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Bill has no participants to mark paid"})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to mark participants paid: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participants)
+}
+
+// writeParticipantPayment records a participant's payment and answers with the participant
+func (h *BillHandler) writeParticipantPayment(c *gin.Context, billID uuid.UUID, participantID uint, status string, amountPaid *float64, expectedVersion *int64) {
+	participant, err := h.billService.UpdateParticipantPayment(billID, participantID, status, amountPaid, expectedVersion, services.UserActor(currentUserID(c)))
+	if participant != nil && !h.isBillEditor(c, billID) {
+		redactParticipant(participant)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		case errors.Is(err, services.ErrInvalidPayment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPreconditionFailed):
+			c.Header("ETag", versionETag(participant.Version()))
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error":   "Participant was modified by someone else. Refresh and try again.",
+				"current": participant,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update payment status: %v", err)})
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(participant.Version()))
+	c.JSON(http.StatusOK, participant)
+}
+
+// GetItemAssignments handles fetching all item assignments for a bill
+func (h *BillHandler) GetItemAssignments(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("Fetching item assignments for bill: %s\n", billID)
+
+	if !h.requireBill(c, billID) {
+		return
+	}
+
+	assignments, err := h.billService.ItemAssignments(billID)
+	if err != nil {
+		fmt.Printf("Database error fetching item assignments: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch item assignments: %v", err)})
+		return
+	}
+
+	fmt.Printf("Found %d item assignments for bill %s\n", len(assignments), billID)
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// AssignItemToParticipant handles assigning an item to a participant
+func (h *BillHandler) AssignItemToParticipant(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	fmt.Printf("Assigning item to participant in bill: %s\n", billID)
+
+	var req models.ItemAssignmentRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	fmt.Printf("Assignment request: %+v\n", req)
+
+	assignment, err := h.billService.AssignItem(billID, req.ItemID, req.ParticipantID, req.Weight, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrItemNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in this bill"})
+		} else if errors.Is(err, services.ErrParticipantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		} else if errors.Is(err, services.ErrAlreadyAssigned) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Item is already assigned to this participant"})
+		} else {
+			fmt.Printf("Database error creating assignment: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to assign item: %v", err)})
+		}
+		return
+	}
+
+	fmt.Printf("Assignment created successfully\n")
+	c.JSON(http.StatusCreated, assignment)
+}
+
+// DeleteParticipant handles deleting a participant from a bill
+func (h *BillHandler) DeleteParticipant(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	fmt.Printf("Deleting participant %d from bill %s\n", participantID, billID)
+
+	removed, err := h.billService.DeleteParticipant(billID, participantID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		} else {
+			fmt.Printf("Database error deleting participant: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete participant: %v", err)})
+		}
+		return
+	}
+
+	fmt.Printf("Participant %d deleted successfully\n", participantID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Participant deleted successfully",
+		"assignments_removed": removed,
+	})
+}
+
+// ClaimParticipant handles linking a participant to the logged-in caller
+func (h *BillHandler) ClaimParticipant(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	participant, err := h.billService.ClaimParticipant(billID, participantID, *userID, services.UserActor(userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		case errors.Is(err, services.ErrAlreadyClaimed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Participant is already claimed, or you already claimed another participant of this bill"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to claim participant: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// ListMyParticipations handles listing the bills the authenticated user claimed a
+// participant on, with what they owe on each
+func (h *BillHandler) ListMyParticipations(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	participations, err := h.billService.ListParticipations(*userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list participations: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, participations)
+}
+
+// RestoreParticipant handles bringing back a participant deleted by mistake, without the
+// item assignments they had
+func (h *BillHandler) RestoreParticipant(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	participantID, ok := bind.UintParam(c, "participantId")
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	participant, err := h.billService.RestoreParticipant(billID, participantID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		case errors.Is(err, services.ErrRestoreExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "Participant was deleted too long ago to restore"})
+		case errors.Is(err, services.ErrInvalidStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "Participants cannot be restored while the bill's image is being processed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to restore participant: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// MergeParticipants handles merging a participant into another that turned out to be the
+// same person, such as "Mike" and "Michael"
+func (h *BillHandler) MergeParticipants(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ParticipantMergeRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	participant, err := h.billService.MergeParticipants(billID, req.SourceParticipantID, req.TargetParticipantID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMerge):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to merge participants: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// DeleteParticipants handles removing several participants from a bill in one call
+func (h *BillHandler) DeleteParticipants(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	var req models.BulkDeleteParticipantsRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	fmt.Printf("Bulk deleting %d participants from bill %s\n", len(req.ParticipantIDs), billID)
+
+	result, err := h.billService.BulkDeleteParticipants(billID, req.ParticipantIDs, req.AllOrNothing, services.UserActor(currentUserID(c)))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrBatchRejected) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  "Some participants cannot be deleted; nothing was removed",
+				"errors": result.Errors,
+			})
+		} else {
+			fmt.Printf("Database error bulk deleting participants: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete participants: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteItemAssignment handles removing an item assignment from a participant
+func (h *BillHandler) DeleteItemAssignment(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
+
+	fmt.Printf("Deleting item assignment in bill: %s\n", billID)
+
+	var req models.ItemAssignmentRequest
+	if !bind.JSON(c, &req) {
+		return
+	}
+
+	fmt.Printf("Delete assignment request: %+v\n", req)
+
+	if err := h.billService.UnassignItem(billID, req.ItemID, req.ParticipantID, services.UserActor(currentUserID(c))); err != nil {
+		if errors.Is(err, services.ErrItemNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in this bill"})
+		} else if errors.Is(err, services.ErrParticipantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
+		} else if errors.Is(err, services.ErrAssignmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item assignment not found"})
+		} else {
+			fmt.Printf("Database error deleting assignment: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete item assignment: %v", err)})
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, bill)
+	fmt.Printf("Assignment deleted successfully\n")
+	c.JSON(http.StatusOK, gin.H{"message": "Item assignment removed successfully"})
 }
 
-// GetBill handles retrieving a bill by ID
-func (h *BillHandler) GetBill(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+// CreateItems handles adding items the receipt scan missed. The body is one item, answered
+// with that item, or an array of items added all together and answered with an array.
+func (h *BillHandler) CreateItems(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	bill, err := h.billService.GetBill(billID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
+	if !bind.LocalizeNumbers(c) {
 		return
 	}
-
-	c.JSON(http.StatusOK, bill)
-}
-
-// UploadBillImage handles image upload for a bill
-func (h *BillHandler) UploadBillImage(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
+	body, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		bind.InvalidBody(c, err)
 		return
 	}
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
 
-	// Get the uploaded file
-	file, err := c.FormFile("image")
+	// A single item is validated on its own so field errors read "name", not "items[0].name"
+	var req models.ItemBatchRequest
+	var single models.ItemRequest
+	if batch {
+		err = json.Unmarshal(body, &req.Items)
+	} else {
+		err = json.Unmarshal(body, &single)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		bind.InvalidBody(c, err)
 		return
 	}
-
-	// Validate file type
-	if !isValidImageType(file.Filename) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only JPG, PNG, and JPEG are allowed"})
+	if batch && !bind.Validate(c, &req, http.StatusUnprocessableEntity) {
 		return
 	}
-
-	// Validate file size (max 10MB)
-	if file.Size > 10*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large. Maximum size is 10MB"})
-		return
+	if !batch {
+		if !bind.Validate(c, &single, http.StatusUnprocessableEntity) {
+			return
+		}
+		req.Items = []models.ItemRequest{single}
 	}
 
-	// Update bill status to processing
-	if err := h.billService.UpdateBillStatus(billID, "processing"); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update bill status: %v", err)})
+	if !h.requireEditableBill(c, billID) {
 		return
 	}
 
-	bill, err := h.billService.UploadBillImage(billID, file)
+	items, err := h.billService.CreateItems(billID, req.Items, services.UserActor(currentUserID(c)))
 	if err != nil {
-		// Check if it's an n8n workflow error
-		if strings.Contains(err.Error(), "failed to process image with AI") {
-			// Status should already be set to "failed" by the service
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to process image with AI. Please try uploading again.",
-				"status":  "failed",
-				"details": "The AI processing service is currently unavailable or encountered an error.",
-			})
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		} else if errors.Is(err, services.ErrInvalidStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be added while the bill's image is being processed"})
+		} else if errors.Is(err, services.ErrInvalidItemDiscount) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		} else {
-			// Revert status to active if upload fails for other reasons
-			h.billService.UpdateBillStatus(billID, "active")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to upload image: %v", err)})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create items: %v", err)})
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Image uploaded successfully and sent for processing",
-		"bill":    bill,
-		"status":  "processing",
-	})
+	if batch {
+		c.JSON(http.StatusCreated, items)
+		return
+	}
+	c.JSON(http.StatusCreated, items[0])
 }
 
-// GetBillSummary handles retrieving bill summary
-func (h *BillHandler) GetBillSummary(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+// UpdateItems handles fixing several items at once, such as prices after a bad scan. The
+// body is a bare array of item patches and the answer is every item of the bill.
+func (h *BillHandler) UpdateItems(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	summary, err := h.billService.GetBillSummary(billID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
+	var req models.ItemPatchBatchRequest
+	if !bind.LocalizeNumbers(c) {
+		return
+	}
+	if err := c.ShouldBindJSON(&req.Items); err != nil {
+		bind.InvalidBody(c, err)
+		return
+	}
+	if !bind.Validate(c, &req, http.StatusUnprocessableEntity) {
 		return
 	}
 
-	c.JSON(http.StatusOK, summary)
-}
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
 
-// AddParticipant handles adding a participant to a bill
-func (h *BillHandler) AddParticipant(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
+	items, err := h.billService.UpdateItems(billID, req.Items, services.UserActor(currentUserID(c)))
 	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		var notInBill *services.ItemsNotInBillError
+		if errors.As(err, &notInBill) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "Some items are not on this bill; nothing was changed",
+				"item_ids": notInBill.ItemIDs,
+			})
+		} else if errors.Is(err, services.ErrInvalidItemDiscount) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update items: %v", err)})
+		}
 		return
 	}
 
-	fmt.Printf("Adding participant to bill: %s\n", billID)
+	c.JSON(http.StatusOK, items)
+}
 
-	var req models.ParticipantRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("JSON bind error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+// UpdateItem handles updating the details of an item on the bill named in the path
+func (h *BillHandler) UpdateItem(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
-
-	fmt.Printf("Participant request: %+v\n", req)
-
-	participant := &models.Participants{
-		BillID:             billID,
-		Name:               req.Name,
-		PaymentStatus:      "unpaid",
-		ShareOfCommonCosts: req.ShareOfCommonCosts,
+	itemID, ok := bind.UintParam(c, "itemId")
+	if !ok {
+		return
 	}
 
-	fmt.Printf("Creating participant: %+v\n", participant)
-
-	if err := h.billService.GetDB().Create(participant).Error; err != nil {
-		fmt.Printf("Database error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add participant: %v", err)})
+	if !h.requireEditableBill(c, billID) {
 		return
 	}
 
-	fmt.Printf("Participant created successfully with ID: %d\n", participant.ID)
-	c.JSON(http.StatusCreated, participant)
-}
-
-// GetParticipants handles fetching all participants for a bill
-func (h *BillHandler) GetParticipants(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+	// If-Match is optional here; without it the last write wins
+	expectedVersion, ok := optionalIfMatch(c)
+	if !ok {
 		return
 	}
 
-	fmt.Printf("Fetching participants for bill: %s\n", billID)
+	var req models.ItemUpdateRequest
 
-	var participants []models.Participants
-	if err := h.billService.GetDB().Where("bill_id = ?", billID).Find(&participants).Error; err != nil {
-		fmt.Printf("Database error fetching participants: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch participants: %v", err)})
+	if !bind.LocalizeNumbers(c) || !bind.JSON(c, &req) {
 		return
 	}
 
-	fmt.Printf("Found %d participants for bill %s\n", len(participants), billID)
-	c.JSON(http.StatusOK, participants)
-}
-
-// GetItemAssignments handles fetching all item assignments for a bill
-func (h *BillHandler) GetItemAssignments(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
+	// Update the item and its bill's totals, unless the bill was deleted
+	updatedItem, err := h.billService.UpdateItem(billID, itemID, &req, services.UserActor(currentUserID(c)), expectedVersion)
 	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		switch {
+		case errors.Is(err, services.ErrNoChanges):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		case errors.Is(err, services.ErrInvalidItemDiscount):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPreconditionFailed):
+			c.Header("ETag", versionETag(updatedItem.Version()))
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error":   "Item was modified by someone else. Refresh and try again.",
+				"current": updatedItem,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update item: %v", err)})
+		}
 		return
 	}
 
-	fmt.Printf("Fetching item assignments for bill: %s\n", billID)
+	c.Header("ETag", versionETag(updatedItem.Version()))
+	c.JSON(http.StatusOK, updatedItem)
+}
 
-	// Get all items for this bill
-	var items []models.Items
-	if err := h.billService.GetDB().Where("bill_id = ?", billID).Find(&items).Error; err != nil {
-		fmt.Printf("Database error fetching items: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch items: %v", err)})
+// SplitItem handles splitting an item into several lines, such as "2x Beer" that two people
+// had one each of, so each line can be assigned on its own. The answer is the resulting items.
+func (h *BillHandler) SplitItem(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+	itemID, ok := bind.UintParam(c, "itemId")
+	if !ok {
 		return
 	}
 
-	fmt.Printf("Found %d items for bill %s\n", len(items), billID)
-	fmt.Printf("Items: %+v\n", items)
-
-	// Get all item assignments for these items
-	var assignments []models.ItemAssignments
-	if len(items) > 0 {
-		itemIDs := make([]uint, len(items))
-		for i, item := range items {
-			itemIDs[i] = item.ID
-		}
-
-		fmt.Printf("Looking for assignments for items: %v\n", itemIDs)
-
-		if err := h.billService.GetDB().Where("item_id IN ?", itemIDs).Find(&assignments).Error; err != nil {
-			fmt.Printf("Database error fetching assignments: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to fetch item assignments: %v", err)})
-			return
-		}
+	body, err := c.GetRawData()
+	if err != nil {
+		bind.InvalidBody(c, err)
+		return
+	}
+	var req models.ItemSplitRequest
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		err = json.Unmarshal(body, &req.Rows)
 	} else {
-		fmt.Printf("No items found for bill %s, returning empty assignments\n", billID)
+		err = json.Unmarshal(body, &req)
 	}
-
-	fmt.Printf("Found %d item assignments for bill %s\n", len(assignments), billID)
-	fmt.Printf("Assignments: %+v\n", assignments)
-
-	c.JSON(http.StatusOK, assignments)
-}
-
-// AssignItemToParticipant handles assigning an item to a participant
-func (h *BillHandler) AssignItemToParticipant(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
 	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+		bind.InvalidBody(c, err)
 		return
 	}
-
-	fmt.Printf("Assigning item to participant in bill: %s\n", billID)
-
-	var req models.ItemAssignmentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("JSON bind error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+	if !bind.Validate(c, &req, http.StatusUnprocessableEntity) {
 		return
 	}
-
-	fmt.Printf("Assignment request: %+v\n", req)
-
-	// Check if the item belongs to this bill
-	var item models.Items
-	if err := h.billService.GetDB().Where("id = ? AND bill_id = ?", req.ItemID, billID).First(&item).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Item %d not found in bill %s\n", req.ItemID, billID)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in this bill"})
-		} else {
-			fmt.Printf("Database error finding item: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find item: %v", err)})
-		}
+	if (req.Parts == 0) == (len(req.Rows) == 0) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Send either parts or an array of rows"})
 		return
 	}
 
-	fmt.Printf("Item found: %+v\n", item)
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
 
-	// Check if the participant belongs to this bill
-	var participant models.Participants
-	if err := h.billService.GetDB().Where("id = ? AND bill_id = ?", req.ParticipantID, billID).First(&participant).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Participant %d not found in bill %s\n", req.ParticipantID, billID)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
-		} else {
-			fmt.Printf("Database error finding participant: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find participant: %v", err)})
+	items, err := h.billService.SplitItem(billID, itemID, &req, services.UserActor(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		case errors.Is(err, services.ErrInvalidStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be split while the bill's image is being processed"})
+		case errors.Is(err, services.ErrInvalidSplit), errors.Is(err, services.ErrInvalidItemDiscount):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to split item: %v", err)})
 		}
 		return
 	}
 
-	fmt.Printf("Participant found: %+v\n", participant)
+	c.JSON(http.StatusOK, items)
+}
 
-	// Check if assignment already exists
-	var existingAssignment models.ItemAssignments
-	if err := h.billService.GetDB().Where("item_id = ? AND participant_id = ?", req.ItemID, req.ParticipantID).First(&existingAssignment).Error; err == nil {
-		fmt.Printf("Assignment already exists: %+v\n", existingAssignment)
-		c.JSON(http.StatusConflict, gin.H{"error": "Item is already assigned to this participant"})
+// MergeItems handles merging copies of an item, such as lines a re-upload added twice, into
+// the first item listed
+func (h *BillHandler) MergeItems(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	assignment := &models.ItemAssignments{
-		ItemID:        req.ItemID,
-		ParticipantID: req.ParticipantID,
+	var req models.ItemMergeRequest
+	if !bind.JSON(c, &req) {
+		return
 	}
 
-	fmt.Printf("Creating assignment: %+v\n", assignment)
+	if !h.requireEditableBill(c, billID) {
+		return
+	}
 
-	if err := h.billService.GetDB().Create(assignment).Error; err != nil {
-		fmt.Printf("Database error creating assignment: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to assign item: %v", err)})
+	item, err := h.billService.MergeItems(billID, req.ItemIDs, services.UserActor(currentUserID(c)))
+	if err != nil {
+		var notInBill *services.ItemsNotInBillError
+		switch {
+		case errors.As(err, &notInBill):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "Some items are not on this bill; nothing was changed",
+				"item_ids": notInBill.ItemIDs,
+			})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		case errors.Is(err, services.ErrInvalidStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be merged while the bill's image is being processed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to merge items: %v", err)})
+		}
 		return
 	}
 
-	fmt.Printf("Assignment created successfully\n")
-	c.JSON(http.StatusCreated, assignment)
+	c.JSON(http.StatusOK, item)
 }
 
-// DeleteParticipant handles deleting a participant from a bill
-func (h *BillHandler) DeleteParticipant(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+// ConfirmItems handles confirming extracted items once they have been checked against the
+// receipt, by ID or all at once
+func (h *BillHandler) ConfirmItems(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	participantIDStr := c.Param("participantId")
-	participantID, err := strconv.ParseUint(participantIDStr, 10, 32)
-	if err != nil {
-		fmt.Printf("Participant ID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid participant ID"})
+	var req models.ItemConfirmRequest
+	if !bind.JSON(c, &req) {
 		return
 	}
-
-	fmt.Printf("Deleting participant %d from bill %s\n", participantID, billID)
-
-	// Check if the participant belongs to this bill
-	var participant models.Participants
-	if err := h.billService.GetDB().Where("id = ? AND bill_id = ?", participantID, billID).First(&participant).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Participant %d not found in bill %s\n", participantID, billID)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
-		} else {
-			fmt.Printf("Database error finding participant: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find participant: %v", err)})
-		}
+	if req.All == (len(req.ItemIDs) > 0) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Send either item_ids or all: true"})
 		return
 	}
 
-	fmt.Printf("Participant found: %+v\n", participant)
-
-	// First delete all item assignments for this participant
-	fmt.Printf("Deleting item assignments for participant %d\n", participantID)
-	if err := h.billService.GetDB().Where("participant_id = ?", participantID).Delete(&models.ItemAssignments{}).Error; err != nil {
-		fmt.Printf("Database error deleting item assignments: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete item assignments: %v", err)})
+	if !h.requireEditableBill(c, billID) {
 		return
 	}
 
-	// Then delete the participant
-	fmt.Printf("Deleting participant %d\n", participantID)
-	if err := h.billService.GetDB().Delete(&models.Participants{}, participantID).Error; err != nil {
-		fmt.Printf("Database error deleting participant: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete participant: %v", err)})
+	response, err := h.billService.ConfirmItems(billID, req.ItemIDs, req.All, services.UserActor(currentUserID(c)))
+	if err != nil {
+		var notInBill *services.ItemsNotInBillError
+		if errors.As(err, &notInBill) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "Some items are not on this bill; nothing was changed",
+				"item_ids": notInBill.ItemIDs,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to confirm items: %v", err)})
+		}
 		return
 	}
 
-	fmt.Printf("Participant %d deleted successfully\n", participantID)
-	c.JSON(http.StatusOK, gin.H{"message": "Participant deleted successfully"})
+	c.JSON(http.StatusOK, response)
 }
 
-// DeleteItemAssignment handles removing an item assignment from a participant
-func (h *BillHandler) DeleteItemAssignment(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+// ListItems handles listing a bill's items with who is assigned to each, one page at a time
+func (h *BillHandler) ListItems(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	fmt.Printf("Deleting item assignment in bill: %s\n", billID)
-
-	var req models.ItemAssignmentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("JSON bind error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+	var query models.ItemListQuery
+	if !bind.Query(c, &query) {
 		return
 	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 100
+	}
 
-	fmt.Printf("Delete assignment request: %+v\n", req)
-
-	// Check if the item belongs to this bill
-	var item models.Items
-	if err := h.billService.GetDB().Where("id = ? AND bill_id = ?", req.ItemID, billID).First(&item).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Item %d not found in bill %s\n", req.ItemID, billID)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in this bill"})
-		} else {
-			fmt.Printf("Database error finding item: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find item: %v", err)})
-		}
+	if !h.requireBill(c, billID) {
 		return
 	}
 
-	fmt.Printf("Item found: %+v\n", item)
-
-	// Check if the participant belongs to this bill
-	var participant models.Participants
-	if err := h.billService.GetDB().Where("id = ? AND bill_id = ?", req.ParticipantID, billID).First(&participant).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Participant %d not found in bill %s\n", req.ParticipantID, billID)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found in this bill"})
-		} else {
-			fmt.Printf("Database error finding participant: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find participant: %v", err)})
-		}
+	items, err := h.billService.ListItems(billID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to list items: %v", err)})
 		return
 	}
 
-	fmt.Printf("Participant found: %+v\n", participant)
+	c.JSON(http.StatusOK, items)
+}
 
-	// Check if assignment exists
-	var existingAssignment models.ItemAssignments
-	if err := h.billService.GetDB().Where("item_id = ? AND participant_id = ?", req.ItemID, req.ParticipantID).First(&existingAssignment).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Assignment not found for item %d and participant %d\n", req.ItemID, req.ParticipantID)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item assignment not found"})
-		} else {
-			fmt.Printf("Database error finding assignment: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find assignment: %v", err)})
-		}
+// GetDuplicateItems handles listing groups of items with the same name and price
+func (h *BillHandler) GetDuplicateItems(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	fmt.Printf("Assignment found: %+v\n", existingAssignment)
+	if !h.requireBill(c, billID) {
+		return
+	}
 
-	// Delete the assignment
-	if err := h.billService.GetDB().Where("item_id = ? AND participant_id = ?", req.ItemID, req.ParticipantID).Delete(&models.ItemAssignments{}).Error; err != nil {
-		fmt.Printf("Database error deleting assignment: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete item assignment: %v", err)})
+	groups, err := h.billService.DuplicateItems(billID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find duplicate items: %v", err)})
 		return
 	}
 
-	fmt.Printf("Assignment deleted successfully\n")
-	c.JSON(http.StatusOK, gin.H{"message": "Item assignment removed successfully"})
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
 }
 
-// UpdateItem handles updating an item's details
-func (h *BillHandler) UpdateItem(c *gin.Context) {
-	itemIDStr := c.Param("id")
-	itemID, err := strconv.ParseUint(itemIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+// DeleteItem handles removing an item, such as a line the receipt scan read twice, together
+// with its assignments
+func (h *BillHandler) DeleteItem(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
-
-	var req struct {
-		Name     *string  `json:"name"`
-		Price    *float64 `json:"price"`
-		Quantity *int     `json:"quantity"`
+	itemID, ok := bind.UintParam(c, "itemId")
+	if !ok {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+	if !h.requireEditableBill(c, billID) {
 		return
 	}
 
-	// Update only the fields that were provided
-	updates := make(map[string]interface{})
-	if req.Name != nil {
-		updates["name"] = *req.Name
-	}
-	if req.Price != nil {
-		updates["price"] = *req.Price
-	}
-	if req.Quantity != nil {
-		updates["quantity"] = *req.Quantity
+	if err := h.billService.DeleteItem(billID, itemID, services.UserActor(currentUserID(c))); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		} else if errors.Is(err, services.ErrInvalidStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be deleted while the bill's image is being processed"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete item: %v", err)})
+		}
+		return
 	}
 
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+	c.JSON(http.StatusOK, gin.H{"message": "Item deleted successfully"})
+}
+
+// RestoreItem handles bringing back an item deleted by mistake, without the assignments it
+// had
+func (h *BillHandler) RestoreItem(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+	itemID, ok := bind.UintParam(c, "itemId")
+	if !ok {
 		return
 	}
 
-	// Update the item in the database
-	if err := h.billService.GetDB().Model(&models.Items{}).Where("id = ?", itemID).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update item: %v", err)})
+	if !h.requireEditableBill(c, billID) {
 		return
 	}
 
-	// Get the updated item
-	var updatedItem models.Items
-	if err := h.billService.GetDB().First(&updatedItem, itemID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated item"})
+	item, err := h.billService.RestoreItem(billID, itemID, services.UserActor(currentUserID(c)))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		case errors.Is(err, services.ErrRestoreExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "Item was deleted too long ago to restore"})
+		case errors.Is(err, services.ErrInvalidStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be restored while the bill's image is being processed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to restore item: %v", err)})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedItem)
+	c.JSON(http.StatusOK, item)
 }
 
 // UpdateBill handles updating a bill's details
 func (h *BillHandler) UpdateBill(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	var req struct {
-		TaxAmount *float64 `json:"tax_amount"`
-		TipAmount *float64 `json:"tip_amount"`
+	if !h.requireEditableBill(c, billID) {
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request: %v", err)})
+	// If-Match is optional here; without it the last write wins
+	expectedVersion, ok := optionalIfMatch(c)
+	if !ok {
 		return
 	}
 
-	// Update only the fields that were provided
-	updates := make(map[string]interface{})
-	if req.TaxAmount != nil {
-		updates["tax_amount"] = *req.TaxAmount
-	}
-	if req.TipAmount != nil {
-		updates["tip_amount"] = *req.TipAmount
-	}
+	var req models.BillUpdateRequest
 
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+	if !bind.LocalizeNumbers(c) || !bind.JSON(c, &req) {
 		return
 	}
 
-	// Update the bill in the database
-	if err := h.billService.GetDB().Model(&models.Bills{}).Where("id = ?", billID).Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update bill: %v", err)})
-		return
+	if req.Currency != nil {
+		code, ok := bindCurrency(c, *req.Currency)
+		if !ok {
+			return
+		}
+		req.Currency = &code
 	}
 
-	// Get the updated bill
-	var updatedBill models.Bills
-	if err := h.billService.GetDB().First(&updatedBill, billID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated bill"})
+	// Update the bill and its stored totals
+	updatedBill, err := h.billService.UpdateBill(billID, &req, services.UserActor(currentUserID(c)), expectedVersion)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoChanges):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		case errors.Is(err, services.ErrInvalidUpdate), errors.Is(err, services.ErrInvalidCallbackURL),
+			errors.Is(err, services.ErrCallbacksUnavailable), errors.Is(err, services.ErrNotesTooLong):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+		case errors.Is(err, services.ErrPreconditionFailed):
+			c.Header("ETag", versionETag(updatedBill.Version()))
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error":   "Bill was modified by someone else. Refresh and try again.",
+				"current": updatedBill,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update bill: %v", err)})
+		}
 		return
 	}
 
 	// Return the updated bill directly
+	c.Header("ETag", versionETag(updatedBill.Version()))
 	c.JSON(http.StatusOK, updatedBill)
 }
 
 // ProcessExtractedData handles processing data returned from n8n workflow
 func (h *BillHandler) ProcessExtractedData(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		fmt.Printf("UUID parse error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	// Read the raw body first
+	// Read the raw body first, refusing anything over the callback limit before it is buffered
+	limit := h.billService.CallbackMaxBodySize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(limit))
 	body, err := c.GetRawData()
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":       fmt.Sprintf("Request body exceeds the %d byte limit for extraction callbacks", limit),
+				"limit_bytes": limit,
+			})
+			return
+		}
 		fmt.Printf("Error reading raw body: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
 		return
 	}
-	fmt.Printf("Raw request body: %s\n", string(body))
+	fmt.Printf("Received extraction callback for bill %s (%d bytes)\n", billID, len(body))
 
-	// Parse the JSON manually since we already consumed the body
-	var rawData map[string]interface{}
-	if err := json.Unmarshal(body, &rawData); err != nil {
-		fmt.Printf("JSON unmarshal error: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid JSON: %v", err)})
-		return
-	}
-
-	// Declare variable for extracted data
-	var extractedDataStr string
-
-	// Check if this is the direct data structure from n8n
-	if code, exists := rawData["code"]; exists && code == "API_SPLITBILL_LLMOCR" {
-		fmt.Printf("Detected direct n8n data structure\n")
-
-		// Convert the entire data to JSON string for processing
-		extractedDataBytes, err := json.Marshal(rawData)
-		if err != nil {
-			fmt.Printf("Error marshaling data: %v\n", err)
-			// Update status to failed
-			h.billService.UpdateBillStatus(billID, "failed")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process data"})
-			return
-		}
-		extractedDataStr = string(extractedDataBytes)
-	} else {
-		// Fallback: check if extracted_data field exists
-		extractedData, exists := rawData["extracted_data"]
-		if !exists {
-			fmt.Printf("Missing extracted_data field. Available fields: %v\n", rawData)
-			// Update status to failed
-			h.billService.UpdateBillStatus(billID, "failed")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: extracted_data"})
+	// ?dry_run=true previews item name normalization without touching the bill
+	if c.Query("dry_run") == "true" {
+		if !h.requireBill(c, billID) {
 			return
 		}
-
-		// Convert to string
-		var ok bool
-		extractedDataStr, ok = extractedData.(string)
-		if !ok {
-			fmt.Printf("extracted_data is not a string, it's: %T\n", extractedData)
-			// Update status to failed
-			h.billService.UpdateBillStatus(billID, "failed")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "extracted_data must be a string"})
+		items, skipped, err := h.billService.PreviewExtractionCallback(body)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidPayload) {
+				bind.InvalidBody(c, err)
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to preview extracted data: %v", err)})
+			}
 			return
 		}
-	}
-
-	if err := h.billService.ProcessExtractedData(billID, extractedDataStr); err != nil {
-		// Update status to failed
-		h.billService.UpdateBillStatus(billID, "failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process extracted data: %v", err)})
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "items": items, "skipped": skipped})
 		return
 	}
 
-	// Update status to completed
-	if err := h.billService.UpdateBillStatus(billID, "completed"); err != nil {
-		fmt.Printf("Warning: Failed to update bill status to completed: %v\n", err)
+	// The service decides the bill's status for every outcome
+	if err := h.billService.ProcessExtractionCallback(billID, body); err != nil {
+		if errors.Is(err, services.ErrInvalidPayload) {
+			bind.InvalidBody(c, err)
+		} else if errors.Is(err, services.ErrInvalidStatus) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Bill is not being extracted: %v", err)})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process extracted data: %v", err)})
+		}
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Extracted data processed successfully"})
@@ -642,23 +1926,91 @@ func (h *BillHandler) ProcessExtractedData(c *gin.Context) {
 
 // GetBillStatus handles retrieving the status of a bill
 func (h *BillHandler) GetBillStatus(c *gin.Context) {
-	billIDStr := c.Param("id")
-	billID, err := uuid.Parse(billIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bill ID"})
+	billID, ok := bind.BillID(c)
+	if !ok {
 		return
 	}
 
-	status, err := h.billService.GetBillStatus(billID)
+	status, updatedAt, err := h.billService.GetBillStatus(billID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	// The queue position moves without the bill changing, so it is part of the ETag
+	position, queued := h.billService.QueuePosition(billID)
+	if notModified(c, weakETag(status, updatedAt.UnixMicro(), position, queued)) {
+		return
+	}
+
+	response := gin.H{
 		"bill_id": billID,
 		"status":  status,
-	})
+	}
+	if queued {
+		response["queue_position"] = position
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// statusHeartbeatInterval is how often an idle status stream sends a comment so proxies
+// keep the connection open
+const statusHeartbeatInterval = 15 * time.Second
+
+// StreamBillStatus handles pushing a bill's status changes as server-sent events. The
+// current status is sent first, and the stream ends once the bill is completed or failed.
+func (h *BillHandler) StreamBillStatus(c *gin.Context) {
+	billID, ok := bind.BillID(c)
+	if !ok {
+		return
+	}
+
+	// Subscribe first so a change between the read and the subscription isn't lost
+	updates, cancel := h.billService.SubscribeStatus(billID)
+	defer cancel()
+
+	status, _, err := h.billService.GetBillStatus(billID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Bill not found: %v", err)})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.SSEvent("status", gin.H{"status": status})
+	c.Writer.Flush()
+	if finalStatus(services.BillStatus(status)) {
+		return
+	}
+
+	heartbeat := time.NewTicker(statusHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case next := <-updates:
+			c.SSEvent("status", gin.H{"status": next})
+			c.Writer.Flush()
+			if finalStatus(next) {
+				return
+			}
+		}
+	}
+}
+
+// finalStatus reports whether a status stream has nothing left to wait for
+func finalStatus(status services.BillStatus) bool {
+	return status == services.StatusCompleted || status == services.StatusFailed
 }
 
 // isValidImageType checks if the file is a valid image type
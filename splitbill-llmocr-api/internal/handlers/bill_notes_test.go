@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBillNotes(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.POST("/api/bills", handler.CreateBill)
+	router.PUT("/api/bills/:id", handler.UpdateBill)
+
+	// Notes are stored trimmed
+	w := performJSON(t, router, http.MethodPost, "/api/bills?force=true", map[string]string{
+		"name":  "Birthday",
+		"notes": "  Dad's birthday dinner \n",
+	}, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d (%s), want 201", w.Code, w.Body)
+	}
+	var created models.BillResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode bill: %v", err)
+	}
+	if created.Notes != "Dad's birthday dinner" {
+		t.Errorf("notes = %q, want them trimmed", created.Notes)
+	}
+
+	path := "/api/bills/" + created.ID.String()
+	w = performJSON(t, router, http.MethodPut, path, map[string]string{"notes": " Table 12 "}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %d (%s), want 200", w.Code, w.Body)
+	}
+	var bill models.Bills
+	if err := db.First(&bill, "id = ?", created.ID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+	if bill.Notes != "Table 12" {
+		t.Errorf("stored notes = %q, want %q", bill.Notes, "Table 12")
+	}
+
+	// The limit counts characters, not bytes, and ignores surrounding whitespace
+	atLimit := strings.Repeat("é", models.MaxBillNotesLength)
+	w = performJSON(t, router, http.MethodPut, path, map[string]string{"notes": "  " + atLimit + "  "}, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("notes at the limit: status = %d (%s), want 200", w.Code, w.Body)
+	}
+
+	tooLong := strings.Repeat("a", models.MaxBillNotesLength+1)
+	w = performJSON(t, router, http.MethodPut, path, map[string]string{"notes": tooLong}, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("update with long notes: status = %d (%s), want 400", w.Code, w.Body)
+	}
+	w = performJSON(t, router, http.MethodPost, "/api/bills?force=true", map[string]string{
+		"name":  "Too long",
+		"notes": tooLong,
+	}, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("create with long notes: status = %d (%s), want 400", w.Code, w.Body)
+	}
+
+	// A refused update leaves the notes alone
+	if err := db.First(&bill, "id = ?", created.ID).Error; err != nil {
+		t.Fatalf("failed to reload bill: %v", err)
+	}
+	if bill.Notes != atLimit {
+		t.Errorf("notes changed by a refused update")
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactBill(t *testing.T) {
+	token := "share-token"
+	bill := &models.BillResponse{
+		Name:       "Dinner",
+		ShareToken: &token,
+		Participants: []models.ParticipantResponse{
+			{Name: "Ana", Notes: "owes for wine", Tags: models.Tags{"wine"}, Email: "ana@example.com"},
+			{Name: "Budi"},
+		},
+	}
+
+	redactBill(bill)
+
+	if bill.ShareToken != nil {
+		t.Errorf("share token = %q, want it cleared", *bill.ShareToken)
+	}
+	for _, participant := range bill.Participants {
+		if participant.Notes != "" || participant.Tags != nil || participant.Email != "" {
+			t.Errorf("participant %s kept private fields: %+v", participant.Name, participant)
+		}
+	}
+	if bill.Name != "Dinner" || bill.Participants[0].Name != "Ana" {
+		t.Errorf("redaction changed public fields: %+v", bill)
+	}
+}
+
+func TestRedactParticipant(t *testing.T) {
+	participant := &models.Participants{Name: "Ana", Notes: "vegan", Tags: models.Tags{"veg"}, Email: "ana@example.com"}
+	redactParticipant(participant)
+	if participant.Notes != "" || participant.Tags != nil || participant.Email != "" || participant.Name != "Ana" {
+		t.Errorf("participant = %+v", participant)
+	}
+}
+
+func TestGetBillHidesShareTokenFromNonEditors(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	owner := createTestUser(t, db, "owner")
+	other := createTestUser(t, db, "other")
+
+	bill, err := handler.billService.CreateBill(&models.BillRequest{Name: "Dinner"}, &owner.ID, "", true)
+	if err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		user      *models.RegisterResponse
+		wantToken bool
+	}{
+		{name: "anonymous", wantToken: false},
+		{name: "other user", user: &other, wantToken: false},
+		{name: "owner", user: &owner, wantToken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if tt.user != nil {
+				router.Use(asUser(*tt.user))
+			}
+			router.GET("/api/bills/:id", handler.GetBill)
+
+			rec := performJSON(t, router, http.MethodGet, "/api/bills/"+bill.ID.String(), nil, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var response models.BillResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode bill: %v", err)
+			}
+			if got := response.ShareToken != nil; got != tt.wantToken {
+				t.Errorf("share token present = %v, want %v", got, tt.wantToken)
+			}
+		})
+	}
+}
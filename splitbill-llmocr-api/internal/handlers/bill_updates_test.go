@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// raceWrites sends one PUT per body to path at the same moment, all carrying ifMatch,
+// and returns the status codes in the order of bodies
+func raceWrites(t *testing.T, router http.Handler, path, ifMatch string, bodies []interface{}) []int {
+	t.Helper()
+
+	codes := make([]int, len(bodies))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, body := range bodies {
+		wg.Add(1)
+		go func(i int, body interface{}) {
+			defer wg.Done()
+			<-start
+			codes[i] = performJSON(t, router, http.MethodPut, path, body, map[string]string{"If-Match": ifMatch}).Code
+		}(i, body)
+	}
+	close(start)
+	wg.Wait()
+	return codes
+}
+
+// winner returns the index of the one write that answered 200 while every other one
+// answered 412, or fails the test
+func winner(t *testing.T, codes []int) int {
+	t.Helper()
+
+	won := -1
+	for i, code := range codes {
+		switch {
+		case code == http.StatusOK && won == -1:
+			won = i
+		case code == http.StatusPreconditionFailed:
+		default:
+			t.Fatalf("statuses %v, want exactly one 200 and 412 for the rest", codes)
+		}
+	}
+	if won == -1 {
+		t.Fatalf("statuses %v, want exactly one 200", codes)
+	}
+	return won
+}
+
+func TestUpdateBillConcurrentWriters(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.PUT("/api/bills/:id", handler.UpdateBill)
+
+	created, err := handler.billService.CreateBill(&models.BillRequest{Name: "Dinner"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	var bill models.Bills
+	if err := db.First(&bill, "id = ?", created.ID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+
+	// Both phones read the same version and change the tip
+	tips := []float64{5, 8}
+	codes := raceWrites(t, router, "/api/bills/"+bill.ID.String(), versionETag(bill.Version()), []interface{}{
+		map[string]float64{"tip_amount": tips[0]},
+		map[string]float64{"tip_amount": tips[1]},
+	})
+	won := winner(t, codes)
+
+	if err := db.First(&bill, "id = ?", bill.ID).Error; err != nil {
+		t.Fatalf("failed to reload bill: %v", err)
+	}
+	if bill.TipAmount != tips[won] {
+		t.Errorf("tip = %v, want the winner's %v", bill.TipAmount, tips[won])
+	}
+}
+
+func TestUpdateItemConcurrentWriters(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.PUT("/api/bills/:id/items/:itemId", handler.UpdateItem)
+
+	bill, err := handler.billService.CreateBill(&models.BillRequest{Name: "Lunch"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	items, err := handler.billService.CreateItems(bill.ID, []models.ItemRequest{{Name: "Soup", Price: 6, Quantity: 1}}, "test")
+	if err != nil {
+		t.Fatalf("failed to create item: %v", err)
+	}
+	var item models.Items
+	if err := db.First(&item, items[0].ID).Error; err != nil {
+		t.Fatalf("failed to load item: %v", err)
+	}
+
+	prices := []float64{6.5, 7}
+	path := "/api/bills/" + bill.ID.String() + "/items/" + strconv.FormatUint(uint64(item.ID), 10)
+	codes := raceWrites(t, router, path, versionETag(item.Version()), []interface{}{
+		map[string]float64{"price": prices[0]},
+		map[string]float64{"price": prices[1]},
+	})
+	won := winner(t, codes)
+
+	if err := db.First(&item, item.ID).Error; err != nil {
+		t.Fatalf("failed to reload item: %v", err)
+	}
+	if item.Price != prices[won] {
+		t.Errorf("price = %v, want the winner's %v", item.Price, prices[won])
+	}
+}
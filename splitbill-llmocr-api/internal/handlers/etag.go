@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag formats a row version as a strong ETag value
+func versionETag(version int64) string {
+	return fmt.Sprintf(`"%s"`, models.FormatVersion(version))
+}
+
+// parseIfMatch extracts the row version from an If-Match header.
+// A wildcard returns a nil version meaning "any current version".
+func parseIfMatch(header string) (*int64, bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, true
+	}
+
+	value := strings.Trim(header, `"`)
+	if value == "" || strings.HasPrefix(header, "W/") {
+		return nil, false
+	}
+
+	version, err := strconv.ParseInt(value, 36, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &version, true
+}
+
+// requireIfMatch reads the If-Match header of a write that must not overwrite newer
+// changes. It answers 428 itself when the header is absent and 400 when it is malformed.
+func requireIfMatch(c *gin.Context) (*int64, bool) {
+	if c.GetHeader("If-Match") == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return nil, false
+	}
+	return optionalIfMatch(c)
+}
+
+// optionalIfMatch reads an If-Match header for writes that fall back to last write wins.
+// It returns nil when the header is absent or a wildcard, and answers 400 itself when the
+// header is malformed.
+func optionalIfMatch(c *gin.Context) (*int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil, true
+	}
+	version, ok := parseIfMatch(header)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return nil, false
+	}
+	return version, true
+}
+
+// weakETag builds a weak ETag from the values a response is derived from
+func weakETag(parts ...interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v", parts)))
+	return fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:12]))
+}
+
+// matchesIfNoneMatch reports whether an If-None-Match header lists etag. The comparison
+// is weak, so W/ prefixes are ignored on both sides.
+func matchesIfNoneMatch(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header and, when the client's If-None-Match already names
+// etag, answers 304 without a body and returns true
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	if !matchesIfNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
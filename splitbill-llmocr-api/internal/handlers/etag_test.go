@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMatchesIfNoneMatch(t *testing.T) {
+	etag := weakETag("completed", 42)
+	tests := []struct {
+		name, header string
+		want         bool
+	}{
+		{name: "missing", header: ""},
+		{name: "match", header: etag, want: true},
+		{name: "strong form of the same tag", header: etag[2:], want: true},
+		{name: "one of several", header: `W/"stale", ` + etag, want: true},
+		{name: "wildcard", header: "*", want: true},
+		{name: "mismatch", header: weakETag("processing", 42)},
+	}
+	for _, tt := range tests {
+		if got := matchesIfNoneMatch(tt.header, etag); got != tt.want {
+			t.Errorf("%s: matchesIfNoneMatch(%q) = %v, want %v", tt.name, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name, header string
+		status       int
+		version      int64
+	}{
+		{name: "missing", status: http.StatusPreconditionRequired},
+		{name: "weak", header: `W/"1"`, status: http.StatusBadRequest},
+		{name: "not base 36", header: `"!"`, status: http.StatusBadRequest},
+		{name: "wildcard", header: "*"},
+		{name: "version", header: versionETag(1234), version: 1234},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			version, ok := requireIfMatch(c)
+			if tt.status != 0 {
+				if ok || w.Code != tt.status {
+					t.Fatalf("ok = %v, status %d, want %d", ok, w.Code, tt.status)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("rejected with %d: %s", w.Code, w.Body)
+			}
+			// A wildcard matches any version, which is a nil one
+			if tt.version == 0 && version != nil || tt.version != 0 && (version == nil || *version != tt.version) {
+				t.Errorf("version = %v, want %d", version, tt.version)
+			}
+		})
+	}
+}
+
+func TestConditionalBillReads(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.GET("/api/bills/:id", handler.GetBill)
+	router.GET("/api/bills/:id/status", handler.GetBillStatus)
+	router.PUT("/api/bills/:id", handler.UpdateBill)
+
+	created, err := handler.billService.CreateBill(&models.BillRequest{Name: "Brunch"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	billPath := "/api/bills/" + created.ID.String()
+
+	for _, path := range []string{billPath, billPath + "/status"} {
+		w := performJSON(t, router, http.MethodGet, path, nil, nil)
+		etag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || etag == "" {
+			t.Fatalf("GET %s: status %d, ETag %q", path, w.Code, etag)
+		}
+
+		w = performJSON(t, router, http.MethodGet, path, nil, map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("GET %s with a matching If-None-Match: status %d, %d byte body, want an empty 304", path, w.Code, w.Body.Len())
+		}
+		w = performJSON(t, router, http.MethodGet, path, nil, map[string]string{"If-None-Match": `W/"stale"`})
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s with another If-None-Match: status %d, want 200", path, w.Code)
+		}
+	}
+
+	// A stale If-Match is refused with the current version
+	w := performJSON(t, router, http.MethodGet, billPath, nil, nil)
+	etag := w.Header().Get("ETag")
+	var bill models.Bills
+	if err := db.First(&bill, "id = ?", created.ID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+	stale := versionETag(bill.Version())
+	if w := performJSON(t, router, http.MethodPut, billPath, map[string]float64{"tip_amount": 5}, map[string]string{"If-Match": stale}); w.Code != http.StatusOK {
+		t.Fatalf("PUT with the current version: status %d: %s", w.Code, w.Body)
+	}
+	w = performJSON(t, router, http.MethodPut, billPath, map[string]float64{"tip_amount": 8}, map[string]string{"If-Match": stale})
+	if w.Code != http.StatusPreconditionFailed || w.Header().Get("ETag") == stale {
+		t.Errorf("PUT with a stale version: status %d, ETag %q, want 412 with the new version", w.Code, w.Header().Get("ETag"))
+	}
+
+	// The write changed the bill, so the old read ETag no longer matches
+	w = performJSON(t, router, http.MethodGet, billPath, nil, map[string]string{"If-None-Match": etag})
+	if w.Code != http.StatusOK {
+		t.Errorf("GET after an update with the old ETag: status %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newTestBillHandler returns a bill handler backed by a fresh test database with the
+// default configuration, letting configure adjust it first. Tests skip without a database.
+func newTestBillHandler(t *testing.T, configure func(*config.Config)) (*BillHandler, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db := testdb.Open(t)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	views := cache.NewBillViewCache(cfg.ViewCacheTTL, cfg.ViewCacheMaxEntries)
+	return NewBillHandler(services.NewBillService(db, cfg, storage.NewLocal(t.TempDir()), nil, views)), db
+}
+
+// performJSON sends body as JSON to router and returns the recorded response
+func performJSON(t *testing.T, router http.Handler, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// asUser logs requests in as user, standing in for Authenticate
+func asUser(user models.RegisterResponse) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user", user)
+		c.Next()
+	}
+}
+
+// createTestUser stores a user named name and returns it as the context holds it
+func createTestUser(t *testing.T, db *gorm.DB, name string) models.RegisterResponse {
+	t.Helper()
+
+	user := models.Users{Username: name, Email: name + "@example.com", Password: "x", Name: name}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user %s: %v", name, err)
+	}
+	return models.RegisterResponse{ID: user.ID, Username: user.Username, Email: user.Email, Name: user.Name, Role: user.Role}
+}
@@ -0,0 +1,258 @@
+// Package bind reads path parameters, query strings and JSON bodies into requests for the
+// handlers, answering anything malformed or invalid with a consistent error envelope: an
+// "error" message, a machine-readable "code" and, for validation failures, field-level
+// "details".
+package bind
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// validate is shared by every handler so the struct tag cache is built once
+var validate = newValidator()
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// newValidator creates a validator that reports fields by their JSON names
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("metadata", validateMetadata)
+	v.RegisterValidation("rgbcolor", validateRGBColor)
+	return v
+}
+
+// rgbColorPattern matches a #RRGGBB hex color
+var rgbColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// validateRGBColor accepts #RRGGBB hex colors
+func validateRGBColor(fl validator.FieldLevel) bool {
+	return rgbColorPattern.MatchString(fl.Field().String())
+}
+
+// validateMetadata enforces the size cap and non-empty keys on bill metadata
+func validateMetadata(fl validator.FieldLevel) bool {
+	metadata, ok := fl.Field().Interface().(models.Metadata)
+	if !ok {
+		return false
+	}
+	for key := range metadata {
+		if strings.TrimSpace(key) == "" {
+			return false
+		}
+	}
+	return metadata.Size() <= models.MaxMetadataBytes
+}
+
+// Error codes of the envelopes written by this package
+const (
+	// CodeInvalidParameter is a malformed path parameter, such as a bill ID that isn't a UUID
+	CodeInvalidParameter = "INVALID_PARAMETER"
+	// CodeInvalidQuery is a query parameter that can't be read
+	CodeInvalidQuery = "INVALID_QUERY"
+	// CodeInvalidBody is a body that isn't JSON or doesn't fit the request
+	CodeInvalidBody = "INVALID_BODY"
+	// CodeValidationFailed is a request that was read but broke its rules; details lists
+	// each failing field
+	CodeValidationFailed = "VALIDATION_FAILED"
+)
+
+// abort writes an error envelope with code
+func abort(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{"error": message, "code": code})
+}
+
+// BillID parses the :id path parameter as a bill UUID.
+// It writes a 400 response and returns false when the parameter is malformed.
+func BillID(c *gin.Context) (uuid.UUID, bool) {
+	return parseUUID(c, c.Param("id"), "Invalid bill ID", CodeInvalidParameter)
+}
+
+// UUIDParam parses a UUID path parameter such as :billId.
+// It writes a 400 response and returns false when the parameter is malformed.
+func UUIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	return parseUUID(c, c.Param(name), fmt.Sprintf("Invalid %s", paramLabel(name)), CodeInvalidParameter)
+}
+
+// UUIDQuery parses a required UUID query parameter such as ?source_bill_id.
+// It writes a 400 response and returns false when the parameter is missing or malformed.
+func UUIDQuery(c *gin.Context, name string) (uuid.UUID, bool) {
+	return parseUUID(c, c.Query(name), fmt.Sprintf("Invalid %s", paramLabel(name)), CodeInvalidQuery)
+}
+
+// parseUUID parses value, answering a malformed one with message and code
+func parseUUID(c *gin.Context, value, message, code string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		abort(c, http.StatusBadRequest, code, message)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// UintParam parses a numeric path parameter such as :participantId.
+// It writes a 400 response and returns false when the parameter is not a valid ID.
+func UintParam(c *gin.Context, name string) (uint, bool) {
+	value, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil || value == 0 {
+		abort(c, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid %s", paramLabel(name)))
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// InvalidBody answers a body that could not be read or decoded with a 400
+func InvalidBody(c *gin.Context, err error) {
+	abort(c, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("Invalid request: %v", err))
+}
+
+// JSON binds the JSON body into req and runs the validate struct tags.
+// Malformed bodies produce a 400, validation failures a 422 with field-level details.
+func JSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		InvalidBody(c, err)
+		return false
+	}
+	return Validate(c, req, http.StatusUnprocessableEntity)
+}
+
+// Query binds query parameters into req and runs the validate struct tags.
+// Malformed values and validation failures both produce a 400, the latter with
+// field-level details.
+func Query(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindQuery(req); err != nil {
+		abort(c, http.StatusBadRequest, CodeInvalidQuery, fmt.Sprintf("Invalid query parameters: %v", err))
+		return false
+	}
+	return Validate(c, req, http.StatusBadRequest)
+}
+
+// Validate runs the validate struct tags on an already decoded request, answering
+// failures with status
+func Validate(c *gin.Context, req interface{}, status int) bool {
+	if err := validate.Struct(req); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			InvalidBody(c, err)
+			return false
+		}
+
+		details := make([]FieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			path := fieldPath(fieldErr)
+			message := fieldMessage(fieldErr)
+			// Say how a localized string was read, so a misread number is easy to spot
+			if note, ok := interpretedNumber(c, path); ok {
+				message += " (" + note + ")"
+			}
+			details = append(details, FieldError{Field: path, Message: message})
+		}
+
+		validationFailed(c, status, details)
+		return false
+	}
+	return true
+}
+
+// validationFailed writes the envelope of a request that broke its rules
+func validationFailed(c *gin.Context, status int, details []FieldError) {
+	c.JSON(status, gin.H{
+		"error":   "Validation failed",
+		"code":    CodeValidationFailed,
+		"details": details,
+	})
+}
+
+// fieldPath returns the JSON path of the failing field without the struct name prefix
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
+
+// fieldMessage turns a validator rule into a human readable message
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fieldErr.Param())
+	case "metadata":
+		return fmt.Sprintf("must have non-empty keys and be at most %d bytes as JSON", models.MaxMetadataBytes)
+	case "rgbcolor":
+		return "must be a hex color like #1E88E5"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "unique":
+		if fieldErr.Param() != "" {
+			return fmt.Sprintf("must not repeat the same %s", strings.ToLower(fieldErr.Param()))
+		}
+		return "must not contain duplicates"
+	default:
+		return fmt.Sprintf("failed the '%s' rule", fieldErr.Tag())
+	}
+}
+
+// paramLabel converts a parameter name like "participantId" or "source_bill_id" into
+// "participant ID" or "source bill ID"
+func paramLabel(name string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_id"), "Id"), "ID")
+	if base == "" || base == "id" {
+		return "ID"
+	}
+
+	var label strings.Builder
+	for i, r := range base {
+		switch {
+		case r == '_':
+			label.WriteRune(' ')
+		case unicode.IsUpper(r) && i > 0:
+			label.WriteRune(' ')
+			label.WriteRune(unicode.ToLower(r))
+		default:
+			label.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return label.String() + " ID"
+}
@@ -0,0 +1,231 @@
+package bind
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// envelope is the error body written by this package
+type envelope struct {
+	Error   string       `json:"error"`
+	Code    string       `json:"code"`
+	Details []FieldError `json:"details"`
+}
+
+// perform serves one request through a router that runs handle on route and answers
+// 204 when handle accepts the request
+func perform(t *testing.T, route string, handle func(*gin.Context) bool, method, path, body string) (*httptest.ResponseRecorder, envelope) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		if handle(c) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var got envelope
+	if w.Code != http.StatusNoContent {
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode %s: %v", w.Body, err)
+		}
+	}
+	return w, got
+}
+
+// checkEnvelope fails unless the response has status, code and, when set, message
+func checkEnvelope(t *testing.T, w *httptest.ResponseRecorder, got envelope, status int, code, message string) {
+	t.Helper()
+	if w.Code != status || got.Code != code {
+		t.Fatalf("status %d code %q (%s), want %d %q", w.Code, got.Code, w.Body, status, code)
+	}
+	if message != "" && got.Error != message {
+		t.Errorf("error = %q, want %q", got.Error, message)
+	}
+}
+
+func TestBillID(t *testing.T) {
+	tests := []struct {
+		name, id string
+		ok       bool
+	}{
+		{name: "valid", id: "7f9c24e8-3b12-4f6a-9d3e-2a1b0c9d8e7f", ok: true},
+		{name: "not a UUID", id: "not-a-uuid"},
+		{name: "number", id: "123"},
+		{name: "truncated", id: "7f9c24e8-3b12-4f6a-9d3e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := func(c *gin.Context) bool {
+				_, ok := BillID(c)
+				return ok
+			}
+			w, got := perform(t, "/bills/:id", handle, http.MethodGet, "/bills/"+tt.id, "")
+			if tt.ok {
+				checkEnvelope(t, w, got, http.StatusNoContent, "", "")
+				return
+			}
+			checkEnvelope(t, w, got, http.StatusBadRequest, CodeInvalidParameter, "Invalid bill ID")
+		})
+	}
+}
+
+func TestUUIDParamAndQuery(t *testing.T) {
+	param := func(c *gin.Context) bool {
+		_, ok := UUIDParam(c, "billId")
+		return ok
+	}
+	w, got := perform(t, "/attention/:billId", param, http.MethodPost, "/attention/nope", "")
+	checkEnvelope(t, w, got, http.StatusBadRequest, CodeInvalidParameter, "Invalid bill ID")
+
+	query := func(c *gin.Context) bool {
+		_, ok := UUIDQuery(c, "source_bill_id")
+		return ok
+	}
+	for _, path := range []string{"/copy", "/copy?source_bill_id=", "/copy?source_bill_id=nope"} {
+		w, got := perform(t, "/copy", query, http.MethodPost, path, "")
+		checkEnvelope(t, w, got, http.StatusBadRequest, CodeInvalidQuery, "Invalid source bill ID")
+	}
+	w, got = perform(t, "/copy", query, http.MethodPost, "/copy?source_bill_id=7f9c24e8-3b12-4f6a-9d3e-2a1b0c9d8e7f", "")
+	checkEnvelope(t, w, got, http.StatusNoContent, "", "")
+}
+
+func TestUintParam(t *testing.T) {
+	tests := []struct {
+		name, value string
+		want        uint
+	}{
+		{name: "valid", value: "42", want: 42},
+		{name: "word", value: "abc"},
+		{name: "zero", value: "0"},
+		{name: "negative", value: "-1"},
+		{name: "decimal", value: "1.5"},
+		{name: "too large", value: "4294967296"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id uint
+			handle := func(c *gin.Context) bool {
+				var ok bool
+				id, ok = UintParam(c, "participantId")
+				return ok
+			}
+			w, got := perform(t, "/participants/:participantId", handle, http.MethodGet, "/participants/"+tt.value, "")
+			if tt.want != 0 {
+				checkEnvelope(t, w, got, http.StatusNoContent, "", "")
+				if id != tt.want {
+					t.Errorf("id = %d, want %d", id, tt.want)
+				}
+				return
+			}
+			checkEnvelope(t, w, got, http.StatusBadRequest, CodeInvalidParameter, "Invalid participant ID")
+		})
+	}
+}
+
+// testRequest stands in for a model with binding tags
+type testRequest struct {
+	Name  string  `json:"name" validate:"required,max=5"`
+	Price float64 `json:"price" validate:"gte=0"`
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name, body string
+		status     int
+		code       string
+		fields     []string
+	}{
+		{name: "valid", body: `{"name": "Tea", "price": 3}`, status: http.StatusNoContent},
+		{name: "malformed", body: `{"name": `, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "wrong type", body: `{"name": 5}`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "array", body: `[]`, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "empty", body: ``, status: http.StatusBadRequest, code: CodeInvalidBody},
+		{name: "missing field", body: `{}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed, fields: []string{"name"}},
+		{name: "two rules broken", body: `{"name": "Noodles", "price": -1}`, status: http.StatusUnprocessableEntity, code: CodeValidationFailed, fields: []string{"name", "price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := func(c *gin.Context) bool {
+				var req testRequest
+				return JSON(c, &req)
+			}
+			w, got := perform(t, "/items", handle, http.MethodPost, "/items", tt.body)
+			checkEnvelope(t, w, got, tt.status, tt.code, "")
+			if len(got.Details) != len(tt.fields) {
+				t.Fatalf("details = %+v, want fields %v", got.Details, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if got.Details[i].Field != field || got.Details[i].Message == "" {
+					t.Errorf("details[%d] = %+v, want a message for %s", i, got.Details[i], field)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	type listQuery struct {
+		Page int `form:"page" validate:"omitempty,gte=1"`
+	}
+	handle := func(c *gin.Context) bool {
+		var query listQuery
+		return Query(c, &query)
+	}
+
+	w, got := perform(t, "/bills", handle, http.MethodGet, "/bills?page=two", "")
+	checkEnvelope(t, w, got, http.StatusBadRequest, CodeInvalidQuery, "")
+	w, got = perform(t, "/bills", handle, http.MethodGet, "/bills?page=-1", "")
+	checkEnvelope(t, w, got, http.StatusBadRequest, CodeValidationFailed, "Validation failed")
+	w, got = perform(t, "/bills", handle, http.MethodGet, "/bills?page=2", "")
+	checkEnvelope(t, w, got, http.StatusNoContent, "", "")
+}
+
+func TestLocalizeNumbersRejectsUnreadableNumbers(t *testing.T) {
+	handle := func(c *gin.Context) bool {
+		if !LocalizeNumbers(c) {
+			return false
+		}
+		var req testRequest
+		return JSON(c, &req)
+	}
+
+	w, got := perform(t, "/items", handle, http.MethodPost, "/items", `{"name": "Tea", "price": "twelve"}`)
+	checkEnvelope(t, w, got, http.StatusUnprocessableEntity, CodeValidationFailed, "Validation failed")
+	if len(got.Details) != 1 || got.Details[0].Field != "price" {
+		t.Errorf("details = %+v, want one for price", got.Details)
+	}
+
+	// A number that reads fine but breaks a rule says how it was read
+	w, got = perform(t, "/items", handle, http.MethodPost, "/items", `{"name": "Tea", "price": "-1,5", "locale": "id"}`)
+	checkEnvelope(t, w, got, http.StatusUnprocessableEntity, CodeValidationFailed, "")
+	if len(got.Details) != 1 || !strings.Contains(got.Details[0].Message, `"-1,5" was read as -1.5`) {
+		t.Errorf("details = %+v, want the reading of price", got.Details)
+	}
+}
+
+func TestParamLabel(t *testing.T) {
+	tests := map[string]string{
+		"id":             "ID",
+		"participantId":  "participant ID",
+		"itemId":         "item ID",
+		"userID":         "user ID",
+		"billId":         "bill ID",
+		"source_bill_id": "source bill ID",
+	}
+	for name, want := range tests {
+		if got := paramLabel(name); got != want {
+			t.Errorf("paramLabel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/localenum"
+	"github.com/gin-gonic/gin"
+)
+
+// localizedNumberFields are the amount and quantity fields of manual entry that may also
+// be sent as strings written the way the user's locale writes numbers
+var localizedNumberFields = []string{"price", "quantity", "discount_amount", "tax_amount", "tip_amount", "service_charge_amount"}
+
+// interpretedNumbersKey holds, by field path, how LocalizeNumbers read each string, so
+// validation errors can say which value they are about
+const interpretedNumbersKey = "interpretedNumbers"
+
+// LocalizeNumbers rewrites the localized number fields of a JSON object body, or of each
+// object in an array body, from strings into canonical JSON numbers. A string is read with
+// the object's "locale" field, or else the request's Accept-Language, so "12.500" is 12500
+// for "id" and 12.5 for "en". JSON numbers are left exactly as sent. A string that is not
+// a number in that locale is answered with a 422 and false. Bodies that are not JSON are
+// passed on untouched for the binder to reject.
+func LocalizeNumbers(c *gin.Context) bool {
+	body, err := c.GetRawData()
+	if err != nil {
+		InvalidBody(c, err)
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return true
+	}
+
+	headerLocale := localenum.FromAcceptLanguage(c.GetHeader("Accept-Language"))
+	interpreted := make(map[string]string)
+	var fieldErrors []FieldError
+	switch value := decoded.(type) {
+	case map[string]interface{}:
+		fieldErrors = localizeObject(value, "", headerLocale, interpreted)
+	case []interface{}:
+		for i, element := range value {
+			if object, ok := element.(map[string]interface{}); ok {
+				fieldErrors = append(fieldErrors, localizeObject(object, fmt.Sprintf("items[%d].", i), headerLocale, interpreted)...)
+			}
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		validationFailed(c, http.StatusUnprocessableEntity, fieldErrors)
+		return false
+	}
+	if len(interpreted) == 0 {
+		return true
+	}
+
+	rewritten, err := json.Marshal(decoded)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to read numbers: %v", err)})
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+	c.Set(interpretedNumbersKey, interpreted)
+	return true
+}
+
+// localizeObject rewrites the string number fields of one object in place, recording how
+// each was read under prefix plus its name
+func localizeObject(object map[string]interface{}, prefix, headerLocale string, interpreted map[string]string) []FieldError {
+	locale := headerLocale
+	if explicit, ok := object["locale"].(string); ok && strings.TrimSpace(explicit) != "" {
+		locale = strings.TrimSpace(explicit)
+	}
+
+	var fieldErrors []FieldError
+	for _, field := range localizedNumberFields {
+		text, ok := object[field].(string)
+		if !ok {
+			continue
+		}
+		number, err := localenum.Parse(text, locale)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: prefix + field, Message: fmt.Sprintf("must be a number: %v", err)})
+			continue
+		}
+		canonical := canonicalNumber(number)
+		object[field] = json.Number(canonical)
+		interpreted[prefix+field] = fmt.Sprintf("%q was read as %s", text, canonical)
+	}
+	return fieldErrors
+}
+
+// canonicalNumber formats a number without exponent or trailing zeros
+func canonicalNumber(number *big.Rat) string {
+	if number.IsInt() {
+		return number.Num().String()
+	}
+	return strings.TrimRight(strings.TrimRight(number.FloatString(10), "0"), ".")
+}
+
+// interpretedNumber returns how LocalizeNumbers read the field at path, if it read one
+func interpretedNumber(c *gin.Context, path string) (string, bool) {
+	value, ok := c.Get(interpretedNumbersKey)
+	if !ok {
+		return "", false
+	}
+	note, ok := value.(map[string]string)[path]
+	return note, ok
+}
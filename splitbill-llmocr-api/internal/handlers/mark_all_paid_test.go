@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMarkAllPaid(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.POST("/api/bills/:id/participants/mark-all-paid", handler.MarkAllPaid)
+
+	s := handler.billService
+	bill, err := s.CreateBill(&models.BillRequest{Name: "Picnic"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	path := "/api/bills/" + bill.ID.String() + "/participants/mark-all-paid"
+
+	if w := performJSON(t, router, http.MethodPost, path, map[string]interface{}{}, nil); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("without participants: status %d, want 422: %s", w.Code, w.Body)
+	}
+
+	for _, name := range []string{"Ana", "Ben"} {
+		if _, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: name}, "test"); err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+	}
+
+	// An archived bill keeps its payments as they were
+	if _, err := s.ArchiveBill(bill.ID, "test"); err != nil {
+		t.Fatalf("failed to archive bill: %v", err)
+	}
+	if w := performJSON(t, router, http.MethodPost, path, map[string]interface{}{}, nil); w.Code != http.StatusConflict {
+		t.Errorf("archived bill: status %d, want 409: %s", w.Code, w.Body)
+	}
+	var paid int64
+	db.Model(&models.Participants{}).Where("bill_id = ? AND payment_status = ?", bill.ID, "paid").Count(&paid)
+	if paid != 0 {
+		t.Errorf("%d participants marked paid on an archived bill, want 0", paid)
+	}
+
+	if _, err := s.UnarchiveBill(bill.ID, "test"); err != nil {
+		t.Fatalf("failed to unarchive bill: %v", err)
+	}
+	if w := performJSON(t, router, http.MethodPost, path, map[string]interface{}{}, nil); w.Code != http.StatusOK {
+		t.Fatalf("unarchived bill: status %d, want 200: %s", w.Code, w.Body)
+	}
+	db.Model(&models.Participants{}).Where("bill_id = ? AND payment_status = ?", bill.ID, "paid").Count(&paid)
+	if paid != 2 {
+		t.Errorf("%d participants marked paid, want 2", paid)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeleteParticipantReportsAssignmentsRemoved(t *testing.T) {
+	handler, _ := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.DELETE("/api/bills/:id/participants/:participantId", handler.DeleteParticipant)
+
+	s := handler.billService
+	bill, err := s.CreateBill(&models.BillRequest{Name: "Tapas"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	items, err := s.CreateItems(bill.ID, []models.ItemRequest{
+		{Name: "Patatas", Price: 6, Quantity: 1},
+		{Name: "Croquetas", Price: 8, Quantity: 1},
+	}, "test")
+	if err != nil {
+		t.Fatalf("failed to create items: %v", err)
+	}
+	participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ana"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	for _, item := range items {
+		if _, err := s.AssignItem(bill.ID, item.ID, participant.ID, 1, "test"); err != nil {
+			t.Fatalf("failed to assign item %d: %v", item.ID, err)
+		}
+	}
+
+	path := "/api/bills/" + bill.ID.String() + "/participants/" + strconv.FormatUint(uint64(participant.ID), 10)
+	w := performJSON(t, router, http.MethodDelete, path, nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		AssignmentsRemoved int64 `json:"assignments_removed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.AssignmentsRemoved != 2 {
+		t.Errorf("assignments_removed = %d, want 2", body.AssignmentsRemoved)
+	}
+
+	// Deleting again finds nothing
+	if w := performJSON(t, router, http.MethodDelete, path, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", w.Code)
+	}
+}
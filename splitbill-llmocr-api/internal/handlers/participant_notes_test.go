@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestParticipantNotesAndTags(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	owner := createTestUser(t, db, "owner")
+	bill, err := handler.billService.CreateBill(&models.BillRequest{Name: "Dinner"}, &owner.ID, "", true)
+	if err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+	router := gin.New()
+	router.Use(asUser(owner))
+	router.POST("/api/bills/:id/participants", handler.AddParticipant)
+	router.PUT("/api/bills/:id/participants/:participantId", handler.UpdateParticipant)
+
+	w := performJSON(t, router, http.MethodPost, "/api/bills/"+bill.ID.String()+"/participants", map[string]interface{}{
+		"name":  "Rina",
+		"notes": "pays by GoPay",
+		"tags":  []string{"vegetarian"},
+	}, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("add participant: status %d %s", w.Code, w.Body)
+	}
+	var rina models.Participants
+	if err := json.Unmarshal(w.Body.Bytes(), &rina); err != nil {
+		t.Fatalf("failed to decode participant: %v", err)
+	}
+	path := fmt.Sprintf("/api/bills/%s/participants/%d", bill.ID, rina.ID)
+
+	// Tags are trimmed and kept once, in their first spelling
+	w = performJSON(t, router, http.MethodPut, path, map[string]interface{}{
+		"notes": "  pays by GoPay, no alcohol ",
+		"tags":  []string{" non-drinker ", "Vegetarian", "vegetarian"},
+	}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update participant: status %d %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &rina); err != nil {
+		t.Fatalf("failed to decode participant: %v", err)
+	}
+	if rina.Notes != "pays by GoPay, no alcohol" || len(rina.Tags) != 2 || rina.Tags[0] != "non-drinker" || rina.Tags[1] != "Vegetarian" {
+		t.Errorf("participant = notes %q tags %v", rina.Notes, rina.Tags)
+	}
+
+	tooManyTags := make([]string, 11)
+	for i := range tooManyTags {
+		tooManyTags[i] = fmt.Sprintf("tag%d", i)
+	}
+	invalid := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{name: "notes over 500 characters", body: map[string]interface{}{"notes": strings.Repeat("a", 501)}},
+		{name: "more than 10 tags", body: map[string]interface{}{"tags": tooManyTags}},
+		{name: "tag over 32 characters", body: map[string]interface{}{"tags": []string{strings.Repeat("t", 33)}}},
+		{name: "blank tag", body: map[string]interface{}{"tags": []string{""}}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := performJSON(t, router, http.MethodPut, path, tt.body, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("status %d %s, want 400", w.Code, w.Body)
+			}
+		})
+	}
+
+	var stored models.Participants
+	if err := db.First(&stored, rina.ID).Error; err != nil {
+		t.Fatalf("failed to load participant: %v", err)
+	}
+	if stored.Notes != rina.Notes || len(stored.Tags) != 2 {
+		t.Errorf("refused updates changed the participant: notes %q tags %v", stored.Notes, stored.Tags)
+	}
+
+	// The public activity log names the changed fields, not their values
+	var event models.BillEvents
+	if err := db.Where("bill_id = ? AND action = ?", bill.ID, services.EventParticipantUpdated).Last(&event).Error; err != nil {
+		t.Fatalf("failed to load the update event: %v", err)
+	}
+	if payload, _ := json.Marshal(event.Payload); strings.Contains(string(payload), "GoPay") || strings.Contains(string(payload), "non-drinker") {
+		t.Errorf("event payload leaks notes or tags: %s", payload)
+	}
+}
+
+func TestParticipantNotesRedaction(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	owner := createTestUser(t, db, "owner")
+	other := createTestUser(t, db, "other")
+	bill, err := handler.billService.CreateBill(&models.BillRequest{Name: "Dinner"}, &owner.ID, "", true)
+	if err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+	participant, err := handler.billService.AddParticipant(bill.ID, &models.ParticipantRequest{
+		Name:  "Rina",
+		Notes: "pays by GoPay",
+		Tags:  models.Tags{"vegetarian"},
+	}, services.UserActor(&owner.ID))
+	if err != nil {
+		t.Fatalf("AddParticipant: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		user        *models.RegisterResponse
+		wantPrivate bool
+	}{
+		{name: "anonymous", wantPrivate: false},
+		{name: "other user", user: &other, wantPrivate: false},
+		{name: "owner", user: &owner, wantPrivate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if tt.user != nil {
+				router.Use(asUser(*tt.user))
+			}
+			router.GET("/api/bills/:id", handler.GetBill)
+			router.GET("/api/bills/:id/participants", handler.GetParticipants)
+			router.GET("/api/bills/:id/participants/:participantId", handler.GetParticipant)
+
+			check := func(where, notes string, tags models.Tags) {
+				t.Helper()
+				if got := notes != "" || len(tags) > 0; got != tt.wantPrivate {
+					t.Errorf("%s: notes %q tags %v, want them shown %v", where, notes, tags, tt.wantPrivate)
+				}
+			}
+			get := func(path string, into interface{}) {
+				t.Helper()
+				w := performJSON(t, router, http.MethodGet, path, nil, nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("GET %s: status %d %s", path, w.Code, w.Body)
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), into); err != nil {
+					t.Fatalf("GET %s: failed to decode: %v", path, err)
+				}
+			}
+
+			var response models.BillResponse
+			get("/api/bills/"+bill.ID.String(), &response)
+			if len(response.Participants) != 1 {
+				t.Fatalf("bill has %d participants, want 1", len(response.Participants))
+			}
+			check("bill", response.Participants[0].Notes, response.Participants[0].Tags)
+
+			var list []models.Participants
+			get("/api/bills/"+bill.ID.String()+"/participants", &list)
+			if len(list) != 1 {
+				t.Fatalf("%d participants listed, want 1", len(list))
+			}
+			check("participant list", list[0].Notes, list[0].Tags)
+
+			var single models.Participants
+			get(fmt.Sprintf("/api/bills/%s/participants/%d", bill.ID, participant.ID), &single)
+			check("participant", single.Notes, single.Tags)
+		})
+	}
+}
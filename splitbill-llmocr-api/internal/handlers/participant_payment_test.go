@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecordParticipantPaymentRequiresIfMatch(t *testing.T) {
+	handler, _ := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.POST("/api/bills/:id/participants/:participantId/payment", handler.RecordParticipantPayment)
+
+	bill, err := handler.billService.CreateBill(&models.BillRequest{Name: "Karaoke"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	participant, err := handler.billService.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ana"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	path := "/api/bills/" + bill.ID.String() + "/participants/" + strconv.FormatUint(uint64(participant.ID), 10) + "/payment"
+	etag := versionETag(participant.Version())
+	body := map[string]string{"status": "unpaid"}
+
+	if w := performJSON(t, router, http.MethodPost, path, body, nil); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("without If-Match: status %d, want 428: %s", w.Code, w.Body)
+	}
+	if w := performJSON(t, router, http.MethodPost, path, body, map[string]string{"If-Match": etag}); w.Code != http.StatusOK {
+		t.Fatalf("with the current ETag: status %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := performJSON(t, router, http.MethodPost, path, body, map[string]string{"If-Match": etag}); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("with a stale ETag: status %d, want 412: %s", w.Code, w.Body)
+	}
+}
+
+func TestUpdateParticipantPaymentInterleaved(t *testing.T) {
+	handler, db := newTestBillHandler(t, nil)
+	router := gin.New()
+	router.GET("/api/bills/:id/participants/:participantId", handler.GetParticipant)
+	router.PATCH("/api/bills/:id/participants/:participantId/payment", handler.UpdateParticipantPayment)
+
+	bill, err := handler.billService.CreateBill(&models.BillRequest{Name: "Hotpot"}, nil, "", true)
+	if err != nil {
+		t.Fatalf("failed to create bill: %v", err)
+	}
+	participant, err := handler.billService.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ben"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	path := "/api/bills/" + bill.ID.String() + "/participants/" + strconv.FormatUint(uint64(participant.ID), 10)
+
+	// Both organizers open the participant before either of them saves
+	first := performJSON(t, router, http.MethodGet, path, nil, nil).Header().Get("ETag")
+	second := performJSON(t, router, http.MethodGet, path, nil, nil).Header().Get("ETag")
+	if first == "" || first != second {
+		t.Fatalf("ETags %q and %q, want the same one", first, second)
+	}
+
+	w := performJSON(t, router, http.MethodPatch, path+"/payment", map[string]string{"payment_status": "paid"}, map[string]string{"If-Match": first})
+	if w.Code != http.StatusOK {
+		t.Fatalf("first organizer: status %d, want 200: %s", w.Code, w.Body)
+	}
+	current := w.Header().Get("ETag")
+
+	// The second one, still showing unpaid, is refused and sees what was saved
+	w = performJSON(t, router, http.MethodPatch, path+"/payment", map[string]string{"payment_status": "unpaid"}, map[string]string{"If-Match": second})
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("second organizer: status %d, want 412: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("ETag") != current {
+		t.Errorf("412 ETag = %q, want the current %q", w.Header().Get("ETag"), current)
+	}
+	var conflict struct {
+		Current models.Participants `json:"current"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("failed to decode 412 body: %v", err)
+	}
+	if conflict.Current.PaymentStatus != "paid" {
+		t.Errorf("412 current status = %q, want paid", conflict.Current.PaymentStatus)
+	}
+
+	var stored models.Participants
+	if err := db.First(&stored, participant.ID).Error; err != nil {
+		t.Fatalf("failed to load participant: %v", err)
+	}
+	if stored.PaymentStatus != "paid" {
+		t.Errorf("stale write overwrote the payment: status %s", stored.PaymentStatus)
+	}
+
+	// After refreshing, the second organizer's change goes through
+	w = performJSON(t, router, http.MethodPatch, path+"/payment", map[string]string{"payment_status": "unpaid"}, map[string]string{"If-Match": current})
+	if w.Code != http.StatusOK {
+		t.Errorf("retry with the current ETag: status %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+type StatusHandler struct {
+	billService *services.BillService
+	db          middleware.DatabaseAvailability
+	storage     *storage.Local
+	config      *config.Config
+}
+
+func NewStatusHandler(billService *services.BillService, db middleware.DatabaseAvailability, store *storage.Local, config *config.Config) *StatusHandler {
+	return &StatusHandler{
+		billService: billService,
+		db:          db,
+		storage:     store,
+		config:      config,
+	}
+}
+
+// GetStatus reports service interruptions for the frontend banner.
+// Everything comes from in-memory state, so the endpoint is cheap enough to poll.
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	now := time.Now().UTC()
+	extraction := h.billService.ExtractionStatus()
+	databaseAvailable := h.db.IsAvailable()
+	maintenanceActive := h.config.MaintenanceActive(now)
+	storageStatus := h.storage.Status()
+
+	// Overall status, from most to least severe
+	status := "ok"
+	switch {
+	case maintenanceActive:
+		status = "maintenance"
+	case !databaseAvailable:
+		status = "down"
+	case extraction.Enabled && !extraction.Available:
+		status = "degraded"
+	case !storageStatus.Available:
+		status = "degraded"
+	}
+
+	var maintenance gin.H
+	if h.config.MaintenanceMessage != "" {
+		maintenance = gin.H{
+			"active":    maintenanceActive,
+			"message":   h.config.MaintenanceMessage,
+			"starts_at": h.config.MaintenanceStartsAt,
+			"ends_at":   h.config.MaintenanceEndsAt,
+		}
+	}
+
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, gin.H{
+		"status":      status,
+		"version":     config.Version,
+		"database":    gin.H{"available": databaseAvailable},
+		"extraction":  extraction,
+		"storage":     storageStatus,
+		"maintenance": maintenance,
+		"checked_at":  now.Format(time.RFC3339),
+	})
+}
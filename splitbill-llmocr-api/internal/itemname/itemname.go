@@ -0,0 +1,137 @@
+package itemname
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalization steps that can be switched on with ITEM_NAME_STEPS
+const (
+	StepTitleCase          = "title_case"
+	StepStripCodes         = "strip_codes"
+	StepStripUnits         = "strip_units"
+	StepCollapseWhitespace = "collapse_whitespace"
+)
+
+// KnownSteps lists every step name accepted in the configuration
+var KnownSteps = []string{StepTitleCase, StepStripCodes, StepStripUnits, StepCollapseWhitespace}
+
+// DefaultUnits are the unit and packaging tokens removed by strip_units when none are configured
+var DefaultUnits = []string{"ml", "l", "ltr", "cl", "g", "gr", "kg", "oz", "pc", "pcs", "btl"}
+
+// minCodeDigits keeps short numbers like "Combo 2" while dropping SKU and PLU codes
+const minCodeDigits = 4
+
+// codePattern matches a trailing token made only of digits, such as a SKU
+var codePattern = regexp.MustCompile(fmt.Sprintf(`^\d{%d,}$`, minCodeDigits))
+
+// Pipeline cleans up item names returned by extraction. Steps always run in the same
+// order, whatever order they were configured in: strip codes, strip units, collapse
+// whitespace, title case and finally truncation to MaxLength.
+type Pipeline struct {
+	steps       map[string]bool
+	unitPattern *regexp.Regexp
+	maxLength   int
+}
+
+// IsStep reports whether name is a known normalization step
+func IsStep(name string) bool {
+	for _, step := range KnownSteps {
+		if step == name {
+			return true
+		}
+	}
+	return false
+}
+
+// New builds a pipeline running the given steps; unknown step names are ignored, so check
+// them with IsStep first. units replaces DefaultUnits when not empty, and a positive
+// maxLength truncates names to that many characters.
+func New(steps []string, units []string, maxLength int) *Pipeline {
+	enabled := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		enabled[step] = true
+	}
+
+	if len(units) == 0 {
+		units = DefaultUnits
+	}
+	quoted := make([]string, len(units))
+	for i, unit := range units {
+		quoted[i] = regexp.QuoteMeta(strings.ToLower(unit))
+	}
+
+	return &Pipeline{
+		steps: enabled,
+		// A unit on its own ("BTL") or glued to an amount ("600ML", "1.5L")
+		unitPattern: regexp.MustCompile(`^(\d+([.,]\d+)?)?(` + strings.Join(quoted, "|") + `)$`),
+		maxLength:   maxLength,
+	}
+}
+
+// Normalize applies the configured steps to name. A name that would end up empty is
+// returned trimmed but otherwise unchanged, so an item is never stored without a name.
+func (p *Pipeline) Normalize(name string) string {
+	original := strings.Fields(name)
+	words := original
+
+	if p.steps[StepStripCodes] {
+		for len(words) > 1 && codePattern.MatchString(words[len(words)-1]) {
+			words = words[:len(words)-1]
+		}
+	}
+
+	if p.steps[StepStripUnits] {
+		kept := words[:0:0]
+		for _, word := range words {
+			if !p.unitPattern.MatchString(strings.ToLower(word)) {
+				kept = append(kept, word)
+			}
+		}
+		if len(kept) > 0 {
+			words = kept
+		}
+	}
+
+	// Without collapse_whitespace the original spacing survives unless words were removed
+	normalized := strings.TrimSpace(name)
+	if p.steps[StepCollapseWhitespace] || len(words) != len(original) {
+		normalized = strings.Join(words, " ")
+	}
+
+	if p.steps[StepTitleCase] {
+		normalized = titleCase(normalized)
+	}
+
+	if p.maxLength > 0 && utf8.RuneCountInString(normalized) > p.maxLength {
+		normalized = strings.TrimSpace(string([]rune(normalized)[:p.maxLength]))
+	}
+
+	if normalized == "" {
+		return strings.TrimSpace(name)
+	}
+	return normalized
+}
+
+// titleCase upper-cases the first letter of every word and lower-cases the rest
+func titleCase(s string) string {
+	var out strings.Builder
+	startOfWord := true
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			startOfWord = true
+			out.WriteRune(r)
+			continue
+		}
+		if startOfWord {
+			out.WriteRune(unicode.ToUpper(r))
+		} else {
+			out.WriteRune(unicode.ToLower(r))
+		}
+		startOfWord = false
+	}
+	return out.String()
+}
@@ -0,0 +1,48 @@
+package itemname
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	all := KnownSteps
+	tests := []struct {
+		name      string
+		steps     []string
+		units     []string
+		maxLength int
+		in        string
+		want      string
+	}{
+		{name: "no steps keeps the name", in: "  NASI  GORENG 12345 ", want: "NASI  GORENG 12345"},
+		{name: "all steps", steps: all, in: "ES TEH  MANIS 600ML 123456", want: "Es Teh Manis"},
+		{name: "short numbers stay", steps: []string{StepStripCodes}, in: "Combo 2", want: "Combo 2"},
+		{name: "only a code stays", steps: []string{StepStripCodes}, in: "123456", want: "123456"},
+		{name: "units on their own", steps: []string{StepStripUnits}, in: "Beer 1 BTL", want: "Beer 1"},
+		{name: "decimal units", steps: []string{StepStripUnits}, in: "Water 1,5L", want: "Water"},
+		{name: "configured units replace defaults", steps: []string{StepStripUnits}, units: []string{"box"}, in: "Tissue BOX 500ml", want: "Tissue 500ml"},
+		{name: "nothing but units stays", steps: []string{StepStripUnits}, in: "500ML", want: "500ML"},
+		{name: "collapse whitespace", steps: []string{StepCollapseWhitespace}, in: "Mie   Ayam", want: "Mie Ayam"},
+		{name: "title case", steps: []string{StepTitleCase}, in: "sATE aYAM", want: "Sate Ayam"},
+		{name: "truncate by characters", steps: []string{StepTitleCase}, maxLength: 5, in: "kopi susu", want: "Kopi"},
+		{name: "multibyte truncation", maxLength: 3, in: "café au lait", want: "caf"},
+		{name: "unknown steps are ignored", steps: []string{"shout"}, in: "Teh", want: "Teh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.steps, tt.units, tt.maxLength).Normalize(tt.in)
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsStep(t *testing.T) {
+	for _, step := range KnownSteps {
+		if !IsStep(step) {
+			t.Errorf("IsStep(%q) = false", step)
+		}
+	}
+	if IsStep("uppercase") {
+		t.Error("IsStep(\"uppercase\") = true")
+	}
+}
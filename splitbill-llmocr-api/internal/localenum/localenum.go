@@ -0,0 +1,103 @@
+package localenum
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// canonicalPattern matches plain decimal and scientific notation numbers with a dot as
+// the decimal separator and no grouping. NaN, Inf, hex floats and fractions are refused.
+var canonicalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d{1,3})?$`)
+
+// groupedPattern matches an integer part with thousands grouped in threes, once the
+// grouping separator has been replaced by a space
+var groupedPattern = regexp.MustCompile(`^[+-]?\d{1,3}( \d{3})+$`)
+
+// decimalComma lists the languages that write 12.500,75 rather than 12,500.75. Any other
+// language, and no language at all, uses a decimal point.
+var decimalComma = map[string]bool{
+	"cs": true, "da": true, "de": true, "el": true, "es": true, "fi": true, "fr": true,
+	"hu": true, "id": true, "it": true, "nb": true, "nl": true, "no": true, "pl": true,
+	"pt": true, "ro": true, "ru": true, "sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// spaces are the characters every locale accepts as a thousands separator
+var spaces = strings.NewReplacer("\u00a0", " ", "\u202f", " ", "\t", " ")
+
+// Separators returns the decimal and grouping separators of a locale such as "id-ID".
+// Only the language is looked at.
+func Separators(locale string) (decimal, group byte) {
+	if decimalComma[Language(locale)] {
+		return ',', '.'
+	}
+	return '.', ','
+}
+
+// Language returns the lowercased language subtag of a locale: "pt" for "pt-BR"
+func Language(locale string) string {
+	language, _, _ := strings.Cut(strings.TrimSpace(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	return strings.ToLower(language)
+}
+
+// FromAcceptLanguage returns the first language range of an Accept-Language header, or ""
+// when it names none. Quality values are ignored; clients list their preferred one first.
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag != "" && tag != "*" {
+			return tag
+		}
+	}
+	return ""
+}
+
+// Parse reads text exactly as a number written for locale. With an empty locale only the
+// canonical form is accepted (12500.75 or 1.25e4). With a locale, the locale's decimal
+// separator is used and thousands may be grouped in threes with its grouping separator
+// or a space, so "12.500" is 12500 for "id" and 12.5 for "en". Grouping that is not in
+// threes is refused rather than guessed at.
+func Parse(text, locale string) (*big.Rat, error) {
+	text = strings.TrimSpace(text)
+	if locale != "" {
+		normalized, err := normalize(text, locale)
+		if err != nil {
+			return nil, err
+		}
+		text = normalized
+	}
+
+	if !canonicalPattern.MatchString(text) {
+		return nil, fmt.Errorf("%q is not a finite number", text)
+	}
+	number, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a finite number", text)
+	}
+	return number, nil
+}
+
+// normalize rewrites a number written for locale into the canonical form
+func normalize(text, locale string) (string, error) {
+	decimal, group := Separators(locale)
+
+	grouped := strings.ReplaceAll(spaces.Replace(text), string(group), " ")
+	integer, fraction, hasFraction := strings.Cut(grouped, string(decimal))
+	if strings.Contains(fraction, " ") || strings.ContainsRune(fraction, rune(decimal)) {
+		return "", fmt.Errorf("%q is not a number in %s", text, Language(locale))
+	}
+	if strings.Contains(integer, " ") {
+		if !groupedPattern.MatchString(integer) {
+			return "", fmt.Errorf("%q is not a number in %s: thousands must be grouped in threes", text, Language(locale))
+		}
+		integer = strings.ReplaceAll(integer, " ", "")
+	}
+
+	if hasFraction {
+		return integer + "." + fraction, nil
+	}
+	return integer, nil
+}
@@ -0,0 +1,76 @@
+package localenum
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text   string
+		locale string
+		want   string
+	}{
+		{"12500.75", "", "50003/4"},
+		{"1.25e4", "", "12500"},
+		{"-.5", "", "-1/2"},
+		{"12.500", "id-ID", "12500"},
+		{"12.500", "en-US", "25/2"},
+		{"12.500,75", "id", "50003/4"},
+		{"12,500.75", "en", "50003/4"},
+		{"12 500,75", "fr-FR", "50003/4"},
+		{"12 500", "de", "12500"},
+		{"1.234.567", "pt_BR", "1234567"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.text, tt.locale)
+		if err != nil {
+			t.Errorf("Parse(%q, %q): %v", tt.text, tt.locale, err)
+			continue
+		}
+		if got.RatString() != tt.want {
+			t.Errorf("Parse(%q, %q) = %s, want %s", tt.text, tt.locale, got.RatString(), tt.want)
+		}
+	}
+}
+
+func TestParseRefuses(t *testing.T) {
+	tests := []struct {
+		text   string
+		locale string
+	}{
+		{"12,500.75", ""},
+		{"NaN", ""},
+		{"Inf", ""},
+		{"0x1p3", ""},
+		{"1/2", ""},
+		{"1e1000", ""},
+		{"12.50.0", "en"},
+		{"1.2345", "id"},
+		{"12,5,0", "id"},
+		{"", "en"},
+	}
+	for _, tt := range tests {
+		if got, err := Parse(tt.text, tt.locale); err == nil {
+			t.Errorf("Parse(%q, %q) = %s, want an error", tt.text, tt.locale, got.RatString())
+		}
+	}
+}
+
+func TestLocaleHelpers(t *testing.T) {
+	if got := Language(" pt-BR "); got != "pt" {
+		t.Errorf("Language(pt-BR) = %q", got)
+	}
+	if got := Language("ID_id"); got != "id" {
+		t.Errorf("Language(ID_id) = %q", got)
+	}
+	if decimal, group := Separators("de-DE"); decimal != ',' || group != '.' {
+		t.Errorf("Separators(de-DE) = %q, %q", decimal, group)
+	}
+	if decimal, group := Separators(""); decimal != '.' || group != ',' {
+		t.Errorf("Separators(\"\") = %q, %q", decimal, group)
+	}
+	if got := FromAcceptLanguage("*, id-ID;q=0.9, en;q=0.8"); got != "id-ID" {
+		t.Errorf("FromAcceptLanguage = %q, want id-ID", got)
+	}
+	if got := FromAcceptLanguage(" "); got != "" {
+		t.Errorf("FromAcceptLanguage(\" \") = %q, want empty", got)
+	}
+}
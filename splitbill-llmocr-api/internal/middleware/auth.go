@@ -1,82 +1,141 @@
 package middleware
 
 import (
-	"net/http"
-
+	"errors"
 	"log"
 
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
-func Auth(jwtSecret string, db *gorm.DB) gin.HandlerFunc {
+// authErrorKey is the context key holding why a presented session was not accepted
+const authErrorKey = "auth_error"
+
+// impersonatorKey is the context key holding the admin ID behind an impersonation token
+const impersonatorKey = "impersonator_id"
+
+// Authenticate loads the caller into the context when a valid access token cookie is
+// present and otherwise lets the request through anonymously. It never rejects a request;
+// Authorize decides what anonymous callers may do and reports why a session was refused.
+// User lookups are served from userCache when possible to avoid a database round-trip per request.
+// Requests made with an impersonation token are written to the audit log with both identities.
+func Authenticate(jwtSecret string, db *gorm.DB, userCache cache.UserCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get access token from cookie
 		accessToken, err := c.Cookie("access_token")
 		if err != nil {
-			// If access token is not found, try to refresh using refresh token
-			if _, err := c.Cookie("refresh_token"); err != nil {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
-				c.Abort()
-				return
+			// A refresh token alone means the access token expired
+			if _, err := c.Cookie("refresh_token"); err == nil {
+				// TODO: Implement token refresh logic
+				c.Set(authErrorKey, "Access token expired")
 			}
-
-			// TODO: Implement token refresh logic
-			// For now, just return unauthorized
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token expired"})
-			c.Abort()
+			c.Next()
 			return
 		}
 
-		// Parse and validate token
-		claims := &models.Claims{}
-		token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil {
-			if err == jwt.ErrSignatureInvalid {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token signature"})
-			} else if err == jwt.ErrTokenExpired {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
-			} else {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			}
-			c.Abort()
+		userResponse, impersonatorID, errMessage := authenticate(accessToken, jwtSecret, db, userCache)
+		if errMessage != "" {
+			log.Printf("Auth middleware: continuing anonymously: %s", errMessage)
+			c.Set(authErrorKey, errMessage)
+			c.Next()
 			return
 		}
 
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		// Set user in context
+		c.Set("user", userResponse)
+		if impersonatorID == 0 {
+			c.Next()
 			return
 		}
 
-		// Get user from database
-		var user models.Users
-		if err := db.First(&user, claims.UserID).Error; err != nil {
-			log.Printf("Auth middleware: user not found in database for ID %d", claims.UserID)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			c.Abort()
-			return
-		}
+		c.Set(impersonatorKey, impersonatorID)
+		c.Next()
+		services.RecordAudit(db, models.AuditLogs{
+			ActorID:   impersonatorID,
+			SubjectID: userResponse.ID,
+			Action:    services.AuditImpersonatedRequest,
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.RequestURI(),
+			Status:    c.Writer.Status(),
+		})
+	}
+}
 
-		// Create user response object
-		userResponse := models.RegisterResponse{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
-			Name:     user.Name,
-			Role:     user.Role,
+// authenticate validates an access token and resolves its user, plus the admin behind it
+// for impersonation tokens. On failure it returns the message to send back to the client.
+func authenticate(accessToken, jwtSecret string, db *gorm.DB, userCache cache.UserCache) (models.RegisterResponse, uint, string) {
+	// Parse and validate token
+	claims := &models.Claims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	})
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrSignatureInvalid) {
+			return models.RegisterResponse{}, 0, "Invalid token signature"
+		} else if errors.Is(err, jwt.ErrTokenExpired) {
+			return models.RegisterResponse{}, 0, "Token has expired"
 		}
+		return models.RegisterResponse{}, 0, "Invalid token"
+	}
 
-		log.Printf("Auth middleware: setting user in context: %+v", userResponse)
+	if !token.Valid {
+		return models.RegisterResponse{}, 0, "Invalid token"
+	}
 
-		// Set user in context
-		c.Set("user", userResponse)
+	userResponse, ok := loadUser(claims.UserID, db, userCache)
+	if !ok {
+		return models.RegisterResponse{}, 0, "User not found"
+	}
+	// Signing out everywhere bumps the version, revoking every older token
+	if claims.TokenVersion != userResponse.TokenVersion {
+		return models.RegisterResponse{}, 0, "Session has been revoked"
+	}
+	if claims.ImpersonatorID == 0 {
+		return userResponse, 0, ""
+	}
 
-		c.Next()
+	// Impersonation stops working as soon as the admin loses the role or the user gains it
+	admin, ok := loadUser(claims.ImpersonatorID, db, userCache)
+	if !ok || admin.Role != "admin" || userResponse.Role == "admin" {
+		log.Printf("Auth middleware: refusing impersonation of user %d by user %d", claims.UserID, claims.ImpersonatorID)
+		return models.RegisterResponse{}, 0, "Impersonation is no longer allowed"
+	}
+
+	return userResponse, admin.ID, ""
+}
+
+// loadUser resolves an active user from userCache or the database
+func loadUser(userID uint, db *gorm.DB, userCache cache.UserCache) (models.RegisterResponse, bool) {
+	if userResponse, cached := userCache.Get(userID); cached {
+		return userResponse, true
+	}
+
+	// Get user from database
+	var user models.Users
+	if err := db.First(&user, userID).Error; err != nil {
+		log.Printf("Auth middleware: user not found in database for ID %d", userID)
+		return models.RegisterResponse{}, false
+	}
+
+	if user.IsDeleted {
+		log.Printf("Auth middleware: user %d is disabled", userID)
+		return models.RegisterResponse{}, false
+	}
+
+	// Create user response object
+	userResponse := models.RegisterResponse{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		Name:         user.Name,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
 	}
+	userCache.Set(userResponse)
+	return userResponse, true
 }
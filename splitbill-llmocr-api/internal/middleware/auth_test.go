@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const testJWTSecret = "auth-test-secret"
+
+// authRouter answers GET /whoami with 200 for an authenticated caller and 401 with the
+// reason Authenticate recorded otherwise
+func authRouter(db *gorm.DB, userCache cache.UserCache) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/whoami", Authenticate(testJWTSecret, db, userCache), func(c *gin.Context) {
+		if _, ok := c.Get("user"); ok {
+			c.Status(http.StatusOK)
+			return
+		}
+		reason, _ := c.Get(authErrorKey)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": reason})
+	})
+	return router
+}
+
+// whoami sends token as the access token cookie and returns the response
+func whoami(router http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// signClaims signs claims for user 1 with secret, valid from an hour ago until expiresAt
+func signClaims(t *testing.T, secret string, expiresAt time.Time) string {
+	t.Helper()
+	claims := &models.Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestAuthenticateRejectsBadTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "expired", token: signClaims(t, testJWTSecret, time.Now().Add(-time.Minute)), want: "Token has expired"},
+		{name: "wrong secret", token: signClaims(t, "another-secret", time.Now().Add(time.Hour)), want: "Invalid token signature"},
+		{name: "not a token", token: "not-a-jwt", want: "Invalid token"},
+	}
+	// Every token is refused before the user is looked up, so no database is needed
+	router := authRouter(nil, cache.NewUserCache(time.Minute, 10))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := whoami(router, tt.token)
+			if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("status %d %s, want 401 with %q", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+// newAuthTestUser registers a user through the service and logs them in, returning the
+// user and their access token
+func newAuthTestUser(t *testing.T, users *services.UserService, name string) (*models.RegisterResponse, string) {
+	t.Helper()
+	user, err := users.Register(&models.RegisterRequest{Username: name, Email: name + "@example.com", Password: "password1", Name: name})
+	if err != nil {
+		t.Fatalf("failed to register %s: %v", name, err)
+	}
+	login, err := users.Login(&models.LoginRequest{Username: name, Password: "password1"})
+	if err != nil {
+		t.Fatalf("failed to log in %s: %v", name, err)
+	}
+	return user, login.Token.AccessToken
+}
+
+func authTestConfig() *config.Config {
+	return &config.Config{JWTSecret: testJWTSecret, JWTExpiry: time.Hour}
+}
+
+func TestAuthenticateRevokedSessions(t *testing.T) {
+	db := testdb.Open(t)
+	userCache := cache.NewUserCache(time.Minute, 10)
+	users := services.NewUserService(db, authTestConfig(), userCache)
+	router := authRouter(db, userCache)
+
+	user, token := newAuthTestUser(t, users, "ana")
+	if rec := whoami(router, token); rec.Code != http.StatusOK {
+		t.Fatalf("fresh token: status %d %s, want 200", rec.Code, rec.Body)
+	}
+
+	// The user is cached now; revoking must still take effect on the very next request
+	if err := users.RevokeSessions(user.ID); err != nil {
+		t.Fatalf("RevokeSessions: %v", err)
+	}
+	if rec := whoami(router, token); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "Session has been revoked") {
+		t.Errorf("revoked token: status %d %s, want 401 revoked", rec.Code, rec.Body)
+	}
+
+	login, err := users.Login(&models.LoginRequest{Username: "ana", Password: "password1"})
+	if err != nil {
+		t.Fatalf("failed to log in again: %v", err)
+	}
+	if rec := whoami(router, login.Token.AccessToken); rec.Code != http.StatusOK {
+		t.Errorf("token issued after revoking: status %d %s, want 200", rec.Code, rec.Body)
+	}
+}
+
+func TestAuthenticateDisabledUserWithinTTL(t *testing.T) {
+	const ttl = 200 * time.Millisecond
+	db := testdb.Open(t)
+	userCache := cache.NewUserCache(ttl, 10)
+	users := services.NewUserService(db, authTestConfig(), userCache)
+	router := authRouter(db, userCache)
+
+	user, token := newAuthTestUser(t, users, "ben")
+	if rec := whoami(router, token); rec.Code != http.StatusOK {
+		t.Fatalf("fresh token: status %d %s, want 200", rec.Code, rec.Body)
+	}
+
+	if err := db.Model(&models.Users{}).Where("id = ?", user.ID).Update("is_deleted", true).Error; err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+	time.Sleep(ttl + 50*time.Millisecond)
+	if rec := whoami(router, token); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "User not found") {
+		t.Errorf("disabled user after one TTL: status %d %s, want 401", rec.Code, rec.Body)
+	}
+}
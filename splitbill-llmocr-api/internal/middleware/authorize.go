@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Access is the kind of caller a route admits
+type Access int
+
+const (
+	// AccessPublic admits everyone, logged in or not
+	AccessPublic Access = iota
+	// AccessBillOwner admits everyone for bills without an owner and only the owner otherwise.
+	// The bill comes from the :id parameter.
+	AccessBillOwner
+	// AccessUser admits any logged-in user
+	AccessUser
+	// AccessAdmin admits logged-in users with the admin role
+	AccessAdmin
+)
+
+// String returns the name used for the access level in logs and errors
+func (a Access) String() string {
+	switch a {
+	case AccessPublic:
+		return "public"
+	case AccessBillOwner:
+		return "bill_owner"
+	case AccessUser:
+		return "user"
+	case AccessAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("access(%d)", int(a))
+	}
+}
+
+// Permission declares what a route does and who may call it
+type Permission struct {
+	Resource string
+	Action   string
+	Access   Access
+}
+
+// Permissions maps "METHOD /full/path" route keys to their declared permission
+type Permissions map[string]Permission
+
+// RouteKey builds the Permissions key for a route as gin registers it
+func RouteKey(method, path string) string {
+	return method + " " + path
+}
+
+// BillAuthorizer decides whether a user may change a bill. BillService implements it.
+type BillAuthorizer interface {
+	AuthorizeBillChange(billID uuid.UUID, userID *uint) error
+}
+
+// Authorize enforces the declared permission of the matched route. It must run after
+// Authenticate. Routes without a declaration are refused, so a new endpoint can't ship
+// unprotected by accident, and impersonation sessions may only use read-only methods.
+func Authorize(permissions Permissions, bills BillAuthorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Unmatched requests fall through to the NoRoute and NoMethod handlers
+		if c.FullPath() == "" {
+			c.Next()
+			return
+		}
+
+		permission, declared := permissions[RouteKey(c.Request.Method, c.FullPath())]
+		if !declared {
+			log.Printf("Authorize: no permission declared for %s %s", c.Request.Method, c.FullPath())
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this route has not been configured"})
+			c.Abort()
+			return
+		}
+
+		// Impersonation tokens are for looking, never for changing anything
+		if _, impersonating := c.Get(impersonatorKey); impersonating && !readOnlyMethod(c.Request.Method) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Impersonation sessions are read-only"})
+			c.Abort()
+			return
+		}
+
+		user := contextUser(c)
+		switch permission.Access {
+		case AccessPublic:
+		case AccessUser:
+			if user == nil {
+				abortUnauthenticated(c)
+				return
+			}
+		case AccessAdmin:
+			if user == nil {
+				abortUnauthenticated(c)
+				return
+			}
+			if user.Role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+				c.Abort()
+				return
+			}
+		case AccessBillOwner:
+			if !authorizeBillOwner(c, bills, user) {
+				return
+			}
+		default:
+			log.Printf("Authorize: unknown access %s for %s %s", permission.Access, c.Request.Method, c.FullPath())
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this route has not been configured"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authorizeBillOwner checks the bill named by the route against the caller. Malformed or
+// unknown IDs are left to the handler, which answers 400 or 404 as usual.
+func authorizeBillOwner(c *gin.Context, bills BillAuthorizer, user *models.RegisterResponse) bool {
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return true
+	}
+
+	var userID *uint
+	if user != nil {
+		userID = &user.ID
+	}
+
+	err = bills.AuthorizeBillChange(billID, userID)
+	switch {
+	case err == nil, errors.Is(err, services.ErrNotFound):
+		return true
+	case errors.Is(err, services.ErrAuthRequired):
+		abortUnauthenticated(c)
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner of this bill can change it"})
+		c.Abort()
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find bill: %v", err)})
+		c.Abort()
+	}
+	return false
+}
+
+// readOnlyMethod reports whether method never changes state
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// contextUser returns the user Authenticate stored in the context, if any
+func contextUser(c *gin.Context) *models.RegisterResponse {
+	value, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+	user, ok := value.(models.RegisterResponse)
+	if !ok {
+		return nil
+	}
+	return &user
+}
+
+// abortUnauthenticated answers 401, saying why a presented session was refused when known
+func abortUnauthenticated(c *gin.Context) {
+	message := "Authentication required"
+	if reason := c.GetString(authErrorKey); reason != "" {
+		message = reason
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
+
+// VerifyPermissions returns an error naming every registered route that has no declared
+// permission. Call it once all routes are registered.
+func VerifyPermissions(routes gin.RoutesInfo, permissions Permissions) error {
+	var missing []string
+	for _, route := range routes {
+		if _, declared := permissions[RouteKey(route.Method, route.Path)]; !declared {
+			missing = append(missing, RouteKey(route.Method, route.Path))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("routes without a declared permission: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallbackSecretHeader carries the shared secret on the n8n extraction callback
+const CallbackSecretHeader = "X-Callback-Secret"
+
+// RequireCallbackSecret answers 401 unless the request carries secret in
+// X-Callback-Secret. Both values are hashed before the constant-time comparison so
+// neither their contents nor their lengths leak through timing. An empty secret refuses
+// every request.
+func RequireCallbackSecret(secret string) gin.HandlerFunc {
+	expected := sha256.Sum256([]byte(secret))
+	return func(c *gin.Context) {
+		provided := sha256.Sum256([]byte(c.GetHeader(CallbackSecretHeader)))
+		if secret == "" || subtle.ConstantTimeCompare(provided[:], expected[:]) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing callback secret"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
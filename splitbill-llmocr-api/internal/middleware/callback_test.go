@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireCallbackSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		want       int
+	}{
+		{name: "matching secret", configured: "s3cret", header: "s3cret", want: http.StatusOK},
+		{name: "missing header", configured: "s3cret", want: http.StatusUnauthorized},
+		{name: "wrong secret", configured: "s3cret", header: "s3cre", want: http.StatusUnauthorized},
+		{name: "longer secret", configured: "s3cret", header: "s3cret!", want: http.StatusUnauthorized},
+		{name: "nothing configured", configured: "", header: "", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/callback", RequireCallbackSecret(tt.configured), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/callback", nil)
+			if tt.header != "" {
+				req.Header.Set(CallbackSecretHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
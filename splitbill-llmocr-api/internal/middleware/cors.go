@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/origin"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS sets the cross-origin headers for origins the policy allows and answers preflight
+// requests. Refused origins get no Access-Control-Allow-Origin header, so browsers block them.
+func CORS(policy *origin.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The allowed origin depends on the request, so caches must key on it
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if allowedOrigin := policy.AllowOrigin(c.Request.Header.Get("Origin")); allowedOrigin != "" {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+		}
+		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, If-Match, If-None-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
+		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+
+		// Handle preflight
+		if c.Request.Method == http.MethodOptions {
+			log.Printf("Handling OPTIONS request for: %s", c.Request.URL.Path)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DatabaseAvailability reports whether the database can currently serve requests
+type DatabaseAvailability interface {
+	IsAvailable() bool
+}
+
+// RequireDatabase answers with 503 DB_UNAVAILABLE while the database is being
+// reconnected, instead of letting every handler fail with an opaque 500
+func RequireDatabase(db DatabaseAvailability) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !db.IsAvailable() {
+			c.Header("Retry-After", "30")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Database is temporarily unavailable. Please try again shortly.",
+				"code":  "DB_UNAVAILABLE",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import "net/http"
+
+// RoutePermissions declares who may call every route the API registers. A route that is
+// registered without an entry here stops the server at startup (see VerifyPermissions)
+// and is refused by Authorize.
+var RoutePermissions = Permissions{
+	// Monitoring and static files
+	RouteKey(http.MethodGet, "/health"):             {Resource: "health", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/health/ready"):       {Resource: "health", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/uploads/*filepath"):  {Resource: "upload", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodHead, "/uploads/*filepath"): {Resource: "upload", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/api/features"):       {Resource: "features", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/api/status"):         {Resource: "status", Action: "read", Access: AccessPublic},
+
+	// Accounts
+	RouteKey(http.MethodPost, "/api/auth/register"):                {Resource: "account", Action: "create", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/auth/login"):                   {Resource: "session", Action: "create", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/auth/logout"):                  {Resource: "session", Action: "delete", Access: AccessUser},
+	RouteKey(http.MethodPost, "/api/auth/logout-all"):              {Resource: "session", Action: "delete_all", Access: AccessUser},
+	RouteKey(http.MethodGet, "/api/me"):                            {Resource: "account", Action: "read", Access: AccessUser},
+	RouteKey(http.MethodGet, "/api/me/preferences"):                {Resource: "preferences", Action: "read", Access: AccessUser},
+	RouteKey(http.MethodPut, "/api/me/preferences"):                {Resource: "preferences", Action: "update", Access: AccessUser},
+	RouteKey(http.MethodGet, "/api/me/bills"):                      {Resource: "bill", Action: "list", Access: AccessUser},
+	RouteKey(http.MethodGet, "/api/me/participations"):             {Resource: "participation", Action: "list", Access: AccessUser},
+	RouteKey(http.MethodGet, "/api/me/attention"):                  {Resource: "attention", Action: "list", Access: AccessUser},
+	RouteKey(http.MethodPost, "/api/me/attention/:billId/dismiss"): {Resource: "attention", Action: "dismiss", Access: AccessUser},
+
+	// Bills: anyone holding the link can read and create; changes need the owner once there is one.
+	// Listing needs a login and only shows the caller's own bills.
+	RouteKey(http.MethodGet, "/api/bills"):                        {Resource: "bill", Action: "list", Access: AccessUser},
+	RouteKey(http.MethodPost, "/api/bills/"):                      {Resource: "bill", Action: "create", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/api/bills/:id"):                    {Resource: "bill", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodPut, "/api/bills/:id"):                    {Resource: "bill", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodDelete, "/api/bills/:id"):                 {Resource: "bill", Action: "delete", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/archive"):           {Resource: "bill", Action: "archive", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/unarchive"):         {Resource: "bill", Action: "archive", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/reopen"):            {Resource: "bill", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/finalize"):          {Resource: "bill", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodPut, "/api/bills/:id/manual-shares"):      {Resource: "bill", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodDelete, "/api/bills/:id/manual-shares"):   {Resource: "bill", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodGet, "/api/bills/:id/status"):             {Resource: "bill", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/api/bills/:id/status/stream"):      {Resource: "bill", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/api/bills/:id/summary"):            {Resource: "bill", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodGet, "/api/bills/:id/events"):             {Resource: "bill_event", Action: "list", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/share/regenerate"):  {Resource: "share_link", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodGet, "/api/shared/:token"):                {Resource: "share_link", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/image"):             {Resource: "bill", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodGet, "/api/bills/:id/editors"):            {Resource: "presence", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/editing-heartbeat"): {Resource: "presence", Action: "update", Access: AccessPublic},
+
+	// Extraction callback from n8n, which has no user session; RequireCallbackSecret checks
+	// the shared secret instead
+	RouteKey(http.MethodPost, "/api/bills/:id/process-data"): {Resource: "extraction", Action: "callback", Access: AccessPublic},
+
+	// Share link holders may only claim a participant as themselves or add themselves;
+	// joins are rate limited per link
+	RouteKey(http.MethodPost, "/api/shared/:token/participants"):                      {Resource: "participant", Action: "join", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/shared/:token/participants/:participantId/claim"): {Resource: "participant", Action: "claim", Access: AccessPublic},
+
+	// Participants; payment status stays open so participants can mark themselves paid, and
+	// any logged-in user holding the link can claim a participant as themselves
+	RouteKey(http.MethodGet, "/api/bills/:id/participants"):                          {Resource: "participant", Action: "list", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/participants"):                         {Resource: "participant", Action: "create", Access: AccessBillOwner},
+	RouteKey(http.MethodDelete, "/api/bills/:id/participants"):                       {Resource: "participant", Action: "delete", Access: AccessBillOwner},
+	RouteKey(http.MethodPut, "/api/bills/:id/participants/reorder"):                  {Resource: "participant", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/participants/merge"):                   {Resource: "participant", Action: "merge", Access: AccessBillOwner},
+	RouteKey(http.MethodGet, "/api/bills/:id/participants/:participantId"):           {Resource: "participant", Action: "read", Access: AccessPublic},
+	RouteKey(http.MethodPut, "/api/bills/:id/participants/:participantId"):           {Resource: "participant", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodDelete, "/api/bills/:id/participants/:participantId"):        {Resource: "participant", Action: "delete", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/participants/:participantId/restore"):  {Resource: "participant", Action: "restore", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/participants/:participantId/claim"):    {Resource: "participant", Action: "claim", Access: AccessUser},
+	RouteKey(http.MethodPatch, "/api/bills/:id/participants/:participantId/payment"): {Resource: "payment", Action: "update", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/participants/:participantId/payment"):  {Resource: "payment", Action: "update", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/participants/mark-all-paid"):           {Resource: "payment", Action: "update_all", Access: AccessBillOwner},
+
+	// Who paid the merchant
+	RouteKey(http.MethodPut, "/api/bills/:id/payers"): {Resource: "payer", Action: "update", Access: AccessBillOwner},
+
+	// Items and assignments
+	RouteKey(http.MethodGet, "/api/bills/:id/items"):                       {Resource: "item", Action: "list", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/items"):                      {Resource: "item", Action: "create", Access: AccessBillOwner},
+	RouteKey(http.MethodPut, "/api/bills/:id/items"):                       {Resource: "item", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodPut, "/api/bills/:id/items/:itemId"):               {Resource: "item", Action: "update", Access: AccessBillOwner},
+	RouteKey(http.MethodDelete, "/api/bills/:id/items/:itemId"):            {Resource: "item", Action: "delete", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/items/:itemId/restore"):      {Resource: "item", Action: "restore", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/items/merge"):                {Resource: "item", Action: "merge", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/items/confirm"):              {Resource: "item", Action: "confirm", Access: AccessBillOwner},
+	RouteKey(http.MethodGet, "/api/bills/:id/items/duplicates"):            {Resource: "item", Action: "list", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/items/:itemId/split"):        {Resource: "item", Action: "split", Access: AccessBillOwner},
+	RouteKey(http.MethodGet, "/api/bills/:id/item-assignments"):            {Resource: "assignment", Action: "list", Access: AccessPublic},
+	RouteKey(http.MethodPost, "/api/bills/:id/assign-items"):               {Resource: "assignment", Action: "create", Access: AccessBillOwner},
+	RouteKey(http.MethodDelete, "/api/bills/:id/assign-items"):             {Resource: "assignment", Action: "delete", Access: AccessBillOwner},
+	RouteKey(http.MethodPost, "/api/bills/:id/apply-previous-assignments"): {Resource: "assignment", Action: "create", Access: AccessBillOwner},
+
+	// Support views that can read soft-deleted data, act as a user read-only, or show
+	// outgoing webhook attempts and the running configuration
+	RouteKey(http.MethodGet, "/api/admin/bills"):                   {Resource: "bill", Action: "list_by_status", Access: AccessAdmin},
+	RouteKey(http.MethodGet, "/api/admin/bills/:id"):               {Resource: "bill", Action: "read_deleted", Access: AccessAdmin},
+	RouteKey(http.MethodPost, "/api/admin/bills/:id/force-status"): {Resource: "bill", Action: "force_status", Access: AccessAdmin},
+	RouteKey(http.MethodGet, "/api/admin/stats/extraction"):        {Resource: "extraction_stats", Action: "read", Access: AccessAdmin},
+	RouteKey(http.MethodGet, "/api/admin/abandoned-bills"):         {Resource: "bill", Action: "list_abandoned", Access: AccessAdmin},
+	RouteKey(http.MethodPost, "/api/admin/impersonate/:userId"):    {Resource: "impersonation", Action: "create", Access: AccessAdmin},
+	RouteKey(http.MethodGet, "/api/admin/webhook-deliveries"):      {Resource: "webhook_delivery", Action: "list", Access: AccessAdmin},
+	RouteKey(http.MethodGet, "/api/admin/config"):                  {Resource: "config", Action: "read", Access: AccessAdmin},
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Callers in the permission matrix. The owner owns ownedBill; nobody owns ownerlessBill.
+const (
+	roleAnonymous     = "anonymous"
+	roleUser          = "user"
+	roleOwner         = "owner"
+	roleAdmin         = "admin"
+	roleImpersonation = "impersonation"
+)
+
+var (
+	matrixRoles = []string{roleAnonymous, roleUser, roleOwner, roleAdmin, roleImpersonation}
+
+	matrixUsers = map[string]models.RegisterResponse{
+		roleUser:  {ID: 1, Username: "user", Role: "user"},
+		roleOwner: {ID: 2, Username: "owner", Role: "user"},
+		roleAdmin: {ID: 3, Username: "admin", Role: "admin"},
+		// An admin looking at the app as the plain user
+		roleImpersonation: {ID: 1, Username: "user", Role: "user"},
+	}
+
+	ownedBill     = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	ownerlessBill = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+)
+
+// matrixBills owns ownedBill for the owner and leaves ownerlessBill open, like BillService
+type matrixBills struct{}
+
+func (matrixBills) AuthorizeBillChange(billID uuid.UUID, userID *uint) error {
+	switch {
+	case billID != ownedBill:
+		return nil
+	case userID == nil:
+		return services.ErrAuthRequired
+	case *userID != matrixUsers[roleOwner].ID:
+		return services.ErrForbidden
+	}
+	return nil
+}
+
+// declaredStatus is what each access level answers each caller on a bill with an owner;
+// http.StatusOK means the request reached the handler
+var declaredStatus = map[Access]map[string]int{
+	AccessPublic: {
+		roleAnonymous: http.StatusOK, roleUser: http.StatusOK, roleOwner: http.StatusOK, roleAdmin: http.StatusOK,
+	},
+	AccessBillOwner: {
+		roleAnonymous: http.StatusUnauthorized, roleUser: http.StatusForbidden, roleOwner: http.StatusOK, roleAdmin: http.StatusForbidden,
+	},
+	AccessUser: {
+		roleAnonymous: http.StatusUnauthorized, roleUser: http.StatusOK, roleOwner: http.StatusOK, roleAdmin: http.StatusOK,
+	},
+	AccessAdmin: {
+		roleAnonymous: http.StatusUnauthorized, roleUser: http.StatusForbidden, roleOwner: http.StatusForbidden, roleAdmin: http.StatusOK,
+	},
+}
+
+// expectedStatus returns the status a caller in role should get on a route with access
+func expectedStatus(access Access, role, method string, billID uuid.UUID) int {
+	// Impersonation sessions are refused anything but reads, and read as the user
+	if role == roleImpersonation {
+		if !readOnlyMethod(method) {
+			return http.StatusForbidden
+		}
+		role = roleUser
+	}
+	// Bills without an owner are open to everyone
+	if access == AccessBillOwner && billID == ownerlessBill {
+		return http.StatusOK
+	}
+	return declaredStatus[access][role]
+}
+
+// matrixRouter registers every declared route behind Authorize with a handler that
+// answers 200, and logs callers in as the role named in the X-Test-Role header
+func matrixRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		role := c.GetHeader("X-Test-Role")
+		if user, ok := matrixUsers[role]; ok {
+			c.Set("user", user)
+		}
+		if role == roleImpersonation {
+			c.Set(impersonatorKey, matrixUsers[roleAdmin].ID)
+		}
+		c.Next()
+	})
+	router.Use(Authorize(RoutePermissions, matrixBills{}))
+
+	reached := func(c *gin.Context) { c.Status(http.StatusOK) }
+	for key := range RoutePermissions {
+		method, path, _ := strings.Cut(key, " ")
+		router.Handle(method, path, reached)
+	}
+	return router
+}
+
+// concretePath fills a route's parameters, using billID for the bill
+func concretePath(path string, billID uuid.UUID) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == ":id" || segment == ":billId":
+			segments[i] = billID.String()
+		case strings.HasPrefix(segment, ":"):
+			segments[i] = "1"
+		case strings.HasPrefix(segment, "*"):
+			segments[i] = "file"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestPermissionMatrix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := matrixRouter()
+
+	for key, permission := range RoutePermissions {
+		method, path, _ := strings.Cut(key, " ")
+		if _, known := declaredStatus[permission.Access]; !known {
+			t.Errorf("%s: no expectations for access %s", key, permission.Access)
+			continue
+		}
+
+		billIDs := []uuid.UUID{ownedBill}
+		if permission.Access == AccessBillOwner {
+			billIDs = append(billIDs, ownerlessBill)
+		}
+		for _, billID := range billIDs {
+			for _, role := range matrixRoles {
+				name := fmt.Sprintf("%s as %s", key, role)
+				if billID == ownerlessBill {
+					name += " on a bill without an owner"
+				}
+				t.Run(name, func(t *testing.T) {
+					req := httptest.NewRequest(method, concretePath(path, billID), nil)
+					req.Header.Set("X-Test-Role", role)
+					rec := httptest.NewRecorder()
+					router.ServeHTTP(rec, req)
+
+					want := expectedStatus(permission.Access, role, method, billID)
+					if rec.Code != want {
+						t.Errorf("status = %d, want %d (%s access)", rec.Code, want, permission.Access)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestAuthorizeRefusesUndeclaredRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Authorize(Permissions{}, matrixBills{}))
+	router.GET("/api/undeclared", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/undeclared", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
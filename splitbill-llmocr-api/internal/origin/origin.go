@@ -0,0 +1,124 @@
+package origin
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// wildcardLabel is what * in an origin pattern stands for: one or more characters of a
+// single DNS label, so it can never swallow a dot and reach into another domain
+const wildcardLabel = `[a-z0-9-]+`
+
+// localHosts are the hostnames admitted on any port when localhost is allowed
+var localHosts = map[string]bool{"localhost": true, "127.0.0.1": true, "::1": true}
+
+// Policy decides which browser origins may call the API. An origin is allowed when it
+// equals a configured origin, matches a pattern, or is a local address and localhost is
+// allowed. Everything else is refused.
+type Policy struct {
+	exact          map[string]bool
+	patterns       []*regexp.Regexp
+	allowLocalhost bool
+	allowAll       bool
+}
+
+// CompilePattern turns an origin pattern such as https://*-myteam.vercel.app into an
+// anchored expression. The pattern needs an http or https scheme, no path, and exactly one
+// *, placed in the first host label with at least two labels after it.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	scheme, host, found := strings.Cut(pattern, "://")
+	if !found || (scheme != "http" && scheme != "https") {
+		return nil, fmt.Errorf("origin pattern %q must start with http:// or https://", pattern)
+	}
+	if host == "" || strings.ContainsAny(host, "/?#@\\") {
+		return nil, fmt.Errorf("origin pattern %q must be a scheme and host without a path", pattern)
+	}
+	if strings.Count(host, "*") != 1 {
+		return nil, fmt.Errorf("origin pattern %q must contain exactly one *", pattern)
+	}
+
+	hostname, _, _ := strings.Cut(host, ":")
+	labels := strings.Split(hostname, ".")
+	if !strings.Contains(labels[0], "*") {
+		return nil, fmt.Errorf("origin pattern %q may only use * in the first host label", pattern)
+	}
+	if len(labels) < 3 {
+		return nil, fmt.Errorf("origin pattern %q is too broad; the * needs at least two host labels after it", pattern)
+	}
+
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	return regexp.Compile("^" + regexp.QuoteMeta(prefix) + wildcardLabel + regexp.QuoteMeta(suffix) + "$")
+}
+
+// New builds a policy from exact origins, origin patterns and the localhost toggle. An
+// origin of "*" allows every origin without credentials. Patterns are checked with
+// CompilePattern.
+func New(origins []string, patterns []string, allowLocalhost bool) (*Policy, error) {
+	policy := &Policy{exact: make(map[string]bool, len(origins)), allowLocalhost: allowLocalhost}
+	for _, o := range origins {
+		if o == "*" {
+			policy.allowAll = true
+			continue
+		}
+		policy.exact[normalize(o)] = true
+	}
+	for _, p := range patterns {
+		compiled, err := CompilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		policy.patterns = append(policy.patterns, compiled)
+	}
+	return policy, nil
+}
+
+// Empty reports whether the policy refuses every cross-origin request
+func (p *Policy) Empty() bool {
+	return !p.allowAll && !p.allowLocalhost && len(p.exact) == 0 && len(p.patterns) == 0
+}
+
+// AllowOrigin returns the Access-Control-Allow-Origin value for a request from origin:
+// the origin itself when it is allowed, "*" when every origin is, and "" when it is refused.
+func (p *Policy) AllowOrigin(origin string) string {
+	if p.allowAll {
+		return "*"
+	}
+	if origin == "" {
+		return ""
+	}
+
+	normalized := normalize(origin)
+	if p.exact[normalized] {
+		return origin
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(normalized) {
+			return origin
+		}
+	}
+	if p.allowLocalhost && isLocal(normalized) {
+		return origin
+	}
+	return ""
+}
+
+// normalize lowercases an origin and drops a trailing slash
+func normalize(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// isLocal reports whether origin is a bare http or https origin on a local address
+func isLocal(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	if u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+	return localHosts[u.Hostname()]
+}
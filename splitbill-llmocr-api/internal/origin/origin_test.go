@@ -0,0 +1,82 @@
+package origin
+
+import "testing"
+
+func TestCompilePatternRefuses(t *testing.T) {
+	for _, pattern := range []string{
+		"*.vercel.app",
+		"ftp://*.example.com",
+		"https://*.example.com/path",
+		"https://*.*.example.com",
+		"https://app.*.example.com",
+		"https://*.com",
+		"https://example.com",
+	} {
+		if _, err := CompilePattern(pattern); err == nil {
+			t.Errorf("CompilePattern(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestAllowOrigin(t *testing.T) {
+	policy, err := New(
+		[]string{"https://splitbill.example.com/"},
+		[]string{"https://*-myteam.vercel.app"},
+		true,
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://splitbill.example.com", true},
+		{"HTTPS://SPLITBILL.EXAMPLE.COM", true},
+		{"https://preview-42-myteam.vercel.app", true},
+		{"https://evil.com/x-myteam.vercel.app", false},
+		{"https://a.b-myteam.vercel.app", false},
+		{"https://preview-myteam.vercel.app.evil.com", false},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1:8080", true},
+		{"http://[::1]:5173", true},
+		{"http://localhost:3000/path", false},
+		{"http://user@localhost:3000", false},
+		{"https://other.example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		got := policy.AllowOrigin(tt.origin)
+		if tt.allowed && got != tt.origin {
+			t.Errorf("AllowOrigin(%q) = %q, want it echoed", tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Errorf("AllowOrigin(%q) = %q, want it refused", tt.origin, got)
+		}
+	}
+}
+
+func TestWildcardAndEmpty(t *testing.T) {
+	all, err := New([]string{"*"}, nil, false)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got := all.AllowOrigin("https://anything.example"); got != "*" {
+		t.Errorf("AllowOrigin with * = %q, want *", got)
+	}
+
+	none, err := New(nil, nil, false)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if !none.Empty() {
+		t.Error("a policy without origins should be empty")
+	}
+	if all.Empty() {
+		t.Error("a policy allowing * should not be empty")
+	}
+	if got := none.AllowOrigin("http://localhost:3000"); got != "" {
+		t.Errorf("localhost allowed without the toggle: %q", got)
+	}
+}
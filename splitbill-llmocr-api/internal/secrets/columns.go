@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Column identifies a database column whose values are stored encrypted with a Keyring.
+// Only values that must be read back (webhook URLs, refresh tokens) belong here;
+// secrets that only need to be verified are hashed instead.
+type Column struct {
+	Table  string
+	Column string
+}
+
+// EncryptedColumns lists every column written through a Keyring
+var EncryptedColumns = []Column{
+	{Table: "bills", Column: "callback_url"},
+}
+
+// CheckKeyAvailable fails when encrypted values exist but no keyring is configured,
+// so the server refuses to start rather than serving undecryptable data
+func CheckKeyAvailable(db *gorm.DB, keyring *Keyring) error {
+	if keyring != nil {
+		return nil
+	}
+
+	for _, col := range EncryptedColumns {
+		var count int64
+		if err := db.Table(col.Table).Where(fmt.Sprintf("%s IS NOT NULL AND %s <> ''", col.Column, col.Column)).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check %s.%s: %w", col.Table, col.Column, err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%s.%s holds %d encrypted values but ENCRYPTION_KEYS is not set", col.Table, col.Column, count)
+		}
+	}
+	return nil
+}
+
+// RotateColumns re-encrypts every value not written with the active key.
+// It returns the number of rewritten values per column.
+func RotateColumns(db *gorm.DB, keyring *Keyring) (map[Column]int, error) {
+	rotated := make(map[Column]int, len(EncryptedColumns))
+
+	for _, col := range EncryptedColumns {
+		var rows []struct {
+			ID    string
+			Value string
+		}
+		if err := db.Table(col.Table).
+			Select(fmt.Sprintf("id::text AS id, %s AS value", col.Column)).
+			Where(fmt.Sprintf("%s IS NOT NULL AND %s <> ''", col.Column, col.Column)).
+			Scan(&rows).Error; err != nil {
+			return rotated, fmt.Errorf("failed to read %s.%s: %w", col.Table, col.Column, err)
+		}
+
+		for _, row := range rows {
+			if !keyring.NeedsRotation(row.Value) {
+				continue
+			}
+
+			value, err := keyring.Reencrypt(row.Value)
+			if err != nil {
+				return rotated, fmt.Errorf("failed to re-encrypt %s.%s for id %s: %w", col.Table, col.Column, row.ID, err)
+			}
+
+			if err := db.Table(col.Table).Where("id::text = ?", row.ID).Update(col.Column, value).Error; err != nil {
+				return rotated, fmt.Errorf("failed to update %s.%s for id %s: %w", col.Table, col.Column, row.ID, err)
+			}
+			rotated[col]++
+		}
+	}
+
+	return rotated, nil
+}
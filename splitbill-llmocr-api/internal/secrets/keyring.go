@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownKey is returned when a ciphertext was written with a key that is not configured
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring encrypts values with AES-256-GCM. Ciphertexts are prefixed with the ID of
+// the key that wrote them ("<key-id>:<base64 nonce+ciphertext>"), so old keys can stay
+// configured for reading while new writes use the active key.
+type Keyring struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+}
+
+// ParseKeyring builds a keyring from a "id:base64key,id:base64key" list.
+// Every key must decode to 32 bytes. It returns nil when spec is empty.
+func ParseKeyring(spec, activeID string) (*Keyring, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	keyring := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for _, entry := range strings.Split(spec, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("invalid encryption key entry %q: expected id:base64key", entry)
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate encryption key id %q", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, got %d", id, len(key))
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		keyring.keys[id] = aead
+	}
+
+	if activeID == "" {
+		return nil, fmt.Errorf("an active encryption key id is required")
+	}
+	if _, ok := keyring.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not in the key list", activeID)
+	}
+	keyring.activeID = activeID
+
+	return keyring, nil
+}
+
+// Encrypt seals plaintext with the active key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.keys[k.activeID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.activeID))
+	return k.activeID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a ciphertext written by Encrypt with any configured key
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	id, encoded, found := strings.Cut(ciphertext, ":")
+	if !found {
+		return "", fmt.Errorf("ciphertext has no key id prefix")
+	}
+
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("ciphertext is not valid base64: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("ciphertext is too short")
+	}
+
+	nonce, body := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, body, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a ciphertext was written with a key other than the active one
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	id, _, _ := strings.Cut(ciphertext, ":")
+	return id != k.activeID
+}
+
+// Reencrypt decrypts a ciphertext and seals it again with the active key
+func (k *Keyring) Reencrypt(ciphertext string) (string, error) {
+	plaintext, err := k.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext)
+}
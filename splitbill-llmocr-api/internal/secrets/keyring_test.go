@@ -0,0 +1,103 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a valid base64 key made of b repeated
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestParseKeyring(t *testing.T) {
+	if keyring, err := ParseKeyring("  ", ""); keyring != nil || err != nil {
+		t.Errorf("empty spec = %v, %v; want nil, nil", keyring, err)
+	}
+
+	for name, spec := range map[string]string{
+		"missing id":     ":" + testKey('a'),
+		"not base64":     "k1:not-base64!",
+		"short key":      "k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"duplicate id":   "k1:" + testKey('a') + ",k1:" + testKey('b'),
+		"unknown active": "k1:" + testKey('a'),
+	} {
+		active := "k1"
+		if name == "unknown active" {
+			active = "k2"
+		}
+		if _, err := ParseKeyring(spec, active); err == nil {
+			t.Errorf("%s: ParseKeyring succeeded, want an error", name)
+		}
+	}
+}
+
+func TestKeyringRoundTripAndRotation(t *testing.T) {
+	old, err := ParseKeyring("k1:"+testKey('a'), "k1")
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	ciphertext, err := old.Encrypt("https://example.com/hook")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(ciphertext, "k1:") {
+		t.Fatalf("ciphertext %q lacks the key id", ciphertext)
+	}
+
+	rotated, err := ParseKeyring("k1:"+testKey('a')+", k2:"+testKey('b'), "k2")
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	if !rotated.NeedsRotation(ciphertext) {
+		t.Error("a k1 ciphertext should need rotation once k2 is active")
+	}
+	reencrypted, err := rotated.Reencrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	if rotated.NeedsRotation(reencrypted) {
+		t.Error("a re-encrypted value should not need rotation")
+	}
+	plaintext, err := rotated.Decrypt(reencrypted)
+	if err != nil || plaintext != "https://example.com/hook" {
+		t.Errorf("Decrypt = %q, %v", plaintext, err)
+	}
+
+	if _, err := old.Decrypt(reencrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("decrypting with a missing key = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	keyring, err := ParseKeyring("k1:"+testKey('a')+",k2:"+testKey('b'), "k1")
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	ciphertext, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	_, body, _ := strings.Cut(ciphertext, ":")
+	for name, tampered := range map[string]string{
+		"no prefix":      body,
+		"other key id":   "k2:" + body,
+		"flipped byte":   "k1:" + flipLastByte(body),
+		"too short":      "k1:" + base64.StdEncoding.EncodeToString([]byte("x")),
+		"invalid base64": "k1:***",
+	} {
+		if _, err := keyring.Decrypt(tampered); err == nil {
+			t.Errorf("%s: Decrypt succeeded, want an error", name)
+		}
+	}
+}
+
+// flipLastByte changes the last byte of a base64 value, breaking its authentication tag
+func flipLastByte(encoded string) string {
+	raw, _ := base64.StdEncoding.DecodeString(encoded)
+	raw[len(raw)-1] ^= 0xff
+	return base64.StdEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,20 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// tokenBytes is the randomness in every token: 192 bits
+const tokenBytes = 24
+
+// NewToken returns a random, URL-safe token for links that grant access on their own,
+// such as bill share links
+func NewToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,21 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestNewToken(t *testing.T) {
+	first, err := NewToken()
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	second, _ := NewToken()
+	if first == second {
+		t.Error("two tokens were equal")
+	}
+	if len(first) != base64.RawURLEncoding.EncodedLen(tokenBytes) || strings.ContainsAny(first, "+/=") {
+		t.Errorf("token %q is not %d URL-safe bytes", first, tokenBytes)
+	}
+}
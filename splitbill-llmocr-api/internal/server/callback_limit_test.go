@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+)
+
+const callbackLimitSecret = "callback-limit-test-secret"
+
+// embeddedImageBody is an endless callback body whose extracted data embeds a base64
+// image, the way a misbehaving workflow once sent 40MB. It counts what is read from it.
+type embeddedImageBody struct {
+	prefix *strings.Reader
+	read   int64
+}
+
+func newEmbeddedImageBody() *embeddedImageBody {
+	return &embeddedImageBody{prefix: strings.NewReader(`{"code":"API_SPLITBILL_LLMOCR","image":"data:image/png;base64,`)}
+}
+
+func (b *embeddedImageBody) Read(p []byte) (int, error) {
+	n, _ := b.prefix.Read(p)
+	for i := n; i < len(p); i++ {
+		p[i] = 'A'
+	}
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+func TestExtractionCallbackBodyLimit(t *testing.T) {
+	const limit = 256 << 10
+	router := newOfflineRouter(t, func(cfg *config.Config) {
+		cfg.N8nCallbackSecret = callbackLimitSecret
+		cfg.ExtractionCallbackMaxBodySize = limit
+	})
+	path := "/api/bills/" + validBillID + "/process-data"
+
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.CallbackSecretHeader, callbackLimitSecret)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 40MB is never read, let alone held: reading stops one byte past the limit
+	const sent = 40 << 20
+	body := newEmbeddedImageBody()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	w := post(io.LimitReader(body, sent))
+	runtime.ReadMemStats(&after)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d %s, want 413", w.Code, w.Body)
+	}
+	if body.read > limit+1 {
+		t.Errorf("read %d bytes of the body, want at most %d", body.read, limit+1)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 4<<20 {
+		t.Errorf("allocated %d bytes for a %d byte body, want it bounded by the limit", allocated, sent)
+	}
+
+	// The error names the limit, in words and as a number
+	var response struct {
+		Error      string `json:"error"`
+		LimitBytes int    `json:"limit_bytes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode error: %v", err)
+	}
+	if response.LimitBytes != limit || !strings.Contains(response.Error, fmt.Sprint(limit)) {
+		t.Errorf("error = %q with limit_bytes %d, want it to name %d", response.Error, response.LimitBytes, limit)
+	}
+
+	// A body at the limit gets past the size check
+	atLimit := bytes.Repeat([]byte(" "), limit-2)
+	atLimit = append(append([]byte("{"), atLimit...), '}')
+	if w := post(bytes.NewReader(atLimit)); w.Code == http.StatusRequestEntityTooLarge {
+		t.Errorf("body of exactly %d bytes: status 413 %s", limit, w.Body)
+	}
+}
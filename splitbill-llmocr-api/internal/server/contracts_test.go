@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+// contractsDir holds the recorded n8n callback bodies, one directory per case
+var contractsDir = filepath.Join("testdata", "n8n")
+
+// contractMaxAmount matches the EXTRACTION_MAX_AMOUNT default
+const contractMaxAmount = 99999999
+
+// contract is one recorded callback: payload.json is the body n8n sent and expected.json
+// the shape and data it must parse to, or part of the error it must be rejected with
+type contract struct {
+	Name     string
+	Payload  []byte
+	Expected struct {
+		Currency string `json:"currency"`
+		Shape    string `json:"shape"`
+		Error    string `json:"error"`
+		models.ExtractedItemData
+	}
+}
+
+// loadContracts reads every recorded contract, defaulting the bill currency to USD
+func loadContracts(t *testing.T) []contract {
+	t.Helper()
+
+	entries, err := os.ReadDir(contractsDir)
+	if err != nil {
+		t.Fatalf("failed to read contracts: %v", err)
+	}
+	var contracts []contract
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(contractsDir, entry.Name())
+		c := contract{Name: entry.Name()}
+		if c.Payload, err = os.ReadFile(filepath.Join(dir, "payload.json")); err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		expected, err := os.ReadFile(filepath.Join(dir, "expected.json"))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if err := json.Unmarshal(expected, &c.Expected); err != nil {
+			t.Fatalf("%s: invalid expected.json: %v", c.Name, err)
+		}
+		if c.Expected.Currency == "" {
+			c.Expected.Currency = "USD"
+		}
+		if c.Expected.Items == nil {
+			c.Expected.Items = []models.ExtractedItem{}
+		}
+		contracts = append(contracts, c)
+	}
+	if len(contracts) == 0 {
+		t.Fatalf("no contracts in %s", contractsDir)
+	}
+	return contracts
+}
+
+// TestContractsParse checks that every recorded body parses to exactly the recorded shape
+// and data, or is rejected with the recorded error
+func TestContractsParse(t *testing.T) {
+	for _, c := range loadContracts(t) {
+		t.Run(c.Name, func(t *testing.T) {
+			shape, data, err := services.ParseExtractionCallback(c.Payload, currency.Normalize(c.Expected.Currency), contractMaxAmount)
+			if c.Expected.Error != "" {
+				if err == nil {
+					t.Fatalf("accepted as %s, want an error containing %q", shape, c.Expected.Error)
+				}
+				if !strings.Contains(err.Error(), c.Expected.Error) {
+					t.Fatalf("rejected with %q, want an error containing %q", err, c.Expected.Error)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rejected: %v", err)
+			}
+			if shape != c.Expected.Shape {
+				t.Errorf("parsed as shape %s, want %s", shape, c.Expected.Shape)
+			}
+
+			got, _ := json.Marshal(data)
+			want, _ := json.Marshal(c.Expected.ExtractedItemData)
+			if !bytes.Equal(got, want) {
+				t.Errorf("parsed data differs\n  got:  %s\n  want: %s", got, want)
+			}
+		})
+	}
+}
+
+// TestContractsProcessData posts every recorded body to process-data through the real
+// router and checks what was stored on a bill waiting for its extraction
+func TestContractsProcessData(t *testing.T) {
+	const secret = "contract-secret"
+	db := testdb.Open(t)
+	router := newTestRouter(t, db, func(cfg *config.Config) {
+		cfg.N8nCallbackSecret = secret
+	})
+
+	for _, c := range loadContracts(t) {
+		t.Run(c.Name, func(t *testing.T) {
+			bill := models.Bills{Name: c.Name, Status: string(services.StatusProcessing), Currency: c.Expected.Currency}
+			if err := db.Create(&bill).Error; err != nil {
+				t.Fatalf("failed to create bill: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/bills/"+bill.ID.String()+"/process-data", bytes.NewReader(c.Payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(middleware.CallbackSecretHeader, secret)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if err := db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&bill, "id = ?", bill.ID).Error; err != nil {
+				t.Fatalf("failed to reload bill: %v", err)
+			}
+			if c.Expected.Error != "" {
+				if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), c.Expected.Error) {
+					t.Fatalf("status %d %s, want 400 with %q", w.Code, w.Body, c.Expected.Error)
+				}
+				if bill.Status != string(services.StatusFailed) || len(bill.Items) != 0 {
+					t.Errorf("rejected callback left the bill %s with %d items", bill.Status, len(bill.Items))
+				}
+				return
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d %s, want 200", w.Code, w.Body)
+			}
+
+			want := c.Expected
+			if bill.Status != string(services.StatusCompleted) {
+				t.Errorf("status = %s, want %s", bill.Status, services.StatusCompleted)
+			}
+			if bill.TaxAmount != want.Tax || bill.TipAmount != want.Tip ||
+				bill.PricesIncludeTax != want.PricesIncludeTax || bill.PricesIncludeService != want.PricesIncludeService {
+				t.Errorf("bill tax %v tip %v includes %v/%v, want %v %v %v/%v", bill.TaxAmount, bill.TipAmount,
+					bill.PricesIncludeTax, bill.PricesIncludeService, want.Tax, want.Tip, want.PricesIncludeTax, want.PricesIncludeService)
+			}
+			if len(bill.Items) != len(want.Items) {
+				t.Fatalf("stored %d items, want %d", len(bill.Items), len(want.Items))
+			}
+			for i, item := range bill.Items {
+				expected := want.Items[i]
+				if item.RawName != expected.Name || item.Price != expected.UnitPrice || item.Quantity != expected.Quantity ||
+					item.DiscountAmount != expected.DiscountAmount || category(item.Category) != category(services.NormalizeCategory(expected.Category)) ||
+					item.NeedsReview != expected.NeedsReview || item.Confirmed {
+					t.Errorf("item %d = %+v, want %+v as an unconfirmed draft", i, item, expected)
+				}
+			}
+		})
+	}
+}
+
+// category returns a stored category, or "" for none
+func category(c *string) string {
+	if c == nil {
+		return ""
+	}
+	return *c
+}
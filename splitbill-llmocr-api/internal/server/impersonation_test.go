@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const impersonationSecret = "impersonation-test-secret"
+
+// makeAdmin gives the user the admin role
+func makeAdmin(t *testing.T, db *gorm.DB, userID uint) {
+	t.Helper()
+	if err := db.Model(&models.Users{}).Where("id = ?", userID).Update("role", "admin").Error; err != nil {
+		t.Fatalf("failed to make user %d an admin: %v", userID, err)
+	}
+}
+
+// sessionCookie wraps an access token the way the login route hands it out
+func sessionCookie(token string) *http.Cookie {
+	return &http.Cookie{Name: "access_token", Value: token}
+}
+
+func TestImpersonation(t *testing.T) {
+	db := testdb.Open(t)
+	router := newTestRouter(t, db, func(cfg *config.Config) {
+		cfg.JWTSecret = impersonationSecret
+		cfg.ImpersonationTTL = 5 * time.Minute
+	})
+	adminID, adminSession := logIn(t, router, "support")
+	makeAdmin(t, db, adminID)
+	otherAdminID, _ := logIn(t, router, "support2")
+	makeAdmin(t, db, otherAdminID)
+	ana, anaSession := logIn(t, router, "ana")
+	_, benSession := logIn(t, router, "ben")
+	bill := createBill(t, router, "Ana's dinner", anaSession)
+
+	impersonate := func(userID uint, session *http.Cookie) *httptest.ResponseRecorder {
+		return send(t, router, http.MethodPost, fmt.Sprintf("/api/admin/impersonate/%d", userID), nil, session)
+	}
+	if w := impersonate(ana, benSession); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status %d, want 403", w.Code)
+	}
+	if w := impersonate(otherAdminID, adminSession); w.Code != http.StatusForbidden {
+		t.Errorf("impersonating an admin: status %d, want 403", w.Code)
+	}
+
+	w := impersonate(ana, adminSession)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("impersonate: status %d %s", w.Code, w.Body)
+	}
+	var impersonation models.ImpersonationResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &impersonation); err != nil {
+		t.Fatalf("failed to decode impersonation: %v", err)
+	}
+	if impersonation.User.ID != ana || time.Until(impersonation.ExpiresAt) > 5*time.Minute {
+		t.Errorf("impersonation = user %d until %s, want Ana for at most 5 minutes", impersonation.User.ID, impersonation.ExpiresAt)
+	}
+	session := sessionCookie(impersonation.Token)
+
+	// Reads see what Ana sees
+	w = send(t, router, http.MethodGet, "/api/me/bills", nil, session)
+	var list models.BillListResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil || len(list.Bills) != 1 || list.Bills[0].ID != bill.ID {
+		t.Errorf("impersonated /api/me/bills: status %d %s, want Ana's bill", w.Code, w.Body)
+	}
+
+	// Writes are refused, whatever Ana herself may do
+	writes := []struct{ method, path string }{
+		{http.MethodPut, "/api/bills/" + bill.ID.String()},
+		{http.MethodPost, "/api/bills/"},
+		{http.MethodDelete, "/api/bills/" + bill.ID.String()},
+	}
+	for _, write := range writes {
+		if w := send(t, router, write.method, write.path, map[string]string{"name": "Changed"}, session); w.Code != http.StatusForbidden {
+			t.Errorf("impersonated %s %s: status %d, want 403", write.method, write.path, w.Code)
+		}
+	}
+	var stored models.Bills
+	if err := db.First(&stored, "id = ?", bill.ID).Error; err != nil || stored.Name != "Ana's dinner" {
+		t.Errorf("bill after impersonated writes = %q, %v; want it untouched", stored.Name, err)
+	}
+
+	// Starting the session and every request under it are audited with both identities
+	var logs []models.AuditLogs
+	if err := db.Order("id").Find(&logs).Error; err != nil {
+		t.Fatalf("failed to load audit log: %v", err)
+	}
+	if len(logs) != 2+len(writes) {
+		t.Fatalf("%d audit entries, want %d: %+v", len(logs), 2+len(writes), logs)
+	}
+	if logs[0].Action != services.AuditImpersonationStarted {
+		t.Errorf("first audit entry = %s, want %s", logs[0].Action, services.AuditImpersonationStarted)
+	}
+	for i, entry := range logs {
+		if entry.ActorID != adminID || entry.SubjectID != ana {
+			t.Errorf("audit entry %d = actor %d subject %d, want %d acting as %d", i, entry.ActorID, entry.SubjectID, adminID, ana)
+		}
+	}
+	for i, write := range writes {
+		entry := logs[2+i]
+		if entry.Action != services.AuditImpersonatedRequest || entry.Method != write.method || entry.Path != write.path || entry.Status != http.StatusForbidden {
+			t.Errorf("audit entry for %s %s = %+v", write.method, write.path, entry)
+		}
+	}
+}
+
+func TestImpersonationExpires(t *testing.T) {
+	db := testdb.Open(t)
+	router := newTestRouter(t, db, func(cfg *config.Config) {
+		cfg.JWTSecret = impersonationSecret
+	})
+	adminID, _ := logIn(t, router, "support")
+	makeAdmin(t, db, adminID)
+	ana, _ := logIn(t, router, "ana")
+
+	var user models.Users
+	if err := db.First(&user, ana).Error; err != nil {
+		t.Fatalf("failed to load Ana: %v", err)
+	}
+	// The same token the admin route signs, issued at issued for five minutes
+	sign := func(issued time.Time) *http.Cookie {
+		claims := &models.Claims{
+			UserID:         user.ID,
+			Username:       user.Username,
+			Email:          user.Email,
+			Role:           user.Role,
+			ImpersonatorID: adminID,
+			TokenVersion:   user.TokenVersion,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(issued.Add(5 * time.Minute)),
+				IssuedAt:  jwt.NewNumericDate(issued),
+				NotBefore: jwt.NewNumericDate(issued),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(impersonationSecret))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return sessionCookie(token)
+	}
+
+	if w := send(t, router, http.MethodGet, "/api/me/bills", nil, sign(time.Now().Add(-time.Minute))); w.Code != http.StatusOK {
+		t.Errorf("current impersonation: status %d, want 200", w.Code)
+	}
+	if w := send(t, router, http.MethodGet, "/api/me/bills", nil, sign(time.Now().Add(-time.Hour))); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired impersonation: status %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+)
+
+func TestItemsScopedToBill(t *testing.T) {
+	db := testdb.Open(t)
+	router := newTestRouter(t, db, func(cfg *config.Config) {
+		cfg.JWTSecret = "item-scope-test-secret"
+	})
+	_, anaSession := logIn(t, router, "ana")
+	_, benSession := logIn(t, router, "ben")
+	anas := createBill(t, router, "Ana's dinner", anaSession)
+	bens := createBill(t, router, "Ben's lunch", benSession)
+
+	addItem := func(bill models.BillResponse, session *http.Cookie) models.Items {
+		t.Helper()
+		w := send(t, router, http.MethodPost, "/api/bills/"+bill.ID.String()+"/items", models.ItemRequest{Name: "Noodles", Price: 12, Quantity: 1}, session)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("add item to %s: status %d %s", bill.Name, w.Code, w.Body)
+		}
+		var item models.Items
+		if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+			t.Fatalf("failed to decode item: %v", err)
+		}
+		return item
+	}
+	anasItem := addItem(anas, anaSession)
+	bensItem := addItem(bens, benSession)
+	change := map[string]float64{"price": 1}
+
+	tests := []struct {
+		name, method, path string
+		want               int
+	}{
+		// Ana owns the bill in the path, but the item is not in it; 404 so IDs don't leak
+		{name: "update through another bill", method: http.MethodPut, path: fmt.Sprintf("/api/bills/%s/items/%d", anas.ID, bensItem.ID), want: http.StatusNotFound},
+		{name: "delete through another bill", method: http.MethodDelete, path: fmt.Sprintf("/api/bills/%s/items/%d", anas.ID, bensItem.ID), want: http.StatusNotFound},
+		// Through its own bill, the owner check refuses her
+		{name: "update on Ben's bill", method: http.MethodPut, path: fmt.Sprintf("/api/bills/%s/items/%d", bens.ID, bensItem.ID), want: http.StatusForbidden},
+		{name: "delete on Ben's bill", method: http.MethodDelete, path: fmt.Sprintf("/api/bills/%s/items/%d", bens.ID, bensItem.ID), want: http.StatusForbidden},
+		// The unscoped route is gone
+		{name: "unscoped update", method: http.MethodPut, path: fmt.Sprintf("/api/items/%d", bensItem.ID), want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := send(t, router, tt.method, tt.path, change, anaSession); w.Code != tt.want {
+				t.Errorf("status %d %s, want %d", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+
+	var stored models.Items
+	if err := db.First(&stored, bensItem.ID).Error; err != nil {
+		t.Fatalf("Ben's item is gone: %v", err)
+	}
+	if stored.BillID != bens.ID || stored.Price != 12 {
+		t.Errorf("Ben's item = bill %s price %v, want it untouched", stored.BillID, stored.Price)
+	}
+
+	// Her own item through her own bill still works
+	if w := send(t, router, http.MethodPut, fmt.Sprintf("/api/bills/%s/items/%d", anas.ID, anasItem.ID), change, anaSession); w.Code != http.StatusOK {
+		t.Errorf("update own item: status %d %s, want 200", w.Code, w.Body)
+	}
+}
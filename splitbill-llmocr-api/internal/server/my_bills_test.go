@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+)
+
+// send makes a JSON request to router, logged in with session when it is set
+func send(t *testing.T, router http.Handler, method, path string, body interface{}, session *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if session != nil {
+		req.AddCookie(session)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// logIn registers name and logs them in, returning their ID and access token cookie
+func logIn(t *testing.T, router http.Handler, name string) (uint, *http.Cookie) {
+	t.Helper()
+
+	register := models.RegisterRequest{Username: name, Email: name + "@example.com", Password: "password1", Name: name}
+	if w := send(t, router, http.MethodPost, "/api/auth/register", register, nil); w.Code != http.StatusCreated {
+		t.Fatalf("register %s: status %d %s", name, w.Code, w.Body)
+	}
+	w := send(t, router, http.MethodPost, "/api/auth/login", models.LoginRequest{Username: name, Password: "password1"}, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("log in %s: status %d %s", name, w.Code, w.Body)
+	}
+
+	var login models.LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatalf("failed to decode login: %v", err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "access_token" {
+			return login.User.ID, cookie
+		}
+	}
+	t.Fatalf("log in %s set no access_token cookie", name)
+	return 0, nil
+}
+
+// createBill creates a bill named name through the router and returns it
+func createBill(t *testing.T, router http.Handler, name string, session *http.Cookie) models.BillResponse {
+	t.Helper()
+
+	w := send(t, router, http.MethodPost, "/api/bills/", models.BillRequest{Name: name}, session)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create %s: status %d %s", name, w.Code, w.Body)
+	}
+	var bill models.BillResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &bill); err != nil {
+		t.Fatalf("failed to decode bill: %v", err)
+	}
+	return bill
+}
+
+func TestBillOwnership(t *testing.T) {
+	router := newTestRouter(t, testdb.Open(t), func(cfg *config.Config) {
+		cfg.JWTSecret = "my-bills-test-secret"
+	})
+	ana, anaSession := logIn(t, router, "ana")
+	_, benSession := logIn(t, router, "ben")
+
+	guests := createBill(t, router, "Guest brunch", nil)
+	if guests.UserID != nil {
+		t.Errorf("anonymous bill owner = %d, want none", *guests.UserID)
+	}
+	anas := createBill(t, router, "Ana's dinner", anaSession)
+	if anas.UserID == nil || *anas.UserID != ana {
+		t.Errorf("Ana's bill owner = %v, want %d", anas.UserID, ana)
+	}
+	createBill(t, router, "Ben's lunch", benSession)
+
+	// Guests can still open their bill
+	if w := send(t, router, http.MethodGet, "/api/bills/"+guests.ID.String(), nil, nil); w.Code != http.StatusOK {
+		t.Errorf("guest bill: status %d, want 200", w.Code)
+	}
+
+	w := send(t, router, http.MethodGet, "/api/me/bills", nil, anaSession)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Ana's bills: status %d %s", w.Code, w.Body)
+	}
+	var list models.BillListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode bills: %v", err)
+	}
+	if list.Total != 1 || len(list.Bills) != 1 || list.Bills[0].ID != anas.ID {
+		t.Errorf("Ana's bills = %+v, want only %s", list.Bills, anas.ID)
+	}
+
+	if w := send(t, router, http.MethodGet, "/api/me/bills", nil, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous /api/me/bills: status %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+)
+
+func TestUnknownRoutesAndMethods(t *testing.T) {
+	router := newOfflineRouter(t, nil)
+
+	tests := []struct {
+		name, method, path string
+		status             int
+		code, allow        string
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/api/nope", status: http.StatusNotFound, code: "ROUTE_NOT_FOUND"},
+		{name: "unknown path under a bill", method: http.MethodGet, path: "/api/bills/" + validBillID + "/nope", status: http.StatusNotFound, code: "ROUTE_NOT_FOUND"},
+		{name: "wrong method on the bill list", method: http.MethodDelete, path: "/api/bills", status: http.StatusMethodNotAllowed, code: "METHOD_NOT_ALLOWED", allow: "GET"},
+		{name: "wrong method on bill creation", method: http.MethodPut, path: "/api/bills/", status: http.StatusMethodNotAllowed, code: "METHOD_NOT_ALLOWED", allow: "POST"},
+		{name: "wrong method on a bill action", method: http.MethodGet, path: "/api/bills/" + validBillID + "/archive", status: http.StatusMethodNotAllowed, code: "METHOD_NOT_ALLOWED", allow: "POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			var body struct {
+				Error string `json:"error"`
+				Code  string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not JSON: %q", w.Body)
+			}
+			if w.Code != tt.status || body.Code != tt.code || body.Error == "" {
+				t.Fatalf("status %d code %q (%s), want %d %q", w.Code, body.Code, w.Body, tt.status, tt.code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	router := newOfflineRouter(t, func(cfg *config.Config) {
+		cfg.CORSAllowedOrigins = []string{"https://app.example.com"}
+	})
+
+	// Preflight goes through the CORS middleware even though no route answers OPTIONS
+	for _, path := range []string{"/api/bills/", "/api/bills/" + validBillID + "/participants"} {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("OPTIONS %s: status %d, want 204: %s", path, w.Code, w.Body)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("OPTIONS %s: Access-Control-Allow-Origin = %q", path, got)
+		}
+		if w.Header().Get("Access-Control-Allow-Methods") == "" {
+			t.Errorf("OPTIONS %s: no Access-Control-Allow-Methods", path)
+		}
+	}
+
+	// An origin outside the policy is not allowed
+	req := httptest.NewRequest(http.MethodOptions, "/api/bills/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("refused origin got Access-Control-Allow-Origin %q", got)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDatabaseOutage(t *testing.T) {
+	router := newTestRouterWithDatabase(t, openOfflineDB(t), unavailableDatabase{}, nil)
+
+	tests := []struct {
+		name, method, path string
+		status             int
+		code               string
+	}{
+		{name: "readiness", method: http.MethodGet, path: "/health/ready", status: http.StatusServiceUnavailable},
+		{name: "read", method: http.MethodGet, path: "/api/bills/00000000-0000-0000-0000-000000000001", status: http.StatusServiceUnavailable, code: "DB_UNAVAILABLE"},
+		{name: "write", method: http.MethodPost, path: "/api/bills/", status: http.StatusServiceUnavailable, code: "DB_UNAVAILABLE"},
+		{name: "features", method: http.MethodGet, path: "/api/features", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.code == "" {
+				return
+			}
+
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("code = %q, want %q", body.Code, tt.code)
+			}
+			if w.Header().Get("Retry-After") == "" {
+				t.Error("no Retry-After header")
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+)
+
+// validBillID is a well-formed bill ID that no test database holds
+const validBillID = "00000000-0000-0000-0000-000000000001"
+
+// routePath fills a route's bill ID parameters with id, share tokens with a token and
+// every other parameter with number. It also reports which kinds of parameter it filled.
+func routePath(route, id, number string) (string, bool, bool) {
+	var hasID, hasNumber bool
+	parts := strings.Split(route, "/")
+	for i, part := range parts {
+		switch {
+		case part == ":id", part == ":billId":
+			parts[i], hasID = id, true
+		case part == ":token":
+			parts[i] = "token"
+		case strings.HasPrefix(part, ":"):
+			parts[i], hasNumber = number, true
+		}
+	}
+	return strings.Join(parts, "/"), hasID, hasNumber
+}
+
+func TestMalformedPathParameters(t *testing.T) {
+	const callbackSecret = "params-test-callback"
+	db := testdb.Open(t)
+	router := newTestRouter(t, db, func(cfg *config.Config) {
+		cfg.JWTSecret = "params-test-secret"
+		cfg.N8nCallbackSecret = callbackSecret
+	})
+
+	// An admin may call every route, so each request gets as far as its handler
+	adminID, session := logIn(t, router, "admin")
+	if err := db.Model(&models.Users{}).Where("id = ?", adminID).Update("role", "admin").Error; err != nil {
+		t.Fatalf("failed to make the user an admin: %v", err)
+	}
+
+	for _, route := range router.Routes() {
+		var paths []string
+		if path, hasID, _ := routePath(route.Path, "not-a-uuid", "1"); hasID {
+			paths = append(paths, path)
+		}
+		if path, _, hasNumber := routePath(route.Path, validBillID, "abc"); hasNumber {
+			paths = append(paths, path)
+		}
+
+		for _, path := range paths {
+			t.Run(route.Method+" "+path, func(t *testing.T) {
+				req := httptest.NewRequest(route.Method, path, strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set(middleware.CallbackSecretHeader, callbackSecret)
+				req.AddCookie(session)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				var body struct {
+					Code string `json:"code"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if w.Code != http.StatusBadRequest || body.Code != "INVALID_PARAMETER" {
+					t.Errorf("status %d %s, want 400 INVALID_PARAMETER", w.Code, w.Body)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/handlers"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/origin"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Database is what the router needs from the database connection
+type Database interface {
+	middleware.DatabaseAvailability
+	HealthCheck() error
+}
+
+// Deps are the long-lived pieces the router's handlers and middleware are built from
+type Deps struct {
+	Config *config.Config
+	// Database reports on the connection; DB is the connection itself
+	Database    Database
+	DB          *gorm.DB
+	UserCache   cache.UserCache
+	Storage     *storage.Local
+	UserService *services.UserService
+	BillService *services.BillService
+}
+
+// NewRouter builds the HTTP router: middleware, every route and the health endpoints. It
+// fails when the CORS settings are invalid or a route has no declared permission.
+func NewRouter(deps Deps) (*gin.Engine, error) {
+	cfg, db, userCache, uploadStorage := deps.Config, deps.Database, deps.UserCache, deps.Storage
+	userService, billService := deps.UserService, deps.BillService
+
+	// Initialize handlers
+	log.Println("Initializing handlers...")
+	authHandler := handlers.NewAuthHandler(userService)
+	billHandler := handlers.NewBillHandler(billService)
+	statusHandler := handlers.NewStatusHandler(billService, db, uploadStorage, cfg)
+	adminHandler := handlers.NewAdminHandler(billService, userService, cfg)
+
+	// Initialize router
+	router := gin.New() // Use gin.New() instead of gin.Default() to avoid default middleware
+
+	// Answer wrong methods with 405 (and an Allow header) instead of a generic 404
+	router.HandleMethodNotAllowed = true
+
+	// Add logger middleware
+	router.Use(gin.Logger())
+
+	// Log incoming requests
+	router.Use(func(c *gin.Context) {
+		log.Printf("Incoming request: %s %s", c.Request.Method, c.Request.URL.Path)
+		c.Next()
+	})
+
+	// Add CORS middleware. Development falls back to the local frontend when nothing is
+	// configured; production refuses every cross-origin request instead.
+	corsOrigins := cfg.CORSAllowedOrigins
+	if len(corsOrigins) == 0 && len(cfg.CORSOriginPatterns) == 0 && !cfg.CORSAllowLocalhost && cfg.Environment != "production" {
+		corsOrigins = []string{"http://localhost:3001"}
+	}
+	corsPolicy, err := origin.New(corsOrigins, cfg.CORSOriginPatterns, cfg.CORSAllowLocalhost)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS configuration: %w", err)
+	}
+	if corsPolicy.Empty() {
+		log.Printf("No CORS origins configured; cross-origin requests will be refused")
+	}
+	router.Use(middleware.CORS(corsPolicy))
+
+	// JSON responses for unknown routes and unsupported methods.
+	// Gin sets the Allow header before NoMethod handlers run.
+	router.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Route %s %s not found", c.Request.Method, c.Request.URL.Path),
+			"code":  "ROUTE_NOT_FOUND",
+		})
+	})
+	router.NoMethod(func(c *gin.Context) {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error": fmt.Sprintf("Method %s is not allowed on %s. Allowed: %s", c.Request.Method, c.Request.URL.Path, c.Writer.Header().Get("Allow")),
+			"code":  "METHOD_NOT_ALLOWED",
+		})
+	})
+
+	// Health check endpoint for keep-alive and monitoring
+	router.GET("/health", func(c *gin.Context) {
+		// Check database connectivity
+		if err := db.HealthCheck(); err != nil {
+			log.Printf("Health check failed - database connectivity issue: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":      "unhealthy",
+				"error":       "database connectivity failed",
+				"timestamp":   time.Now().UTC().Format(time.RFC3339),
+				"environment": os.Getenv("APP_ENV"),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":      "healthy",
+			"timestamp":   time.Now().UTC().Format(time.RFC3339),
+			"environment": os.Getenv("APP_ENV"),
+			"database":    "connected",
+		})
+	})
+
+	// Readiness endpoint: reports not-ready while the database supervisor is reconnecting.
+	// Storage problems are reported in detail but only block readiness when uploads require it.
+	router.GET("/health/ready", func(c *gin.Context) {
+		storageStatus := uploadStorage.Status()
+		if !db.IsAvailable() || (cfg.StorageRequired && !storageStatus.Available) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":    "not_ready",
+				"database":  availability(db.IsAvailable()),
+				"storage":   storageStatus,
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "ready",
+			"database":  "available",
+			"storage":   storageStatus,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+
+	// Serve static files (for uploaded images)
+	router.Static("/uploads", cfg.UploadsPath)
+
+	// All API routes
+	api := router.Group("/api")
+
+	// Lets the frontend hide UI for features this deployment has switched off
+	api.GET("/features", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"features": cfg.Features.Map()})
+	})
+
+	// Public status banner data; answers even while the database is down
+	api.GET("/status", statusHandler.GetStatus)
+
+	api.Use(middleware.RequireDatabase(db))
+	if cfg.Features.Enabled(config.FeatureAuth) {
+		// Identify logged-in callers; guests pass through anonymously
+		api.Use(middleware.Authenticate(cfg.JWTSecret, deps.DB, userCache))
+	}
+	// Who may call each route is declared in middleware.RoutePermissions
+	api.Use(middleware.Authorize(middleware.RoutePermissions, billService))
+	{
+		// Public routes
+		if cfg.Features.Enabled(config.FeatureAuth) {
+			auth := api.Group("/auth")
+			{
+				auth.POST("/register", authHandler.Register)
+				auth.POST("/login", authHandler.Login)
+			}
+		}
+
+		bills := api.Group("/bills")
+		{
+			bills.GET("", billHandler.ListBills)
+			bills.POST("/", billHandler.CreateBill)
+			bills.GET("/:id", billHandler.GetBill)
+			bills.PUT("/:id", billHandler.UpdateBill)
+			bills.DELETE("/:id", billHandler.DeleteBill)
+			bills.POST("/:id/archive", billHandler.ArchiveBill)
+			bills.POST("/:id/unarchive", billHandler.UnarchiveBill)
+			bills.POST("/:id/reopen", billHandler.ReopenBill)
+			bills.POST("/:id/finalize", billHandler.FinalizeBill)
+			bills.POST("/:id/share/regenerate", billHandler.RegenerateShareToken)
+			bills.GET("/:id/status", billHandler.GetBillStatus)
+			bills.GET("/:id/status/stream", billHandler.StreamBillStatus)
+			bills.GET("/:id/summary", billHandler.GetBillSummary)
+			bills.GET("/:id/events", billHandler.ListBillEvents)
+			bills.GET("/:id/items", billHandler.ListItems)
+			bills.POST("/:id/items", billHandler.CreateItems)
+			bills.PUT("/:id/items", billHandler.UpdateItems)
+			bills.PUT("/:id/items/:itemId", billHandler.UpdateItem)
+			bills.DELETE("/:id/items/:itemId", billHandler.DeleteItem)
+			bills.POST("/:id/items/:itemId/restore", billHandler.RestoreItem)
+			bills.POST("/:id/items/:itemId/split", billHandler.SplitItem)
+			bills.POST("/:id/items/merge", billHandler.MergeItems)
+			bills.POST("/:id/items/confirm", billHandler.ConfirmItems)
+			bills.GET("/:id/items/duplicates", billHandler.GetDuplicateItems)
+			bills.GET("/:id/participants", billHandler.GetParticipants)
+			bills.POST("/:id/participants", billHandler.AddParticipant)
+			bills.DELETE("/:id/participants", billHandler.DeleteParticipants)
+			bills.PUT("/:id/participants/reorder", billHandler.ReorderParticipants)
+			bills.POST("/:id/participants/merge", billHandler.MergeParticipants)
+			bills.PUT("/:id/payers", billHandler.SetBillPayers)
+			bills.PUT("/:id/manual-shares", billHandler.SetManualShares)
+			bills.DELETE("/:id/manual-shares", billHandler.ClearManualShares)
+			bills.GET("/:id/participants/:participantId", billHandler.GetParticipant)
+			bills.PUT("/:id/participants/:participantId", billHandler.UpdateParticipant)
+			bills.DELETE("/:id/participants/:participantId", billHandler.DeleteParticipant)
+			bills.POST("/:id/participants/:participantId/restore", billHandler.RestoreParticipant)
+			bills.GET("/:id/item-assignments", billHandler.GetItemAssignments)
+			bills.POST("/:id/editing-heartbeat", billHandler.EditingHeartbeat)
+			bills.GET("/:id/editors", billHandler.GetEditors)
+			bills.POST("/:id/assign-items", billHandler.AssignItemToParticipant)
+			bills.DELETE("/:id/assign-items", billHandler.DeleteItemAssignment)
+			bills.POST("/:id/apply-previous-assignments", billHandler.ApplyPreviousAssignments)
+
+			if cfg.Features.Enabled(config.FeatureExtraction) {
+				bills.POST("/:id/image", billHandler.UploadBillImage)
+				bills.POST("/:id/process-data", middleware.RequireCallbackSecret(cfg.N8nCallbackSecret), billHandler.ProcessExtractedData)
+			}
+
+			if cfg.Features.Enabled(config.FeaturePayments) {
+				bills.PATCH("/:id/participants/:participantId/payment", billHandler.UpdateParticipantPayment)
+				bills.POST("/:id/participants/:participantId/payment", billHandler.RecordParticipantPayment)
+				bills.POST("/:id/participants/mark-all-paid", billHandler.MarkAllPaid)
+			}
+		}
+
+		// Read-only access through share links
+		api.GET("/shared/:token", billHandler.GetSharedBill)
+		api.POST("/shared/:token/participants", billHandler.JoinSharedBill)
+		api.POST("/shared/:token/participants/:participantId/claim", billHandler.ClaimSharedParticipant)
+
+		// Routes for logged-in users
+		if cfg.Features.Enabled(config.FeatureAuth) {
+			protected := api.Group("")
+			{
+				protected.GET("/me", authHandler.GetMe)
+				protected.GET("/me/preferences", authHandler.GetPreferences)
+				protected.PUT("/me/preferences", authHandler.UpdatePreferences)
+				protected.GET("/me/bills", billHandler.ListMyBills)
+				protected.GET("/me/participations", billHandler.ListMyParticipations)
+				protected.GET("/me/attention", billHandler.GetAttention)
+				protected.POST("/me/attention/:billId/dismiss", billHandler.DismissAttention)
+				protected.POST("/bills/:id/participants/:participantId/claim", billHandler.ClaimParticipant)
+				protected.POST("/auth/logout", authHandler.Logout)
+				protected.POST("/auth/logout-all", authHandler.LogoutAll)
+			}
+
+			// Admin-only support views; these can read soft-deleted data
+			admin := protected.Group("/admin")
+			{
+				admin.GET("/bills", adminHandler.ListBills)
+				admin.GET("/bills/:id", adminHandler.GetBill)
+				admin.POST("/bills/:id/force-status", adminHandler.ForceBillStatus)
+				admin.GET("/abandoned-bills", adminHandler.ListAbandonedBills)
+				admin.GET("/stats/extraction", adminHandler.GetExtractionStats)
+				admin.POST("/impersonate/:userId", adminHandler.Impersonate)
+				admin.GET("/webhook-deliveries", adminHandler.ListWebhookDeliveries)
+				admin.GET("/config", adminHandler.GetConfig)
+			}
+		}
+	}
+
+	// Refuse to start with a route nobody declared a permission for
+	if err := middleware.VerifyPermissions(router.Routes(), middleware.RoutePermissions); err != nil {
+		return nil, fmt.Errorf("invalid route permissions: %w", err)
+	}
+	return router, nil
+}
+
+// availability renders a health flag the way the health endpoints report it
+func availability(available bool) string {
+	if available {
+		return "available"
+	}
+	return "unavailable"
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/middleware"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/services"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// availableDatabase always reports the database as reachable
+type availableDatabase struct{}
+
+func (availableDatabase) IsAvailable() bool  { return true }
+func (availableDatabase) HealthCheck() error { return nil }
+
+// unavailableDatabase is a database the supervisor is still reconnecting
+type unavailableDatabase struct{}
+
+func (unavailableDatabase) IsAvailable() bool  { return false }
+func (unavailableDatabase) HealthCheck() error { return errors.New("connection refused") }
+
+// newOfflineRouter builds the real router on a connection that is never opened, which is
+// enough to inspect its routes
+func newOfflineRouter(t *testing.T, configure func(*config.Config)) *gin.Engine {
+	t.Helper()
+	return newTestRouter(t, openOfflineDB(t), configure)
+}
+
+// openOfflineDB returns a connection that is never opened
+func openOfflineDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("failed to open offline database: %v", err)
+	}
+	return db
+}
+
+// newTestRouter builds the real router on db with the default configuration, letting
+// configure adjust it first
+func newTestRouter(t *testing.T, db *gorm.DB, configure func(*config.Config)) *gin.Engine {
+	t.Helper()
+	return newTestRouterWithDatabase(t, db, availableDatabase{}, configure)
+}
+
+// newTestRouterWithDatabase is newTestRouter with database reporting on the connection
+func newTestRouterWithDatabase(t *testing.T, db *gorm.DB, database Database, configure func(*config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	userCache := cache.NewUserCache(cfg.AuthCacheTTL, cfg.AuthCacheMaxEntries)
+	store := storage.NewLocal(t.TempDir())
+	views := cache.NewBillViewCache(cfg.ViewCacheTTL, cfg.ViewCacheMaxEntries)
+	router, err := NewRouter(Deps{
+		Config:      cfg,
+		Database:    database,
+		DB:          db,
+		UserCache:   userCache,
+		Storage:     store,
+		UserService: services.NewUserService(db, cfg, userCache),
+		BillService: services.NewBillService(db, cfg, store, nil, views),
+	})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	return router
+}
+
+func TestRoutesMatchDeclaredPermissions(t *testing.T) {
+	router := newOfflineRouter(t, func(cfg *config.Config) {
+		cfg.Features = config.Features{}
+	})
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[middleware.RouteKey(route.Method, route.Path)] = true
+	}
+	for key := range middleware.RoutePermissions {
+		if !registered[key] {
+			t.Errorf("permission declared for %s, which is not a route", key)
+		}
+	}
+	if err := middleware.VerifyPermissions(router.Routes(), middleware.RoutePermissions); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// abandonedBillCondition matches bills with no image, no items and no participants that
+// nobody has touched since the cutoff. A bill whose image is queued or being extracted is
+// never empty, even before the image path is set, so the status must be active (or unset)
+// or final, and no image hash may be recorded. Bills with an owner only match when the
+// owner opted in through their preferences; anonymous bills have nobody to ask.
+const abandonedBillCondition = "bills.image_path = '' AND bills.image_hash = '' " +
+	"AND bills.status IN ('', 'active', 'completed', 'failed', 'archived') AND bills.updated_at < ? " +
+	"AND NOT EXISTS (SELECT 1 FROM items WHERE items.bill_id = bills.id AND items.deleted_at IS NULL) " +
+	"AND NOT EXISTS (SELECT 1 FROM participants WHERE participants.bill_id = bills.id AND participants.deleted_at IS NULL) " +
+	"AND (bills.user_id IS NULL OR bills.user_id IN (SELECT user_id FROM user_preferences WHERE cleanup_empty_bills))"
+
+// excludeAbandonedBills hides bills the next cleanup would delete, so lists do not show
+// them in the meantime. Nothing is hidden when the cleanup is disabled.
+func (s *BillService) excludeAbandonedBills(bills *gorm.DB, now time.Time) *gorm.DB {
+	if s.abandonedBillAge <= 0 {
+		return bills
+	}
+	return bills.Where("NOT ("+abandonedBillCondition+")", now.Add(-s.abandonedBillAge))
+}
+
+// FindAbandonedBills returns the bills untouched since before now minus age that
+// SweepAbandonedBills would delete, the longest untouched first
+func (s *BillService) FindAbandonedBills(now time.Time, age time.Duration) (*models.AbandonedBillsResponse, error) {
+	cutoff := now.Add(-age)
+
+	bills := []models.AbandonedBill{}
+	if err := s.db.Model(&models.Bills{}).
+		Select("bills.id, bills.name, bills.user_id, bills.created_at, bills.updated_at").
+		Where(abandonedBillCondition, cutoff).
+		Order("bills.updated_at ASC").
+		Scan(&bills).Error; err != nil {
+		return nil, fmt.Errorf("failed to find abandoned bills: %w", err)
+	}
+	return &models.AbandonedBillsResponse{Bills: bills, Count: len(bills), Cutoff: cutoff}, nil
+}
+
+// SweepAbandonedBills soft-deletes the bills FindAbandonedBills returns and records each
+// deletion in the audit log, with no actor and the owner, if any, as subject. It returns
+// how many bills were deleted.
+func (s *BillService) SweepAbandonedBills(now time.Time, age time.Duration) (int, error) {
+	abandoned, err := s.FindAbandonedBills(now, age)
+	if err != nil {
+		return 0, err
+	}
+
+	swept := 0
+	for _, bill := range abandoned.Bills {
+		// Re-check the condition so a bill edited meanwhile is kept
+		result := s.db.Where("bills.id = ?", bill.ID).
+			Where(abandonedBillCondition, abandoned.Cutoff).
+			Delete(&models.Bills{})
+		if result.Error != nil {
+			return swept, fmt.Errorf("failed to delete abandoned bill %s: %w", bill.ID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var subjectID uint
+		if bill.UserID != nil {
+			subjectID = *bill.UserID
+		}
+		RecordAudit(s.db, models.AuditLogs{
+			SubjectID: subjectID,
+			Action:    AuditAbandonedBillDeleted,
+			Path:      "/api/bills/" + bill.ID.String(),
+		})
+		swept++
+	}
+	return swept, nil
+}
+
+// StartAbandonedBillSweeper runs SweepAbandonedBills every interval until ctx is
+// cancelled. Nothing is started when the configured age is zero.
+func (s *BillService) StartAbandonedBillSweeper(ctx context.Context, interval time.Duration) {
+	if s.abandonedBillAge <= 0 {
+		fmt.Println("Abandoned bill cleanup disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				swept, err := s.SweepAbandonedBills(now, s.abandonedBillAge)
+				if err != nil {
+					fmt.Printf("Abandoned bill sweep failed: %v\n", err)
+					continue
+				}
+				fmt.Printf("Abandoned bill sweep: %d empty bill(s) deleted\n", swept)
+			}
+		}
+	}()
+}
+
+// AbandonedBillAge is how long an empty bill stays untouched before it is cleaned up,
+// or zero when the cleanup is disabled
+func (s *BillService) AbandonedBillAge() time.Duration {
+	return s.abandonedBillAge
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+func TestFindAbandonedBillsSkipsBillsWithAnImage(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	now := time.Now()
+	stale := now.Add(-48 * time.Hour)
+
+	tests := []struct {
+		name      string
+		status    BillStatus
+		imagePath string
+		imageHash string
+		abandoned bool
+	}{
+		{name: "empty active", status: StatusActive, abandoned: true},
+		{name: "empty status", status: "", abandoned: true},
+		{name: "failed without image", status: StatusFailed, abandoned: true},
+		{name: "completed without image", status: StatusCompleted, abandoned: true},
+		{name: "queued", status: StatusQueued},
+		{name: "processing", status: StatusProcessing},
+		{name: "image hash recorded", status: StatusActive, imageHash: "abc123"},
+		{name: "image stored", status: StatusFailed, imagePath: "uploads/receipt.jpg", imageHash: "abc123"},
+	}
+
+	want := make(map[uuid.UUID]bool, len(tests))
+	names := make(map[uuid.UUID]string, len(tests))
+	for _, tt := range tests {
+		bill := createTestBill(t, s, tt.name, nil)
+		if err := db.Model(&models.Bills{}).Where("id = ?", bill.ID).UpdateColumns(map[string]interface{}{
+			"status":     string(tt.status),
+			"image_path": tt.imagePath,
+			"image_hash": tt.imageHash,
+			"updated_at": stale,
+		}).Error; err != nil {
+			t.Fatalf("failed to age bill %s: %v", tt.name, err)
+		}
+		want[bill.ID] = tt.abandoned
+		names[bill.ID] = tt.name
+	}
+
+	found, err := s.FindAbandonedBills(now, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("FindAbandonedBills: %v", err)
+	}
+	got := make(map[uuid.UUID]bool, len(found.Bills))
+	for _, bill := range found.Bills {
+		got[bill.ID] = true
+	}
+	for id, abandoned := range want {
+		if got[id] != abandoned {
+			t.Errorf("%s: abandoned = %v, want %v", names[id], got[id], abandoned)
+		}
+	}
+
+	swept, err := s.SweepAbandonedBills(now, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("SweepAbandonedBills: %v", err)
+	}
+	if swept != 4 {
+		t.Errorf("swept %d bills, want 4", swept)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAssignItemChecksBothSides(t *testing.T) {
+	s, _ := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+	otherID, otherItems, otherParticipants := seedAssignedBill(t, s)
+	noodles, tea := items[0], items[1]
+	ana := participants[0]
+
+	tests := []struct {
+		name                  string
+		itemID, participantID uint
+		want                  error
+	}{
+		{name: "item from another bill", itemID: otherItems[1], participantID: ana, want: ErrItemNotFound},
+		{name: "participant from another bill", itemID: tea, participantID: otherParticipants[0], want: ErrParticipantNotFound},
+		{name: "already assigned", itemID: noodles, participantID: ana, want: ErrAlreadyAssigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.AssignItem(billID, tt.itemID, tt.participantID, 1, "test"); !errors.Is(err, tt.want) {
+				t.Errorf("AssignItem err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if _, err := s.AssignItem(billID, tea, ana, 2, "test"); err != nil {
+		t.Errorf("AssignItem: %v", err)
+	}
+	if _, err := s.AssignItem(otherID, tea, ana, 1, "test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AssignItem on another bill err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUnassignItemReportsMissingAssignments(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+	_, otherItems, _ := seedAssignedBill(t, s)
+	noodles, tea := items[0], items[1]
+	ana, ben := participants[0], participants[1]
+
+	if err := s.UnassignItem(billID, tea, ana, "test"); !errors.Is(err, ErrAssignmentNotFound) {
+		t.Errorf("UnassignItem of a missing assignment err = %v, want ErrAssignmentNotFound", err)
+	}
+	if err := s.UnassignItem(billID, otherItems[1], ben, "test"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("UnassignItem of another bill's item err = %v, want ErrItemNotFound", err)
+	}
+
+	if err := s.UnassignItem(billID, noodles, ana, "test"); err != nil {
+		t.Fatalf("UnassignItem: %v", err)
+	}
+	if got := liveAssignments(t, db); got[[2]uint{noodles, ana}] {
+		t.Error("the assignment is still there")
+	}
+	if err := s.UnassignItem(billID, noodles, ana, "test"); !errors.Is(err, ErrAssignmentNotFound) {
+		t.Errorf("second UnassignItem err = %v, want ErrAssignmentNotFound", err)
+	}
+}
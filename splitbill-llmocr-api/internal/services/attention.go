@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons a bill shows up in its owner's attention list
+const (
+	AttentionExtractionFailed = "extraction_failed"
+	AttentionUnassignedItems  = "unassigned_items"
+)
+
+// unassignedItemGrace is how long items may sit without anyone assigned before the bill
+// needs attention
+const unassignedItemGrace = 24 * time.Hour
+
+// attentionRow is one bill from the attention query with the counts behind each reason
+type attentionRow struct {
+	ID              uuid.UUID
+	Name            string
+	Status          string
+	UpdatedAt       time.Time
+	UnassignedItems int64
+	Fingerprint     *string
+}
+
+// Attention returns the user's bills that need attention, most urgent first: failed
+// extractions, then bills with the most unassigned items. Bills the user dismissed are
+// left out until their reasons change. Everything comes from one query.
+func (s *BillService) Attention(userID uint, now time.Time) (*models.AttentionResponse, error) {
+	cutoff := now.Add(-unassignedItemGrace)
+	unassigned := "(SELECT COUNT(*) FROM items WHERE items.bill_id = bills.id AND items.deleted_at IS NULL AND items.created_at < ? " +
+		"AND NOT EXISTS (SELECT 1 FROM item_assignments WHERE item_assignments.item_id = items.id AND item_assignments.deleted_at IS NULL))"
+
+	var rows []attentionRow
+	if err := s.db.Model(&models.Bills{}).
+		Select("bills.id, bills.name, bills.status, bills.updated_at, "+unassigned+" AS unassigned_items, attention_dismissals.fingerprint", cutoff).
+		Joins("LEFT JOIN attention_dismissals ON attention_dismissals.bill_id = bills.id AND attention_dismissals.user_id = bills.user_id").
+		Where("bills.user_id = ? AND bills.status <> ?", userID, string(StatusArchived)).
+		Where("bills.status = ? OR "+unassigned+" > 0", string(StatusFailed), cutoff).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to find bills needing attention: %w", err)
+	}
+
+	items := []models.AttentionItem{}
+	for _, row := range rows {
+		reasons := attentionReasons(row)
+		if row.Fingerprint != nil && *row.Fingerprint == attentionFingerprint(row.UpdatedAt, reasons) {
+			continue
+		}
+		items = append(items, models.AttentionItem{
+			BillID:    row.ID,
+			Name:      row.Name,
+			Status:    row.Status,
+			Reasons:   reasons,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if pi, pj := attentionPriority(items[i]), attentionPriority(items[j]); pi != pj {
+			return pi > pj
+		}
+		return items[i].UpdatedAt.After(items[j].UpdatedAt)
+	})
+
+	return &models.AttentionResponse{Bills: items, Total: len(items)}, nil
+}
+
+// DismissAttention hides one of the user's bills from their attention list until its
+// reasons change. It returns ErrNotFound when the bill isn't the user's or needs no attention.
+func (s *BillService) DismissAttention(userID uint, billID uuid.UUID, now time.Time) error {
+	response, err := s.Attention(userID, now)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range response.Bills {
+		if item.BillID != billID {
+			continue
+		}
+		dismissal := models.AttentionDismissals{
+			UserID:      userID,
+			BillID:      billID,
+			Fingerprint: attentionFingerprint(item.UpdatedAt, item.Reasons),
+		}
+		if err := s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "bill_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "updated_at"}),
+		}).Create(&dismissal).Error; err != nil {
+			return fmt.Errorf("failed to dismiss bill: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("bill %s needs no attention: %w", billID, ErrNotFound)
+}
+
+// attentionReasons lists why a bill from the attention query needs attention
+func attentionReasons(row attentionRow) []models.AttentionReason {
+	reasons := []models.AttentionReason{}
+	if BillStatus(row.Status) == StatusFailed {
+		reasons = append(reasons, models.AttentionReason{Code: AttentionExtractionFailed, Count: 1})
+	}
+	if row.UnassignedItems > 0 {
+		reasons = append(reasons, models.AttentionReason{Code: AttentionUnassignedItems, Count: row.UnassignedItems})
+	}
+	return reasons
+}
+
+// attentionFingerprint identifies the condition a dismissal hides. A failure is tied to
+// when the bill last changed, so a retry that fails again shows up anew.
+func attentionFingerprint(updatedAt time.Time, reasons []models.AttentionReason) string {
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		if reason.Code == AttentionExtractionFailed {
+			parts = append(parts, fmt.Sprintf("%s@%d", reason.Code, updatedAt.UnixMicro()))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s:%d", reason.Code, reason.Count))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// attentionPriority ranks failed extractions above everything else, then by unassigned items
+func attentionPriority(item models.AttentionItem) int64 {
+	var priority int64
+	for _, reason := range item.Reasons {
+		switch reason.Code {
+		case AttentionExtractionFailed:
+			priority += 1 << 32
+		case AttentionUnassignedItems:
+			priority += reason.Count
+		}
+	}
+	return priority
+}
+
+// deleteAttentionDismissals forgets every dismissal of a bill within tx
+func deleteAttentionDismissals(tx *gorm.DB, billID uuid.UUID) error {
+	if err := tx.Where("bill_id = ?", billID).Delete(&models.AttentionDismissals{}).Error; err != nil {
+		return fmt.Errorf("failed to delete attention dismissals: %w", err)
+	}
+	return nil
+}
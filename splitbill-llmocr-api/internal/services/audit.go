@@ -0,0 +1,23 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// Audit log actions
+const (
+	AuditImpersonationStarted = "impersonation_started"
+	AuditImpersonatedRequest  = "impersonated_request"
+	AuditAbandonedBillDeleted = "abandoned_bill_deleted"
+)
+
+// RecordAudit writes an entry to the audit log. A failed write is logged rather than
+// returned so it never changes the outcome of the request being audited.
+func RecordAudit(db *gorm.DB, entry models.AuditLogs) {
+	if err := db.Create(&entry).Error; err != nil {
+		fmt.Printf("Failed to record audit entry %s for admin %d as user %d: %v\n", entry.Action, entry.ActorID, entry.SubjectID, err)
+	}
+}
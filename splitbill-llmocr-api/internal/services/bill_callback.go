@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCallbackURL is returned when a bill's callback_url is malformed or points
+// somewhere callbacks may not go
+var ErrInvalidCallbackURL = errors.New("invalid callback URL")
+
+// ErrCallbacksUnavailable is returned when a callback_url is set on a server without the
+// signing secret or encryption keys callbacks need
+var ErrCallbacksUnavailable = errors.New("callback URLs are not enabled on this server")
+
+// WebhookBillCallback names the per-bill callback in the webhook delivery log
+const WebhookBillCallback = "bill_callback"
+
+// CallbackSignatureHeader carries "sha256=<hex HMAC-SHA256 of the body>" keyed with
+// CALLBACK_SIGNING_SECRET
+const CallbackSignatureHeader = "X-Signature"
+
+// callbackRetries is how many times a failed callback is retried
+const callbackRetries = 3
+
+// CallbackPayload is the body posted to a bill's callback URL once extraction finishes
+type CallbackPayload struct {
+	BillID    uuid.UUID `json:"bill_id"`
+	Status    string    `json:"status"`
+	ItemCount int64     `json:"item_count"`
+	Total     float64   `json:"total"`
+}
+
+// SealCallbackURL validates a callback URL and encrypts it for storage. An empty URL
+// clears the callback and seals to an empty string.
+func (s *BillService) SealCallbackURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if s.keyring == nil || s.callbackSecret == "" {
+		return "", ErrCallbacksUnavailable
+	}
+	if err := validateCallbackURL(raw, s.callbackAllowPrivate); err != nil {
+		return "", err
+	}
+
+	sealed, err := s.keyring.Encrypt(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt callback URL: %w", err)
+	}
+	return sealed, nil
+}
+
+// validateCallbackURL accepts absolute http and https URLs. Unless allowPrivate is set,
+// hosts on loopback, private or link-local networks are refused.
+func validateCallbackURL(raw string, allowPrivate bool) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCallbackURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrInvalidCallbackURL)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: host is required", ErrInvalidCallbackURL)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: credentials in the URL are not allowed", ErrInvalidCallbackURL)
+	}
+	if !allowPrivate && privateHost(host) {
+		return fmt.Errorf("%w: private network hosts are not allowed", ErrInvalidCallbackURL)
+	}
+	return nil
+}
+
+// privateHost reports whether host is an IP on a non-public network or a name that only
+// resolves locally. Other names are checked again when the callback connects.
+func privateHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return privateIP(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host == "localhost" || strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal")
+}
+
+// privateIP reports whether ip is loopback, private, link-local or unspecified
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// newCallbackClient builds the HTTP client for bill callbacks. Unless allowPrivate is set
+// it refuses to connect to non-public addresses, which also covers DNS names that resolve
+// to one, and it never follows redirects.
+func newCallbackClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || privateIP(ip) {
+				return fmt.Errorf("callback to non-public address %s refused", host)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// notifyCallback posts the bill's outcome to its callback URL in the background. Failures
+// are logged and retried but never change the bill.
+func (s *BillService) notifyCallback(billID uuid.UUID, status BillStatus) {
+	var bill models.Bills
+	if err := s.db.Select("id", "callback_url", "grand_total").Where("id = ?", billID).First(&bill).Error; err != nil {
+		fmt.Printf("Failed to load bill %s for its callback: %v\n", billID, err)
+		return
+	}
+	if bill.CallbackURL == "" {
+		return
+	}
+	if s.keyring == nil || s.callbackSecret == "" {
+		fmt.Printf("Skipping callback for bill %s: callbacks are not configured\n", billID)
+		return
+	}
+
+	callbackURL, err := s.keyring.Decrypt(bill.CallbackURL)
+	if err != nil {
+		fmt.Printf("Failed to decrypt callback URL for bill %s: %v\n", billID, err)
+		return
+	}
+
+	var itemCount int64
+	if err := s.db.Model(&models.Items{}).Where("bill_id = ?", billID).Count(&itemCount).Error; err != nil {
+		fmt.Printf("Failed to count items for the callback of bill %s: %v\n", billID, err)
+		return
+	}
+
+	body, err := json.Marshal(CallbackPayload{
+		BillID:    billID,
+		Status:    string(status),
+		ItemCount: itemCount,
+		Total:     bill.GrandTotal,
+	})
+	if err != nil {
+		fmt.Printf("Failed to encode callback for bill %s: %v\n", billID, err)
+		return
+	}
+
+	go deliverWebhook(s.db, WebhookBillCallback, billID, 1+callbackRetries, func() (int, error) {
+		return s.postCallback(callbackURL, body)
+	})
+}
+
+// postCallback sends one signed callback request and returns the response status
+func (s *BillService) postCallback(callbackURL string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CallbackSignatureHeader, "sha256="+signHMAC(s.callbackSecret, body))
+
+	resp, err := s.callbackClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("callback answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
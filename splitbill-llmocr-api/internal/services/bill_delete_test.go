@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+func TestDeleteBillRollsBack(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Picnic", nil)
+
+	items, err := s.CreateItems(bill.ID, []models.ItemRequest{{Name: "Sandwich", Price: 7, Quantity: 2}}, "test")
+	if err != nil {
+		t.Fatalf("failed to create items: %v", err)
+	}
+	participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ana"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	if _, err := s.AssignItem(bill.ID, items[0].ID, participant.ID, 1, "test"); err != nil {
+		t.Fatalf("failed to assign item: %v", err)
+	}
+
+	// The bill row goes last, after its items, participants and assignments
+	failDeletes(t, db, "bills")
+	if err := s.DeleteBill(bill.ID); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteBill err = %v, want the injected failure", err)
+	}
+
+	var bills, itemRows, participants, assignments int64
+	db.Model(&models.Bills{}).Where("id = ?", bill.ID).Count(&bills)
+	db.Model(&models.Items{}).Scopes(ScopeBill(bill.ID)).Count(&itemRows)
+	db.Model(&models.Participants{}).Scopes(ScopeBill(bill.ID)).Count(&participants)
+	db.Model(&models.ItemAssignments{}).Where("item_id = ?", items[0].ID).Count(&assignments)
+	if bills != 1 || itemRows != 1 || participants != 1 || assignments != 1 {
+		t.Errorf("after the failed delete: %d bills, %d items, %d participants, %d assignments, want 1 of each",
+			bills, itemRows, participants, assignments)
+	}
+}
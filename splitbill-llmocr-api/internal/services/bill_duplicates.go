@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateBill is returned when a new bill duplicates one created moments ago and
+// duplicates are rejected
+var ErrDuplicateBill = errors.New("duplicate bill")
+
+// DuplicateBillError is returned when a new bill duplicates a recent one and duplicates
+// are rejected. It matches ErrDuplicateBill with errors.Is.
+type DuplicateBillError struct {
+	BillID uuid.UUID
+}
+
+func (e *DuplicateBillError) Error() string {
+	return fmt.Sprintf("duplicate of bill %s", e.BillID)
+}
+
+// Is makes errors.Is(err, ErrDuplicateBill) true for duplicate bill errors
+func (e *DuplicateBillError) Is(target error) bool {
+	return target == ErrDuplicateBill
+}
+
+// findDuplicateBill returns the bill a new bill named name would duplicate: one created
+// since now minus the duplicate window with the same name that has no image and no items
+// yet, by the same owner or, for anonymous bills, from the same address. It returns nil
+// when there is none or detection is disabled. Two requests racing each other can both
+// get through; this catches double submits, it doesn't guarantee uniqueness.
+func (s *BillService) findDuplicateBill(name string, userID *uint, creatorIP string, now time.Time) (*models.Bills, error) {
+	if s.duplicateBillWindow <= 0 {
+		return nil, nil
+	}
+
+	bills := s.db.Where("bills.name = ? AND bills.created_at >= ? AND bills.image_path = ''", name, now.Add(-s.duplicateBillWindow)).
+		Where("NOT EXISTS (SELECT 1 FROM items WHERE items.bill_id = bills.id AND items.deleted_at IS NULL)")
+	if userID != nil {
+		bills = bills.Where("bills.user_id = ?", *userID)
+	} else {
+		if creatorIP == "" {
+			return nil, nil
+		}
+		bills = bills.Where("bills.user_id IS NULL AND bills.creator_ip = ?", creatorIP)
+	}
+
+	var bill models.Bills
+	if err := bills.Order("bills.created_at DESC").First(&bill).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check for duplicate bills: %w", err)
+	}
+	return &bill, nil
+}
+
+// duplicateBillResponse answers a create that duplicates bill: the existing bill marked
+// with duplicate_of, or a DuplicateBillError when duplicates are rejected
+func (s *BillService) duplicateBillResponse(bill *models.Bills) (*models.BillResponse, error) {
+	if s.duplicateBillMode == config.DuplicateBillReject {
+		return nil, &DuplicateBillError{BillID: bill.ID}
+	}
+
+	response, err := s.GetBill(bill.ID)
+	if err != nil {
+		return nil, err
+	}
+	response.DuplicateOf = &bill.ID
+	return response, nil
+}
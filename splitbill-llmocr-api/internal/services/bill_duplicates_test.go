@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+func TestFindDuplicateBillWindowBoundary(t *testing.T) {
+	s, db := newTestBillService(t, func(cfg *config.Config) {
+		cfg.DuplicateBillWindow = 2 * time.Minute
+	})
+	created := createTestBill(t, s, "Dinner", nil)
+
+	// Compare against the stored timestamp, which Postgres keeps to the microsecond
+	var bill models.Bills
+	if err := db.First(&bill, "id = ?", created.ID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+	edge := bill.CreatedAt.Add(2 * time.Minute)
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		found bool
+	}{
+		{name: "inside the window", now: bill.CreatedAt.Add(time.Minute), found: true},
+		{name: "on the boundary", now: edge, found: true},
+		{name: "just past the window", now: edge.Add(time.Microsecond), found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duplicate, err := s.findDuplicateBill("Dinner", nil, "192.0.2.1", tt.now)
+			if err != nil {
+				t.Fatalf("findDuplicateBill: %v", err)
+			}
+			if (duplicate != nil) != tt.found {
+				t.Errorf("found duplicate = %v, want %v", duplicate != nil, tt.found)
+			}
+		})
+	}
+}
+
+func TestCreateBillDuplicateMatching(t *testing.T) {
+	s, db := newTestBillService(t, func(cfg *config.Config) {
+		cfg.DuplicateBillWindow = 2 * time.Minute
+	})
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	anonymous, err := s.CreateBill(&models.BillRequest{Name: "Lunch"}, nil, "192.0.2.1", false)
+	if err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+	owned, err := s.CreateBill(&models.BillRequest{Name: "Lunch"}, &alice, "192.0.2.1", false)
+	if err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+	if owned.DuplicateOf != nil {
+		t.Fatal("a logged-in user's bill matched an anonymous bill from the same address")
+	}
+
+	tests := []struct {
+		name   string
+		userID *uint
+		ip     string
+		force  bool
+		want   *models.BillResponse
+	}{
+		{name: "anonymous, same address", ip: "192.0.2.1", want: anonymous},
+		{name: "anonymous, other address", ip: "198.51.100.7"},
+		{name: "owner, other address", userID: &alice, ip: "198.51.100.7", want: owned},
+		{name: "other user, same address", userID: &bob, ip: "192.0.2.1"},
+		{name: "owner forcing a new bill", userID: &alice, ip: "192.0.2.1", force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bill, err := s.CreateBill(&models.BillRequest{Name: "Lunch"}, tt.userID, tt.ip, tt.force)
+			if err != nil {
+				t.Fatalf("CreateBill: %v", err)
+			}
+			if tt.want == nil {
+				if bill.DuplicateOf != nil {
+					t.Errorf("got duplicate_of %s, want a new bill", *bill.DuplicateOf)
+				}
+				return
+			}
+			if bill.DuplicateOf == nil || *bill.DuplicateOf != tt.want.ID || bill.ID != tt.want.ID {
+				t.Errorf("got bill %s (duplicate_of %v), want the existing bill %s", bill.ID, bill.DuplicateOf, tt.want.ID)
+			}
+		})
+	}
+}
+
+func TestCreateBillRejectsDuplicates(t *testing.T) {
+	s, _ := newTestBillService(t, func(cfg *config.Config) {
+		cfg.DuplicateBillWindow = 2 * time.Minute
+		cfg.DuplicateBillMode = config.DuplicateBillReject
+	})
+	first := createTestBill(t, s, "Drinks", nil)
+
+	_, err := s.CreateBill(&models.BillRequest{Name: "Drinks"}, nil, "192.0.2.1", false)
+	var duplicateErr *DuplicateBillError
+	if !errors.As(err, &duplicateErr) || !errors.Is(err, ErrDuplicateBill) {
+		t.Fatalf("err = %v, want a DuplicateBillError", err)
+	}
+	if duplicateErr.BillID != first.ID {
+		t.Errorf("duplicate of %s, want %s", duplicateErr.BillID, first.ID)
+	}
+
+	if _, err := s.CreateBill(&models.BillRequest{Name: "Drinks"}, nil, "192.0.2.1", true); err != nil {
+		t.Errorf("force still rejected: %v", err)
+	}
+}
+
+func TestCreateBillDuplicateWindowDisabled(t *testing.T) {
+	s, _ := newTestBillService(t, func(cfg *config.Config) {
+		cfg.DuplicateBillWindow = 0
+	})
+	createTestBill(t, s, "Snacks", nil)
+
+	bill, err := s.CreateBill(&models.BillRequest{Name: "Snacks"}, nil, "192.0.2.1", false)
+	if err != nil {
+		t.Fatalf("CreateBill: %v", err)
+	}
+	if bill.DuplicateOf != nil {
+		t.Error("duplicate detection ran with a zero window")
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Actions recorded in a bill's activity log
+const (
+	EventBillUpdated         = "bill.updated"
+	EventStatusChanged       = "status.changed"
+	EventPayersSet           = "payers.set"
+	EventManualSharesSet     = "manual_shares.set"
+	EventManualSharesClear   = "manual_shares.cleared"
+	EventItemCreated         = "item.created"
+	EventItemUpdated         = "item.updated"
+	EventItemDeleted         = "item.deleted"
+	EventItemRestored        = "item.restored"
+	EventItemSplit           = "item.split"
+	EventItemsMerged         = "items.merged"
+	EventItemsSkipped        = "items.skipped"
+	EventItemsConfirmed      = "items.confirmed"
+	EventParticipantAdded    = "participant.added"
+	EventParticipantUpdated  = "participant.updated"
+	EventParticipantRemoved  = "participant.removed"
+	EventParticipantRestored = "participant.restored"
+	EventParticipantsMerged  = "participants.merged"
+	EventParticipantClaimed  = "participant.claimed"
+	EventPaymentRecorded     = "payment.recorded"
+	EventPaymentsMarkedPaid  = "payments.marked_paid"
+	EventAssignmentAdded     = "assignment.added"
+	EventAssignmentRemoved   = "assignment.removed"
+	EventAssignmentsCopied   = "assignments.copied"
+)
+
+// Actors for changes nobody asked for directly
+const (
+	ActorSystem     = "system"
+	ActorExtraction = "extraction"
+)
+
+// summaryFields are the bill columns that change the summary's numbers; only edits to
+// these are logged
+var summaryFields = []string{
+	"tax_amount", "tip_amount", "service_charge_amount", "discount_amount", "currency",
+	"rounding_mode", "payer_participant_id", "prices_include_tax", "prices_include_service",
+}
+
+// UserActor names the caller of a request in the activity log
+func UserActor(userID *uint) string {
+	if userID == nil {
+		return "guest"
+	}
+	return fmt.Sprintf("user:%d", *userID)
+}
+
+// AdminActor names an admin acting through the admin endpoints in the activity log
+func AdminActor(userID uint) string {
+	return fmt.Sprintf("admin:%d", userID)
+}
+
+// recordEvent appends to a bill's activity log. It runs after the change was committed,
+// and a failure is only logged: the log must never undo or fail the change it describes.
+func (s *BillService) recordEvent(billID uuid.UUID, actor, action string, payload models.EventPayload) {
+	event := models.BillEvents{
+		BillID:  billID,
+		Actor:   actor,
+		Action:  action,
+		Payload: payload,
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		fmt.Printf("Failed to record %s event for bill %s: %v\n", action, billID, err)
+	}
+	// Every logged change may change the bill's summary or assignments
+	s.views.Invalidate(billID)
+
+	// Every logged change except a status move shows on the share page
+	if action != EventStatusChanged {
+		s.revalidator.schedule(billID, "")
+	}
+}
+
+// recordStatusChange logs a status transition and tells the bill's status streams. Once
+// extraction has finished it also tells the frontend and the bill's callback URL.
+func (s *BillService) recordStatusChange(billID uuid.UUID, actor string, from, to BillStatus) {
+	s.statusBroker.publish(billID, to)
+	if to == StatusCompleted {
+		s.revalidator.schedule(billID, "")
+	}
+	// Finalizing or unarchiving also completes a bill, but only extraction calls back
+	if from.Extracting() && (to == StatusCompleted || to == StatusFailed) {
+		s.notifyCallback(billID, to)
+	}
+	s.recordEvent(billID, actor, EventStatusChanged, models.EventPayload{"from": from, "to": to})
+}
+
+// ListBillEvents returns one page of a bill's activity log, newest first
+func (s *BillService) ListBillEvents(billID uuid.UUID, page, pageSize int) (*models.BillEventListResponse, error) {
+	events := s.db.Model(&models.BillEvents{}).Where("bill_id = ?", billID).Session(&gorm.Session{})
+
+	var total int64
+	if err := events.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bill events: %w", err)
+	}
+
+	list := []models.BillEvents{}
+	if err := events.
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to list bill events: %w", err)
+	}
+
+	return &models.BillEventListResponse{
+		Events:   list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// summaryChanges picks the summary-affecting fields out of a bill update
+func summaryChanges(updates map[string]interface{}) models.EventPayload {
+	changes := models.EventPayload{}
+	for _, field := range summaryFields {
+		if value, ok := updates[field]; ok {
+			changes[field] = value
+		}
+	}
+	return changes
+}
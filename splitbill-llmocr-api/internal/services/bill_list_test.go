@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+func TestListBillsOnlyListsTheCallersBills(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	alice := createTestUser(t, db, "alice")
+	bob := createTestUser(t, db, "bob")
+
+	alicesBill := createTestBill(t, s, "Alice's dinner", &alice)
+	bobsBill := createTestBill(t, s, "Bob's lunch", &bob)
+	createTestBill(t, s, "Guest brunch", nil)
+
+	tests := []struct {
+		name   string
+		userID uint
+		want   string
+	}{
+		{name: "alice", userID: alice, want: alicesBill.ID.String()},
+		{name: "bob", userID: bob, want: bobsBill.ID.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := s.ListBills(tt.userID, &models.BillListQuery{Page: 1, PageSize: 20})
+			if err != nil {
+				t.Fatalf("ListBills: %v", err)
+			}
+			if list.Total != 1 || len(list.Bills) != 1 {
+				t.Fatalf("got %d bills (total %d), want only the caller's", len(list.Bills), list.Total)
+			}
+			if got := list.Bills[0].ID.String(); got != tt.want {
+				t.Errorf("listed bill %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetBillPayers replaces who paid the merchant for a bill. Every payer must be a participant
+// of the bill, at most once, and the amounts must add up to the bill total give or take one
+// minor unit per payer. An empty list removes the payers, leaving payer_participant_id as
+// the one who paid everything.
+func (s *BillService) SetBillPayers(billID uuid.UUID, payers []models.BillPayerRequest, actor string) ([]models.BillPayerResponse, error) {
+	response := []models.BillPayerResponse{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			Preload("Participants", ParticipantOrder).
+			First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		names := make(map[uint]string, len(bill.Participants))
+		for _, participant := range bill.Participants {
+			names[participant.ID] = participant.Name
+		}
+
+		code := s.billCurrency(bill.Currency)
+		rows := make([]models.BillPayers, 0, len(payers))
+		seen := make(map[uint]bool, len(payers))
+		var paid int64
+		for _, payer := range payers {
+			name, ok := names[payer.ParticipantID]
+			if !ok {
+				return fmt.Errorf("%w: participant %d is not on this bill", ErrInvalidPayers, payer.ParticipantID)
+			}
+			if seen[payer.ParticipantID] {
+				return fmt.Errorf("%w: participant %d is listed more than once", ErrInvalidPayers, payer.ParticipantID)
+			}
+			seen[payer.ParticipantID] = true
+
+			amount := currency.ToMinor(payer.AmountPaid, code)
+			paid += amount
+			rows = append(rows, models.BillPayers{
+				BillID:        billID,
+				ParticipantID: payer.ParticipantID,
+				AmountPaid:    currency.FromMinor(amount, code),
+			})
+			response = append(response, models.BillPayerResponse{
+				ParticipantID: payer.ParticipantID,
+				Name:          name,
+				AmountPaid:    currency.FromMinor(amount, code),
+			})
+		}
+
+		if len(rows) > 0 {
+			_, total := billTotalsMinor(&bill, bill.Items, code)
+			if diff := paid - total; diff > int64(len(rows)) || diff < -int64(len(rows)) {
+				return fmt.Errorf("%w: payers paid %s in total but the bill comes to %s", ErrInvalidPayers,
+					formatAmount(paid, code), formatAmount(total, code))
+			}
+		}
+
+		if err := tx.Where("bill_id = ?", billID).Delete(&models.BillPayers{}).Error; err != nil {
+			return fmt.Errorf("failed to delete bill payers: %w", err)
+		}
+		if len(rows) > 0 {
+			if err := tx.Create(&rows).Error; err != nil {
+				return fmt.Errorf("failed to create bill payers: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventPayersSet, models.EventPayload{"payers": response})
+	return response, nil
+}
+
+// formatAmount prints minor units of code with the currency's decimal places
+func formatAmount(units int64, code string) string {
+	return fmt.Sprintf("%.*f %s", currency.Decimals(code), currency.FromMinor(units, code), code)
+}
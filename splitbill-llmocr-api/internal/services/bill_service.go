@@ -2,25 +2,286 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/itemname"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/secrets"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/splitmath"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrNotFound is returned when a bill or one of its children does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrItemNotFound and ErrParticipantNotFound are returned when an assignment names an item
+// or participant that isn't on the bill. Both match ErrNotFound.
+var (
+	ErrItemNotFound        = fmt.Errorf("item %w", ErrNotFound)
+	ErrParticipantNotFound = fmt.Errorf("participant %w", ErrNotFound)
+)
+
+// ErrAssignmentNotFound is returned when an item is unassigned from a participant it isn't
+// assigned to. It matches ErrNotFound.
+var ErrAssignmentNotFound = fmt.Errorf("item assignment %w", ErrNotFound)
+
+// ErrAlreadyAssigned is returned when an item is assigned to a participant twice
+var ErrAlreadyAssigned = errors.New("item already assigned to this participant")
+
+// ErrBatchRejected is returned when an all-or-nothing batch contains invalid entries
+var ErrBatchRejected = errors.New("batch rejected")
+
+// ErrInvalidPayload is returned when an extraction callback body cannot be understood
+var ErrInvalidPayload = errors.New("invalid extraction payload")
+
+// ErrInvalidOrder is returned when a reorder request is not a permutation of the existing rows
+var ErrInvalidOrder = errors.New("invalid order")
+
+// ErrPreconditionFailed is returned when a conditional write was based on a stale version
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrAuthRequired is returned when an owned bill is changed without a logged-in user
+var ErrAuthRequired = errors.New("authentication required")
+
+// ErrForbidden is returned when a user changes a bill owned by someone else
+var ErrForbidden = errors.New("forbidden")
+
+// ErrArchived is returned when an archived bill is changed
+var ErrArchived = errors.New("bill is archived")
+
+// ErrInvalidStatus is returned when a bill's status does not allow the requested transition
+var ErrInvalidStatus = errors.New("invalid status for this operation")
+
+// ErrUnassignedItems is returned when a bill is finalized while items have nobody assigned
+var ErrUnassignedItems = errors.New("bill has unassigned items")
+
+// ErrInvalidPayers is returned when the payers of a bill don't name its participants or
+// don't add up to its total
+var ErrInvalidPayers = errors.New("invalid payers")
+
+// ErrItemsNotInBill is returned when a bulk item update names items the bill doesn't have
+var ErrItemsNotInBill = errors.New("items not in bill")
+
+// ErrInvalidManualShares is returned when manual shares don't name the bill's participants
+// or don't add up to its itemized total
+var ErrInvalidManualShares = errors.New("invalid manual shares")
+
+// ErrInvalidItemDiscount is returned when an item's discount exceeds its price times quantity
+var ErrInvalidItemDiscount = errors.New("invalid item discount")
+
+// ErrInvalidSplit is returned when an item split doesn't add back up to the item
+var ErrInvalidSplit = errors.New("invalid item split")
+
+// ErrUnconfirmedItems is returned when a bill is finalized while extracted items are
+// still unconfirmed
+var ErrUnconfirmedItems = errors.New("bill has unconfirmed items")
+
+// ErrInvalidMerge is returned when a participant is merged into itself
+var ErrInvalidMerge = errors.New("invalid participant merge")
+
+// ErrNoChanges is returned when a partial update sets no fields
+var ErrNoChanges = errors.New("no fields to update")
+
+// ErrInvalidUpdate is returned when a partial update sets a field to a value the bill
+// can't take
+var ErrInvalidUpdate = errors.New("invalid update")
+
+// ErrNotesTooLong is returned when a bill's notes run past models.MaxBillNotesLength
+var ErrNotesTooLong = fmt.Errorf("notes must be at most %d characters", models.MaxBillNotesLength)
+
+// ErrAlreadyClaimed is returned when a participant is claimed by someone else already, or
+// the caller already claimed another participant of the bill
+var ErrAlreadyClaimed = errors.New("participant already claimed")
+
+// ErrConfirmationRequired is returned when a destructive change was asked for without confirm
+var ErrConfirmationRequired = errors.New("confirmation required")
+
 type BillService struct {
-	db *gorm.DB
+	db               *gorm.DB
+	features         config.Features
+	storage          *storage.Local
+	storageRequired  bool
+	extractionQueue  *extractionQueue
+	extractionHealth *extractionHealth
+	itemNames        *itemname.Pipeline
+	presence         *presenceStore
+	joinLimiter      *joinLimiter
+	statusBroker     *statusBroker
+	revalidator      *revalidator
+	defaultCurrency  string
+	maxAmount        int64
+	// Suggested tip and tax percentages for new bills without preferences
+	defaultTipPercent *float64
+	defaultTaxPercent *float64
+	legacyShares      bool
+
+	// How images are sent to n8n
+	n8nPayloadFormat   string
+	n8nJSONMaxBodySize int
+
+	// Largest extraction callback body accepted from n8n
+	callbackMaxBodySize int
+
+	// Empty bills untouched this long are cleaned up (0 disables)
+	abandonedBillAge time.Duration
+
+	// Creating a bill that repeats one made within duplicateBillWindow returns or rejects
+	// it, as duplicateBillMode says (0 disables)
+	duplicateBillWindow time.Duration
+	duplicateBillMode   string
+
+	// Deleted items and participants can be restored for restoreWindow and are purged
+	// after deletedRowRetention (0 keeps them)
+	restoreWindow       time.Duration
+	deletedRowRetention time.Duration
+
+	// Per-bill callbacks; keyring is nil when no encryption keys are configured
+	keyring              *secrets.Keyring
+	callbackSecret       string
+	callbackAllowPrivate bool
+	callbackClient       *http.Client
+
+	// Summaries and assignments kept between polls, warmed once extraction finishes
+	views cache.BillViewCache
+}
+
+func NewBillService(db *gorm.DB, config *config.Config, store *storage.Local, keyring *secrets.Keyring, views cache.BillViewCache) *BillService {
+	return &BillService{
+		db:                db,
+		features:          config.Features,
+		storage:           store,
+		storageRequired:   config.StorageRequired,
+		extractionQueue:   newExtractionQueue(config.ExtractionConcurrency, config.ExtractionQueueSize),
+		extractionHealth:  &extractionHealth{},
+		itemNames:         itemname.New(config.ItemNameSteps, config.ItemNameUnits, config.ItemNameMaxLength),
+		presence:          newPresenceStore(config.EditingPresenceTTL, maxTrackedEditors),
+		joinLimiter:       newJoinLimiter(config.SharedJoinLimit, config.SharedJoinWindow),
+		statusBroker:      newStatusBroker(),
+		revalidator:       newRevalidator(db, config.RevalidateURL, config.RevalidateSecret, config.RevalidateDebounce, config.RevalidateMaxAttempts),
+		defaultCurrency:   config.DefaultCurrency,
+		maxAmount:         config.ExtractionMaxAmount,
+		defaultTipPercent: config.DefaultTipPercent,
+		defaultTaxPercent: config.DefaultTaxPercent,
+		legacyShares:      config.SummaryLegacyShares,
+
+		n8nPayloadFormat:   config.N8nPayloadFormat,
+		n8nJSONMaxBodySize: config.N8nJSONMaxBodySize,
+
+		callbackMaxBodySize: config.ExtractionCallbackMaxBodySize,
+
+		abandonedBillAge: config.AbandonedBillAge,
+
+		duplicateBillWindow: config.DuplicateBillWindow,
+		duplicateBillMode:   config.DuplicateBillMode,
+
+		restoreWindow:       config.DeletedRowRestoreWindow,
+		deletedRowRetention: config.DeletedRowRetention,
+
+		keyring:              keyring,
+		callbackSecret:       config.CallbackSigningSecret,
+		callbackAllowPrivate: config.CallbackAllowPrivateHosts,
+		callbackClient:       newCallbackClient(config.CallbackAllowPrivateHosts),
+
+		views: views,
+	}
+}
+
+// StartExtractionWorkers starts the pool that sends queued images to n8n.
+// Workers stop once ctx is cancelled. Nothing is started when extraction is disabled.
+func (s *BillService) StartExtractionWorkers(ctx context.Context) {
+	if !s.features.Enabled(config.FeatureExtraction) {
+		fmt.Println("Extraction feature disabled, not starting extraction workers")
+		return
+	}
+	s.extractionQueue.start(ctx, s.runExtraction)
+}
+
+// StartPresenceSweeper periodically forgets editors whose heartbeats stopped.
+// It stops once ctx is cancelled.
+func (s *BillService) StartPresenceSweeper(ctx context.Context) {
+	s.presence.start(ctx)
+}
+
+// ScopeBill restricts a query on a bill's child rows (items, participants) to that
+// bill, and only while the bill itself has not been soft-deleted
+func ScopeBill(billID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("bill_id = ? AND EXISTS (SELECT 1 FROM bills WHERE bills.id = ? AND bills.deleted_at IS NULL)", billID, billID)
+	}
+}
+
+// ParticipantOrder sorts participants the way organizers arranged them on screen
+func ParticipantOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// BillExists returns ErrNotFound when the bill does not exist or was soft-deleted
+func (s *BillService) BillExists(billID uuid.UUID) error {
+	var count int64
+	if err := s.db.Model(&models.Bills{}).Where("id = ?", billID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to find bill: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+	}
+	return nil
+}
+
+// AuthorizeBillChange checks that userID may modify the bill. Bills without an owner stay
+// open to everyone, and ownership is not enforced while the auth feature is disabled.
+func (s *BillService) AuthorizeBillChange(billID uuid.UUID, userID *uint) error {
+	var bill models.Bills
+	if err := s.db.Select("id, user_id").First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	if bill.UserID == nil || !s.features.Enabled(config.FeatureAuth) {
+		return nil
+	}
+	if userID == nil {
+		return fmt.Errorf("bill %s: %w", billID, ErrAuthRequired)
+	}
+	if *userID != *bill.UserID {
+		return fmt.Errorf("bill %s: %w", billID, ErrForbidden)
+	}
+	return nil
 }
 
-func NewBillService(db *gorm.DB) *BillService {
-	return &BillService{db: db}
+// EnsureBillEditable returns ErrNotFound for missing bills and ErrArchived for archived
+// ones, which no longer accept changes to their contents
+func (s *BillService) EnsureBillEditable(billID uuid.UUID) error {
+	var bill models.Bills
+	if err := s.db.Select("id, status").First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	if BillStatus(bill.Status) == StatusArchived {
+		return fmt.Errorf("bill %s: %w", billID, ErrArchived)
+	}
+	return nil
 }
 
 // GetDB returns the database instance
@@ -28,68 +289,373 @@ func (s *BillService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// CreateBill creates a new bill
-func (s *BillService) CreateBill(req *models.BillRequest) (*models.BillResponse, error) {
+// CreateBill creates a new bill owned by userID, or by nobody when userID is nil, in which
+// case creatorIP is kept to recognize repeats. Every bill gets a share token for read-only
+// links. Unless force is set, a bill repeating one created moments ago by the same owner
+// or address is not created again; see findDuplicateBill.
+func (s *BillService) CreateBill(req *models.BillRequest, userID *uint, creatorIP string, force bool) (*models.BillResponse, error) {
+	notes, err := billNotes(req.Notes)
+	if err != nil {
+		return nil, err
+	}
+	callbackURL, err := s.SealCallbackURL(req.CallbackURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != nil {
+		creatorIP = ""
+	}
+	if !force {
+		duplicate, err := s.findDuplicateBill(req.Name, userID, creatorIP, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if duplicate != nil {
+			return s.duplicateBillResponse(duplicate)
+		}
+	}
+
+	shareToken, err := secrets.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
 	bill := &models.Bills{
-		ID:        uuid.New(),
-		Name:      req.Name,
-		Status:    "active",
-		TaxAmount: req.TaxAmount,
-		TipAmount: req.TipAmount,
+		ID:           uuid.New(),
+		Name:         req.Name,
+		UserID:       userID,
+		ShareToken:   &shareToken,
+		Status:       string(StatusActive),
+		Currency:     req.Currency,
+		RoundingMode: RoundingLargestRemainder,
+		SplitMode:    SplitItemized,
+		Notes:        notes,
+		Metadata:     req.Metadata,
+		CallbackURL:  callbackURL,
+		CreatorIP:    creatorIP,
+	}
+	if req.TaxAmount != nil {
+		bill.TaxAmount = *req.TaxAmount
+	}
+	if req.TipAmount != nil {
+		bill.TipAmount = *req.TipAmount
+	}
+	defaulted, err := s.applyBillDefaults(bill, req, userID)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.db.Create(bill).Error; err != nil {
 		return nil, fmt.Errorf("failed to create bill: %w", err)
 	}
 
-	return s.getBillResponse(bill), nil
+	s.revalidator.schedule(bill.ID, "")
+	response := s.getBillResponse(bill)
+	response.Defaulted = defaulted
+	return response, nil
+}
+
+// ListBills returns one page of the bills owned by userID, newest first, with item and
+// participant counts instead of the full children. Bills due for the abandoned bill
+// cleanup are left out.
+func (s *BillService) ListBills(userID uint, query *models.BillListQuery) (*models.BillListResponse, error) {
+	bills := s.excludeAbandonedBills(s.db.Model(&models.Bills{}), time.Now()).Where("user_id = ?", userID)
+	if query.Status != "" {
+		bills = bills.Where("status = ?", query.Status)
+	} else if !query.IncludeArchived {
+		bills = bills.Where("status <> ?", string(StatusArchived))
+	}
+	if q := strings.TrimSpace(query.Q); q != "" {
+		bills = bills.Where("bills.name ILIKE ?", "%"+likeEscaper.Replace(q)+"%")
+	}
+	if query.CreatedAfter != nil {
+		bills = bills.Where("bills.created_at > ?", *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		bills = bills.Where("bills.created_at < ?", *query.CreatedBefore)
+	}
+	// The filtered query is shared by the count and the page
+	bills = bills.Session(&gorm.Session{})
+
+	var total int64
+	if err := bills.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count bills: %w", err)
+	}
+
+	list := []models.BillListItem{}
+	if err := bills.
+		Select("bills.id, bills.name, bills.status, bills.tax_amount, bills.tip_amount, bills.item_subtotal, bills.grand_total, bills.created_at, " +
+			"(SELECT COUNT(*) FROM items WHERE items.bill_id = bills.id AND items.deleted_at IS NULL) AS item_count, " +
+			"(SELECT COUNT(*) FROM participants WHERE participants.bill_id = bills.id AND participants.deleted_at IS NULL) AS participant_count").
+		Order(billListOrder(query.Sort, query.Order)).
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Scan(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to list bills: %w", err)
+	}
+
+	return &models.BillListResponse{
+		Bills:    list,
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in user input so they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// billListOrder returns the ORDER BY for a bill list. Names sort A to Z and dates newest
+// first unless order says otherwise; the ID breaks ties so pages never overlap.
+func billListOrder(sort, order string) string {
+	column := "bills.created_at"
+	if sort == "name" {
+		column = "bills.name"
+		if order == "" {
+			order = "asc"
+		}
+	}
+	if order == "" {
+		order = "desc"
+	}
+	return fmt.Sprintf("%s %s, bills.id %s", column, strings.ToUpper(order), strings.ToUpper(order))
 }
 
 // GetBill retrieves a bill by ID
 func (s *BillService) GetBill(id uuid.UUID) (*models.BillResponse, error) {
 	var bill models.Bills
-	if err := s.db.Preload("Items").Preload("Participants").First(&bill, "id = ?", id).Error; err != nil {
+	if err := s.db.Preload("Items").Preload("Participants", ParticipantOrder).First(&bill, "id = ?", id).Error; err != nil {
 		return nil, fmt.Errorf("bill not found: %w", err)
 	}
 
+	s.backfillParticipantColors(id, bill.Participants)
 	return s.getBillResponse(&bill), nil
 }
 
-// UploadBillImage uploads an image for a bill and triggers n8n workflow
-func (s *BillService) UploadBillImage(billID uuid.UUID, file *multipart.FileHeader) (*models.BillResponse, error) {
-	// Check if bill exists
-	bill, err := s.GetBill(billID)
+// GetSharedBill resolves a share token to the bill and its summary. Unknown and
+// regenerated tokens return ErrNotFound.
+func (s *BillService) GetSharedBill(token string) (*models.SharedBillResponse, error) {
+	bill, err := s.sharedBill(token)
 	if err != nil {
-		return nil, fmt.Errorf("bill not found: %w", err)
+		return nil, err
+	}
+
+	response, err := s.GetBill(bill.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Participant notes, tags and emails are for the bill's editors only
+	for i := range response.Participants {
+		response.Participants[i].Notes = ""
+		response.Participants[i].Tags = nil
+		response.Participants[i].Email = ""
+	}
+	summary, err := s.GetBillSummary(bill.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SharedBillResponse{Bill: *response, Summary: *summary}, nil
+}
+
+// RegenerateShareToken gives the bill a new share token, so links with the old one stop working
+func (s *BillService) RegenerateShareToken(billID uuid.UUID) (string, error) {
+	token, err := secrets.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	var bill models.Bills
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "share_token").First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if err := tx.Model(&models.Bills{}).Where("id = ?", billID).Update("share_token", token).Error; err != nil {
+			return fmt.Errorf("failed to update share token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The old link's page has to go as well as the new one being rendered
+	var revoked string
+	if bill.ShareToken != nil {
+		revoked = *bill.ShareToken
+	}
+	s.revalidator.schedule(billID, revoked)
+	return token, nil
+}
+
+// UploadBillImage uploads an image for a bill and triggers n8n workflow.
+// If the same image (by SHA-256) was already extracted successfully for this bill the
+// trigger is skipped and duplicate is true, unless force is set. timer carries the stage
+// timings of the upload on to the extraction; failures after the bill is accepted for
+// extraction are recorded with the stages they got through.
+func (s *BillService) UploadBillImage(billID uuid.UUID, file *multipart.FileHeader, force bool, timer *StageTimer) (bill *models.BillResponse, duplicate bool, err error) {
+	// Check if bill exists
+	var existing models.Bills
+	if err := s.db.First(&existing, "id = ?", billID).Error; err != nil {
+		return nil, false, fmt.Errorf("bill not found: %w", err)
 	}
 
 	// Read file data
+	timer.Start(StagePreprocessing)
 	fileBytes, err := s.readFileData(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file data: %w", err)
+		return nil, false, fmt.Errorf("failed to read file data: %w", err)
+	}
+
+	hashBytes := sha256.Sum256(fileBytes)
+	imageHash := hex.EncodeToString(hashBytes[:])
+
+	// Skip the paid LLM call when this exact image was already extracted for the bill
+	if !force && existing.ImageHash == imageHash && BillStatus(existing.Status) == StatusCompleted {
+		fmt.Printf("Duplicate image upload for bill %s, reusing existing extraction\n", billID)
+		bill, err := s.GetBill(billID)
+		if err != nil {
+			return nil, false, err
+		}
+		return bill, true, nil
+	}
+
+	// Bills already waiting for or going through extraction can't take another image
+	if !CanTransition(BillStatus(existing.Status), StatusQueued) {
+		return nil, false, &TransitionError{BillID: billID, From: BillStatus(existing.Status), To: StatusQueued}
+	}
+
+	// Save image to disk, sharing the stored file when another bill already
+	// uploaded the same bytes
+	timer.Start(StageStorageWrite)
+	imagePath := s.findStoredImage(billID, imageHash)
+	if imagePath == "" {
+		imagePath, err = s.storage.Save(fmt.Sprintf("bill_%s_%s", billID.String(), file.Filename), fileBytes)
+		if err != nil {
+			if s.storageRequired {
+				s.saveStageTimings(billID, timer, TimingsFailed, timer.Stop())
+				return nil, false, err
+			}
+			// Extraction-only mode: carry on without a stored copy of the image
+			fmt.Printf("Failed to save image to disk, continuing without it: %v\n", err)
+			imagePath = ""
+		}
+	}
+
+	if err := s.db.Model(&models.Bills{}).Where("id = ?", billID).Updates(map[string]interface{}{
+		"image_hash": imageHash,
+		"image_path": imagePath,
+	}).Error; err != nil {
+		fmt.Printf("Failed to store image metadata for bill %s: %v\n", billID, err)
+	}
+
+	// Mark the bill as queued before the job becomes visible to the workers,
+	// so a fast worker's "processing" update is never overwritten
+	if err := s.UpdateBillStatus(billID, StatusQueued); err != nil {
+		s.saveStageTimings(billID, timer, TimingsFailed, timer.Stop())
+		return nil, false, fmt.Errorf("failed to update bill status: %w", err)
+	}
+
+	// Queue the image; a worker flips the bill to "processing" when n8n has capacity
+	timer.Start(StageQueueWait)
+	position, err := s.extractionQueue.enqueue(&extractionJob{
+		billID:    billID,
+		imageData: fileBytes,
+		filename:  file.Filename,
+		queuedAt:  time.Now(),
+		timer:     timer,
+	})
+	if err != nil {
+		// Nothing was queued, so put the bill back the way it was
+		s.saveStageTimings(billID, timer, TimingsFailed, timer.Stop())
+		s.UpdateBillStatus(billID, BillStatus(existing.Status))
+		return nil, false, err
+	}
+	fmt.Printf("Bill %s queued for extraction at position %d\n", billID, position)
+
+	bill, err = s.GetBill(billID)
+	if err != nil {
+		return nil, false, err
+	}
+	return bill, false, nil
+}
+
+// runExtraction sends a dequeued image to n8n. Time spent waiting in the queue
+// is reported separately from the n8n processing time.
+func (s *BillService) runExtraction(job *extractionJob) {
+	timer := job.timer
+	if err := s.UpdateBillStatus(job.billID, StatusProcessing); err != nil {
+		fmt.Printf("Failed to mark bill %s as processing: %v\n", job.billID, err)
+		s.saveStageTimings(job.billID, timer, TimingsFailed, timer.Stop())
+		return
+	}
+
+	// The row is written before the call so a fast callback finds it
+	timer.Start(StageProviderCall)
+	timingsID := s.saveStageTimings(job.billID, timer, TimingsProcessing, "")
+	err := s.triggerN8nWorkflowWithImage(job.billID, job.imageData, job.filename)
+	timer.Stop()
+
+	failedStage := ""
+	if err != nil {
+		failedStage = StageProviderCall
+	}
+	if timingsID != 0 {
+		s.mergeStageTimings(timingsID, timer, failedStage)
 	}
 
-	// Save image to disk (optional, for backup)
-	imagePath := fmt.Sprintf("./uploads/bill_%s_%s", billID.String(), file.Filename)
-	if err := os.MkdirAll("./uploads", 0755); err != nil {
-		fmt.Printf("Failed to create uploads directory: %v\n", err)
-		// Don't fail the upload for this, continue with n8n
+	timings := timer.Timings()
+	queueWait := time.Duration(timings[StageQueueWait]) * time.Millisecond
+	processing := time.Duration(timings[StageProviderCall]) * time.Millisecond
+	if err != nil {
+		s.extractionHealth.recordFailure()
+		// triggerN8nWorkflowWithImage already set the status to "failed"
+		fmt.Printf("N8n workflow failed for bill %s after %v in queue and %v processing: %v\n", job.billID, queueWait, processing, err)
+		return
 	}
+	s.extractionHealth.recordTriggered()
+	fmt.Printf("Extraction for bill %s sent after %v in queue and %v processing\n", job.billID, queueWait, processing)
+}
 
-	if err := os.WriteFile(imagePath, fileBytes, 0644); err != nil {
-		fmt.Printf("Failed to save image to disk: %v\n", err)
-		// Don't fail the upload for this, continue with n8n
+// ExtractionStatus reports extraction availability from in-memory state
+func (s *BillService) ExtractionStatus() ExtractionStatus {
+	status := s.extractionHealth.snapshot()
+	status.Enabled = s.features.Enabled(config.FeatureExtraction)
+	status.Queued = s.extractionQueue.length()
+	if !status.Enabled {
+		status.Available = false
 	}
+	return status
+}
+
+// CallbackMaxBodySize is the largest extraction callback body the API accepts, in bytes
+func (s *BillService) CallbackMaxBodySize() int {
+	return s.callbackMaxBodySize
+}
+
+// QueuePosition returns the 1-based position of a bill waiting for extraction
+func (s *BillService) QueuePosition(billID uuid.UUID) (int, bool) {
+	return s.extractionQueue.position(billID)
+}
 
-	// Trigger n8n workflow with image data
-	if err := s.triggerN8nWorkflowWithImage(billID, fileBytes, file.Filename); err != nil {
-		// If n8n workflow fails, the status should already be set to "failed"
-		// but let's make sure we return a proper error message
-		fmt.Printf("N8n workflow failed for bill %s: %v\n", billID, err)
-		return nil, fmt.Errorf("failed to process image with AI: %w", err)
+// findStoredImage returns the stored path of an identical image uploaded for another bill,
+// or an empty string when there is none on disk
+func (s *BillService) findStoredImage(billID uuid.UUID, imageHash string) string {
+	var other models.Bills
+	if err := s.db.Select("image_path").
+		Where("image_hash = ? AND id <> ? AND image_path <> ''", imageHash, billID).
+		First(&other).Error; err != nil {
+		return ""
 	}
 
-	return bill, nil
+	if !s.storage.Exists(other.ImagePath) {
+		return ""
+	}
+	return other.ImagePath
 }
 
 // readFileData reads the file data from multipart.FileHeader into bytes
@@ -116,64 +682,42 @@ func (s *BillService) triggerN8nWorkflowWithImage(billID uuid.UUID, imageData []
 		err := fmt.Errorf("N8N_WEBHOOK_URL not configured")
 		fmt.Printf("N8N_WEBHOOK_URL not configured, skipping workflow trigger for bill %s\n", billID)
 		// Update bill status to failed since we can't process
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
+		if updateErr := s.UpdateBillStatus(billID, StatusFailed); updateErr != nil {
 			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
 		}
 		return err
 	}
 
-	// Create multipart form data
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
-
-	// Add bill_id field
-	if err := writer.WriteField("bill_id", billID.String()); err != nil {
-		fmt.Printf("Failed to write bill_id field: %v\n", err)
-		// Update bill status to failed
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
-			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
-		}
-		return fmt.Errorf("failed to write bill_id field: %v", err)
+	// Build the body in the configured format
+	var payload *n8nPayload
+	var err error
+	if s.n8nPayloadFormat == config.N8nPayloadJSON {
+		payload, err = buildJSONPayload(billID, imageData, filename, s.n8nJSONMaxBodySize)
+	} else {
+		payload, err = buildMultipartPayload(billID, imageData, filename)
 	}
-
-	// Add image file
-	part, err := writer.CreateFormFile("image", filename)
 	if err != nil {
-		fmt.Printf("Failed to create form file: %v\n", err)
-		// Update bill status to failed
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
-			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
-		}
-		return fmt.Errorf("failed to create form file: %v", err)
-	}
-	if _, err := part.Write(imageData); err != nil {
-		fmt.Printf("Failed to write image data: %v\n", err)
+		fmt.Printf("Failed to build n8n payload: %v\n", err)
 		// Update bill status to failed
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
+		if updateErr := s.UpdateBillStatus(billID, StatusFailed); updateErr != nil {
 			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
 		}
-		return fmt.Errorf("failed to write image data: %v", err)
+		return err
 	}
 
-	// Get the Content-Type BEFORE closing the writer
-	contentType := writer.FormDataContentType()
-
-	// Close the writer to finalize the multipart data
-	writer.Close()
-
 	// Send request to n8n
-	req, err := http.NewRequest("POST", n8nWebhookURL, &requestBody)
+	req, err := http.NewRequest("POST", n8nWebhookURL, bytes.NewReader(payload.body))
 	if err != nil {
 		fmt.Printf("Failed to create request: %v\n", err)
 		// Update bill status to failed
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
+		if updateErr := s.UpdateBillStatus(billID, StatusFailed); updateErr != nil {
 			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
 		}
 		return fmt.Errorf("failed to create request: %v", err)
 	}
 
-	// Set the Content-Type header with the boundary
-	req.Header.Set("Content-Type", contentType)
+	// Set the Content-Type header (with the boundary for multipart)
+	req.Header.Set("Content-Type", payload.contentType)
 
 	// Set timeout for the request
 	client := &http.Client{
@@ -184,7 +728,7 @@ func (s *BillService) triggerN8nWorkflowWithImage(billID uuid.UUID, imageData []
 	if err != nil {
 		fmt.Printf("Failed to send request to n8n: %v\n", err)
 		// Update bill status to failed
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
+		if updateErr := s.UpdateBillStatus(billID, StatusFailed); updateErr != nil {
 			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
 		}
 		return fmt.Errorf("failed to send request to n8n: %v", err)
@@ -198,7 +742,7 @@ func (s *BillService) triggerN8nWorkflowWithImage(billID uuid.UUID, imageData []
 		fmt.Printf("Request headers: %v\n", req.Header)
 
 		// Update bill status to failed since n8n workflow failed
-		if updateErr := s.UpdateBillStatus(billID, "failed"); updateErr != nil {
+		if updateErr := s.UpdateBillStatus(billID, StatusFailed); updateErr != nil {
 			fmt.Printf("Failed to update bill status to failed: %v\n", updateErr)
 		}
 
@@ -209,136 +753,1161 @@ func (s *BillService) triggerN8nWorkflowWithImage(billID uuid.UUID, imageData []
 	return nil
 }
 
-// ProcessExtractedData processes the data returned from n8n workflow
-func (s *BillService) ProcessExtractedData(billID uuid.UUID, extractedData string) error {
+// RecordEditingHeartbeat marks a user, or a guest participant when userID is nil, as
+// editing the bill and returns everyone currently editing it. This is presence only;
+// nothing is locked.
+func (s *BillService) RecordEditingHeartbeat(billID uuid.UUID, user *models.RegisterResponse, participantID *uint) ([]models.BillEditor, error) {
+	var editor models.BillEditor
+	if user != nil {
+		userID := user.ID
+		editor = models.BillEditor{UserID: &userID, Name: user.Name}
+	} else {
+		participant, err := s.GetParticipant(billID, *participantID)
+		if err != nil {
+			return nil, err
+		}
+		id := participant.ID
+		editor = models.BillEditor{ParticipantID: &id, Name: participant.Name}
+	}
+
+	now := time.Now()
+	if err := s.presence.touch(billID, editor, now); err != nil {
+		return nil, err
+	}
+	return s.presence.list(billID, now), nil
+}
+
+// BillEditors returns everyone who sent an editing heartbeat for the bill recently
+func (s *BillService) BillEditors(billID uuid.UUID) []models.BillEditor {
+	return s.presence.list(billID, time.Now())
+}
+
+// ProcessExtractionCallback handles the body n8n posts back for a bill. BillService owns
+// every status change of this flow: an unusable payload marks the bill failed, and the
+// extracted data is stored together with the completed status.
+func (s *BillService) ProcessExtractionCallback(billID uuid.UUID, body []byte) error {
+	var rawData map[string]interface{}
+	if err := decodeJSON(body, &rawData); err != nil {
+		return fmt.Errorf("%w: invalid JSON: %v", ErrInvalidPayload, err)
+	}
+
+	timer := NewStageTimer()
+	timer.Start(StageCallbackParse)
+	shape, extractedData, err := extractedDataFromCallback(rawData)
+	if err != nil {
+		s.markExtractionFailed(billID)
+		s.finishStageTimings(billID, timer, timer.Stop())
+		return err
+	}
+	fmt.Printf("Extraction callback for bill %s has shape %s\n", billID, shape)
+
+	return s.ProcessExtractedData(billID, extractedData, timer)
+}
+
+// PreviewExtractionCallback parses an n8n callback body like ProcessExtractionCallback
+// but only reports the items it would store, with raw and normalized names, and the rows
+// it would skip. Nothing is written and the bill's status is left alone.
+func (s *BillService) PreviewExtractionCallback(body []byte) ([]models.ExtractedItemPreview, []models.SkippedExtractedItem, error) {
+	_, extractedItems, err := ParseExtractionCallback(body, s.defaultCurrency, s.maxAmount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	previews := make([]models.ExtractedItemPreview, 0, len(extractedItems.Items))
+	for _, item := range extractedItems.Items {
+		previews = append(previews, models.ExtractedItemPreview{
+			RawName:        item.Name,
+			Name:           s.itemNames.Normalize(item.Name),
+			Price:          item.UnitPrice,
+			Quantity:       item.Quantity,
+			LineTotal:      item.LineTotal,
+			DiscountAmount: item.DiscountAmount,
+			Category:       item.Category,
+			NeedsReview:    item.NeedsReview,
+		})
+	}
+	return previews, extractedItems.Skipped, nil
+}
+
+// extractedDataFromCallback returns the shape of a decoded callback body and the extracted
+// data JSON it carries
+func extractedDataFromCallback(rawData map[string]interface{}) (string, string, error) {
+	if stripped := stripImageFields(rawData); len(stripped) > 0 {
+		fmt.Printf("Warning: ignoring image-like fields in extraction callback: %v\n", stripped)
+	}
+
+	if code, exists := rawData["code"]; exists && code == "API_SPLITBILL_LLMOCR" {
+		// Direct n8n data structure: the whole body is the extracted data
+		fmt.Printf("Detected direct n8n data structure\n")
+		extractedDataBytes, err := json.Marshal(rawData)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to process data: %w", err)
+		}
+		return CallbackShapeDirect, string(extractedDataBytes), nil
+	}
+
+	// Fallback: the data is wrapped in an extracted_data string
+	value, exists := rawData["extracted_data"]
+	if !exists {
+		fmt.Printf("Missing extracted_data field. Available fields: %v\n", rawData)
+		return "", "", fmt.Errorf("%w: missing required field: extracted_data", ErrInvalidPayload)
+	}
+
+	extractedData, ok := value.(string)
+	if !ok {
+		fmt.Printf("extracted_data is not a string, it's: %T\n", value)
+		return "", "", fmt.Errorf("%w: extracted_data must be a string", ErrInvalidPayload)
+	}
+	return CallbackShapeWrapped, extractedData, nil
+}
+
+// markExtractionFailed flips a bill to failed after its extraction could not be stored
+func (s *BillService) markExtractionFailed(billID uuid.UUID) {
+	if err := s.UpdateBillStatus(billID, StatusFailed); err != nil {
+		fmt.Printf("Failed to update bill status to failed: %v\n", err)
+	}
+}
+
+// ProcessExtractedData stores extracted items and marks the bill completed in one
+// transaction, so a bill can never keep its data while stuck in processing.
+// Any failure marks the bill failed instead. timer is in the callback_parse stage and
+// the outcome is recorded with the bill's extraction timings.
+func (s *BillService) ProcessExtractedData(billID uuid.UUID, extractedData string, timer *StageTimer) error {
 	var bill models.Bills
 	if err := s.db.First(&bill, "id = ?", billID).Error; err != nil {
 		return fmt.Errorf("bill not found: %w", err)
 	}
 
-	// Parse the extracted data
-	var extractedItems models.ExtractedItemData
-	if err := json.Unmarshal([]byte(extractedData), &extractedItems); err != nil {
-		fmt.Printf("Failed to parse JSON: %v\n", err)
-		return fmt.Errorf("failed to parse extracted data: %w", err)
+	// A stray or repeated callback must not touch a bill that isn't being extracted
+	if !BillStatus(bill.Status).Extracting() {
+		return &TransitionError{BillID: billID, From: BillStatus(bill.Status), To: StatusCompleted}
+	}
+
+	// Parse the extracted data, keeping amounts exact to the bill currency's minor units
+	extractedItems, err := parseExtractedData(extractedData, s.billCurrency(bill.Currency), s.maxAmount)
+	if err != nil {
+		fmt.Printf("Failed to parse extracted data for bill %s: %v\n", billID, err)
+		s.markExtractionFailed(billID)
+		s.finishStageTimings(billID, timer, timer.Stop())
+		return err
+	}
+	if len(extractedItems.Skipped) > 0 {
+		fmt.Printf("Skipped %d invalid extracted item(s) for bill %s: %+v\n", len(extractedItems.Skipped), billID, extractedItems.Skipped)
+	}
+
+	// The receipt total should match the items plus whatever tax and service they don't already include
+	if extractedItems.Total > 0 {
+		code := s.billCurrency(bill.Currency)
+		lines := make([]int64, len(extractedItems.Items))
+		for i, item := range extractedItems.Items {
+			lines[i] = itemLineMinor(item.UnitPrice, item.Quantity, item.DiscountAmount, code)
+		}
+		expected := extractedTotalMinor(&extractedItems, lines, code)
+		if !reconciles(expected, currency.ToMinor(extractedItems.Total, code)) {
+			fmt.Printf("Extracted total for bill %s does not reconcile: receipt says %.2f, items add up to %.2f\n",
+				billID, extractedItems.Total, currency.FromMinor(expected, code))
+		}
 	}
 
-	// Start a transaction
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	timer.Start(StageDBPersist)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Update bill with extracted data (only tax and tip amounts)
+		if err := tx.Model(&bill).Updates(map[string]interface{}{
+			"tax_amount":             extractedItems.Tax,
+			"tip_amount":             extractedItems.Tip,
+			"prices_include_tax":     extractedItems.PricesIncludeTax,
+			"prices_include_service": extractedItems.PricesIncludeService,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update bill: %w", err)
 		}
-	}()
 
-	// Update bill with extracted data (only tax and tip amounts)
-	if err := tx.Model(&bill).Updates(map[string]interface{}{
-		"tax_amount": extractedItems.Tax,
-		"tip_amount": extractedItems.Tip,
-	}).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update bill: %w", err)
+		// Extracted items are a draft until someone confirms them
+		items := make([]models.Items, 0, len(extractedItems.Items))
+		for _, item := range extractedItems.Items {
+			items = append(items, models.Items{
+				BillID:         billID,
+				Name:           s.itemNames.Normalize(item.Name),
+				RawName:        item.Name,
+				Price:          item.UnitPrice,
+				Quantity:       item.Quantity,
+				DiscountAmount: item.DiscountAmount,
+				Category:       NormalizeCategory(item.Category),
+				NeedsReview:    item.NeedsReview,
+				Source:         ItemSourceOCR,
+			})
+		}
+		if err := createDraftItems(tx, items); err != nil {
+			return err
+		}
+
+		if err := s.refreshBillTotals(tx, billID); err != nil {
+			return err
+		}
+
+		// The completed status commits with the data or not at all
+		from, err := transitionStatus(tx, billID, StatusCompleted)
+		if err != nil {
+			return err
+		}
+		if !from.Extracting() {
+			return &TransitionError{BillID: billID, From: from, To: StatusCompleted}
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.markExtractionFailed(billID)
+		s.finishStageTimings(billID, timer, timer.Stop())
+		return err
 	}
+	timer.Stop()
+	s.finishStageTimings(billID, timer, "")
 
-	// Create items from extracted data
 	for _, item := range extractedItems.Items {
-		dbItem := models.Items{
-			BillID:   billID,
-			Name:     item.Name,
-			Price:    item.Price,
-			Quantity: item.Quantity,
+		s.recordEvent(billID, ActorExtraction, EventItemCreated, models.EventPayload{
+			"name":            s.itemNames.Normalize(item.Name),
+			"price":           item.UnitPrice,
+			"quantity":        item.Quantity,
+			"discount_amount": item.DiscountAmount,
+		})
+	}
+	if len(extractedItems.Skipped) > 0 {
+		s.recordEvent(billID, ActorExtraction, EventItemsSkipped, models.EventPayload{
+			"items": extractedItems.Skipped,
+		})
+	}
+	s.recordStatusChange(billID, ActorExtraction, BillStatus(bill.Status), StatusCompleted)
+	s.warmBillViews(billID)
+
+	s.extractionHealth.recordSuccess()
+	return nil
+}
+
+// DeleteBill removes a bill's item assignments, items and participants and soft-deletes
+// the bill in one transaction. Its stored image files are removed afterwards unless
+// another bill still shares them.
+func (s *BillService) DeleteBill(billID uuid.UUID) error {
+	var bill models.Bills
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		// Deleted bills are not restored, so their assignments go for good
+		if err := tx.Unscoped().Where("item_id IN (?)", tx.Unscoped().Model(&models.Items{}).Select("id").Where("bill_id = ?", billID)).
+			Delete(&models.ItemAssignments{}).Error; err != nil {
+			return fmt.Errorf("failed to delete item assignments: %w", err)
+		}
+
+		if err := tx.Where("bill_id = ?", billID).Delete(&models.Items{}).Error; err != nil {
+			return fmt.Errorf("failed to delete items: %w", err)
+		}
+
+		if err := tx.Where("bill_id = ?", billID).Delete(&models.BillPayers{}).Error; err != nil {
+			return fmt.Errorf("failed to delete bill payers: %w", err)
 		}
 
-		if err := tx.Create(&dbItem).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to create item: %w", err)
+		if err := tx.Where("bill_id = ?", billID).Delete(&models.Participants{}).Error; err != nil {
+			return fmt.Errorf("failed to delete participants: %w", err)
 		}
+
+		if err := deleteAttentionDismissals(tx, billID); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&bill).Error; err != nil {
+			return fmt.Errorf("failed to delete bill: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Don't send a deleted bill to n8n
+	s.extractionQueue.remove(billID)
+	s.presence.remove(billID)
+	s.views.Invalidate(billID)
+
+	if bill.ShareToken != nil {
+		s.revalidator.schedule(billID, *bill.ShareToken)
+	}
+
+	s.removeBillImages(billID)
+	return nil
+}
+
+// removeBillImages deletes a bill's stored uploads, keeping any file that another
+// bill still references through the duplicate-image lookup
+func (s *BillService) removeBillImages(billID uuid.UUID) {
+	paths, err := s.storage.List(fmt.Sprintf("bill_%s_", billID.String()))
+	if err != nil {
+		fmt.Printf("Failed to list images for bill %s: %v\n", billID, err)
+		return
+	}
+
+	for _, path := range paths {
+		// Older uploads stored the path with a leading "./"
+		var shared int64
+		if err := s.db.Model(&models.Bills{}).Where("image_path IN ?", []string{path, "./" + path}).Count(&shared).Error; err != nil {
+			fmt.Printf("Failed to check whether %s is shared: %v\n", path, err)
+			continue
+		}
+		if shared > 0 {
+			continue
+		}
+
+		if err := s.storage.Remove(path); err != nil {
+			fmt.Printf("Failed to remove image %s: %v\n", path, err)
+		}
+	}
+}
+
+// AddParticipant creates a participant at the end of the bill's display order
+func (s *BillService) AddParticipant(billID uuid.UUID, req *models.ParticipantRequest, actor string) (*models.Participants, error) {
+	participant := &models.Participants{
+		BillID:             billID,
+		Name:               req.Name,
+		PaymentStatus:      "unpaid",
+		ShareOfCommonCosts: req.ShareOfCommonCosts,
+		Notes:              strings.TrimSpace(req.Notes),
+		Tags:               normalizeTags(req.Tags),
+	}
+	if err := s.insertParticipant(participant, actor); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// insertParticipant creates participant at the end of its bill's display order with the
+// next free color, and logs it as added
+func (s *BillService) insertParticipant(participant *models.Participants, actor string) error {
+	billID := participant.BillID
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var last struct{ Position int }
+		if err := tx.Model(&models.Participants{}).
+			Select("COALESCE(MAX(position), 0) AS position").
+			Where("bill_id = ?", billID).
+			Scan(&last).Error; err != nil {
+			return fmt.Errorf("failed to find participant position: %w", err)
+		}
+		participant.Position = last.Position + 1
+
+		colors, err := billParticipantColors(tx, billID)
+		if err != nil {
+			return err
+		}
+		participant.Color = nextParticipantColor(colors)
+
+		if err := tx.Create(participant).Error; err != nil {
+			return fmt.Errorf("failed to add participant: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordEvent(billID, actor, EventParticipantAdded, models.EventPayload{
+		"participant_id": participant.ID,
+		"name":           participant.Name,
+	})
+	return nil
+}
+
+// ReorderParticipants applies a new display order. participantIDs must list every
+// participant of the bill exactly once; anything else is rejected with ErrInvalidOrder.
+func (s *BillService) ReorderParticipants(billID uuid.UUID, participantIDs []uint) ([]models.Participants, error) {
+	var participants []models.Participants
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		if err := tx.Model(&models.Participants{}).
+			Scopes(ScopeBill(billID)).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &current).Error; err != nil {
+			return fmt.Errorf("failed to find participants: %w", err)
+		}
+
+		if !isPermutation(current, participantIDs) {
+			return ErrInvalidOrder
+		}
+
+		for i, id := range participantIDs {
+			if err := tx.Model(&models.Participants{}).
+				Where("id = ? AND bill_id = ?", id, billID).
+				Update("position", i+1).Error; err != nil {
+				return fmt.Errorf("failed to update participant position: %w", err)
+			}
+		}
+
+		return tx.Scopes(ScopeBill(billID), ParticipantOrder).Find(&participants).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.views.Invalidate(billID)
+	return participants, nil
+}
+
+// isPermutation reports whether got contains exactly the IDs in want, each once
+func isPermutation(want, got []uint) bool {
+	if len(want) != len(got) {
+		return false
+	}
+
+	remaining := make(map[uint]bool, len(want))
+	for _, id := range want {
+		remaining[id] = true
+	}
+	for _, id := range got {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
+// DeleteParticipant removes a participant and all of their item assignments in one
+// transaction, and returns how many assignments were removed. If any step fails nothing is
+// removed. A participant of another bill is ErrNotFound.
+func (s *BillService) DeleteParticipant(billID uuid.UUID, participantID uint, actor string) (int64, error) {
+	var participant models.Participants
+	var removed int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Check if the participant belongs to this bill
+		if err := tx.Scopes(ScopeBill(billID)).Where("id = ?", participantID).First(&participant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find participant: %w", err)
+		}
+
+		// Delete the assignments first so the participant row is never left without them.
+		// They go for good, so restoring the participant doesn't bring them back.
+		assignments := tx.Unscoped().Where("participant_id = ?", participantID).Delete(&models.ItemAssignments{})
+		if assignments.Error != nil {
+			return fmt.Errorf("failed to delete item assignments: %w", assignments.Error)
+		}
+		removed = assignments.RowsAffected
+
+		if err := tx.Delete(&participant).Error; err != nil {
+			return fmt.Errorf("failed to delete participant: %w", err)
+		}
+
+		// A deleted participant can't stay the designated payer
+		if err := tx.Model(&models.Bills{}).
+			Where("id = ? AND payer_participant_id = ?", billID, participantID).
+			Update("payer_participant_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to clear bill payer: %w", err)
+		}
+
+		if err := tx.Where("bill_id = ? AND participant_id = ?", billID, participantID).Delete(&models.BillPayers{}).Error; err != nil {
+			return fmt.Errorf("failed to delete bill payer: %w", err)
+		}
+
+		// A manual bill's total is the sum of its participants' shares
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.recordEvent(billID, actor, EventParticipantRemoved, models.EventPayload{
+		"participant_id":      participant.ID,
+		"name":                participant.Name,
+		"assignments_removed": removed,
+	})
+	return removed, nil
+}
+
+// BulkDeleteParticipants removes the given participants and their item assignments in one
+// transaction. Participants that are not on the bill, are its designated payer or are already marked paid are reported
+// individually; with allOrNothing any such entry rejects the whole batch with ErrBatchRejected.
+func (s *BillService) BulkDeleteParticipants(billID uuid.UUID, participantIDs []uint, allOrNothing bool, actor string) (*models.BulkDeleteParticipantsResponse, error) {
+	result := &models.BulkDeleteParticipantsResponse{Errors: []models.BulkDeleteError{}}
+	var removed []models.Participants
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var participants []models.Participants
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(ScopeBill(billID)).
+			Where("id IN ?", participantIDs).
+			Find(&participants).Error; err != nil {
+			return fmt.Errorf("failed to find participants: %w", err)
+		}
+
+		var bill models.Bills
+		if err := tx.Select("payer_participant_id").First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		var payers []models.BillPayers
+		if err := tx.Scopes(ScopeBill(billID)).Find(&payers).Error; err != nil {
+			return fmt.Errorf("failed to find bill payers: %w", err)
+		}
+		fronted := make(map[uint]bool, len(payers))
+		for _, payer := range payers {
+			fronted[payer.ParticipantID] = true
+		}
+
+		found := make(map[uint]models.Participants, len(participants))
+		for _, participant := range participants {
+			found[participant.ID] = participant
+		}
+
+		// Validate each requested ID, keeping the caller's order and skipping repeats
+		seen := make(map[uint]bool, len(participantIDs))
+		deletable := make([]uint, 0, len(participantIDs))
+		for _, id := range participantIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			participant, ok := found[id]
+			switch {
+			case !ok:
+				result.Errors = append(result.Errors, models.BulkDeleteError{ParticipantID: id, Error: "Participant not found in this bill"})
+			case bill.PayerParticipantID != nil && *bill.PayerParticipantID == id:
+				result.Errors = append(result.Errors, models.BulkDeleteError{ParticipantID: id, Error: "Participant is the bill's designated payer"})
+			case fronted[id]:
+				result.Errors = append(result.Errors, models.BulkDeleteError{ParticipantID: id, Error: "Participant paid part of the bill"})
+			case participant.PaymentStatus == "paid":
+				result.Errors = append(result.Errors, models.BulkDeleteError{ParticipantID: id, Error: "Participant is marked as paid"})
+			default:
+				deletable = append(deletable, id)
+				removed = append(removed, participant)
+			}
+		}
+
+		if allOrNothing && len(result.Errors) > 0 {
+			return ErrBatchRejected
+		}
+		if len(deletable) == 0 {
+			return nil
+		}
+
+		assignments := tx.Unscoped().Where("participant_id IN ?", deletable).Delete(&models.ItemAssignments{})
+		if assignments.Error != nil {
+			return fmt.Errorf("failed to delete item assignments: %w", assignments.Error)
+		}
+
+		deleted := tx.Where("bill_id = ? AND id IN ?", billID, deletable).Delete(&models.Participants{})
+		if deleted.Error != nil {
+			return fmt.Errorf("failed to delete participants: %w", deleted.Error)
+		}
+
+		result.DeletedAssignments = assignments.RowsAffected
+		result.DeletedParticipants = deleted.RowsAffected
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrBatchRejected) {
+			return result, err
+		}
+		return nil, err
 	}
 
-	return tx.Commit().Error
+	for _, participant := range removed {
+		s.recordEvent(billID, actor, EventParticipantRemoved, models.EventPayload{
+			"participant_id": participant.ID,
+			"name":           participant.Name,
+		})
+	}
+	return result, nil
+}
+
+// AssignItem assigns an item to a participant with a weight, their part of the item
+// relative to its other assignees; zero is a weight of one. An item or participant that
+// isn't on the bill is ErrItemNotFound or ErrParticipantNotFound, and an existing
+// assignment is ErrAlreadyAssigned.
+func (s *BillService) AssignItem(billID uuid.UUID, itemID, participantID uint, weight int, actor string) (*models.ItemAssignments, error) {
+	if weight == 0 {
+		weight = 1
+	}
+	assignment := &models.ItemAssignments{
+		ItemID:        itemID,
+		ParticipantID: participantID,
+		Weight:        weight,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findAssignable(tx, billID, itemID, participantID); err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.ItemAssignments{}).Where("item_id = ? AND participant_id = ?", itemID, participantID).Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to find item assignment: %w", err)
+		}
+		if existing > 0 {
+			return ErrAlreadyAssigned
+		}
+
+		if err := tx.Create(assignment).Error; err != nil {
+			return fmt.Errorf("failed to assign item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventAssignmentAdded, models.EventPayload{
+		"item_id":        itemID,
+		"participant_id": participantID,
+		"weight":         weight,
+	})
+	return assignment, nil
+}
+
+// UnassignItem removes an item's assignment to a participant. An item or participant that
+// isn't on the bill is ErrItemNotFound or ErrParticipantNotFound, and a missing assignment
+// ErrAssignmentNotFound.
+func (s *BillService) UnassignItem(billID uuid.UUID, itemID, participantID uint, actor string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := findAssignable(tx, billID, itemID, participantID); err != nil {
+			return err
+		}
+
+		// Removed by hand, so it is not brought back when the item is restored
+		result := tx.Unscoped().Where("item_id = ? AND participant_id = ? AND deleted_at IS NULL", itemID, participantID).Delete(&models.ItemAssignments{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete item assignment: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAssignmentNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordEvent(billID, actor, EventAssignmentRemoved, models.EventPayload{
+		"item_id":        itemID,
+		"participant_id": participantID,
+	})
+	return nil
 }
 
-// GetBillSummary calculates and returns bill summary
+// findAssignable checks that both sides of an assignment are on the bill
+func findAssignable(tx *gorm.DB, billID uuid.UUID, itemID, participantID uint) error {
+	var items int64
+	if err := tx.Model(&models.Items{}).Scopes(ScopeBill(billID)).Where("id = ?", itemID).Count(&items).Error; err != nil {
+		return fmt.Errorf("failed to find item: %w", err)
+	}
+	if items == 0 {
+		return fmt.Errorf("item %d in bill %s: %w", itemID, billID, ErrItemNotFound)
+	}
+
+	var participants int64
+	if err := tx.Model(&models.Participants{}).Scopes(ScopeBill(billID)).Where("id = ?", participantID).Count(&participants).Error; err != nil {
+		return fmt.Errorf("failed to find participant: %w", err)
+	}
+	if participants == 0 {
+		return fmt.Errorf("participant %d in bill %s: %w", participantID, billID, ErrParticipantNotFound)
+	}
+	return nil
+}
+
+// GetParticipant retrieves a single participant scoped to its bill
+func (s *BillService) GetParticipant(billID uuid.UUID, participantID uint) (*models.Participants, error) {
+	var participant models.Participants
+	if err := s.db.Scopes(ScopeBill(billID)).Where("id = ?", participantID).First(&participant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find participant: %w", err)
+	}
+
+	list := []models.Participants{participant}
+	s.backfillParticipantColors(billID, list)
+	return &list[0], nil
+}
+
+// UpdateParticipantPayment records a participant's payment status and what they paid back
+// if their row is still at expectedVersion (nil skips the check). On a version mismatch the
+// current participant is returned together with ErrPreconditionFailed so the caller can
+// refresh. An amount that doesn't fit the status or exceeds their share is ErrInvalidPayment.
+func (s *BillService) UpdateParticipantPayment(billID uuid.UUID, participantID uint, status string, amountPaid *float64, expectedVersion *int64, actor string) (*models.Participants, error) {
+	var participant models.Participants
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so two concurrent writers can't both pass the version check
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(ScopeBill(billID)).
+			Where("id = ?", participantID).
+			First(&participant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find participant: %w", err)
+		}
+
+		if expectedVersion != nil && participant.Version() != *expectedVersion {
+			return ErrPreconditionFailed
+		}
+
+		owed, code, err := s.participantOwed(tx, billID, participantID)
+		if err != nil {
+			return err
+		}
+		updates, err := paymentColumns(status, amountPaid, owed, code, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&participant).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+
+		// Reload so updated_at carries the precision stored by the database
+		return tx.First(&participant, participant.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return &participant, err
+		}
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventPaymentRecorded, models.EventPayload{
+		"participant_id": participant.ID,
+		"name":           participant.Name,
+		"payment_status": participant.PaymentStatus,
+		"amount_paid":    participant.AmountPaid,
+	})
+	return &participant, nil
+}
+
+// itemsSubtotalMinor returns the sum of price times quantity, less item discounts, over
+// the bill's items in minor units of the bill's currency, together with that currency
+func (s *BillService) itemsSubtotalMinor(billID uuid.UUID) (int64, string, error) {
+	code, err := s.billCurrencyOf(s.db, billID)
+	if err != nil {
+		return 0, "", err
+	}
+	var items []models.Items
+	if err := s.db.Scopes(ScopeBill(billID)).Select("price, quantity, discount_amount").Find(&items).Error; err != nil {
+		return 0, "", fmt.Errorf("failed to find items: %w", err)
+	}
+
+	subtotal, _ := splitmath.Totals(itemLinesMinor(items, code), splitmath.Charges{})
+	return subtotal, code, nil
+}
+
+// GetBillSummary returns a bill's summary, from the view cache when it holds a current one.
+// The total is items plus tax, tip and service charge, less the discount. Amounts are
+// split in the currency's minor units, so cents for USD and whole rupiah for IDR; leftover
+// units are assigned according to the bill's rounding mode and reported so every client
+// shows the same numbers. Unconfirmed extracted items are counted, with
+// has_unconfirmed_items set as a warning.
 func (s *BillService) GetBillSummary(billID uuid.UUID) (*models.BillSummary, error) {
+	if cached, ok := s.views.Summary(billID); ok {
+		summary := *cached
+		return &summary, nil
+	}
+
+	generation := s.views.Generation()
+	summary, err := s.billSummary(billID)
+	if err != nil {
+		return nil, err
+	}
+	cached := *summary
+	s.views.SetSummary(billID, generation, &cached)
+	return summary, nil
+}
+
+// billSummary calculates a bill's summary from the database
+func (s *BillService) billSummary(billID uuid.UUID) (*models.BillSummary, error) {
 	var bill models.Bills
-	if err := s.db.Preload("Items").Preload("Participants").First(&bill, "id = ?", billID).Error; err != nil {
+	if err := s.db.Preload("Items.ItemAssignments").Preload("Participants", ParticipantOrder).Preload("Payers").First(&bill, "id = ?", billID).Error; err != nil {
 		return nil, fmt.Errorf("bill not found: %w", err)
 	}
 
-	// Calculate total items
-	var totalItems float64
+	code := s.billCurrency(bill.Currency)
+	alloc := billAllocation(&bill, code)
+
+	var unconfirmed int64
 	for _, item := range bill.Items {
-		totalItems += item.Price * float64(item.Quantity)
+		if !item.Confirmed {
+			unconfirmed++
+		}
+	}
+
+	names := make(map[uint]string, len(bill.Participants))
+	for _, participant := range bill.Participants {
+		names[participant.ID] = participant.Name
+	}
+
+	// Shares follow bill.Participants, which is also the order of alloc.Shares
+	participantShares := make([]models.ParticipantShare, 0, len(alloc.Shares))
+	absorbedBy := []models.RoundingAbsorption{}
+	var collected, outstanding int64
+	for i, share := range alloc.Shares {
+		paidBack := currency.ToMinor(bill.Participants[i].AmountPaid, code)
+		collected += paidBack
+		if paidBack < share.Owed {
+			outstanding += share.Owed - paidBack
+		}
+		participantShares = append(participantShares, models.ParticipantShare{
+			ParticipantID: share.ParticipantID,
+			Name:          names[share.ParticipantID],
+			PaymentStatus: bill.Participants[i].PaymentStatus,
+			Split:         currency.FromMinor(share.Split, code),
+			CommonCosts:   currency.FromMinor(share.Owed-share.Split, code),
+			RoundingCents: share.Absorbed,
+			Owed:          currency.FromMinor(share.Owed, code),
+			Paid:          currency.FromMinor(share.Paid, code),
+		})
+		if share.Absorbed != 0 {
+			absorbedBy = append(absorbedBy, models.RoundingAbsorption{
+				ParticipantID: share.ParticipantID,
+				Name:          names[share.ParticipantID],
+				Cents:         share.Absorbed,
+			})
+		}
 	}
 
-	// Calculate participant shares
-	participantShares := make(map[string]float64)
-	totalParticipants := len(bill.Participants)
-	if totalParticipants > 0 {
-		sharePerPerson := (totalItems + bill.TaxAmount + bill.TipAmount) / float64(totalParticipants)
-		for _, participant := range bill.Participants {
-			participantShares[participant.Name] = sharePerPerson + participant.ShareOfCommonCosts
+	// Kept for one release while clients move to the array
+	var sharesByName map[string]float64
+	if s.legacyShares {
+		sharesByName = make(map[string]float64, len(participantShares))
+		for _, share := range participantShares {
+			sharesByName[share.Name] = share.Owed
 		}
 	}
 
+	// Who fronted the merchant and the transfers that settle up
+	payers := make([]models.BillPayerResponse, 0, len(alloc.Payments))
+	for _, payment := range alloc.Payments {
+		payers = append(payers, models.BillPayerResponse{
+			ParticipantID: payment.ParticipantID,
+			Name:          names[payment.ParticipantID],
+			AmountPaid:    currency.FromMinor(payment.Amount, code),
+		})
+	}
+	settlements := make([]models.Settlement, 0, len(alloc.Transfers))
+	for _, transfer := range alloc.Transfers {
+		settlements = append(settlements, models.Settlement{
+			FromParticipantID: transfer.FromParticipantID,
+			FromName:          names[transfer.FromParticipantID],
+			ToParticipantID:   transfer.ToParticipantID,
+			ToName:            names[transfer.ToParticipantID],
+			Amount:            currency.FromMinor(transfer.Amount, code),
+		})
+	}
+
 	return &models.BillSummary{
-		BillID:            billID,
-		TotalItems:        totalItems,
-		TaxAmount:         bill.TaxAmount,
-		TipAmount:         bill.TipAmount,
-		TotalBill:         totalItems + bill.TaxAmount + bill.TipAmount,
-		ParticipantShares: participantShares,
+		BillID:                  billID,
+		Currency:                code,
+		TotalItems:              currency.FromMinor(alloc.Subtotal, code),
+		TaxAmount:               bill.TaxAmount,
+		TipAmount:               bill.TipAmount,
+		ServiceChargeAmount:     bill.ServiceChargeAmount,
+		DiscountAmount:          bill.DiscountAmount,
+		TotalBill:               currency.FromMinor(alloc.Total, code),
+		PricesIncludeTax:        bill.PricesIncludeTax,
+		PricesIncludeService:    bill.PricesIncludeService,
+		ParticipantShares:       participantShares,
+		ParticipantSharesByName: sharesByName,
+		SplitMode:               bill.SplitMode,
+		RoundingMode:            alloc.Rounding,
+		ResidualCents:           alloc.Residual,
+		AbsorbedBy:              absorbedBy,
+		Payers:                  payers,
+		Settlements:             settlements,
+		TotalCollected:          currency.FromMinor(collected, code),
+		TotalOutstanding:        currency.FromMinor(outstanding, code),
+		HasUnconfirmedItems:     unconfirmed > 0,
+		UnconfirmedItems:        unconfirmed,
 	}, nil
 }
 
-// UpdateBillStatus updates the status of a bill
-func (s *BillService) UpdateBillStatus(billID uuid.UUID, status string) error {
-	return s.db.Model(&models.Bills{}).Where("id = ?", billID).Update("status", status).Error
+// billCurrency returns code, or the configured default for bills created before
+// currencies were stored
+func (s *BillService) billCurrency(code string) string {
+	if code == "" {
+		return s.defaultCurrency
+	}
+	return code
 }
 
-// GetBillStatus returns the current status of a bill
-func (s *BillService) GetBillStatus(billID uuid.UUID) (string, error) {
+// GetBillForAdmin loads a bill and all of its children with Unscoped, so support staff
+// can inspect bills that were soft-deleted. It never writes.
+func (s *BillService) GetBillForAdmin(billID uuid.UUID) (*models.AdminBillResponse, error) {
 	var bill models.Bills
-	err := s.db.Select("status").Where("id = ?", billID).First(&bill).Error
+	if err := s.db.Unscoped().Preload("Items").Preload("Participants", ParticipantOrder).First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	itemIDs := make([]uint, len(bill.Items))
+	for i, item := range bill.Items {
+		itemIDs[i] = item.ID
+	}
+
+	assignments := []models.ItemAssignments{}
+	if len(itemIDs) > 0 {
+		if err := s.db.Unscoped().Where("item_id IN ?", itemIDs).Find(&assignments).Error; err != nil {
+			return nil, fmt.Errorf("failed to find item assignments: %w", err)
+		}
+	}
+
+	timings, err := s.latestStageTimings(billID, 10)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	response := &models.AdminBillResponse{
+		BillResponse:      *s.getBillResponse(&bill),
+		ItemAssignments:   assignments,
+		ExtractionTimings: timings,
 	}
-	return bill.Status, nil
+	if bill.DeletedAt.Valid {
+		response.DeletedAt = &bill.DeletedAt.Time
+	}
+	return response, nil
+}
+
+// ArchiveBill hides a bill from the default bill list and freezes its contents.
+// Bills waiting for or going through extraction cannot be archived.
+func (s *BillService) ArchiveBill(billID uuid.UUID, actor string) (*models.BillResponse, error) {
+	var from BillStatus
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		from = BillStatus(bill.Status)
+		if from == StatusArchived {
+			return nil
+		}
+		_, err := transitionStatus(tx, billID, StatusArchived)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if from != StatusArchived {
+		s.recordStatusChange(billID, actor, from, StatusArchived)
+	}
+
+	return s.GetBill(billID)
+}
+
+// UnarchiveBill brings an archived bill back. It returns to completed when it has
+// extracted items and to active otherwise.
+func (s *BillService) UnarchiveBill(billID uuid.UUID, actor string) (*models.BillResponse, error) {
+	var status BillStatus
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		if BillStatus(bill.Status) != StatusArchived {
+			return fmt.Errorf("bill %s is %s: %w", billID, bill.Status, ErrInvalidStatus)
+		}
+
+		var itemCount int64
+		if err := tx.Model(&models.Items{}).Where("bill_id = ?", billID).Count(&itemCount).Error; err != nil {
+			return fmt.Errorf("failed to count items: %w", err)
+		}
+		status = StatusActive
+		if itemCount > 0 {
+			status = StatusCompleted
+		}
+		_, err := transitionStatus(tx, billID, status)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordStatusChange(billID, actor, StatusArchived, status)
+
+	return s.GetBill(billID)
+}
+
+// ReopenBill moves a completed bill back to active so its items can be corrected.
+// Bills in any other status are refused with a *TransitionError.
+func (s *BillService) ReopenBill(billID uuid.UUID, actor string) (*models.BillResponse, error) {
+	var from BillStatus
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		from, err = transitionStatus(tx, billID, StatusActive)
+		if err == nil && from != StatusCompleted {
+			return &TransitionError{BillID: billID, From: from, To: StatusActive}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordStatusChange(billID, actor, from, StatusActive)
+
+	return s.GetBill(billID)
+}
+
+// FinalizeBill marks an active bill completed once every item is confirmed and has someone
+// assigned. It returns an *UnconfirmedItemsError or an *UnassignedItemsError listing the
+// items that aren't.
+func (s *BillService) FinalizeBill(billID uuid.UUID, actor string) (*models.BillResponse, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		from, err := transitionStatus(tx, billID, StatusCompleted)
+		if err != nil {
+			return err
+		}
+		if from != StatusActive {
+			return &TransitionError{BillID: billID, From: from, To: StatusCompleted}
+		}
+
+		unconfirmed := []uint{}
+		if err := tx.Model(&models.Items{}).Where("bill_id = ? AND NOT confirmed", billID).Order("id").Pluck("id", &unconfirmed).Error; err != nil {
+			return fmt.Errorf("failed to find unconfirmed items: %w", err)
+		}
+		if len(unconfirmed) > 0 {
+			return &UnconfirmedItemsError{BillID: billID, ItemIDs: unconfirmed}
+		}
+
+		unassigned := []uint{}
+		if err := tx.Model(&models.Items{}).
+			Where("bill_id = ? AND NOT EXISTS (SELECT 1 FROM item_assignments WHERE item_assignments.item_id = items.id AND item_assignments.deleted_at IS NULL)", billID).
+			Order("id").
+			Pluck("id", &unassigned).Error; err != nil {
+			return fmt.Errorf("failed to find unassigned items: %w", err)
+		}
+		if len(unassigned) > 0 {
+			return &UnassignedItemsError{BillID: billID, ItemIDs: unassigned}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.recordStatusChange(billID, actor, StatusActive, StatusCompleted)
+
+	return s.GetBill(billID)
+}
+
+// UpdateBillStatus moves a bill to status. It returns a *TransitionError when the bill's
+// current status can't move there.
+func (s *BillService) UpdateBillStatus(billID uuid.UUID, status BillStatus) error {
+	var from BillStatus
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		from, err = transitionStatus(tx, billID, status)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordStatusChange(billID, ActorSystem, from, status)
+	return nil
+}
+
+// GetBillStatus returns the current status of a bill and when the bill last changed
+func (s *BillService) GetBillStatus(billID uuid.UUID) (string, time.Time, error) {
+	var bill models.Bills
+	err := s.db.Select("status", "updated_at").Where("id = ?", billID).First(&bill).Error
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return bill.Status, bill.UpdatedAt, nil
+}
+
+// BillVersion returns a value that changes whenever GetBill's response would: the bill's
+// own updated_at and status plus the count and latest change of its items and participants.
+// It is one small query, so pollers can be answered without loading the bill.
+func (s *BillService) BillVersion(billID uuid.UUID) (string, error) {
+	var version struct {
+		UpdatedAt             time.Time
+		Status                string
+		ItemCount             int64
+		ItemsUpdatedAt        *time.Time
+		ParticipantCount      int64
+		ParticipantsUpdatedAt *time.Time
+	}
+	if err := s.db.Model(&models.Bills{}).
+		Select("bills.updated_at, bills.status, "+
+			"(SELECT COUNT(*) FROM items WHERE items.bill_id = bills.id AND items.deleted_at IS NULL) AS item_count, "+
+			"(SELECT MAX(updated_at) FROM items WHERE items.bill_id = bills.id AND items.deleted_at IS NULL) AS items_updated_at, "+
+			"(SELECT COUNT(*) FROM participants WHERE participants.bill_id = bills.id AND participants.deleted_at IS NULL) AS participant_count, "+
+			"(SELECT MAX(updated_at) FROM participants WHERE participants.bill_id = bills.id AND participants.deleted_at IS NULL) AS participants_updated_at").
+		Where("bills.id = ?", billID).
+		Take(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return "", fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	unixMicro := func(t *time.Time) int64 {
+		if t == nil {
+			return 0
+		}
+		return t.UnixMicro()
+	}
+	return fmt.Sprintf("%d:%s:%d:%d:%d:%d", version.UpdatedAt.UnixMicro(), version.Status,
+		version.ItemCount, unixMicro(version.ItemsUpdatedAt),
+		version.ParticipantCount, unixMicro(version.ParticipantsUpdatedAt)), nil
 }
 
 // getBillResponse converts a Bills model to BillResponse
 func (s *BillService) getBillResponse(bill *models.Bills) *models.BillResponse {
 	response := &models.BillResponse{
-		ID:        bill.ID,
-		Name:      bill.Name,
-		Status:    bill.Status,
-		TaxAmount: bill.TaxAmount,
-		TipAmount: bill.TipAmount,
-		CreatedAt: bill.CreatedAt,
+		ID:                   bill.ID,
+		Name:                 bill.Name,
+		UserID:               bill.UserID,
+		ShareToken:           bill.ShareToken,
+		Status:               bill.Status,
+		Currency:             s.billCurrency(bill.Currency),
+		TaxAmount:            bill.TaxAmount,
+		TipAmount:            bill.TipAmount,
+		ServiceChargeAmount:  bill.ServiceChargeAmount,
+		DiscountAmount:       bill.DiscountAmount,
+		RoundingMode:         bill.RoundingMode,
+		SplitMode:            bill.SplitMode,
+		PayerParticipantID:   bill.PayerParticipantID,
+		PricesIncludeTax:     bill.PricesIncludeTax,
+		PricesIncludeService: bill.PricesIncludeService,
+		Notes:                bill.Notes,
+		Metadata:             bill.Metadata,
+		HasCallbackURL:       bill.CallbackURL != "",
+		SuggestedTipPercent:  bill.SuggestedTipPercent,
+		SuggestedTaxPercent:  bill.SuggestedTaxPercent,
+		Version:              models.FormatVersion(bill.Version()),
+		CreatedAt:            bill.CreatedAt,
+	}
+	if response.Metadata == nil {
+		response.Metadata = models.Metadata{}
 	}
 
 	// Convert items
 	for _, item := range bill.Items {
-		response.Items = append(response.Items, models.ItemResponse{
-			ID:        item.ID,
-			BillID:    item.BillID,
-			Name:      item.Name,
-			Price:     item.Price,
-			Quantity:  item.Quantity,
-			CreatedAt: item.CreatedAt,
-		})
+		response.Items = append(response.Items, itemResponse(&item))
 	}
 
 	// Convert participants
 	for _, participant := range bill.Participants {
-		response.Participants = append(response.Participants, models.ParticipantResponse{
-			ID:                 participant.ID,
-			BillID:             participant.BillID,
-			Name:               participant.Name,
-			PaymentStatus:      participant.PaymentStatus,
-			ShareOfCommonCosts: participant.ShareOfCommonCosts,
-			CreatedAt:          participant.CreatedAt,
-		})
+		response.Participants = append(response.Participants, participantResponse(&participant))
 	}
 
 	return response
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BillStatus is where a bill is in its lifecycle
+type BillStatus string
+
+const (
+	StatusActive     BillStatus = "active"
+	StatusQueued     BillStatus = "queued"
+	StatusProcessing BillStatus = "processing"
+	StatusCompleted  BillStatus = "completed"
+	StatusFailed     BillStatus = "failed"
+	StatusArchived   BillStatus = "archived"
+)
+
+// statusTransitions lists the statuses each status may move to. Uploading queues a bill,
+// a worker picks it up, and the n8n callback or a failure ends processing. Failed and
+// completed bills can be uploaded again, and a queued bill goes back to where it was when
+// the queue refuses it. Owners finalize an active bill and reopen a completed one.
+var statusTransitions = map[BillStatus][]BillStatus{
+	StatusActive:     {StatusQueued, StatusArchived, StatusCompleted},
+	StatusQueued:     {StatusProcessing, StatusFailed, StatusActive, StatusCompleted},
+	StatusProcessing: {StatusCompleted, StatusFailed},
+	StatusCompleted:  {StatusQueued, StatusArchived, StatusActive},
+	StatusFailed:     {StatusQueued, StatusProcessing, StatusArchived},
+	StatusArchived:   {StatusActive, StatusCompleted},
+}
+
+// CanTransition reports whether a bill may move from one status to another
+func CanTransition(from, to BillStatus) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Extracting reports whether a bill in this status is waiting for or going through
+// extraction, the only time extracted data may complete it
+func (s BillStatus) Extracting() bool {
+	return s == StatusQueued || s == StatusProcessing
+}
+
+// TransitionError is returned when a bill can't move to the requested status.
+// It matches ErrInvalidStatus with errors.Is.
+type TransitionError struct {
+	BillID uuid.UUID
+	From   BillStatus
+	To     BillStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("bill %s cannot go from %s to %s", e.BillID, e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrInvalidStatus) true for transition errors
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidStatus
+}
+
+// UnassignedItemsError is returned when a bill can't be finalized because some of its
+// items have nobody assigned. It matches ErrUnassignedItems with errors.Is.
+type UnassignedItemsError struct {
+	BillID  uuid.UUID
+	ItemIDs []uint
+}
+
+func (e *UnassignedItemsError) Error() string {
+	return fmt.Sprintf("bill %s has %d unassigned items", e.BillID, len(e.ItemIDs))
+}
+
+// Is makes errors.Is(err, ErrUnassignedItems) true for unassigned item errors
+func (e *UnassignedItemsError) Is(target error) bool {
+	return target == ErrUnassignedItems
+}
+
+// UnconfirmedItemsError is returned when a bill can't be finalized because some of its
+// extracted items are still unconfirmed. It matches ErrUnconfirmedItems with errors.Is.
+type UnconfirmedItemsError struct {
+	BillID  uuid.UUID
+	ItemIDs []uint
+}
+
+func (e *UnconfirmedItemsError) Error() string {
+	return fmt.Sprintf("bill %s has %d unconfirmed items", e.BillID, len(e.ItemIDs))
+}
+
+// Is makes errors.Is(err, ErrUnconfirmedItems) true for unconfirmed item errors
+func (e *UnconfirmedItemsError) Is(target error) bool {
+	return target == ErrUnconfirmedItems
+}
+
+// transitionStatus moves a bill to status within tx, locking the row so the check and
+// the write see the same status. It returns the status the bill had before.
+func transitionStatus(tx *gorm.DB, billID uuid.UUID, status BillStatus) (BillStatus, error) {
+	var bill models.Bills
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return "", fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	from := BillStatus(bill.Status)
+	if !CanTransition(from, status) {
+		return from, &TransitionError{BillID: billID, From: from, To: status}
+	}
+
+	if err := tx.Model(&models.Bills{}).Where("id = ?", billID).Update("status", string(status)).Error; err != nil {
+		return from, fmt.Errorf("failed to update bill status: %w", err)
+	}
+	return from, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+var allStatuses = []BillStatus{StatusActive, StatusQueued, StatusProcessing, StatusCompleted, StatusFailed, StatusArchived}
+
+func TestCanTransition(t *testing.T) {
+	// Every pair not listed here must be rejected
+	allowed := map[[2]BillStatus]bool{
+		{StatusActive, StatusQueued}:        true,
+		{StatusActive, StatusArchived}:      true,
+		{StatusActive, StatusCompleted}:     true,
+		{StatusQueued, StatusProcessing}:    true,
+		{StatusQueued, StatusFailed}:        true,
+		{StatusQueued, StatusActive}:        true,
+		{StatusQueued, StatusCompleted}:     true,
+		{StatusProcessing, StatusCompleted}: true,
+		{StatusProcessing, StatusFailed}:    true,
+		{StatusCompleted, StatusQueued}:     true,
+		{StatusCompleted, StatusArchived}:   true,
+		{StatusCompleted, StatusActive}:     true,
+		{StatusFailed, StatusQueued}:        true,
+		{StatusFailed, StatusProcessing}:    true,
+		{StatusFailed, StatusArchived}:      true,
+		{StatusArchived, StatusActive}:      true,
+		{StatusArchived, StatusCompleted}:   true,
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			want := allowed[[2]BillStatus{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestUpdateBillStatusRejectsIllegalTransitions(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+
+	tests := []struct {
+		from, to BillStatus
+		ok       bool
+	}{
+		{from: StatusProcessing, to: StatusCompleted, ok: true},
+		{from: StatusFailed, to: StatusProcessing, ok: true},
+		{from: StatusCompleted, to: StatusProcessing},
+		{from: StatusArchived, to: StatusQueued},
+		{from: StatusActive, to: StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			bill := createTestBill(t, s, "Status", nil)
+			if err := db.Model(&models.Bills{}).Where("id = ?", bill.ID).UpdateColumn("status", string(tt.from)).Error; err != nil {
+				t.Fatalf("failed to set status: %v", err)
+			}
+
+			err := s.UpdateBillStatus(bill.ID, tt.to)
+			want := tt.to
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("UpdateBillStatus: %v", err)
+				}
+			} else {
+				var transition *TransitionError
+				if !errors.As(err, &transition) || !errors.Is(err, ErrInvalidStatus) {
+					t.Fatalf("UpdateBillStatus err = %v, want a TransitionError", err)
+				}
+				if transition.From != tt.from || transition.To != tt.to {
+					t.Errorf("TransitionError = %s to %s, want %s to %s", transition.From, transition.To, tt.from, tt.to)
+				}
+				want = tt.from
+			}
+
+			status, _, err := s.GetBillStatus(bill.ID)
+			if err != nil {
+				t.Fatalf("GetBillStatus: %v", err)
+			}
+			if BillStatus(status) != want {
+				t.Errorf("status = %s, want %s", status, want)
+			}
+		})
+	}
+}
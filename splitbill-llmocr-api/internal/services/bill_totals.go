@@ -0,0 +1,285 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/splitmath"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// billTotalsMinor returns the item subtotal and the grand total of a bill in minor units of
+// code, as splitmath.Totals defines them. The grand total of a manual bill is the sum of
+// its manual shares, so bill.Participants must be loaded for those.
+func billTotalsMinor(bill *models.Bills, items []models.Items, code string) (int64, int64) {
+	subtotal, total := splitmath.Totals(itemLinesMinor(items, code), billCharges(bill, code))
+	if bill.SplitMode == SplitManual {
+		total = splitmath.ManualTotal(billParticipants(bill, code))
+	}
+	return subtotal, total
+}
+
+// billParticipants returns a bill's participants in minor units of code, in display order
+func billParticipants(bill *models.Bills, code string) []splitmath.Participant {
+	participants := make([]splitmath.Participant, len(bill.Participants))
+	for i, participant := range bill.Participants {
+		participants[i] = splitmath.Participant{
+			ID:    participant.ID,
+			Extra: currency.ToMinor(participant.ShareOfCommonCosts, code),
+		}
+		if participant.ManualAmount != nil {
+			participants[i].Amount = currency.ToMinor(*participant.ManualAmount, code)
+		}
+	}
+	return participants
+}
+
+// billItems returns a bill's items in minor units of code, with their assignments, which
+// must be loaded
+func billItems(items []models.Items, code string) []splitmath.Item {
+	lines := itemLinesMinor(items, code)
+	split := make([]splitmath.Item, len(items))
+	for i, item := range items {
+		split[i] = splitmath.Item{Line: lines[i]}
+		for _, assignment := range item.ItemAssignments {
+			split[i].Assignments = append(split[i].Assignments, splitmath.Assignment{
+				ParticipantID: assignment.ParticipantID,
+				Weight:        int64(assignment.Weight),
+			})
+		}
+	}
+	return split
+}
+
+// itemLinesMinor returns each item's price times quantity, less its discount, in minor
+// units of code
+func itemLinesMinor(items []models.Items, code string) []int64 {
+	lines := make([]int64, len(items))
+	for i, item := range items {
+		lines[i] = itemLineMinor(item.Price, item.Quantity, item.DiscountAmount, code)
+	}
+	return lines
+}
+
+// itemLineMinor returns price times quantity less discount in minor units of code, never
+// below zero
+func itemLineMinor(price, quantity, discount float64, code string) int64 {
+	line := currency.ToMinor(price*quantity, code) - currency.ToMinor(discount, code)
+	if line < 0 {
+		return 0
+	}
+	return line
+}
+
+// addAmounts adds stored amounts in minor units of code, so the sum carries no float error
+func addAmounts(code string, amounts ...float64) float64 {
+	var sum int64
+	for _, amount := range amounts {
+		sum += currency.ToMinor(amount, code)
+	}
+	return currency.FromMinor(sum, code)
+}
+
+// checkItemDiscount returns ErrInvalidItemDiscount when an item's discount is more than
+// its price times quantity, compared in minor units of code
+func checkItemDiscount(item *models.Items, code string) error {
+	if currency.ToMinor(item.DiscountAmount, code) > currency.ToMinor(item.Price*item.Quantity, code) {
+		return fmt.Errorf("%w: discount_amount %.2f of item %q exceeds its price times quantity of %.2f",
+			ErrInvalidItemDiscount, item.DiscountAmount, item.Name, item.Price*item.Quantity)
+	}
+	return nil
+}
+
+// billCharges returns a bill's tax, tip, service charge and discount in minor units of code
+func billCharges(bill *models.Bills, code string) splitmath.Charges {
+	return splitmath.Charges{
+		Tax:                  currency.ToMinor(bill.TaxAmount, code),
+		Tip:                  currency.ToMinor(bill.TipAmount, code),
+		ServiceCharge:        currency.ToMinor(bill.ServiceChargeAmount, code),
+		Discount:             currency.ToMinor(bill.DiscountAmount, code),
+		PricesIncludeTax:     bill.PricesIncludeTax,
+		PricesIncludeService: bill.PricesIncludeService,
+	}
+}
+
+// billAllocation splits a bill loaded with its items and their assignments, participants
+// and payers. Every view of a bill's shares or settlements should start from it.
+func billAllocation(bill *models.Bills, code string) splitmath.Allocation {
+	payments := make([]splitmath.Payment, len(bill.Payers))
+	for i, payer := range bill.Payers {
+		payments[i] = splitmath.Payment{
+			ParticipantID: payer.ParticipantID,
+			Amount:        currency.ToMinor(payer.AmountPaid, code),
+		}
+	}
+
+	return splitmath.Compute(splitmath.Input{
+		Items:        billItems(bill.Items, code),
+		Charges:      billCharges(bill, code),
+		Participants: billParticipants(bill, code),
+		Rounding:     bill.RoundingMode,
+		PayerID:      bill.PayerParticipantID,
+		Payments:     payments,
+		Manual:       bill.SplitMode == SplitManual,
+	})
+}
+
+// BillTotals returns the item_subtotal and grand_total stored on a bill, computed the same
+// way as GetBillSummary. defaultCurrency is used for bills without a currency. Manual bills
+// need their participants loaded.
+func BillTotals(bill *models.Bills, items []models.Items, defaultCurrency string) (float64, float64) {
+	code := bill.Currency
+	if code == "" {
+		code = defaultCurrency
+	}
+	subtotal, total := billTotalsMinor(bill, items, code)
+	return currency.FromMinor(subtotal, code), currency.FromMinor(total, code)
+}
+
+// billCurrencyOf returns the currency of a bill, or the default for bills without one. A
+// bill that doesn't exist is ErrNotFound.
+func (s *BillService) billCurrencyOf(db *gorm.DB, billID uuid.UUID) (string, error) {
+	var bill models.Bills
+	if err := db.Select("id", "currency").First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return "", fmt.Errorf("failed to find bill: %w", err)
+	}
+	return s.billCurrency(bill.Currency), nil
+}
+
+// refreshBillTotals recomputes the stored totals of a bill. Call it inside the transaction
+// that changed the bill's items or amounts, so readers never see stale totals.
+func (s *BillService) refreshBillTotals(tx *gorm.DB, billID uuid.UUID) error {
+	var bill models.Bills
+	if err := tx.Preload("Items").Preload("Participants").First(&bill, "id = ?", billID).Error; err != nil {
+		return fmt.Errorf("failed to load bill totals: %w", err)
+	}
+
+	subtotal, total := BillTotals(&bill, bill.Items, s.defaultCurrency)
+	// UpdateColumns leaves updated_at alone, since the change that triggered this already set it
+	if err := tx.Model(&models.Bills{}).Where("id = ?", billID).UpdateColumns(map[string]interface{}{
+		"item_subtotal": subtotal,
+		"grand_total":   total,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update bill totals: %w", err)
+	}
+	return nil
+}
+
+// UpdateBill applies a partial update to a bill and refreshes its stored totals in the
+// same transaction, returning the updated bill. Only the fields set in req change, and
+// billUpdates lists the errors a bad request gets. Changes to the summary's numbers are
+// logged. When expectedVersion is set and the bill has moved on, nothing is written and
+// the current bill is returned together with ErrPreconditionFailed; nil keeps last write wins.
+func (s *BillService) UpdateBill(billID uuid.UUID, req *models.BillUpdateRequest, actor string, expectedVersion *int64) (*models.Bills, error) {
+	updates, err := s.billUpdates(billID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var current models.Bills
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if expectedVersion != nil {
+			// Lock the row so two concurrent writers can't both pass the version check
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", billID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+				}
+				return fmt.Errorf("failed to find bill: %w", err)
+			}
+			if current.Version() != *expectedVersion {
+				return ErrPreconditionFailed
+			}
+		}
+
+		result := tx.Model(&models.Bills{}).Where("id = ?", billID).Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update bill: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return &current, err
+		}
+		return nil, err
+	}
+	if changes := summaryChanges(updates); len(changes) > 0 {
+		s.recordEvent(billID, actor, EventBillUpdated, changes)
+	}
+
+	var bill models.Bills
+	if err := s.db.First(&bill, "id = ?", billID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch updated bill: %w", err)
+	}
+	return &bill, nil
+}
+
+// UpdateItem applies a partial update to an item of the bill, unless the bill was deleted,
+// and refreshes the bill's stored totals in the same transaction. Items of other bills are
+// ErrNotFound, and a request that sets nothing is ErrNoChanges. expectedVersion works as
+// in UpdateBill.
+func (s *BillService) UpdateItem(billID uuid.UUID, itemID uint, req *models.ItemUpdateRequest, actor string, expectedVersion *int64) (*models.Items, error) {
+	updates, err := itemUpdates(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var item models.Items
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if expectedVersion != nil {
+			// Lock the row so two concurrent writers can't both pass the version check
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ScopeBill(billID)).Where("id = ?", itemID).First(&item).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+				}
+				return fmt.Errorf("failed to find item: %w", err)
+			}
+			if item.Version() != *expectedVersion {
+				return ErrPreconditionFailed
+			}
+		}
+
+		result := tx.Model(&models.Items{}).Scopes(ScopeBill(billID)).Where("id = ?", itemID).Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update item: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+		}
+
+		if err := tx.First(&item, itemID).Error; err != nil {
+			return fmt.Errorf("failed to fetch updated item: %w", err)
+		}
+		// Checked after the update, since price, quantity and discount can change separately
+		code, err := s.billCurrencyOf(tx, item.BillID)
+		if err != nil {
+			return err
+		}
+		if err := checkItemDiscount(&item, code); err != nil {
+			return err
+		}
+		return s.refreshBillTotals(tx, item.BillID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return &item, err
+		}
+		return nil, err
+	}
+
+	s.recordEvent(item.BillID, actor, EventItemUpdated, models.EventPayload{
+		"item_id": item.ID,
+		"changes": updates,
+	})
+	return &item, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/splitmath"
+)
+
+func TestBillItemsCarriesAssignments(t *testing.T) {
+	items := []models.Items{
+		{Price: 12.5, Quantity: 2, DiscountAmount: 1, ItemAssignments: []models.ItemAssignments{
+			{ParticipantID: 1, Weight: 2},
+			{ParticipantID: 2, Weight: 1},
+		}},
+		{Price: 3, Quantity: 1, DiscountAmount: 5},
+	}
+
+	want := []splitmath.Item{
+		{Line: 2400, Assignments: []splitmath.Assignment{{ParticipantID: 1, Weight: 2}, {ParticipantID: 2, Weight: 1}}},
+		{Line: 0},
+	}
+	if got := billItems(items, "USD"); !reflect.DeepEqual(got, want) {
+		t.Errorf("billItems = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckItemDiscountUsesMinorUnits(t *testing.T) {
+	item := models.Items{Name: "Ramen", Price: 100, Quantity: 1, DiscountAmount: 100.4}
+
+	// Yen has no minor unit, so the discount rounds to the price
+	if err := checkItemDiscount(&item, "JPY"); err != nil {
+		t.Errorf("JPY: unexpected error %v", err)
+	}
+	if err := checkItemDiscount(&item, "USD"); !errors.Is(err, ErrInvalidItemDiscount) {
+		t.Errorf("USD: err = %v, want ErrInvalidItemDiscount", err)
+	}
+}
+
+func TestAddAmounts(t *testing.T) {
+	if got := addAmounts("USD", 0.1, 0.2); got != 0.3 {
+		t.Errorf("addAmounts USD = %v, want 0.3", got)
+	}
+	if got := addAmounts("IDR", 15000, 2500.4); got != 17500 {
+		t.Errorf("addAmounts IDR = %v, want 17500", got)
+	}
+}
+
+func TestBillSummaryFollowsAssignments(t *testing.T) {
+	s, _ := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Dinner", nil)
+
+	items, err := s.CreateItems(bill.ID, []models.ItemRequest{
+		{Name: "Steak", Price: 30, Quantity: 1},
+		{Name: "Salad", Price: 9, Quantity: 1},
+		{Name: "Bread", Price: 6, Quantity: 1},
+	}, "test")
+	if err != nil {
+		t.Fatalf("failed to create items: %v", err)
+	}
+	var participants []uint
+	for _, name := range []string{"Ana", "Ben", "Cy"} {
+		participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: name}, "test")
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		participants = append(participants, participant.ID)
+	}
+
+	// Ana has the steak, Ben and Cy share the salad two to one, and the bread is shared
+	assign := func(item, participant uint, weight int) {
+		t.Helper()
+		if _, err := s.AssignItem(bill.ID, item, participant, weight, "test"); err != nil {
+			t.Fatalf("failed to assign item %d: %v", item, err)
+		}
+	}
+	assign(items[0].ID, participants[0], 0)
+	assign(items[1].ID, participants[1], 2)
+	assign(items[1].ID, participants[2], 1)
+
+	summary, err := s.GetBillSummary(bill.ID)
+	if err != nil {
+		t.Fatalf("GetBillSummary: %v", err)
+	}
+	want := map[uint]float64{participants[0]: 32, participants[1]: 8, participants[2]: 5}
+	for _, share := range summary.ParticipantShares {
+		if share.Split != want[share.ParticipantID] {
+			t.Errorf("%s split = %v, want %v", share.Name, share.Split, want[share.ParticipantID])
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// billUpdates turns a partial bill update into the columns to write, checking the fields
+// that depend on the bill: the discount against its items, the payer against its
+// participants and the payer_absorbs rounding mode against its payer. The currency must
+// already be normalized. A missing bill is ErrNotFound, a request that sets nothing is
+// ErrNoChanges and a value the bill can't take is ErrInvalidUpdate.
+func (s *BillService) billUpdates(billID uuid.UUID, req *models.BillUpdateRequest) (map[string]interface{}, error) {
+	var bill models.Bills
+	if err := s.db.First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name must not be blank", ErrInvalidUpdate)
+		}
+		updates["name"] = name
+	}
+	if req.Currency != nil {
+		updates["currency"] = *req.Currency
+	}
+	// An amount entered by hand replaces the suggested percentage
+	if req.TaxAmount != nil {
+		updates["tax_amount"] = *req.TaxAmount
+		updates["suggested_tax_percent"] = nil
+	}
+	if req.TipAmount != nil {
+		updates["tip_amount"] = *req.TipAmount
+		updates["suggested_tip_percent"] = nil
+	}
+	if req.ServiceChargeAmount != nil {
+		updates["service_charge_amount"] = *req.ServiceChargeAmount
+	}
+	if req.DiscountAmount != nil {
+		if *req.DiscountAmount < 0 {
+			return nil, fmt.Errorf("%w: discount_amount must not be negative", ErrInvalidUpdate)
+		}
+		subtotal, code, err := s.itemsSubtotalMinor(billID)
+		if err != nil {
+			return nil, err
+		}
+		if currency.ToMinor(*req.DiscountAmount, code) > subtotal {
+			return nil, fmt.Errorf("%w: discount_amount must not exceed the item subtotal of %.*f",
+				ErrInvalidUpdate, currency.Decimals(code), currency.FromMinor(subtotal, code))
+		}
+		updates["discount_amount"] = *req.DiscountAmount
+	}
+	if req.RoundingMode != nil {
+		updates["rounding_mode"] = *req.RoundingMode
+	}
+	if req.PayerParticipantID != nil {
+		// The payer has to be one of this bill's participants
+		if _, err := s.GetParticipant(billID, *req.PayerParticipantID); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, fmt.Errorf("%w: payer_participant_id must reference a participant of this bill", ErrInvalidUpdate)
+			}
+			return nil, err
+		}
+		updates["payer_participant_id"] = *req.PayerParticipantID
+	}
+	if req.PricesIncludeTax != nil {
+		updates["prices_include_tax"] = *req.PricesIncludeTax
+	}
+	if req.PricesIncludeService != nil {
+		updates["prices_include_service"] = *req.PricesIncludeService
+	}
+	if req.Notes != nil {
+		notes, err := billNotes(*req.Notes)
+		if err != nil {
+			return nil, err
+		}
+		updates["notes"] = notes
+	}
+	if req.Metadata != nil {
+		updates["metadata"] = *req.Metadata
+	}
+	if req.CallbackURL != nil {
+		// An empty callback_url removes the callback
+		sealed, err := s.SealCallbackURL(*req.CallbackURL)
+		if err != nil {
+			return nil, err
+		}
+		updates["callback_url"] = sealed
+	}
+
+	if len(updates) == 0 {
+		return nil, ErrNoChanges
+	}
+
+	// payer_absorbs needs someone to absorb the leftover cents
+	if req.RoundingMode != nil && *req.RoundingMode == RoundingPayerAbsorbs &&
+		req.PayerParticipantID == nil && bill.PayerParticipantID == nil {
+		return nil, fmt.Errorf("%w: payer_participant_id is required for the payer_absorbs rounding mode", ErrInvalidUpdate)
+	}
+	return updates, nil
+}
+
+// billNotes trims a bill's notes and checks what is left against
+// models.MaxBillNotesLength, counting characters rather than bytes
+func billNotes(notes string) (string, error) {
+	notes = strings.TrimSpace(notes)
+	if utf8.RuneCountInString(notes) > models.MaxBillNotesLength {
+		return "", ErrNotesTooLong
+	}
+	return notes, nil
+}
+
+// participantUpdates turns a partial participant update into the columns to write. The
+// payment columns depend on the participant's share and are left to paymentColumns. A
+// request that sets nothing is ErrNoChanges, and a blank name, a payment status while
+// payments are switched off or an amount_paid without a payment status is ErrInvalidUpdate.
+func (s *BillService) participantUpdates(req *models.ParticipantUpdateRequest) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name must not be blank", ErrInvalidUpdate)
+		}
+		updates["name"] = name
+	}
+	if req.ShareOfCommonCosts != nil {
+		updates["share_of_common_costs"] = *req.ShareOfCommonCosts
+	}
+	if req.PaymentStatus != nil {
+		if !s.features.Enabled(config.FeaturePayments) {
+			return nil, fmt.Errorf("%w: payment_status can't be changed while payments are disabled", ErrInvalidUpdate)
+		}
+		updates["payment_status"] = *req.PaymentStatus
+	} else if req.AmountPaid != nil {
+		return nil, fmt.Errorf("%w: amount_paid can only be sent with payment_status", ErrInvalidUpdate)
+	}
+	if req.Notes != nil {
+		updates["notes"] = strings.TrimSpace(*req.Notes)
+	}
+	if req.Tags != nil {
+		updates["tags"] = normalizeTags(req.Tags)
+	}
+	if req.Color != nil {
+		updates["color"] = strings.ToUpper(*req.Color)
+	}
+
+	if len(updates) == 0 {
+		return nil, ErrNoChanges
+	}
+	return updates, nil
+}
+
+// itemUpdates turns a partial item update into the columns to write, or ErrNoChanges when
+// it sets nothing
+func itemUpdates(req *models.ItemUpdateRequest) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+	}
+	if req.Quantity != nil {
+		updates["quantity"] = roundQuantity(*req.Quantity)
+	}
+	if req.DiscountAmount != nil {
+		updates["discount_amount"] = *req.DiscountAmount
+	}
+	if req.Category != nil {
+		updates["category"] = NormalizeCategory(*req.Category)
+	}
+
+	if len(updates) == 0 {
+		return nil, ErrNoChanges
+	}
+	markReviewed(updates)
+	return updates, nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// ptr returns a pointer to v, for the optional fields of partial updates
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestUpdateBill(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, _, _ := seedAssignedBill(t, s)
+
+	bill, err := s.UpdateBill(billID, &models.BillUpdateRequest{Name: ptr("  Team lunch "), TipAmount: ptr(5.0)}, "test", nil)
+	if err != nil {
+		t.Fatalf("UpdateBill: %v", err)
+	}
+	if bill.Name != "Team lunch" || bill.TipAmount != 5 {
+		t.Errorf("bill = %q with tip %v, want %q with tip 5", bill.Name, bill.TipAmount, "Team lunch")
+	}
+	// The stored totals follow in the same write
+	if bill.ItemSubtotal != 15 || bill.GrandTotal != 20 {
+		t.Errorf("totals = %v/%v, want 15/20", bill.ItemSubtotal, bill.GrandTotal)
+	}
+
+	var events int64
+	db.Model(&models.BillEvents{}).Where("bill_id = ? AND action = ?", billID, EventBillUpdated).Count(&events)
+	if events != 1 {
+		t.Errorf("%d bill update events, want 1", events)
+	}
+
+	tests := []struct {
+		name    string
+		billID  uuid.UUID
+		req     models.BillUpdateRequest
+		version *int64
+		want    error
+	}{
+		{name: "missing bill", billID: uuid.New(), req: models.BillUpdateRequest{TipAmount: ptr(1.0)}, want: ErrNotFound},
+		{name: "no fields", billID: billID, want: ErrNoChanges},
+		{name: "blank name", billID: billID, req: models.BillUpdateRequest{Name: ptr("  ")}, want: ErrInvalidUpdate},
+		{name: "stale version", billID: billID, req: models.BillUpdateRequest{TipAmount: ptr(8.0)}, version: ptr(bill.Version() - 1), want: ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UpdateBill(tt.billID, &tt.req, "test", tt.version); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	var stored models.Bills
+	if err := db.First(&stored, "id = ?", billID).Error; err != nil {
+		t.Fatalf("failed to reload bill: %v", err)
+	}
+	if stored.TipAmount != 5 {
+		t.Errorf("tip = %v after refused updates, want 5", stored.TipAmount)
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, _ := seedAssignedBill(t, s)
+	otherID, otherItems, _ := seedAssignedBill(t, s)
+	noodles := items[0]
+
+	item, err := s.UpdateItem(billID, noodles, &models.ItemUpdateRequest{Price: ptr(14.0)}, "test", nil)
+	if err != nil {
+		t.Fatalf("UpdateItem: %v", err)
+	}
+	if item.Price != 14 {
+		t.Errorf("price = %v, want 14", item.Price)
+	}
+	var bill models.Bills
+	if err := db.First(&bill, "id = ?", billID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+	if bill.ItemSubtotal != 17 {
+		t.Errorf("subtotal = %v, want 17", bill.ItemSubtotal)
+	}
+
+	tests := []struct {
+		name    string
+		billID  uuid.UUID
+		itemID  uint
+		req     models.ItemUpdateRequest
+		version *int64
+		want    error
+	}{
+		{name: "missing item", billID: billID, itemID: 1 << 30, req: models.ItemUpdateRequest{Price: ptr(1.0)}, want: ErrNotFound},
+		{name: "item of another bill", billID: billID, itemID: otherItems[0], req: models.ItemUpdateRequest{Price: ptr(1.0)}, want: ErrNotFound},
+		{name: "no fields", billID: billID, itemID: noodles, want: ErrNoChanges},
+		{name: "discount over the price", billID: billID, itemID: noodles, req: models.ItemUpdateRequest{DiscountAmount: ptr(20.0)}, want: ErrInvalidItemDiscount},
+		{name: "stale version", billID: billID, itemID: noodles, req: models.ItemUpdateRequest{Price: ptr(9.0)}, version: ptr(item.Version() - 1), want: ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UpdateItem(tt.billID, tt.itemID, &tt.req, "test", tt.version); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Nothing refused above was written, on either bill
+	var stored models.Items
+	if err := db.First(&stored, noodles).Error; err != nil {
+		t.Fatalf("failed to reload item: %v", err)
+	}
+	if stored.Price != 14 || stored.DiscountAmount != 0 {
+		t.Errorf("noodles = %v less %v, want 14 less 0", stored.Price, stored.DiscountAmount)
+	}
+	if err := db.First(&stored, otherItems[0]).Error; err != nil {
+		t.Fatalf("failed to load the other bill's item: %v", err)
+	}
+	if stored.BillID != otherID || stored.Price != 12 {
+		t.Errorf("other bill's noodles = %v, want 12", stored.Price)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// ItemAssignments returns every assignment of a bill's items, from the view cache when it
+// holds a current list
+func (s *BillService) ItemAssignments(billID uuid.UUID) ([]models.ItemAssignments, error) {
+	if assignments, ok := s.views.Assignments(billID); ok {
+		return assignments, nil
+	}
+
+	generation := s.views.Generation()
+	assignments, err := s.itemAssignments(billID)
+	if err != nil {
+		return nil, err
+	}
+	s.views.SetAssignments(billID, generation, assignments)
+	return assignments, nil
+}
+
+// itemAssignments loads every assignment of a bill's items from the database. A bill
+// without items has no assignments at all, which is a nil slice.
+func (s *BillService) itemAssignments(billID uuid.UUID) ([]models.ItemAssignments, error) {
+	var itemIDs []uint
+	if err := s.db.Model(&models.Items{}).Scopes(ScopeBill(billID)).Pluck("id", &itemIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch items: %w", err)
+	}
+
+	var assignments []models.ItemAssignments
+	if len(itemIDs) == 0 {
+		return assignments, nil
+	}
+	if err := s.db.Where("item_id IN ?", itemIDs).Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch item assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+// warmBillViews computes and caches a bill's summary and assignments in the background,
+// so the client's first poll after extraction doesn't run the aggregate queries while the
+// user waits. It gives up if the bill changes again first; the next read computes the
+// views then. Nothing happens when the view cache is disabled.
+func (s *BillService) warmBillViews(billID uuid.UUID) {
+	if !s.views.Enabled() {
+		return
+	}
+
+	generation := s.views.Generation()
+	go func() {
+		if s.views.Stale(billID, generation) {
+			return
+		}
+		if _, ok := s.views.Summary(billID); !ok {
+			summary, err := s.billSummary(billID)
+			if err != nil {
+				fmt.Printf("Failed to warm summary of bill %s: %v\n", billID, err)
+				return
+			}
+			s.views.SetSummary(billID, generation, summary)
+		}
+
+		if s.views.Stale(billID, generation) {
+			return
+		}
+		if _, ok := s.views.Assignments(billID); !ok {
+			assignments, err := s.itemAssignments(billID)
+			if err != nil {
+				fmt.Printf("Failed to warm assignments of bill %s: %v\n", billID, err)
+				return
+			}
+			s.views.SetAssignments(billID, generation, assignments)
+		}
+	}()
+}
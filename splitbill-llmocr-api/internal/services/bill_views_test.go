@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// changeBehindTheCache raises the noodles' price and drops Ana's share of them straight in
+// the database, without the activity event a service write would record
+func changeBehindTheCache(t *testing.T, db *gorm.DB, noodles, ana uint) {
+	t.Helper()
+
+	if err := db.Model(&models.Items{}).Where("id = ?", noodles).Update("price", 20).Error; err != nil {
+		t.Fatalf("failed to change the price: %v", err)
+	}
+	if err := db.Where("item_id = ? AND participant_id = ?", noodles, ana).Delete(&models.ItemAssignments{}).Error; err != nil {
+		t.Fatalf("failed to drop the assignment: %v", err)
+	}
+}
+
+// billViews reads the bill's summary total and assignment count through the service
+func billViews(t *testing.T, s *BillService, billID uuid.UUID) (float64, int) {
+	t.Helper()
+
+	summary, err := s.GetBillSummary(billID)
+	if err != nil {
+		t.Fatalf("GetBillSummary: %v", err)
+	}
+	assignments, err := s.ItemAssignments(billID)
+	if err != nil {
+		t.Fatalf("ItemAssignments: %v", err)
+	}
+	return summary.TotalItems, len(assignments)
+}
+
+func TestBillViewsCachedUntilEvent(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+
+	if total, assigned := billViews(t, s, billID); total != 15 || assigned != 3 {
+		t.Fatalf("first read = total %v, %d assignments; want 15 and 3", total, assigned)
+	}
+
+	// The second read is served from the cache, so it misses a change made behind it
+	changeBehindTheCache(t, db, items[0], participants[0])
+	if total, assigned := billViews(t, s, billID); total != 15 || assigned != 3 {
+		t.Errorf("second read = total %v, %d assignments; want the cached 15 and 3", total, assigned)
+	}
+
+	// Recording the change drops the cached views
+	s.recordEvent(billID, "test", EventBillUpdated, nil)
+	if total, assigned := billViews(t, s, billID); total != 23 || assigned != 2 {
+		t.Errorf("read after the event = total %v, %d assignments; want 23 and 2", total, assigned)
+	}
+}
+
+func TestBillViewsUncached(t *testing.T) {
+	s, db := newTestBillService(t, func(cfg *config.Config) {
+		cfg.ViewCacheTTL = 0
+	})
+	billID, items, participants := seedAssignedBill(t, s)
+
+	billViews(t, s, billID)
+	changeBehindTheCache(t, db, items[0], participants[0])
+	if total, assigned := billViews(t, s, billID); total != 23 || assigned != 2 {
+		t.Errorf("read with the cache off = total %v, %d assignments; want 23 and 2", total, assigned)
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRestoreExpired is returned when a deleted item or participant is restored after the
+// restore window has passed
+var ErrRestoreExpired = errors.New("restore window has passed")
+
+// restoreItemAssignments brings back the assignments deleted with an item and returns
+// how many. Only deleting an item sets assignments aside: removing one by hand, merging
+// and deleting a participant remove them for good.
+func restoreItemAssignments(tx *gorm.DB, itemID uint) (int64, error) {
+	result := tx.Unscoped().Model(&models.ItemAssignments{}).
+		Where("item_id = ? AND deleted_at IS NOT NULL", itemID).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to restore item assignments: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RestoreItem brings back an item deleted within the restore window, together with the
+// assignments deleted with it, and refreshes the bill's stored totals. An item that isn't
+// deleted is returned as it is. Items of other or deleted bills, and items deleted for
+// good, are ErrNotFound; items can't be restored while the bill's image is being extracted.
+func (s *BillService) RestoreItem(billID uuid.UUID, itemID uint, actor string) (*models.ItemResponse, error) {
+	var item models.Items
+	var assignments int64
+	restored := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ScopeBill(billID)).Where("id = ?", itemID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find item: %w", err)
+		}
+		if !item.DeletedAt.Valid {
+			return nil
+		}
+		if err := s.checkRestorable(item.DeletedAt.Time); err != nil {
+			return err
+		}
+
+		var bill models.Bills
+		if err := tx.Select("id", "status").First(&bill, "id = ?", billID).Error; err != nil {
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if BillStatus(bill.Status).Extracting() {
+			return fmt.Errorf("bill %s is %s: %w", billID, bill.Status, ErrInvalidStatus)
+		}
+
+		if err := tx.Unscoped().Model(&item).Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to restore item: %w", err)
+		}
+		var err error
+		if assignments, err = restoreItemAssignments(tx, item.ID); err != nil {
+			return err
+		}
+		if err := tx.First(&item, item.ID).Error; err != nil {
+			return fmt.Errorf("failed to fetch restored item: %w", err)
+		}
+		restored = true
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if restored {
+		s.recordEvent(billID, actor, EventItemRestored, models.EventPayload{
+			"item_id":              item.ID,
+			"name":                 item.Name,
+			"assignments_restored": assignments,
+		})
+	}
+	response := itemResponse(&item)
+	return &response, nil
+}
+
+// RestoreParticipant brings back a participant deleted within the restore window, in
+// their old place in the participant order, and refreshes the bill's stored totals. Their
+// item assignments and payer roles were removed for good, so they come back with none. A
+// participant that isn't deleted is returned as they are. Participants of other or
+// deleted bills, and participants deleted for good, are ErrNotFound; participants can't
+// be restored while the bill's image is being extracted.
+func (s *BillService) RestoreParticipant(billID uuid.UUID, participantID uint, actor string) (*models.Participants, error) {
+	var participant models.Participants
+	restored := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ScopeBill(billID)).Where("id = ?", participantID).First(&participant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find participant: %w", err)
+		}
+		if !participant.DeletedAt.Valid {
+			return nil
+		}
+		if err := s.checkRestorable(participant.DeletedAt.Time); err != nil {
+			return err
+		}
+
+		var bill models.Bills
+		if err := tx.Select("id", "status").First(&bill, "id = ?", billID).Error; err != nil {
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if BillStatus(bill.Status).Extracting() {
+			return fmt.Errorf("bill %s is %s: %w", billID, bill.Status, ErrInvalidStatus)
+		}
+
+		if err := tx.Unscoped().Model(&participant).Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to restore participant: %w", err)
+		}
+		if err := tx.First(&participant, participant.ID).Error; err != nil {
+			return fmt.Errorf("failed to fetch restored participant: %w", err)
+		}
+		restored = true
+		// A manual bill's total is the sum of its participants' shares
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if restored {
+		s.recordEvent(billID, actor, EventParticipantRestored, models.EventPayload{
+			"participant_id": participant.ID,
+			"name":           participant.Name,
+		})
+	}
+	return &participant, nil
+}
+
+// checkRestorable returns ErrRestoreExpired when a row deleted at deletedAt is past the
+// restore window
+func (s *BillService) checkRestorable(deletedAt time.Time) error {
+	if time.Since(deletedAt) > s.restoreWindow {
+		return fmt.Errorf("deleted at %s: %w", deletedAt.UTC().Format(time.RFC3339), ErrRestoreExpired)
+	}
+	return nil
+}
+
+// PurgeDeletedRows removes for good the items and participants deleted before now minus
+// retention, and returns how many of each were removed. The assignments set aside with
+// the items go first; participants' assignments and payer roles went when they were deleted.
+func (s *BillService) PurgeDeletedRows(now time.Time, retention time.Duration) (int64, int64, error) {
+	cutoff := now.Add(-retention)
+
+	if err := s.db.Unscoped().
+		Where("item_id IN (?)", s.db.Unscoped().Model(&models.Items{}).Select("id").Where("deleted_at < ?", cutoff)).
+		Delete(&models.ItemAssignments{}).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to purge deleted item assignments: %w", err)
+	}
+	items := s.db.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Items{})
+	if items.Error != nil {
+		return 0, 0, fmt.Errorf("failed to purge deleted items: %w", items.Error)
+	}
+	participants := s.db.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Participants{})
+	if participants.Error != nil {
+		return items.RowsAffected, 0, fmt.Errorf("failed to purge deleted participants: %w", participants.Error)
+	}
+	return items.RowsAffected, participants.RowsAffected, nil
+}
+
+// StartDeletedRowPurger runs PurgeDeletedRows every interval until ctx is cancelled.
+// Nothing is started when the configured retention is zero.
+func (s *BillService) StartDeletedRowPurger(ctx context.Context, interval time.Duration) {
+	if s.deletedRowRetention <= 0 {
+		fmt.Println("Deleted item and participant purge disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				items, participants, err := s.PurgeDeletedRows(now, s.deletedRowRetention)
+				if err != nil {
+					fmt.Printf("Deleted row purge failed: %v\n", err)
+					continue
+				}
+				fmt.Printf("Deleted row purge: %d item(s) and %d participant(s) removed\n", items, participants)
+			}
+		}
+	}()
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// liveAssignments returns every assignment in the database that is not deleted, keyed by
+// item and participant
+func liveAssignments(t *testing.T, db *gorm.DB) map[[2]uint]bool {
+	t.Helper()
+	var assignments []models.ItemAssignments
+	if err := db.Find(&assignments).Error; err != nil {
+		t.Fatalf("failed to load assignments: %v", err)
+	}
+	got := make(map[[2]uint]bool, len(assignments))
+	for _, a := range assignments {
+		got[[2]uint{a.ItemID, a.ParticipantID}] = true
+	}
+	return got
+}
+
+// seedAssignedBill creates a bill with two items and two participants, Ana sharing the
+// noodles with Ben and Ben having the tea
+func seedAssignedBill(t *testing.T, s *BillService) (uuid.UUID, [2]uint, [2]uint) {
+	t.Helper()
+	bill := createTestBill(t, s, "Lunch", nil)
+
+	items, err := s.CreateItems(bill.ID, []models.ItemRequest{
+		{Name: "Noodles", Price: 12, Quantity: 1},
+		{Name: "Tea", Price: 3, Quantity: 1},
+	}, "test")
+	if err != nil {
+		t.Fatalf("failed to create items: %v", err)
+	}
+	var participants [2]uint
+	for i, name := range []string{"Ana", "Ben"} {
+		participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: name}, "test")
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		participants[i] = participant.ID
+	}
+	itemIDs := [2]uint{items[0].ID, items[1].ID}
+	for _, a := range [][2]uint{{itemIDs[0], participants[0]}, {itemIDs[0], participants[1]}, {itemIDs[1], participants[1]}} {
+		if _, err := s.AssignItem(bill.ID, a[0], a[1], 1, "test"); err != nil {
+			t.Fatalf("failed to assign item %d: %v", a[0], err)
+		}
+	}
+	return bill.ID, itemIDs, participants
+}
+
+func TestRestoreParticipantLeavesAssignmentsGone(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+	ana, ben := participants[0], participants[1]
+
+	if _, err := s.DeleteParticipant(billID, ben, "test"); err != nil {
+		t.Fatalf("DeleteParticipant: %v", err)
+	}
+	restored, err := s.RestoreParticipant(billID, ben, "test")
+	if err != nil {
+		t.Fatalf("RestoreParticipant: %v", err)
+	}
+	if restored.DeletedAt.Valid {
+		t.Fatalf("participant %d is still deleted", ben)
+	}
+
+	// Ben is back with nothing assigned; Ana keeps her share of the noodles
+	want := map[[2]uint]bool{{items[0], ana}: true}
+	if got := liveAssignments(t, db); len(got) != len(want) || !got[[2]uint{items[0], ana}] {
+		t.Errorf("assignments after restoring Ben = %v, want %v", got, want)
+	}
+	var kept int64
+	db.Unscoped().Model(&models.ItemAssignments{}).Where("participant_id = ?", ben).Count(&kept)
+	if kept != 0 {
+		t.Errorf("%d of Ben's assignments were kept, want none", kept)
+	}
+}
+
+func TestRestoreItemBringsBackAssignments(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+	noodles, tea := items[0], items[1]
+	ana, ben := participants[0], participants[1]
+
+	// Ana's noodles are unassigned by hand, so they are gone for good
+	if err := s.UnassignItem(billID, noodles, ana, "test"); err != nil {
+		t.Fatalf("UnassignItem: %v", err)
+	}
+	if err := s.DeleteItem(billID, noodles, "test"); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	if got := liveAssignments(t, db); len(got) != 1 || !got[[2]uint{tea, ben}] {
+		t.Fatalf("assignments after deleting the noodles = %v, want only Ben's tea", got)
+	}
+
+	if _, err := s.RestoreItem(billID, noodles, "test"); err != nil {
+		t.Fatalf("RestoreItem: %v", err)
+	}
+	want := map[[2]uint]bool{{noodles, ben}: true, {tea, ben}: true}
+	if got := liveAssignments(t, db); len(got) != len(want) || !got[[2]uint{noodles, ben}] || !got[[2]uint{tea, ben}] {
+		t.Errorf("assignments after restoring the noodles = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreParticipantWhileExtracting(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Brunch", nil)
+
+	participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ana"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	if _, err := s.DeleteParticipant(bill.ID, participant.ID, "test"); err != nil {
+		t.Fatalf("DeleteParticipant: %v", err)
+	}
+	if err := db.Model(&models.Bills{}).Where("id = ?", bill.ID).UpdateColumn("status", string(StatusProcessing)).Error; err != nil {
+		t.Fatalf("failed to mark bill processing: %v", err)
+	}
+
+	if _, err := s.RestoreParticipant(bill.ID, participant.ID, "test"); !errors.Is(err, ErrInvalidStatus) {
+		t.Errorf("RestoreParticipant err = %v, want ErrInvalidStatus", err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// extractionFailureThreshold is how many n8n triggers in a row must fail before
+// extraction is reported as unavailable
+const extractionFailureThreshold = 3
+
+// ExtractionStatus is a snapshot of how the extraction pipeline is doing
+type ExtractionStatus struct {
+	Enabled             bool       `json:"enabled"`
+	Available           bool       `json:"available"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastFailureAt       *time.Time `json:"last_failure_at"`
+	Queued              int        `json:"queued"`
+}
+
+// extractionHealth tracks n8n outcomes in memory so status checks never probe n8n
+type extractionHealth struct {
+	mu                  sync.Mutex
+	consecutiveFailures int
+	lastSuccessAt       *time.Time
+	lastFailureAt       *time.Time
+}
+
+// recordTriggered notes that n8n accepted an image
+func (h *extractionHealth) recordTriggered() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.consecutiveFailures = 0
+}
+
+// recordFailure notes that n8n could not be reached or rejected an image
+func (h *extractionHealth) recordFailure() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.consecutiveFailures++
+	h.lastFailureAt = &now
+}
+
+// recordSuccess notes that extracted data came back and was stored
+func (h *extractionHealth) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.consecutiveFailures = 0
+	h.lastSuccessAt = &now
+}
+
+// snapshot returns the current counters
+func (h *extractionHealth) snapshot() ExtractionStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return ExtractionStatus{
+		Available:           h.consecutiveFailures < extractionFailureThreshold,
+		ConsecutiveFailures: h.consecutiveFailures,
+		LastSuccessAt:       h.lastSuccessAt,
+		LastFailureAt:       h.lastFailureAt,
+	}
+}
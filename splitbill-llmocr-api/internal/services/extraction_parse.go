@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/localenum"
+)
+
+// Callback body shapes the extraction callback accepts. A change to the structure a shape
+// accepts gets a new version, and every shape has recorded fixtures under internal/server/testdata/n8n.
+const (
+	// CallbackShapeDirect is the extracted data itself, tagged with code API_SPLITBILL_LLMOCR
+	CallbackShapeDirect = "direct/v1"
+	// CallbackShapeWrapped carries the extracted data as a JSON string in extracted_data
+	CallbackShapeWrapped = "wrapped/v1"
+)
+
+// maxQuantity bounds extracted quantities; no receipt line legitimately comes near it
+const maxQuantity = 1_000_000
+
+// rawExtractedData mirrors models.ExtractedItemData but keeps amounts undecoded so they
+// can arrive as numbers, scientific notation or strings
+type rawExtractedData struct {
+	Items                []rawExtractedItem `json:"items"`
+	Tax                  interface{}        `json:"tax"`
+	Tip                  interface{}        `json:"tip"`
+	Total                interface{}        `json:"total"`
+	PricesIncludeTax     bool               `json:"prices_include_tax"`
+	PricesIncludeService bool               `json:"prices_include_service"`
+}
+
+// rawExtractedItem mirrors models.ExtractedItem with undecoded amounts. Price is either a
+// unit price or a line total and is only read when neither unit_price nor line_total is
+// given. Discount is a line-level promotion, optional like the bill-level amounts.
+// Category is kept only when it is a string.
+type rawExtractedItem struct {
+	Name      string      `json:"name"`
+	Price     interface{} `json:"price"`
+	UnitPrice interface{} `json:"unit_price"`
+	LineTotal interface{} `json:"line_total"`
+	Quantity  interface{} `json:"quantity"`
+	Discount  interface{} `json:"discount"`
+	Category  interface{} `json:"category"`
+}
+
+// decodeJSON decodes data keeping numbers as json.Number, so nothing is rounded through
+// float64 before the extraction parser sees it
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// maxCallbackFieldLength bounds a single string field of the callback body; anything longer
+// is an embedded image or file, not extraction output
+const maxCallbackFieldLength = 64 << 10
+
+// stripImageFields removes top-level callback fields that carry images, such as a base64
+// copy of the receipt echoed back by the workflow, so they are never parsed or stored. It
+// returns the removed field names in order.
+func stripImageFields(rawData map[string]interface{}) []string {
+	var stripped []string
+	for key, value := range rawData {
+		if key == "extracted_data" {
+			continue
+		}
+		text, isString := value.(string)
+		if strings.Contains(strings.ToLower(key), "image") ||
+			(isString && (strings.HasPrefix(text, "data:") || len(text) > maxCallbackFieldLength)) {
+			delete(rawData, key)
+			stripped = append(stripped, key)
+		}
+	}
+	sort.Strings(stripped)
+	return stripped
+}
+
+// ParseExtractionCallback parses a callback body exactly as the /process-data route does,
+// without touching any bill, and reports which shape it matched. code and maxAmount are
+// the currency and amount limit of the bill it would be stored on.
+func ParseExtractionCallback(body []byte, code string, maxAmount int64) (string, models.ExtractedItemData, error) {
+	var rawData map[string]interface{}
+	if err := decodeJSON(body, &rawData); err != nil {
+		return "", models.ExtractedItemData{}, fmt.Errorf("%w: invalid JSON: %v", ErrInvalidPayload, err)
+	}
+
+	shape, extractedData, err := extractedDataFromCallback(rawData)
+	if err != nil {
+		return "", models.ExtractedItemData{}, err
+	}
+
+	data, err := parseExtractedData(extractedData, code, maxAmount)
+	if err != nil {
+		return shape, models.ExtractedItemData{}, err
+	}
+	return shape, data, nil
+}
+
+// parseExtractedData decodes the extracted data JSON from n8n. Every amount is rounded to
+// whole minor units of code and must lie between zero and maxAmount; a bad value is
+// reported with the item and field it came from. Rows without a positive price and
+// quantity are skipped rather than stored, and data left with no items is rejected.
+// Each item's unit price and line total are reconciled as reconcileLinePrices describes.
+func parseExtractedData(data string, code string, maxAmount int64) (models.ExtractedItemData, error) {
+	var raw rawExtractedData
+	if err := decodeJSON([]byte(data), &raw); err != nil {
+		return models.ExtractedItemData{}, fmt.Errorf("%w: failed to parse extracted data: %v", ErrInvalidPayload, err)
+	}
+
+	parsed := models.ExtractedItemData{
+		Items:                make([]models.ExtractedItem, 0, len(raw.Items)),
+		PricesIncludeTax:     raw.PricesIncludeTax,
+		PricesIncludeService: raw.PricesIncludeService,
+	}
+
+	amounts := []struct {
+		field string
+		value interface{}
+		dest  *float64
+	}{
+		{"tax", raw.Tax, &parsed.Tax},
+		{"tip", raw.Tip, &parsed.Tip},
+		{"total", raw.Total, &parsed.Total},
+	}
+	for _, amount := range amounts {
+		value, err := parseAmount(amount.value, code, maxAmount)
+		if err != nil {
+			return models.ExtractedItemData{}, fmt.Errorf("%w: %s: %v", ErrInvalidPayload, amount.field, err)
+		}
+		*amount.dest = value
+	}
+
+	var readings []linePrices
+	for i, item := range raw.Items {
+		skip := func(reason string) {
+			parsed.Skipped = append(parsed.Skipped, models.SkippedExtractedItem{Index: i, Name: item.Name, Reason: reason})
+		}
+		if reason := itemSkipReason(item); reason != "" {
+			skip(reason)
+			continue
+		}
+
+		unitPrice, err := parseAmount(item.UnitPrice, code, maxAmount)
+		if err != nil {
+			return models.ExtractedItemData{}, fmt.Errorf("%w: items[%d] (%q) unit_price: %v", ErrInvalidPayload, i, item.Name, err)
+		}
+		lineTotal, err := parseAmount(item.LineTotal, code, maxAmount)
+		if err != nil {
+			return models.ExtractedItemData{}, fmt.Errorf("%w: items[%d] (%q) line_total: %v", ErrInvalidPayload, i, item.Name, err)
+		}
+		var price float64
+		if unitPrice == 0 && lineTotal == 0 {
+			price, err = parseAmount(item.Price, code, maxAmount)
+			if err != nil {
+				return models.ExtractedItemData{}, fmt.Errorf("%w: items[%d] (%q) price: %v", ErrInvalidPayload, i, item.Name, err)
+			}
+		}
+		quantity, err := parseQuantity(item.Quantity)
+		if err != nil {
+			return models.ExtractedItemData{}, fmt.Errorf("%w: items[%d] (%q) quantity: %v", ErrInvalidPayload, i, item.Name, err)
+		}
+		// A price below half a minor unit rounds to nothing
+		reading, ok := readLinePrices(
+			currency.ToMinor(unitPrice, code),
+			currency.ToMinor(lineTotal, code),
+			currency.ToMinor(price, code),
+			quantity,
+		)
+		if !ok {
+			skip(reasonPriceNotPositive)
+			continue
+		}
+		discount, err := parseAmount(item.Discount, code, maxAmount)
+		if err != nil {
+			return models.ExtractedItemData{}, fmt.Errorf("%w: items[%d] (%q) discount: %v", ErrInvalidPayload, i, item.Name, err)
+		}
+		reading.discount = currency.ToMinor(discount, code)
+		if reading.discount > reading.primary.line {
+			return models.ExtractedItemData{}, fmt.Errorf("%w: items[%d] (%q) discount: exceeds its line total", ErrInvalidPayload, i, item.Name)
+		}
+		category, _ := item.Category.(string)
+		parsed.Items = append(parsed.Items, models.ExtractedItem{
+			Name:           item.Name,
+			Quantity:       quantity,
+			DiscountAmount: discount,
+			Category:       strings.TrimSpace(category),
+		})
+		readings = append(readings, reading)
+	}
+
+	if len(parsed.Items) == 0 {
+		return models.ExtractedItemData{}, fmt.Errorf("%w: no valid items (%d skipped)", ErrInvalidPayload, len(parsed.Skipped))
+	}
+	reconcileLinePrices(&parsed, readings, code)
+	return parsed, nil
+}
+
+// Reasons an extracted row is skipped
+const (
+	reasonPriceNotPositive    = "price must be greater than zero"
+	reasonQuantityNotPositive = "quantity must be greater than zero"
+)
+
+// itemSkipReason returns why an extracted row cannot be stored as an item, or "" when its
+// price and quantity are both positive. Values that are not numbers at all are left for
+// the full parse to reject.
+func itemSkipReason(item rawExtractedItem) string {
+	if !hasPositivePrice(item) {
+		return reasonPriceNotPositive
+	}
+	// A quantity below half a thousandth rounds to nothing
+	if quantity, err := parseDecimal(item.Quantity); err == nil && (quantity == nil || quantity.Cmp(big.NewRat(1, 2*quantityScale)) < 0) {
+		return reasonQuantityNotPositive
+	}
+	return ""
+}
+
+// hasPositivePrice reports whether a row's price is positive. A positive unit_price or
+// line_total stands in for price, a zero one counts as missing and a negative one never
+// passes.
+func hasPositivePrice(item rawExtractedItem) bool {
+	explicit := false
+	for _, value := range []interface{}{item.UnitPrice, item.LineTotal} {
+		number, err := parseDecimal(value)
+		if err != nil {
+			return true
+		}
+		if number == nil || number.Sign() == 0 {
+			continue
+		}
+		if number.Sign() < 0 {
+			return false
+		}
+		explicit = true
+	}
+	if explicit {
+		return true
+	}
+	price, err := parseDecimal(item.Price)
+	return err != nil || (price != nil && price.Sign() > 0)
+}
+
+// parseAmount converts one decoded amount to a value holding exactly whole minor units of
+// code. Missing amounts are zero.
+func parseAmount(value interface{}, code string, maxAmount int64) (float64, error) {
+	number, err := parseDecimal(value)
+	if err != nil || number == nil {
+		return 0, err
+	}
+
+	if number.Sign() < 0 {
+		return 0, fmt.Errorf("%s must not be negative", number.FloatString(currency.Decimals(code)))
+	}
+	if number.Cmp(new(big.Rat).SetInt64(maxAmount)) > 0 {
+		return 0, fmt.Errorf("%s exceeds the maximum of %d", number.FloatString(currency.Decimals(code)), maxAmount)
+	}
+
+	// Round half up to whole minor units without going through float64
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currency.Decimals(code))), nil)
+	scaled := new(big.Rat).Mul(number, new(big.Rat).SetInt(scale))
+	scaled.Add(scaled, big.NewRat(1, 2))
+	units := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+
+	return currency.FromMinor(units.Int64(), code), nil
+}
+
+// parseQuantity converts one decoded quantity, which may be a weight such as 0.46, to a
+// value rounded half up to thousandths. Missing quantities are zero.
+func parseQuantity(value interface{}) (float64, error) {
+	number, err := parseDecimal(value)
+	if err != nil || number == nil {
+		return 0, err
+	}
+
+	if number.Sign() < 0 {
+		return 0, fmt.Errorf("%s must not be negative", number.FloatString(3))
+	}
+	if number.Cmp(new(big.Rat).SetInt64(maxQuantity)) > 0 {
+		return 0, fmt.Errorf("%s exceeds the maximum of %d", number.FloatString(3), maxQuantity)
+	}
+
+	scaled := new(big.Rat).Mul(number, new(big.Rat).SetInt64(quantityScale))
+	scaled.Add(scaled, big.NewRat(1, 2))
+	thousandths := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+	return fromThousandths(thousandths.Int64()), nil
+}
+
+// parseDecimal reads a JSON number or numeric string exactly, in the canonical form n8n
+// sends. It returns nil for a missing value or an empty string.
+func parseDecimal(value interface{}) (*big.Rat, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		text = v.String()
+	case string:
+		text = strings.TrimSpace(v)
+		if text == "" {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf("expected a number, got %T", value)
+	}
+
+	return localenum.Parse(text, "")
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseExtractionCallbackShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		shape string
+	}{
+		{
+			name:  "direct",
+			body:  `{"code":"API_SPLITBILL_LLMOCR","items":[{"name":"Tea","price":2.5,"quantity":2}],"tax":0.5}`,
+			shape: CallbackShapeDirect,
+		},
+		{
+			name:  "wrapped",
+			body:  `{"extracted_data":"{\"items\":[{\"name\":\"Tea\",\"price\":\"2.50\",\"quantity\":2}],\"tax\":\"0.5\"}"}`,
+			shape: CallbackShapeWrapped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shape, data, err := ParseExtractionCallback([]byte(tt.body), "USD", 1000)
+			if err != nil {
+				t.Fatalf("ParseExtractionCallback: %v", err)
+			}
+			if shape != tt.shape {
+				t.Errorf("shape = %s, want %s", shape, tt.shape)
+			}
+			if len(data.Items) != 1 || data.Items[0].Name != "Tea" || data.Items[0].Quantity != 2 || data.Tax != 0.5 {
+				t.Errorf("data = %+v", data)
+			}
+		})
+	}
+}
+
+func TestParseExtractionCallbackRejects(t *testing.T) {
+	for name, body := range map[string]string{
+		"invalid JSON":           `{`,
+		"missing extracted_data": `{"items":[]}`,
+		"extracted_data object":  `{"extracted_data":{}}`,
+		"no valid items":         `{"extracted_data":"{\"items\":[{\"name\":\"Free\",\"price\":0,\"quantity\":1}]}"}`,
+		"negative tax":           `{"extracted_data":"{\"items\":[{\"name\":\"Tea\",\"price\":1,\"quantity\":1}],\"tax\":-1}"}`,
+		"over the maximum":       `{"extracted_data":"{\"items\":[{\"name\":\"Tea\",\"price\":1001,\"quantity\":1}]}"}`,
+		"non-numeric price":      `{"extracted_data":"{\"items\":[{\"name\":\"Tea\",\"price\":\"abc\",\"quantity\":1}]}"}`,
+		"discount over the line": `{"extracted_data":"{\"items\":[{\"name\":\"Tea\",\"price\":1,\"quantity\":1,\"discount\":2}]}"}`,
+	} {
+		if _, _, err := ParseExtractionCallback([]byte(body), "USD", 1000); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: err = %v, want ErrInvalidPayload", name, err)
+		}
+	}
+}
+
+func TestParseExtractionCallbackSkipsRows(t *testing.T) {
+	body := `{"extracted_data":"{\"items\":[{\"name\":\"Tea\",\"price\":1,\"quantity\":1},{\"name\":\"Note\",\"price\":0,\"quantity\":1},{\"name\":\"Ghost\",\"price\":1,\"quantity\":0.0001}]}"}`
+	_, data, err := ParseExtractionCallback([]byte(body), "USD", 1000)
+	if err != nil {
+		t.Fatalf("ParseExtractionCallback: %v", err)
+	}
+	if len(data.Items) != 1 || len(data.Skipped) != 2 {
+		t.Fatalf("items %+v, skipped %+v", data.Items, data.Skipped)
+	}
+	if data.Skipped[0].Reason != reasonPriceNotPositive || data.Skipped[1].Reason != reasonQuantityNotPositive {
+		t.Errorf("skip reasons = %q, %q", data.Skipped[0].Reason, data.Skipped[1].Reason)
+	}
+}
+
+func TestParseAmountRoundsToMinorUnits(t *testing.T) {
+	tests := []struct {
+		code  string
+		value interface{}
+		want  float64
+	}{
+		{"USD", "12.345", 12.35},
+		{"USD", "12.344", 12.34},
+		{"USD", "1.5e1", 15},
+		{"IDR", "12500.5", 12501},
+		{"IDR", nil, 0},
+		{"IDR", " ", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.value, tt.code, 100000)
+		if err != nil || got != tt.want {
+			t.Errorf("parseAmount(%v, %s) = %v, %v; want %v", tt.value, tt.code, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseAmount(true, "USD", 100); err == nil {
+		t.Error("parseAmount accepted a boolean")
+	}
+}
+
+func TestParseQuantityRoundsToThousandths(t *testing.T) {
+	got, err := parseQuantity("0.4565")
+	if err != nil || got != 0.457 {
+		t.Errorf("parseQuantity(0.4565) = %v, %v; want 0.457", got, err)
+	}
+	if _, err := parseQuantity("-1"); err == nil {
+		t.Error("parseQuantity accepted a negative quantity")
+	}
+	if _, err := parseQuantity("1000001"); err == nil {
+		t.Error("parseQuantity accepted a quantity over the maximum")
+	}
+}
+
+func TestStripImageFields(t *testing.T) {
+	raw := map[string]interface{}{
+		"image_base64":   "abc",
+		"receipt":        "data:image/png;base64,AAAA",
+		"extracted_data": "data:kept",
+		"status":         "ok",
+	}
+	stripped := stripImageFields(raw)
+	if len(stripped) != 2 || stripped[0] != "image_base64" || stripped[1] != "receipt" {
+		t.Errorf("stripped = %v", stripped)
+	}
+	if _, ok := raw["extracted_data"]; !ok {
+		t.Error("extracted_data was stripped")
+	}
+	if _, ok := raw["status"]; !ok {
+		t.Error("status was stripped")
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrQueueFull is returned when too many extractions are already waiting
+var ErrQueueFull = errors.New("extraction queue is full")
+
+// extractionJob is an uploaded image waiting to be sent to the n8n workflow
+type extractionJob struct {
+	billID    uuid.UUID
+	imageData []byte
+	filename  string
+	queuedAt  time.Time
+	timer     *StageTimer
+}
+
+// extractionQueue limits how many n8n extractions run at once. Jobs beyond the
+// limit wait in FIFO order and are drained by a fixed pool of workers.
+type extractionQueue struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	pending     []*extractionJob
+	maxPending  int
+	concurrency int
+	closed      bool
+}
+
+// newExtractionQueue creates a queue with the given worker count and pending capacity
+func newExtractionQueue(concurrency, maxPending int) *extractionQueue {
+	q := &extractionQueue{
+		maxPending:  maxPending,
+		concurrency: concurrency,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// enqueue adds a job and returns its 1-based queue position. A bill that is
+// already waiting keeps its place and has its image replaced.
+func (q *extractionQueue) enqueue(job *extractionJob) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, pending := range q.pending {
+		if pending.billID == job.billID {
+			job.queuedAt = pending.queuedAt
+			q.pending[i] = job
+			return i + 1, nil
+		}
+	}
+
+	if len(q.pending) >= q.maxPending {
+		return 0, ErrQueueFull
+	}
+
+	q.pending = append(q.pending, job)
+	q.cond.Signal()
+	return len(q.pending), nil
+}
+
+// position returns the 1-based queue position of a bill that is still waiting
+func (q *extractionQueue) position(billID uuid.UUID) (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, job := range q.pending {
+		if job.billID == billID {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// remove drops a bill's job if it is still waiting
+func (q *extractionQueue) remove(billID uuid.UUID) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, job := range q.pending {
+		if job.billID == billID {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// length returns how many jobs are waiting
+func (q *extractionQueue) length() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// next blocks until a job is available or the queue is closed
+func (q *extractionQueue) next() (*extractionJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+
+	job := q.pending[0]
+	q.pending[0] = nil
+	q.pending = q.pending[1:]
+	return job, true
+}
+
+// start launches the worker pool. Workers stop once ctx is cancelled.
+func (q *extractionQueue) start(ctx context.Context, run func(*extractionJob)) {
+	for i := 0; i < q.concurrency; i++ {
+		go func() {
+			for {
+				job, ok := q.next()
+				if !ok {
+					return
+				}
+				run(job)
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		q.mu.Lock()
+		q.closed = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}()
+}
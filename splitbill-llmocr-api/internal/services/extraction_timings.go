@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Outcomes of an extraction_timings row
+const (
+	TimingsProcessing = "processing"
+	TimingsCompleted  = "completed"
+	TimingsFailed     = "failed"
+)
+
+// maxStatsAttempts bounds how many extractions the stage percentiles are computed from
+const maxStatsAttempts = 10000
+
+// saveStageTimings records the stages an extraction has been through with outcome, which
+// becomes failed when failedStage is set. It returns the row's ID, or zero when it could
+// not be written; timings are diagnostics and never fail the extraction.
+func (s *BillService) saveStageTimings(billID uuid.UUID, timer *StageTimer, outcome, failedStage string) uint {
+	row := models.ExtractionTimings{
+		BillID:      billID,
+		Stages:      timer.Timings(),
+		Outcome:     outcome,
+		FailedStage: failedStage,
+	}
+	if failedStage != "" {
+		row.Outcome = TimingsFailed
+	}
+	if err := s.db.Create(&row).Error; err != nil {
+		fmt.Printf("Failed to record extraction timings for bill %s: %v\n", billID, err)
+		return 0
+	}
+	return row.ID
+}
+
+// mergeStageTimings adds the timer's stages to an existing row. A failedStage marks the
+// row failed; the callback can land while the provider call is still being timed, so an
+// outcome is only ever set here for failures.
+func (s *BillService) mergeStageTimings(id uint, timer *StageTimer, failedStage string) {
+	updates := map[string]interface{}{}
+	if err := s.mergeStagesInto(updates, timer); err != nil {
+		fmt.Printf("Failed to encode extraction timings: %v\n", err)
+		return
+	}
+	if failedStage != "" {
+		updates["outcome"] = TimingsFailed
+		updates["failed_stage"] = failedStage
+	}
+	if err := s.db.Model(&models.ExtractionTimings{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		fmt.Printf("Failed to update extraction timings %d: %v\n", id, err)
+	}
+}
+
+// finishStageTimings adds the callback's stages to the bill's extraction that is awaiting
+// it and sets the outcome. Without such a row, for instance after a restart, the callback
+// stages are recorded on their own.
+func (s *BillService) finishStageTimings(billID uuid.UUID, timer *StageTimer, failedStage string) {
+	var row models.ExtractionTimings
+	err := s.db.Select("id").
+		Where("bill_id = ? AND outcome = ?", billID, TimingsProcessing).
+		Order("id DESC").
+		First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.saveStageTimings(billID, timer, TimingsCompleted, failedStage)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Failed to find extraction timings for bill %s: %v\n", billID, err)
+		return
+	}
+
+	updates := map[string]interface{}{"outcome": TimingsCompleted}
+	if failedStage != "" {
+		updates["outcome"] = TimingsFailed
+		updates["failed_stage"] = failedStage
+	}
+	if err := s.mergeStagesInto(updates, timer); err != nil {
+		fmt.Printf("Failed to encode extraction timings: %v\n", err)
+		return
+	}
+	if err := s.db.Model(&models.ExtractionTimings{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+		fmt.Printf("Failed to update extraction timings %d: %v\n", row.ID, err)
+	}
+}
+
+// abandonStageTimings marks the bill's extraction that is awaiting a callback as failed
+// in stage, for callbacks that will never come
+func (s *BillService) abandonStageTimings(billID uuid.UUID, stage string) {
+	if err := s.db.Model(&models.ExtractionTimings{}).
+		Where("bill_id = ? AND outcome = ?", billID, TimingsProcessing).
+		Updates(map[string]interface{}{"outcome": TimingsFailed, "failed_stage": stage}).Error; err != nil {
+		fmt.Printf("Failed to update extraction timings for bill %s: %v\n", billID, err)
+	}
+}
+
+// mergeStagesInto sets updates to merge the timer's stages into the stored ones in the
+// database, so concurrent writers never drop each other's stages
+func (s *BillService) mergeStagesInto(updates map[string]interface{}, timer *StageTimer) error {
+	stages, err := json.Marshal(timer.Timings())
+	if err != nil {
+		return err
+	}
+	updates["stages"] = gorm.Expr("stages || ?::jsonb", string(stages))
+	return nil
+}
+
+// latestStageTimings returns a bill's most recent extraction timings, newest first
+func (s *BillService) latestStageTimings(billID uuid.UUID, limit int) ([]models.ExtractionTimings, error) {
+	timings := []models.ExtractionTimings{}
+	if err := s.db.Where("bill_id = ?", billID).Order("id DESC").Limit(limit).Find(&timings).Error; err != nil {
+		return nil, fmt.Errorf("failed to find extraction timings: %w", err)
+	}
+	return timings, nil
+}
+
+// ExtractionStats returns per-stage duration percentiles over the extractions started
+// since the given time, at most the latest maxStatsAttempts of them
+func (s *BillService) ExtractionStats(since time.Time) (*models.ExtractionStatsResponse, error) {
+	var rows []models.ExtractionTimings
+	if err := s.db.Select("stages", "outcome").
+		Where("created_at >= ?", since).
+		Order("id DESC").
+		Limit(maxStatsAttempts).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load extraction timings: %w", err)
+	}
+
+	response := &models.ExtractionStatsResponse{
+		Since:    since,
+		Attempts: len(rows),
+		Outcomes: map[string]int{},
+		Stages:   make([]models.ExtractionStageStats, 0, len(ExtractionStages)),
+	}
+	byStage := make(map[string][]int64)
+	for _, row := range rows {
+		response.Outcomes[row.Outcome]++
+		for stage, ms := range row.Stages {
+			byStage[stage] = append(byStage[stage], ms)
+		}
+	}
+	for _, stage := range ExtractionStages {
+		durations := byStage[stage]
+		stats := models.ExtractionStageStats{Stage: stage, Count: len(durations)}
+		if len(durations) > 0 {
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+			stats.P50Ms = percentile(durations, 50)
+			stats.P90Ms = percentile(durations, 90)
+			stats.P99Ms = percentile(durations, 99)
+			stats.MaxMs = durations[len(durations)-1]
+		}
+		response.Stages = append(response.Stages, stats)
+	}
+	return response, nil
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must not be empty
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
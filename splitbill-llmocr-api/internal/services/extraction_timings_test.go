@@ -0,0 +1,160 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// stepClock is a clock that moves forward by step every time it is read
+func stepClock(step time.Duration) func() time.Time {
+	now := time.Unix(1700000000, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestStageTimer(t *testing.T) {
+	timer := NewStageTimer()
+	timer.now = stepClock(10 * time.Millisecond)
+
+	if stage := timer.Stop(); stage != "" {
+		t.Errorf("Stop with nothing running = %q, want none", stage)
+	}
+
+	// Each Start ends the stage before it
+	timer.Start(StageValidation)
+	timer.Start(StagePreprocessing)
+	timer.Start(StageStorageWrite)
+	if stage := timer.Stop(); stage != StageStorageWrite {
+		t.Errorf("Stop = %q, want %q", stage, StageStorageWrite)
+	}
+	// A stage run again adds to its time
+	timer.Start(StageValidation)
+	timer.Stop()
+
+	timings := timer.Timings()
+	want := models.StageTimings{StageValidation: 20, StagePreprocessing: 10, StageStorageWrite: 10}
+	if len(timings) != len(want) {
+		t.Fatalf("timings = %v, want %v", timings, want)
+	}
+	for stage, ms := range want {
+		if timings[stage] != ms {
+			t.Errorf("%s = %dms, want %dms", stage, timings[stage], ms)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	for _, tt := range []struct {
+		p    int
+		want int64
+	}{{50, 50}, {90, 90}, {99, 100}, {0, 10}} {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("p%d = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile([]int64{7}, 99); got != 7 {
+		t.Errorf("p99 of one = %d, want 7", got)
+	}
+}
+
+// runStubbedExtraction uploads an image for the bill and sends it to a provider that answers
+// status, timing the stages as the upload handler and the workers do
+func runStubbedExtraction(t *testing.T, s *BillService, billID uuid.UUID, status int) {
+	t.Helper()
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(provider.Close)
+	t.Setenv("N8N_WEBHOOK_URL", provider.URL)
+
+	timer := NewStageTimer()
+	timer.Start(StageValidation)
+	if _, _, err := s.UploadBillImage(billID, testImage(t, "receipt.jpg", []byte("receipt photo")), false, timer); err != nil {
+		t.Fatalf("UploadBillImage: %v", err)
+	}
+	job, ok := s.extractionQueue.next()
+	if !ok || job.billID != billID {
+		t.Fatalf("bill %s was not queued", billID)
+	}
+	s.runExtraction(job)
+}
+
+// billStageTimings loads the bill's only extraction timings
+func billStageTimings(t *testing.T, s *BillService, billID uuid.UUID) models.ExtractionTimings {
+	t.Helper()
+
+	rows, err := s.latestStageTimings(billID, 10)
+	if err != nil {
+		t.Fatalf("latestStageTimings: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("%d extraction timings for the bill, want 1", len(rows))
+	}
+	return rows[0]
+}
+
+func TestStageTimingsRecordEveryStage(t *testing.T) {
+	s, _ := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Receipt", nil)
+
+	runStubbedExtraction(t, s, bill.ID, http.StatusOK)
+	if err := s.ProcessExtractionCallback(bill.ID, []byte(`{"code":"API_SPLITBILL_LLMOCR","items":[{"name":"Tea","price":2.5,"quantity":2}]}`)); err != nil {
+		t.Fatalf("ProcessExtractionCallback: %v", err)
+	}
+
+	row := billStageTimings(t, s, bill.ID)
+	if row.Outcome != TimingsCompleted || row.FailedStage != "" {
+		t.Errorf("outcome = %s failed in %q, want completed", row.Outcome, row.FailedStage)
+	}
+	for _, stage := range ExtractionStages {
+		if _, recorded := row.Stages[stage]; !recorded {
+			t.Errorf("stage %s was not recorded: %v", stage, row.Stages)
+		}
+	}
+
+	// The stats count the run in every stage
+	stats, err := s.ExtractionStats(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ExtractionStats: %v", err)
+	}
+	if stats.Attempts != 1 || stats.Outcomes[TimingsCompleted] != 1 {
+		t.Errorf("stats = %d attempts, outcomes %v; want one completed", stats.Attempts, stats.Outcomes)
+	}
+	for _, stage := range stats.Stages {
+		if stage.Count != 1 {
+			t.Errorf("stats for %s count %d runs, want 1", stage.Stage, stage.Count)
+		}
+	}
+}
+
+func TestStageTimingsRecordFailure(t *testing.T) {
+	s, _ := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Receipt", nil)
+
+	runStubbedExtraction(t, s, bill.ID, http.StatusBadGateway)
+
+	row := billStageTimings(t, s, bill.ID)
+	if row.Outcome != TimingsFailed || row.FailedStage != StageProviderCall {
+		t.Errorf("outcome = %s failed in %q, want failed in %s", row.Outcome, row.FailedStage, StageProviderCall)
+	}
+	// Everything up to the provider call is kept; nothing after it ran
+	for _, stage := range []string{StageValidation, StagePreprocessing, StageStorageWrite, StageQueueWait, StageProviderCall} {
+		if _, recorded := row.Stages[stage]; !recorded {
+			t.Errorf("completed stage %s was not recorded: %v", stage, row.Stages)
+		}
+	}
+	for _, stage := range []string{StageCallbackParse, StageDBPersist} {
+		if _, recorded := row.Stages[stage]; recorded {
+			t.Errorf("stage %s recorded though it never ran", stage)
+		}
+	}
+}
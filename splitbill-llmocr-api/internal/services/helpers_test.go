@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/storage"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/testdb"
+	"gorm.io/gorm"
+)
+
+// newTestBillService returns a bill service on a fresh test database with the default
+// configuration, letting configure adjust it first. Tests skip without a database.
+func newTestBillService(t *testing.T, configure func(*config.Config)) (*BillService, *gorm.DB) {
+	t.Helper()
+
+	db := testdb.Open(t)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	views := cache.NewBillViewCache(cfg.ViewCacheTTL, cfg.ViewCacheMaxEntries)
+	return NewBillService(db, cfg, storage.NewLocal(t.TempDir()), nil, views), db
+}
+
+// createTestUser stores a user named name and returns its ID
+func createTestUser(t *testing.T, db *gorm.DB, name string) uint {
+	t.Helper()
+
+	user := models.Users{Username: name, Email: fmt.Sprintf("%s@example.com", name), Password: "x", Name: name}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user %s: %v", name, err)
+	}
+	return user.ID
+}
+
+// createTestBill creates a bill named name through the service, owned by userID when set
+func createTestBill(t *testing.T, s *BillService, name string, userID *uint) *models.BillResponse {
+	t.Helper()
+
+	bill, err := s.CreateBill(&models.BillRequest{Name: name}, userID, "192.0.2.1", true)
+	if err != nil {
+		t.Fatalf("failed to create bill %s: %v", name, err)
+	}
+	return bill
+}
+
+// errInjected is the error failDeletes makes a delete fail with
+var errInjected = errors.New("injected failure")
+
+// failDeletes makes every delete from table fail with errInjected, standing in for the
+// connection dropping partway through a transaction
+func failDeletes(t *testing.T, db *gorm.DB, table string) {
+	t.Helper()
+
+	name := "test:fail_delete_" + table
+	if err := db.Callback().Delete().Before("gorm:delete").Register(name, func(tx *gorm.DB) {
+		if tx.Statement.Table == table {
+			tx.AddError(errInjected)
+		}
+	}); err != nil {
+		t.Fatalf("failed to register %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		db.Callback().Delete().Remove(name)
+	})
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// testImage returns data as an uploaded file called name
+func testImage(t *testing.T, name string, data []byte) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", name)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("failed to parse form: %v", err)
+	}
+	return req.MultipartForm.File["image"][0]
+}
+
+// finishExtraction takes the bill's job off the queue and marks the bill completed, as a
+// successful extraction would
+func finishExtraction(t *testing.T, s *BillService, db *gorm.DB, billID uuid.UUID) {
+	t.Helper()
+
+	if !s.extractionQueue.remove(billID) {
+		t.Fatalf("bill %s was not queued", billID)
+	}
+	if err := db.Model(&models.Bills{}).Where("id = ?", billID).Update("status", string(StatusCompleted)).Error; err != nil {
+		t.Fatalf("failed to complete bill: %v", err)
+	}
+}
+
+func TestUploadBillImageDuplicates(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Receipt", nil)
+	receipt := []byte("receipt photo")
+
+	upload := func(data []byte, force bool) (status string, queued, duplicate bool) {
+		t.Helper()
+		got, duplicate, err := s.UploadBillImage(bill.ID, testImage(t, "receipt.jpg", data), force, NewStageTimer())
+		if err != nil {
+			t.Fatalf("UploadBillImage: %v", err)
+		}
+		_, queued = s.QueuePosition(bill.ID)
+		return got.Status, queued, duplicate
+	}
+
+	if _, queued, duplicate := upload(receipt, false); duplicate || !queued {
+		t.Fatalf("first upload: duplicate %v, queued %v; want a queued extraction", duplicate, queued)
+	}
+	finishExtraction(t, s, db, bill.ID)
+
+	// The same photo again reuses the extraction instead of queuing another
+	status, queued, duplicate := upload(receipt, false)
+	if !duplicate || queued || status != string(StatusCompleted) {
+		t.Errorf("same image: duplicate %v, queued %v, status %s; want the existing extraction", duplicate, queued, status)
+	}
+
+	// force runs it again
+	if _, queued, duplicate := upload(receipt, true); duplicate || !queued {
+		t.Errorf("forced upload: duplicate %v, queued %v; want a queued extraction", duplicate, queued)
+	}
+	finishExtraction(t, s, db, bill.ID)
+
+	// A different photo is extracted like any new one
+	if _, queued, duplicate := upload([]byte("another receipt"), false); duplicate || !queued {
+		t.Errorf("different image: duplicate %v, queued %v; want a queued extraction", duplicate, queued)
+	}
+}
+
+func TestUploadBillImageSharesStoredCopies(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	first := createTestBill(t, s, "Receipt", nil)
+	second := createTestBill(t, s, "Same receipt", nil)
+	receipt := []byte("receipt photo")
+
+	for _, billID := range []uuid.UUID{first.ID, second.ID} {
+		if _, _, err := s.UploadBillImage(billID, testImage(t, "receipt.jpg", receipt), false, NewStageTimer()); err != nil {
+			t.Fatalf("UploadBillImage: %v", err)
+		}
+	}
+
+	var bills []models.Bills
+	if err := db.Where("id IN ?", []uuid.UUID{first.ID, second.ID}).Find(&bills).Error; err != nil {
+		t.Fatalf("failed to load bills: %v", err)
+	}
+	if len(bills) != 2 || bills[0].ImagePath == "" || bills[0].ImagePath != bills[1].ImagePath {
+		t.Errorf("image paths %q and %q, want one stored copy", bills[0].ImagePath, bills[1].ImagePath)
+	}
+	if bills[0].ImageHash != bills[1].ImageHash {
+		t.Errorf("image hashes differ for the same bytes")
+	}
+}
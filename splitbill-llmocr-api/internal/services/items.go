@@ -0,0 +1,699 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Where an item came from
+const (
+	ItemSourceManual = "manual"
+	ItemSourceOCR    = "ocr"
+)
+
+// CreateItem adds an item by hand, for one the receipt scan missed
+func (s *BillService) CreateItem(billID uuid.UUID, req *models.ItemRequest, actor string) (*models.ItemResponse, error) {
+	items, err := s.CreateItems(billID, []models.ItemRequest{*req}, actor)
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// CreateItems adds several items by hand in one transaction, so either all of them are
+// added or none are. Items can't be added while the bill's image is being extracted,
+// since the extracted items are about to land.
+func (s *BillService) CreateItems(billID uuid.UUID, reqs []models.ItemRequest, actor string) ([]models.ItemResponse, error) {
+	items := make([]models.Items, 0, len(reqs))
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status", "currency").First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if BillStatus(bill.Status).Extracting() {
+			return fmt.Errorf("bill %s is %s: %w", billID, bill.Status, ErrInvalidStatus)
+		}
+
+		code := s.billCurrency(bill.Currency)
+		for _, req := range reqs {
+			item := models.Items{
+				BillID:         billID,
+				Name:           s.itemNames.Normalize(req.Name),
+				RawName:        req.Name,
+				Price:          req.Price,
+				Quantity:       roundQuantity(req.Quantity),
+				DiscountAmount: req.DiscountAmount,
+			}
+			if req.Category != nil {
+				item.Category = NormalizeCategory(*req.Category)
+			}
+			if err := checkItemDiscount(&item, code); err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return fmt.Errorf("failed to create items: %w", err)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.ItemResponse, 0, len(items))
+	for _, item := range items {
+		s.recordEvent(billID, actor, EventItemCreated, models.EventPayload{
+			"item_id":         item.ID,
+			"name":            item.Name,
+			"price":           item.Price,
+			"quantity":        item.Quantity,
+			"discount_amount": item.DiscountAmount,
+		})
+		response = append(response, itemResponse(&item))
+	}
+	return response, nil
+}
+
+// DeleteItem removes an item of the bill and its assignments in one transaction and
+// refreshes the bill's stored totals. Items of other or deleted bills, and items already
+// removed, are ErrNotFound; items can't be removed while the bill's image is being extracted.
+func (s *BillService) DeleteItem(billID uuid.UUID, itemID uint, actor string) error {
+	var item models.Items
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ScopeBill(billID)).Where("id = ?", itemID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find item: %w", err)
+		}
+
+		var bill models.Bills
+		if err := tx.Select("id", "status").First(&bill, "id = ?", item.BillID).Error; err != nil {
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if BillStatus(bill.Status).Extracting() {
+			return fmt.Errorf("bill %s is %s: %w", bill.ID, bill.Status, ErrInvalidStatus)
+		}
+
+		// Delete the assignments first so no assignment is left pointing at a missing item.
+		// Both are soft-deleted, so restoring the item brings the assignments back.
+		if err := tx.Where("item_id = ?", itemID).Delete(&models.ItemAssignments{}).Error; err != nil {
+			return fmt.Errorf("failed to delete item assignments: %w", err)
+		}
+		if err := tx.Delete(&item).Error; err != nil {
+			return fmt.Errorf("failed to delete item: %w", err)
+		}
+		return s.refreshBillTotals(tx, item.BillID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordEvent(item.BillID, actor, EventItemDeleted, models.EventPayload{
+		"item_id":  item.ID,
+		"name":     item.Name,
+		"price":    item.Price,
+		"quantity": item.Quantity,
+	})
+	return nil
+}
+
+// ItemsNotInBillError is returned when a bulk item update names items that are not on the
+// bill. It matches ErrItemsNotInBill with errors.Is.
+type ItemsNotInBillError struct {
+	BillID  uuid.UUID
+	ItemIDs []uint
+}
+
+func (e *ItemsNotInBillError) Error() string {
+	return fmt.Sprintf("bill %s has no items %v", e.BillID, e.ItemIDs)
+}
+
+// Is makes errors.Is(err, ErrItemsNotInBill) true for items not in bill errors
+func (e *ItemsNotInBillError) Is(target error) bool {
+	return target == ErrItemsNotInBill
+}
+
+// UpdateItems applies several partial item updates in one transaction and returns all of
+// the bill's items afterwards. If any patch names an item that isn't on the bill, nothing
+// is written and an *ItemsNotInBillError lists them.
+func (s *BillService) UpdateItems(billID uuid.UUID, patches []models.ItemPatchRequest, actor string) ([]models.ItemResponse, error) {
+	changed := make(map[uint]map[string]interface{}, len(patches))
+	var items []models.Items
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, 0, len(patches))
+		for _, patch := range patches {
+			ids = append(ids, patch.ID)
+		}
+
+		var found []uint
+		if err := tx.Model(&models.Items{}).Scopes(ScopeBill(billID)).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+			return fmt.Errorf("failed to find items: %w", err)
+		}
+		onBill := make(map[uint]bool, len(found))
+		for _, id := range found {
+			onBill[id] = true
+		}
+		var missing []uint
+		for _, id := range ids {
+			if !onBill[id] {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			return &ItemsNotInBillError{BillID: billID, ItemIDs: missing}
+		}
+
+		for _, patch := range patches {
+			updates, err := itemUpdates(&models.ItemUpdateRequest{
+				Name:           patch.Name,
+				Price:          patch.Price,
+				Quantity:       patch.Quantity,
+				DiscountAmount: patch.DiscountAmount,
+				Category:       patch.Category,
+			})
+			if errors.Is(err, ErrNoChanges) {
+				continue
+			}
+			if err := tx.Model(&models.Items{}).Where("id = ?", patch.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update item %d: %w", patch.ID, err)
+			}
+			changed[patch.ID] = updates
+		}
+
+		if err := s.refreshBillTotals(tx, billID); err != nil {
+			return err
+		}
+		if err := tx.Where("bill_id = ?", billID).Order("id").Find(&items).Error; err != nil {
+			return fmt.Errorf("failed to fetch items: %w", err)
+		}
+		code, err := s.billCurrencyOf(tx, billID)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			if _, ok := changed[items[i].ID]; !ok {
+				continue
+			}
+			if err := checkItemDiscount(&items[i], code); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.ItemResponse, 0, len(items))
+	for _, item := range items {
+		if updates, ok := changed[item.ID]; ok {
+			s.recordEvent(billID, actor, EventItemUpdated, models.EventPayload{
+				"item_id": item.ID,
+				"changes": updates,
+			})
+		}
+		response = append(response, itemResponse(&item))
+	}
+	return response, nil
+}
+
+// ListItems returns one page of a bill's items in ID order, each with the participants
+// assigned to it. The assignments come from one joined query rather than a lookup per item.
+func (s *BillService) ListItems(billID uuid.UUID, query *models.ItemListQuery) (*models.ItemListResponse, error) {
+	items := s.db.Model(&models.Items{}).Scopes(ScopeBill(billID))
+	if query.Unassigned {
+		items = items.Where("NOT EXISTS (SELECT 1 FROM item_assignments WHERE item_assignments.item_id = items.id AND item_assignments.deleted_at IS NULL)")
+	}
+	// The filtered query is shared by the count and the page
+	items = items.Session(&gorm.Session{})
+
+	var total int64
+	if err := items.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count items: %w", err)
+	}
+
+	var rows []struct {
+		models.Items
+		AssignedParticipantIDs string
+	}
+	if err := items.
+		Select("items.*, COALESCE(json_agg(item_assignments.participant_id ORDER BY item_assignments.participant_id) " +
+			"FILTER (WHERE item_assignments.participant_id IS NOT NULL), '[]') AS assigned_participant_ids").
+		Joins("LEFT JOIN item_assignments ON item_assignments.item_id = items.id AND item_assignments.deleted_at IS NULL").
+		Group("items.id").
+		Order("items.id").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+
+	list := make([]models.ItemListEntry, 0, len(rows))
+	for _, row := range rows {
+		var participantIDs []uint
+		if err := json.Unmarshal([]byte(row.AssignedParticipantIDs), &participantIDs); err != nil {
+			return nil, fmt.Errorf("failed to read assignments of item %d: %w", row.ID, err)
+		}
+		list = append(list, models.ItemListEntry{
+			ItemResponse:           itemResponse(&row.Items),
+			AssignedParticipantIDs: participantIDs,
+			IsUnassigned:           len(participantIDs) == 0,
+		})
+	}
+
+	return &models.ItemListResponse{
+		Items:    list,
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}, nil
+}
+
+// markReviewed clears an item's needs_review flag when updates set its price or quantity,
+// since whoever did has checked the amounts against the receipt
+func markReviewed(updates map[string]interface{}) {
+	_, price := updates["price"]
+	_, quantity := updates["quantity"]
+	if price || quantity {
+		updates["needs_review"] = false
+	}
+}
+
+// createDraftItems creates items as unconfirmed drafts. Confirmed defaults to true in the
+// database and GORM writes that default in place of false, so the drafts are unconfirmed
+// with a second statement.
+func createDraftItems(tx *gorm.DB, items []models.Items) error {
+	if err := tx.Create(&items).Error; err != nil {
+		return fmt.Errorf("failed to create items: %w", err)
+	}
+	ids := make([]uint, len(items))
+	for i := range items {
+		ids[i] = items[i].ID
+		items[i].Confirmed = false
+	}
+	if err := tx.Model(&models.Items{}).Where("id IN ?", ids).UpdateColumn("confirmed", false).Error; err != nil {
+		return fmt.Errorf("failed to mark items unconfirmed: %w", err)
+	}
+	return nil
+}
+
+// ConfirmItems confirms the given items of a bill, or every item when all is set, and
+// reports how many changed and how many are left unconfirmed. Items already confirmed
+// are left alone. If any ID isn't on the bill, nothing is written and an
+// *ItemsNotInBillError lists them.
+func (s *BillService) ConfirmItems(billID uuid.UUID, itemIDs []uint, all bool, actor string) (*models.ItemConfirmResponse, error) {
+	response := &models.ItemConfirmResponse{}
+	var confirmedIDs []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if !all {
+			var found []uint
+			if err := tx.Model(&models.Items{}).Scopes(ScopeBill(billID)).Where("id IN ?", itemIDs).Pluck("id", &found).Error; err != nil {
+				return fmt.Errorf("failed to find items: %w", err)
+			}
+			onBill := make(map[uint]bool, len(found))
+			for _, id := range found {
+				onBill[id] = true
+			}
+			var missing []uint
+			for _, id := range itemIDs {
+				if !onBill[id] {
+					missing = append(missing, id)
+				}
+			}
+			if len(missing) > 0 {
+				return &ItemsNotInBillError{BillID: billID, ItemIDs: missing}
+			}
+		}
+
+		drafts := tx.Model(&models.Items{}).Scopes(ScopeBill(billID)).Where("NOT confirmed")
+		if !all {
+			drafts = drafts.Where("id IN ?", itemIDs)
+		}
+		if err := drafts.Order("id").Pluck("id", &confirmedIDs).Error; err != nil {
+			return fmt.Errorf("failed to find unconfirmed items: %w", err)
+		}
+		if len(confirmedIDs) > 0 {
+			// UpdateColumn keeps item versions, so an edit in flight isn't refused for it
+			if err := tx.Model(&models.Items{}).Where("id IN ?", confirmedIDs).UpdateColumn("confirmed", true).Error; err != nil {
+				return fmt.Errorf("failed to confirm items: %w", err)
+			}
+		}
+		response.Confirmed = int64(len(confirmedIDs))
+		if err := tx.Model(&models.Items{}).Scopes(ScopeBill(billID)).Where("NOT confirmed").Count(&response.Unconfirmed).Error; err != nil {
+			return fmt.Errorf("failed to count unconfirmed items: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(confirmedIDs) > 0 {
+		s.recordEvent(billID, actor, EventItemsConfirmed, models.EventPayload{
+			"item_ids": confirmedIDs,
+		})
+	}
+	return response, nil
+}
+
+// itemResponse converts an Items model to ItemResponse
+func itemResponse(item *models.Items) models.ItemResponse {
+	return models.ItemResponse{
+		ID:             item.ID,
+		BillID:         item.BillID,
+		Name:           item.Name,
+		RawName:        item.RawName,
+		Price:          item.Price,
+		Quantity:       item.Quantity,
+		DiscountAmount: item.DiscountAmount,
+		Category:       item.Category,
+		NeedsReview:    item.NeedsReview,
+		Source:         item.Source,
+		Confirmed:      item.Confirmed,
+		Version:        models.FormatVersion(item.Version()),
+		CreatedAt:      item.CreatedAt,
+	}
+}
+
+// UncategorizedItems is the category_totals key for items without a category
+const UncategorizedItems = "uncategorized"
+
+// NormalizeCategory trims an item category; a blank one becomes nil, meaning none
+func NormalizeCategory(category string) *string {
+	category = strings.TrimSpace(category)
+	if category == "" {
+		return nil
+	}
+	return &category
+}
+
+// CategoryTotals returns the bill's item totals, after item discounts, by lowercased
+// category. Items without a category are counted under UncategorizedItems.
+func (s *BillService) CategoryTotals(billID uuid.UUID) (map[string]float64, error) {
+	var bill models.Bills
+	if err := s.db.Select("id", "currency").First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find bill: %w", err)
+	}
+	var items []models.Items
+	if err := s.db.Scopes(ScopeBill(billID)).Select("price, quantity, discount_amount, category").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to find items: %w", err)
+	}
+
+	code := s.billCurrency(bill.Currency)
+	minor := make(map[string]int64)
+	for _, item := range items {
+		key := UncategorizedItems
+		if item.Category != nil && strings.TrimSpace(*item.Category) != "" {
+			key = strings.ToLower(strings.TrimSpace(*item.Category))
+		}
+		minor[key] += itemLineMinor(item.Price, item.Quantity, item.DiscountAmount, code)
+	}
+
+	totals := make(map[string]float64, len(minor))
+	for key, amount := range minor {
+		totals[key] = currency.FromMinor(amount, code)
+	}
+	return totals, nil
+}
+
+// SplitItem splits an item into several lines in one transaction, such as "2x Beer" into
+// one beer each, and returns the resulting items. The item itself becomes the first part,
+// keeping its assignments and discount; the other parts are new items with the same name
+// and category. With parts, the quantity is spread as evenly as possible at the same unit
+// price, in whole units or, for a fractional quantity such as a weight, in thousandths.
+// With rows, the quantities must add up to the item's and the line totals to its
+// own within one minor unit; anything else is ErrInvalidSplit.
+func (s *BillService) SplitItem(billID uuid.UUID, itemID uint, req *models.ItemSplitRequest, actor string) ([]models.ItemResponse, error) {
+	var original models.Items
+	var items []models.Items
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ScopeBill(billID)).Where("id = ?", itemID).First(&original).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("item %d: %w", itemID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find item: %w", err)
+		}
+
+		var bill models.Bills
+		if err := tx.Select("id", "status", "currency").First(&bill, "id = ?", billID).Error; err != nil {
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if BillStatus(bill.Status).Extracting() {
+			return fmt.Errorf("bill %s is %s: %w", billID, bill.Status, ErrInvalidStatus)
+		}
+
+		code := s.billCurrency(bill.Currency)
+		parts, err := splitParts(&original, req, code)
+		if err != nil {
+			return err
+		}
+
+		// The item keeps its ID as the first part, so its assignments stay with it
+		first := original
+		first.Quantity = parts[0].Quantity
+		first.Price = parts[0].Price
+		if err := checkItemDiscount(&first, code); err != nil {
+			return err
+		}
+		if err := tx.Model(&first).Updates(map[string]interface{}{
+			"quantity": first.Quantity,
+			"price":    first.Price,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update item: %w", err)
+		}
+		if err := tx.First(&first, first.ID).Error; err != nil {
+			return fmt.Errorf("failed to fetch updated item: %w", err)
+		}
+		items = append(items, first)
+
+		created := make([]models.Items, 0, len(parts)-1)
+		for _, part := range parts[1:] {
+			created = append(created, models.Items{
+				BillID:   billID,
+				Name:     original.Name,
+				RawName:  original.RawName,
+				Price:    part.Price,
+				Quantity: part.Quantity,
+				Category: original.Category,
+				Source:   original.Source,
+			})
+		}
+		// The new lines are as confirmed as the item they came from
+		if original.Confirmed {
+			if err := tx.Create(&created).Error; err != nil {
+				return fmt.Errorf("failed to create items: %w", err)
+			}
+		} else if err := createDraftItems(tx, created); err != nil {
+			return err
+		}
+		items = append(items, created...)
+
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.ItemResponse, 0, len(items))
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		response = append(response, itemResponse(&item))
+		ids = append(ids, item.ID)
+	}
+	s.recordEvent(billID, actor, EventItemSplit, models.EventPayload{
+		"item_id":  original.ID,
+		"name":     original.Name,
+		"item_ids": ids,
+	})
+	return response, nil
+}
+
+// splitParts works out the lines an item is split into and checks that they add back up
+// to it
+func splitParts(item *models.Items, req *models.ItemSplitRequest, code string) ([]models.ItemSplitPart, error) {
+	if len(req.Rows) == 0 {
+		step := int64(1)
+		if isWholeQuantity(item.Quantity) {
+			step = quantityScale
+		}
+		steps := toThousandths(item.Quantity) / step
+		if int64(req.Parts) > steps {
+			return nil, fmt.Errorf("%w: an item of quantity %g can't be split into %d parts; send rows with prices instead",
+				ErrInvalidSplit, item.Quantity, req.Parts)
+		}
+		parts := make([]models.ItemSplitPart, req.Parts)
+		for i := range parts {
+			share := steps / int64(req.Parts)
+			if int64(i) < steps%int64(req.Parts) {
+				share++
+			}
+			parts[i] = models.ItemSplitPart{Quantity: fromThousandths(share * step), Price: item.Price}
+		}
+		return parts, nil
+	}
+
+	var quantity, total int64
+	for i := range req.Rows {
+		row := &req.Rows[i]
+		row.Quantity = roundQuantity(row.Quantity)
+		quantity += toThousandths(row.Quantity)
+		total += currency.ToMinor(row.Price*row.Quantity, code)
+	}
+	if quantity != toThousandths(item.Quantity) {
+		return nil, fmt.Errorf("%w: the rows' quantities add up to %g, not the item's %g", ErrInvalidSplit,
+			fromThousandths(quantity), item.Quantity)
+	}
+	expected := currency.ToMinor(item.Price*item.Quantity, code)
+	if diff := total - expected; diff > 1 || diff < -1 {
+		return nil, fmt.Errorf("%w: the rows add up to %.2f, not the item's %.2f", ErrInvalidSplit,
+			currency.FromMinor(total, code), currency.FromMinor(expected, code))
+	}
+	return req.Rows, nil
+}
+
+// MergeItems merges items of a bill into the first one listed, for lines a re-upload
+// added twice, and returns the merged item. It keeps the first item's name, price and
+// category, sums the quantities and discounts, and moves every assignment onto it, so
+// someone assigned to several of the copies is assigned once. The other items are
+// deleted. If any ID isn't on the bill, nothing is written and an *ItemsNotInBillError
+// lists them.
+func (s *BillService) MergeItems(billID uuid.UUID, itemIDs []uint, actor string) (*models.ItemResponse, error) {
+	var target models.Items
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Select("id", "status").First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if BillStatus(bill.Status).Extracting() {
+			return fmt.Errorf("bill %s is %s: %w", billID, bill.Status, ErrInvalidStatus)
+		}
+
+		var items []models.Items
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ScopeBill(billID)).Where("id IN ?", itemIDs).Find(&items).Error; err != nil {
+			return fmt.Errorf("failed to find items: %w", err)
+		}
+		byID := make(map[uint]models.Items, len(items))
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+		var missing []uint
+		for _, id := range itemIDs {
+			if _, ok := byID[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			return &ItemsNotInBillError{BillID: billID, ItemIDs: missing}
+		}
+
+		target = byID[itemIDs[0]]
+		others := itemIDs[1:]
+		for _, id := range others {
+			target.Quantity = roundQuantity(target.Quantity + byID[id].Quantity)
+			target.DiscountAmount += byID[id].DiscountAmount
+		}
+
+		// Copy the others' assignments onto the target, skipping people already on it
+		var assignments []models.ItemAssignments
+		if err := tx.Where("item_id IN ?", others).Find(&assignments).Error; err != nil {
+			return fmt.Errorf("failed to find item assignments: %w", err)
+		}
+		if len(assignments) > 0 {
+			moved := make([]models.ItemAssignments, 0, len(assignments))
+			for _, assignment := range assignments {
+				moved = append(moved, models.ItemAssignments{ItemID: target.ID, ParticipantID: assignment.ParticipantID, Weight: assignment.Weight})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&moved).Error; err != nil {
+				return fmt.Errorf("failed to move item assignments: %w", err)
+			}
+		}
+
+		if err := tx.Unscoped().Where("item_id IN ?", others).Delete(&models.ItemAssignments{}).Error; err != nil {
+			return fmt.Errorf("failed to delete item assignments: %w", err)
+		}
+		// The copies live on in the target, so they are not kept for restoring
+		if err := tx.Unscoped().Where("id IN ?", others).Delete(&models.Items{}).Error; err != nil {
+			return fmt.Errorf("failed to delete items: %w", err)
+		}
+		if err := tx.Model(&target).Updates(map[string]interface{}{
+			"quantity":        target.Quantity,
+			"discount_amount": target.DiscountAmount,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update item: %w", err)
+		}
+		if err := tx.First(&target, target.ID).Error; err != nil {
+			return fmt.Errorf("failed to fetch merged item: %w", err)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventItemsMerged, models.EventPayload{
+		"item_id":         target.ID,
+		"name":            target.Name,
+		"merged_item_ids": itemIDs[1:],
+		"quantity":        target.Quantity,
+	})
+	response := itemResponse(&target)
+	return &response, nil
+}
+
+// DuplicateItems returns the groups of two or more items on the bill with the same name
+// and price, ordered by their first item, so clients can offer to merge them
+func (s *BillService) DuplicateItems(billID uuid.UUID) ([]models.DuplicateItemGroup, error) {
+	code, err := s.billCurrencyOf(s.db, billID)
+	if err != nil {
+		return nil, err
+	}
+	var items []models.Items
+	if err := s.db.Scopes(ScopeBill(billID)).Order("id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to find items: %w", err)
+	}
+
+	type key struct {
+		name  string
+		price int64
+	}
+	groups := []models.DuplicateItemGroup{}
+	var keys []key
+	byKey := make(map[key][]models.ItemResponse)
+	for _, item := range items {
+		k := key{name: item.Name, price: currency.ToMinor(item.Price, code)}
+		if _, seen := byKey[k]; !seen {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], itemResponse(&item))
+	}
+	for _, k := range keys {
+		if len(byKey[k]) < 2 {
+			continue
+		}
+		groups = append(groups, models.DuplicateItemGroup{
+			Name:  k.name,
+			Price: byKey[k][0].Price,
+			Items: byKey[k],
+		})
+	}
+	return groups, nil
+}
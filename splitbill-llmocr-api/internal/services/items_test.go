@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+func TestDeleteItemRollsBack(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+	noodles := items[0]
+
+	var before models.Bills
+	if err := db.First(&before, "id = ?", billID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+
+	// The noodles' assignments are deleted first, so the item's delete is the second step
+	failDeletes(t, db, "items")
+	if err := s.DeleteItem(billID, noodles, "test"); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteItem err = %v, want the injected failure", err)
+	}
+
+	var item models.Items
+	if err := db.First(&item, noodles).Error; err != nil {
+		t.Fatalf("item is gone after the failed delete: %v", err)
+	}
+	got := liveAssignments(t, db)
+	for _, participant := range participants {
+		if !got[[2]uint{noodles, participant}] {
+			t.Errorf("participant %d lost the noodles after the failed delete", participant)
+		}
+	}
+	var after models.Bills
+	if err := db.First(&after, "id = ?", billID).Error; err != nil {
+		t.Fatalf("failed to reload bill: %v", err)
+	}
+	if after.ItemSubtotal != before.ItemSubtotal || after.GrandTotal != before.GrandTotal {
+		t.Errorf("totals %v/%v after the failed delete, want %v/%v", after.ItemSubtotal, after.GrandTotal, before.ItemSubtotal, before.GrandTotal)
+	}
+}
+
+func TestUpdateItemsRejectsMixedBatch(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, _ := seedAssignedBill(t, s)
+	_, others, _ := seedAssignedBill(t, s)
+	noodles, tea := items[0], items[1]
+
+	// One patch names the other bill's item and one names no item, so nothing is written
+	_, err := s.UpdateItems(billID, []models.ItemPatchRequest{
+		{ID: noodles, Price: ptr(13.0)},
+		{ID: others[1], Price: ptr(1.0)},
+		{ID: tea, Name: ptr("Green tea")},
+		{ID: 1 << 30, Price: ptr(1.0)},
+	}, "test")
+	var notInBill *ItemsNotInBillError
+	if !errors.As(err, &notInBill) || !errors.Is(err, ErrItemsNotInBill) {
+		t.Fatalf("err = %v, want an ItemsNotInBillError", err)
+	}
+	if len(notInBill.ItemIDs) != 2 || notInBill.ItemIDs[0] != others[1] || notInBill.ItemIDs[1] != 1<<30 {
+		t.Errorf("missing items = %v, want [%d %d]", notInBill.ItemIDs, others[1], 1<<30)
+	}
+	var stored models.Items
+	if err := db.First(&stored, noodles).Error; err != nil {
+		t.Fatalf("failed to load item: %v", err)
+	}
+	if stored.Price != 12 {
+		t.Errorf("noodles price = %v after a rejected batch, want 12", stored.Price)
+	}
+
+	// The same batch without the strangers goes through as a whole
+	updated, err := s.UpdateItems(billID, []models.ItemPatchRequest{
+		{ID: noodles, Price: ptr(13.0)},
+		{ID: tea, Name: ptr("Green tea")},
+	}, "test")
+	if err != nil {
+		t.Fatalf("UpdateItems: %v", err)
+	}
+	if len(updated) != 2 || updated[0].Price != 13 || updated[1].Name != "Green tea" {
+		t.Errorf("items = %+v, want noodles at 13 and green tea", updated)
+	}
+	var bill models.Bills
+	if err := db.First(&bill, "id = ?", billID).Error; err != nil {
+		t.Fatalf("failed to load bill: %v", err)
+	}
+	if bill.ItemSubtotal != 16 {
+		t.Errorf("subtotal = %v, want 16", bill.ItemSubtotal)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"math"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/splitmath"
+)
+
+// lineReading is one way of reading an extracted row's prices, in minor units
+type lineReading struct {
+	unit int64
+	line int64
+}
+
+// linePrices holds how an extracted row's prices can be read. alternative is set when the
+// row is ambiguous: a bare price on a row of several units may be the unit price or the
+// line total, and a unit_price and line_total that disagree cannot both be right.
+type linePrices struct {
+	primary     lineReading
+	alternative *lineReading
+	conflicting bool
+	discount    int64
+}
+
+// readLinePrices returns the readings of a row from its unit price, line total and bare
+// price in minor units, zero when missing, and false when none gives a positive unit price.
+// The quantity must be positive and may be fractional, such as a weight.
+func readLinePrices(unitPrice, lineTotal, price int64, quantity float64) (linePrices, bool) {
+	switch {
+	case unitPrice > 0 && lineTotal > 0:
+		// A printed unit price may itself be rounded, so allow for that in the line total
+		tolerance := math.Max(quantity, 1)
+		if diff := float64(multiplyRounded(unitPrice, quantity) - lineTotal); diff > -tolerance && diff < tolerance {
+			return linePrices{primary: lineReading{unit: unitPrice, line: lineTotal}}, true
+		}
+		prices := linePrices{primary: lineReading{unit: unitPrice, line: multiplyRounded(unitPrice, quantity)}, conflicting: true}
+		if unit := divideRounded(lineTotal, quantity); unit > 0 {
+			prices.alternative = &lineReading{unit: unit, line: lineTotal}
+		}
+		return prices, true
+	case unitPrice > 0:
+		return linePrices{primary: lineReading{unit: unitPrice, line: multiplyRounded(unitPrice, quantity)}}, true
+	case lineTotal > 0:
+		unit := divideRounded(lineTotal, quantity)
+		return linePrices{primary: lineReading{unit: unit, line: lineTotal}}, unit > 0
+	case price > 0:
+		// On a row of several units, or of a weight, a bare price may be either
+		prices := linePrices{primary: lineReading{unit: price, line: multiplyRounded(price, quantity)}}
+		if unit := divideRounded(price, quantity); quantity != 1 && unit > 0 {
+			prices.alternative = &lineReading{unit: unit, line: price}
+		}
+		return prices, true
+	}
+	return linePrices{}, false
+}
+
+// reconcileLinePrices sets the unit price and line total of data's items from readings,
+// one per item. Ambiguous rows are read the same way throughout the receipt, the way that
+// makes the items add up to its total: prices per unit when that works, otherwise line
+// totals. When neither does, or a receipt with conflicting prices has no total to check
+// against, the unit prices are kept and those rows are flagged for review.
+func reconcileLinePrices(data *models.ExtractedItemData, readings []linePrices, code string) {
+	useAlternative := false
+	unresolved := false
+	if data.Total > 0 && hasAlternatives(readings) {
+		total := currency.ToMinor(data.Total, code)
+		switch {
+		case reconciles(extractedTotalMinor(data, readingLines(readings, false), code), total):
+		case reconciles(extractedTotalMinor(data, readingLines(readings, true), code), total):
+			useAlternative = true
+		default:
+			unresolved = true
+		}
+	}
+
+	for i, prices := range readings {
+		reading := prices.read(useAlternative)
+		data.Items[i].UnitPrice = currency.FromMinor(reading.unit, code)
+		data.Items[i].LineTotal = currency.FromMinor(reading.line, code)
+		switch {
+		case prices.conflicting && (data.Total <= 0 || prices.alternative == nil):
+			data.Items[i].NeedsReview = true
+		case prices.alternative != nil:
+			data.Items[i].NeedsReview = unresolved
+		}
+	}
+}
+
+// read returns the alternative reading when asked for and usable, else the primary one.
+// An alternative that leaves less than the row's discount is never usable.
+func (p linePrices) read(alternative bool) lineReading {
+	if alternative && p.alternative != nil && p.alternative.line >= p.discount {
+		return *p.alternative
+	}
+	return p.primary
+}
+
+// hasAlternatives reports whether any row can be read more than one way
+func hasAlternatives(readings []linePrices) bool {
+	for _, prices := range readings {
+		if prices.alternative != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readingLines returns every row's line total less its discount under one reading
+func readingLines(readings []linePrices, alternative bool) []int64 {
+	lines := make([]int64, len(readings))
+	for i, prices := range readings {
+		lines[i] = prices.read(alternative).line - prices.discount
+	}
+	return lines
+}
+
+// extractedTotalMinor returns what a receipt with the given item lines and data's tax,
+// tip and inclusion flags should total, in minor units of code
+func extractedTotalMinor(data *models.ExtractedItemData, lines []int64, code string) int64 {
+	_, total := splitmath.Totals(lines, splitmath.Charges{
+		Tax:                  currency.ToMinor(data.Tax, code),
+		Tip:                  currency.ToMinor(data.Tip, code),
+		PricesIncludeTax:     data.PricesIncludeTax,
+		PricesIncludeService: data.PricesIncludeService,
+	})
+	return total
+}
+
+// reconciles reports whether an expected total matches a receipt's within a minor unit
+func reconciles(expected, total int64) bool {
+	diff := expected - total
+	return diff >= -1 && diff <= 1
+}
+
+// multiplyRounded multiplies a minor amount by a quantity, rounding to the nearest unit
+func multiplyRounded(amount int64, quantity float64) int64 {
+	return int64(math.Round(float64(amount) * quantity))
+}
+
+// divideRounded divides a minor amount by a quantity, rounding to the nearest unit
+func divideRounded(amount int64, quantity float64) int64 {
+	return int64(math.Round(float64(amount) / quantity))
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Split modes of a bill
+const (
+	// SplitItemized works shares out from items, assignments and common costs
+	SplitItemized = "itemized"
+	// SplitManual uses the amount set on each participant and ignores the items
+	SplitManual = "manual"
+)
+
+// SetManualShares sets what each participant owes and switches the bill to the manual
+// split mode. Every share must name a participant of the bill, at most once; participants
+// left out owe nothing. When the bill has items or charges, the shares must add up to its
+// itemized total give or take one minor unit per share.
+func (s *BillService) SetManualShares(billID uuid.UUID, shares []models.ManualShareRequest, actor string) (*models.BillSummary, error) {
+	var total int64
+	var code string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			Preload("Participants", ParticipantOrder).
+			First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		onBill := make(map[uint]bool, len(bill.Participants))
+		for _, participant := range bill.Participants {
+			onBill[participant.ID] = true
+		}
+
+		code = s.billCurrency(bill.Currency)
+		amounts := make(map[uint]int64, len(shares))
+		for _, share := range shares {
+			if !onBill[share.ParticipantID] {
+				return fmt.Errorf("%w: participant %d is not on this bill", ErrInvalidManualShares, share.ParticipantID)
+			}
+			if _, ok := amounts[share.ParticipantID]; ok {
+				return fmt.Errorf("%w: participant %d is listed more than once", ErrInvalidManualShares, share.ParticipantID)
+			}
+			amounts[share.ParticipantID] = currency.ToMinor(share.Amount, code)
+			total += amounts[share.ParticipantID]
+		}
+
+		// Compare against the itemized total, whatever mode the bill is in now
+		itemized := bill
+		itemized.SplitMode = SplitItemized
+		if _, expected := billTotalsMinor(&itemized, bill.Items, code); expected != 0 {
+			if diff := total - expected; diff > int64(len(shares)) || diff < -int64(len(shares)) {
+				return fmt.Errorf("%w: shares add up to %s but the bill comes to %s", ErrInvalidManualShares,
+					formatAmount(total, code), formatAmount(expected, code))
+			}
+		}
+
+		for _, participant := range bill.Participants {
+			amount := currency.FromMinor(amounts[participant.ID], code)
+			if err := tx.Model(&models.Participants{}).Where("id = ?", participant.ID).
+				Update("manual_amount", amount).Error; err != nil {
+				return fmt.Errorf("failed to set manual share: %w", err)
+			}
+		}
+		if err := tx.Model(&models.Bills{}).Where("id = ?", billID).Update("split_mode", SplitManual).Error; err != nil {
+			return fmt.Errorf("failed to set split mode: %w", err)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventManualSharesSet, models.EventPayload{
+		"shares": shares,
+		"total":  currency.FromMinor(total, code),
+	})
+	return s.GetBillSummary(billID)
+}
+
+// ClearManualShares switches a bill back to the itemized split mode. The manual amounts
+// are discarded, so callers must pass confirm.
+func (s *BillService) ClearManualShares(billID uuid.UUID, confirm bool, actor string) (*models.BillSummary, error) {
+	if !confirm {
+		return nil, fmt.Errorf("%w: switching back to itemized discards the manual shares", ErrConfirmationRequired)
+	}
+
+	var cleared bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "split_mode").First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+		if bill.SplitMode != SplitManual {
+			return nil
+		}
+		cleared = true
+
+		if err := tx.Model(&models.Participants{}).Where("bill_id = ?", billID).
+			Update("manual_amount", nil).Error; err != nil {
+			return fmt.Errorf("failed to clear manual shares: %w", err)
+		}
+		if err := tx.Model(&models.Bills{}).Where("id = ?", billID).Update("split_mode", SplitItemized).Error; err != nil {
+			return fmt.Errorf("failed to set split mode: %w", err)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cleared {
+		s.recordEvent(billID, actor, EventManualSharesClear, models.EventPayload{})
+	}
+	return s.GetBillSummary(billID)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ErrN8nPayloadTooLarge is returned when an image would make the JSON body sent to n8n
+// larger than N8N_JSON_MAX_BODY_BYTES
+var ErrN8nPayloadTooLarge = errors.New("n8n payload too large")
+
+// n8nPayload is a request body for the n8n webhook and its Content-Type
+type n8nPayload struct {
+	body        []byte
+	contentType string
+}
+
+// n8nJSONBody is the JSON form of the image sent to n8n, for setups whose proxies mangle
+// multipart bodies. ImageBase64 is a data URI.
+type n8nJSONBody struct {
+	BillID      string `json:"bill_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ImageBase64 string `json:"image_base64"`
+}
+
+// buildMultipartPayload sends the image as the "image" file of a multipart form, next to
+// a bill_id field
+func buildMultipartPayload(billID uuid.UUID, imageData []byte, filename string) (*n8nPayload, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("bill_id", billID.String()); err != nil {
+		return nil, fmt.Errorf("failed to write bill_id field: %v", err)
+	}
+
+	part, err := writer.CreateFormFile("image", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %v", err)
+	}
+	if _, err := part.Write(imageData); err != nil {
+		return nil, fmt.Errorf("failed to write image data: %v", err)
+	}
+
+	// Close the writer to finalize the multipart data
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize multipart body: %v", err)
+	}
+	return &n8nPayload{body: body.Bytes(), contentType: writer.FormDataContentType()}, nil
+}
+
+// buildJSONPayload sends the image as a base64 data URI in a JSON body. Bodies over
+// maxBytes are refused with ErrN8nPayloadTooLarge before anything is encoded.
+func buildJSONPayload(billID uuid.UUID, imageData []byte, filename string, maxBytes int) (*n8nPayload, error) {
+	contentType := http.DetectContentType(imageData)
+	prefix := "data:" + contentType + ";base64,"
+
+	// The envelope around the data URI is small; check the part that grows with the image
+	if size := len(prefix) + base64.StdEncoding.EncodedLen(len(imageData)); size > maxBytes {
+		return nil, fmt.Errorf("%w: a %d byte image encodes to %d bytes, the limit is %d",
+			ErrN8nPayloadTooLarge, len(imageData), size, maxBytes)
+	}
+
+	body, err := json.Marshal(n8nJSONBody{
+		BillID:      billID.String(),
+		Filename:    filename,
+		ContentType: contentType,
+		ImageBase64: prefix + base64.StdEncoding.EncodeToString(imageData),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON body: %v", err)
+	}
+	if len(body) > maxBytes {
+		return nil, fmt.Errorf("%w: the body is %d bytes, the limit is %d", ErrN8nPayloadTooLarge, len(body), maxBytes)
+	}
+	return &n8nPayload{body: body, contentType: "application/json"}, nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// participantPalette is the set of colors handed out to participants, in order. The
+// colors are far enough apart to tell participants apart on assignment chips.
+var participantPalette = []string{
+	"#E53935", "#1E88E5", "#43A047", "#FB8C00", "#8E24AA", "#00ACC1",
+	"#FDD835", "#6D4C41", "#D81B60", "#3949AB", "#7CB342", "#546E7A",
+}
+
+// nextParticipantColor returns the palette color used least among used, the earliest in
+// the palette on a tie. No color repeats on a bill until the palette is exhausted.
+func nextParticipantColor(used []string) string {
+	counts := make(map[string]int, len(used))
+	for _, color := range used {
+		counts[strings.ToUpper(color)]++
+	}
+	next := participantPalette[0]
+	for _, color := range participantPalette[1:] {
+		if counts[color] < counts[next] {
+			next = color
+		}
+	}
+	return next
+}
+
+// billParticipantColors returns the colors of a bill's participants that have one
+func billParticipantColors(db *gorm.DB, billID uuid.UUID) ([]string, error) {
+	var colors []string
+	if err := db.Model(&models.Participants{}).
+		Where("bill_id = ? AND color <> ''", billID).
+		Pluck("color", &colors).Error; err != nil {
+		return nil, fmt.Errorf("failed to find participant colors: %w", err)
+	}
+	return colors, nil
+}
+
+// backfillParticipantColors gives a color to the participants of a bill created before
+// colors were stored, in the order given, and saves it. Colors are display-only, so a
+// failure is logged and leaves the participants without one; updated_at is left alone so
+// versions and ETags don't change.
+func (s *BillService) backfillParticipantColors(billID uuid.UUID, participants []models.Participants) {
+	missing := false
+	for _, participant := range participants {
+		if participant.Color == "" {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return
+	}
+
+	used, err := billParticipantColors(s.db, billID)
+	if err != nil {
+		fmt.Printf("Failed to backfill participant colors for bill %s: %v\n", billID, err)
+		return
+	}
+	for i := range participants {
+		if participants[i].Color != "" {
+			continue
+		}
+		color := nextParticipantColor(used)
+		// Another reader may have colored the participant first; keep theirs
+		result := s.db.Model(&models.Participants{}).
+			Where("id = ? AND color = ''", participants[i].ID).
+			UpdateColumn("color", color)
+		if result.Error != nil {
+			fmt.Printf("Failed to backfill color of participant %d: %v\n", participants[i].ID, result.Error)
+			return
+		}
+		if result.RowsAffected == 0 {
+			var current []string
+			if err := s.db.Model(&models.Participants{}).Where("id = ?", participants[i].ID).Pluck("color", &current).Error; err != nil || len(current) == 0 {
+				continue
+			}
+			color = current[0]
+		}
+		participants[i].Color = color
+		used = append(used, color)
+	}
+}
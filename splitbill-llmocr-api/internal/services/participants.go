@@ -0,0 +1,297 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListParticipants returns a bill's participants in display order, narrowed by query
+func (s *BillService) ListParticipants(billID uuid.UUID, query *models.ParticipantListQuery) ([]models.Participants, error) {
+	participants := s.db.Scopes(ScopeBill(billID), ParticipantOrder)
+	if q := strings.TrimSpace(query.Q); q != "" {
+		pattern := "%" + likeEscaper.Replace(q) + "%"
+		participants = participants.Where(
+			"participants.name ILIKE ? OR participants.notes ILIKE ? OR EXISTS (SELECT 1 FROM jsonb_array_elements_text(participants.tags) AS tag WHERE tag ILIKE ?)",
+			pattern, pattern, pattern)
+	}
+	if tag := strings.TrimSpace(query.Tag); tag != "" {
+		participants = participants.Where(
+			"EXISTS (SELECT 1 FROM jsonb_array_elements_text(participants.tags) AS tag WHERE lower(tag) = lower(?))", tag)
+	}
+
+	var list []models.Participants
+	if err := participants.Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch participants: %w", err)
+	}
+	s.backfillParticipantColors(billID, list)
+	return list, nil
+}
+
+// UpdateParticipant applies a partial update to a participant of the bill and returns it.
+// Share of common costs feeds the stored totals, so they are refreshed in the same transaction.
+// A payment status is recorded as by UpdateParticipantPayment, against the share before
+// the update. A participant of another bill is ErrNotFound.
+func (s *BillService) UpdateParticipant(billID uuid.UUID, participantID uint, req *models.ParticipantUpdateRequest, actor string) (*models.ParticipantResponse, error) {
+	updates, err := s.participantUpdates(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var participant models.Participants
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.PaymentStatus != nil {
+			owed, code, err := s.participantOwed(tx, billID, participantID)
+			if err != nil {
+				return err
+			}
+			payment, err := paymentColumns(*req.PaymentStatus, req.AmountPaid, owed, code, time.Now())
+			if err != nil {
+				return err
+			}
+			for column, value := range payment {
+				updates[column] = value
+			}
+		}
+
+		result := tx.Model(&models.Participants{}).Scopes(ScopeBill(billID)).Where("id = ?", participantID).Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update participant: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+		}
+		if err := tx.First(&participant, participantID).Error; err != nil {
+			return fmt.Errorf("failed to fetch updated participant: %w", err)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	// Only field names: the activity log is public and notes and tags are not
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	s.recordEvent(billID, actor, EventParticipantUpdated, models.EventPayload{
+		"participant_id": participantID,
+		"fields":         fields,
+	})
+	response := participantResponse(&participant)
+	return &response, nil
+}
+
+// participantResponse converts a Participants model to ParticipantResponse
+func participantResponse(participant *models.Participants) models.ParticipantResponse {
+	return models.ParticipantResponse{
+		ID:                 participant.ID,
+		BillID:             participant.BillID,
+		Name:               participant.Name,
+		UserID:             participant.UserID,
+		Email:              participant.Email,
+		ClaimedAt:          participant.ClaimedAt,
+		PaymentStatus:      participant.PaymentStatus,
+		AmountPaid:         participant.AmountPaid,
+		PaidAt:             participant.PaidAt,
+		ShareOfCommonCosts: participant.ShareOfCommonCosts,
+		ManualAmount:       participant.ManualAmount,
+		Notes:              participant.Notes,
+		Tags:               participant.Tags,
+		Color:              participant.Color,
+		Position:           participant.Position,
+		CreatedAt:          participant.CreatedAt,
+	}
+}
+
+// MergeParticipants merges the source participant into the target, for two rows that
+// turn out to be the same person, and returns the target. In one transaction the source's
+// assignments move to the target, skipping items the target already has, what the source
+// fronted as a payer is added to the target's, and their shares of common costs, any
+// manual amounts and what they paid back are summed. The target keeps its name and counts
+// as paid only if both were, or as partly paid when either paid something back. The source
+// is then removed. Either participant not being on the bill is
+// ErrNotFound, and merging a participant into itself is ErrInvalidMerge.
+func (s *BillService) MergeParticipants(billID uuid.UUID, sourceID, targetID uint, actor string) (*models.Participants, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("%w: a participant cannot be merged into itself", ErrInvalidMerge)
+	}
+
+	var source, target models.Participants
+	var moved, skipped int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var participants []models.Participants
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(ScopeBill(billID)).
+			Where("id IN ?", []uint{sourceID, targetID}).
+			Find(&participants).Error; err != nil {
+			return fmt.Errorf("failed to find participants: %w", err)
+		}
+		for _, participant := range participants {
+			if participant.ID == sourceID {
+				source = participant
+			} else {
+				target = participant
+			}
+		}
+		if source.ID == 0 {
+			return fmt.Errorf("participant %d not found in bill %s: %w", sourceID, billID, ErrNotFound)
+		}
+		if target.ID == 0 {
+			return fmt.Errorf("participant %d not found in bill %s: %w", targetID, billID, ErrNotFound)
+		}
+
+		// Copy the source's assignments onto the target, skipping items it already has
+		var assignments []models.ItemAssignments
+		if err := tx.Where("participant_id = ?", sourceID).Find(&assignments).Error; err != nil {
+			return fmt.Errorf("failed to find item assignments: %w", err)
+		}
+		if len(assignments) > 0 {
+			copies := make([]models.ItemAssignments, 0, len(assignments))
+			for _, assignment := range assignments {
+				copies = append(copies, models.ItemAssignments{ItemID: assignment.ItemID, ParticipantID: targetID, Weight: assignment.Weight})
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&copies)
+			if result.Error != nil {
+				return fmt.Errorf("failed to move item assignments: %w", result.Error)
+			}
+			moved = result.RowsAffected
+			skipped = int64(len(assignments)) - moved
+		}
+		if err := tx.Unscoped().Where("participant_id = ?", sourceID).Delete(&models.ItemAssignments{}).Error; err != nil {
+			return fmt.Errorf("failed to delete item assignments: %w", err)
+		}
+
+		code, err := s.billCurrencyOf(tx, billID)
+		if err != nil {
+			return err
+		}
+		if err := mergeBillPayer(tx, billID, sourceID, targetID, code); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Bills{}).
+			Where("id = ? AND payer_participant_id = ?", billID, sourceID).
+			Update("payer_participant_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move bill payer: %w", err)
+		}
+
+		updates := map[string]interface{}{
+			"share_of_common_costs": addAmounts(code, target.ShareOfCommonCosts, source.ShareOfCommonCosts),
+		}
+		if source.ManualAmount != nil || target.ManualAmount != nil {
+			var manual float64
+			for _, amount := range []*float64{source.ManualAmount, target.ManualAmount} {
+				if amount != nil {
+					manual = addAmounts(code, manual, *amount)
+				}
+			}
+			updates["manual_amount"] = manual
+		}
+		// What either paid back is kept; the target counts as paid only if both were
+		collected := addAmounts(code, target.AmountPaid, source.AmountPaid)
+		updates["amount_paid"] = collected
+		switch {
+		case source.PaymentStatus == PaymentPaid && target.PaymentStatus == PaymentPaid:
+		case collected > 0:
+			updates["payment_status"] = PaymentPartial
+		default:
+			updates["payment_status"] = PaymentUnpaid
+		}
+		if source.PaidAt != nil && (target.PaidAt == nil || source.PaidAt.After(*target.PaidAt)) {
+			updates["paid_at"] = source.PaidAt
+		}
+		// An account that claimed the source now holds the target, unless it is claimed itself
+		if target.UserID == nil && source.UserID != nil {
+			updates["user_id"] = *source.UserID
+		}
+		if err := tx.Model(&target).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update participant: %w", err)
+		}
+
+		// The source lives on in the target, so it is not kept for restoring
+		if err := tx.Unscoped().Delete(&source).Error; err != nil {
+			return fmt.Errorf("failed to delete participant: %w", err)
+		}
+		if err := tx.First(&target, targetID).Error; err != nil {
+			return fmt.Errorf("failed to fetch merged participant: %w", err)
+		}
+		return s.refreshBillTotals(tx, billID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventParticipantsMerged, models.EventPayload{
+		"participant_id":        target.ID,
+		"name":                  target.Name,
+		"merged_participant_id": source.ID,
+		"merged_name":           source.Name,
+		"assignments_moved":     moved,
+		"assignments_skipped":   skipped,
+	})
+	return &target, nil
+}
+
+// mergeBillPayer moves what the source participant fronted onto the target, adding it to
+// what the target fronted when both paid the merchant. Amounts are added in minor units
+// of code.
+func mergeBillPayer(tx *gorm.DB, billID uuid.UUID, sourceID, targetID uint, code string) error {
+	var payers []models.BillPayers
+	if err := tx.Scopes(ScopeBill(billID)).Where("participant_id IN ?", []uint{sourceID, targetID}).Find(&payers).Error; err != nil {
+		return fmt.Errorf("failed to find bill payers: %w", err)
+	}
+
+	var source, target *models.BillPayers
+	for i := range payers {
+		if payers[i].ParticipantID == sourceID {
+			source = &payers[i]
+		} else {
+			target = &payers[i]
+		}
+	}
+	switch {
+	case source == nil:
+		return nil
+	case target == nil:
+		if err := tx.Model(source).Update("participant_id", targetID).Error; err != nil {
+			return fmt.Errorf("failed to move bill payer: %w", err)
+		}
+	default:
+		if err := tx.Delete(source).Error; err != nil {
+			return fmt.Errorf("failed to delete bill payer: %w", err)
+		}
+		if err := tx.Model(target).Update("amount_paid", addAmounts(code, target.AmountPaid, source.AmountPaid)).Error; err != nil {
+			return fmt.Errorf("failed to update bill payer: %w", err)
+		}
+	}
+	return nil
+}
+
+// normalizeTags trims tags and drops blanks and repeats, comparing without case and
+// keeping the first spelling
+func normalizeTags(tags models.Tags) models.Tags {
+	normalized := models.Tags{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
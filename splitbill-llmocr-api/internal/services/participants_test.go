@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+func TestDeleteParticipantRollsBack(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Hotpot", nil)
+
+	items, err := s.CreateItems(bill.ID, []models.ItemRequest{
+		{Name: "Beef", Price: 18, Quantity: 1},
+		{Name: "Tofu", Price: 5, Quantity: 1},
+	}, "test")
+	if err != nil {
+		t.Fatalf("failed to create items: %v", err)
+	}
+	participant, err := s.AddParticipant(bill.ID, &models.ParticipantRequest{Name: "Ben"}, "test")
+	if err != nil {
+		t.Fatalf("failed to add participant: %v", err)
+	}
+	for _, item := range items {
+		if _, err := s.AssignItem(bill.ID, item.ID, participant.ID, 1, "test"); err != nil {
+			t.Fatalf("failed to assign item %d: %v", item.ID, err)
+		}
+	}
+
+	// The assignments are deleted first, so the participant's delete is the second step
+	failDeletes(t, db, "participants")
+	if _, err := s.DeleteParticipant(bill.ID, participant.ID, "test"); !errors.Is(err, errInjected) {
+		t.Fatalf("DeleteParticipant err = %v, want the injected failure", err)
+	}
+
+	var participants, assignments int64
+	db.Model(&models.Participants{}).Where("id = ?", participant.ID).Count(&participants)
+	db.Model(&models.ItemAssignments{}).Where("participant_id = ?", participant.ID).Count(&assignments)
+	if participants != 1 || assignments != 2 {
+		t.Errorf("after the failed delete: %d participants, %d assignments, want 1 and 2", participants, assignments)
+	}
+}
+
+func TestBulkDeleteParticipantsRollsBack(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, _, participants := seedAssignedBill(t, s)
+
+	failDeletes(t, db, "participants")
+	if _, err := s.BulkDeleteParticipants(billID, participants[:], false, "test"); !errors.Is(err, errInjected) {
+		t.Fatalf("BulkDeleteParticipants err = %v, want the injected failure", err)
+	}
+
+	var remaining int64
+	db.Model(&models.Participants{}).Scopes(ScopeBill(billID)).Count(&remaining)
+	if got := liveAssignments(t, db); remaining != 2 || len(got) != 3 {
+		t.Errorf("after the failed delete: %d participants, %d assignments, want 2 and 3", remaining, len(got))
+	}
+}
+
+func TestBulkDeleteParticipantsMixedBatch(t *testing.T) {
+	for _, allOrNothing := range []bool{false, true} {
+		s, db := newTestBillService(t, nil)
+		billID, _, assigned := seedAssignedBill(t, s)
+		_, _, others := seedAssignedBill(t, s)
+
+		// Cat is the bill's payer and Dan already paid, so neither can go
+		var added [2]uint
+		for i, name := range []string{"Cat", "Dan"} {
+			participant, err := s.AddParticipant(billID, &models.ParticipantRequest{Name: name}, "test")
+			if err != nil {
+				t.Fatalf("failed to add %s: %v", name, err)
+			}
+			added[i] = participant.ID
+		}
+		cat, dan := added[0], added[1]
+		db.Model(&models.Bills{}).Where("id = ?", billID).Update("payer_participant_id", cat)
+		db.Model(&models.Participants{}).Where("id = ?", dan).Update("payment_status", PaymentPaid)
+
+		ids := []uint{assigned[0], cat, 1 << 30, assigned[1], dan, others[0], assigned[0]}
+		result, err := s.BulkDeleteParticipants(billID, ids, allOrNothing, "test")
+
+		wantErrors := []uint{cat, 1 << 30, dan, others[0]}
+		if result == nil || len(result.Errors) != len(wantErrors) {
+			t.Fatalf("allOrNothing=%v: result = %+v, want errors for %v", allOrNothing, result, wantErrors)
+		}
+		for i, id := range wantErrors {
+			if result.Errors[i].ParticipantID != id || result.Errors[i].Error == "" {
+				t.Errorf("allOrNothing=%v: errors[%d] = %+v, want one for %d", allOrNothing, i, result.Errors[i], id)
+			}
+		}
+
+		var remaining int64
+		db.Model(&models.Participants{}).Scopes(ScopeBill(billID)).Count(&remaining)
+		if allOrNothing {
+			if !errors.Is(err, ErrBatchRejected) {
+				t.Errorf("all or nothing: err = %v, want ErrBatchRejected", err)
+			}
+			if remaining != 4 || result.DeletedParticipants != 0 {
+				t.Errorf("all or nothing: %d participants left, %d deleted, want 4 and 0", remaining, result.DeletedParticipants)
+			}
+			continue
+		}
+
+		// Ana and Ben go with their three assignments, once each despite the repeat
+		if err != nil {
+			t.Fatalf("BulkDeleteParticipants: %v", err)
+		}
+		if result.DeletedParticipants != 2 || result.DeletedAssignments != 3 {
+			t.Errorf("deleted %d participants and %d assignments, want 2 and 3", result.DeletedParticipants, result.DeletedAssignments)
+		}
+		if remaining != 2 {
+			t.Errorf("%d participants left, want Cat and Dan", remaining)
+		}
+		db.Model(&models.Participants{}).Where("id = ?", others[0]).Count(&remaining)
+		if remaining != 1 {
+			t.Error("a participant of another bill was deleted")
+		}
+	}
+}
+
+func TestMergeParticipants(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	billID, items, participants := seedAssignedBill(t, s)
+	noodles, tea := items[0], items[1]
+	ana, ben := participants[0], participants[1]
+
+	// Both paid part of their share before anyone noticed they are the same person
+	for id, amount := range map[uint]float64{ana: 4, ben: 3} {
+		if _, err := s.UpdateParticipantPayment(billID, id, PaymentPartial, ptr(amount), nil, "test"); err != nil {
+			t.Fatalf("failed to record payment of %d: %v", id, err)
+		}
+	}
+
+	// Ben's noodles overlap with Ana's and are skipped, his tea moves over
+	merged, err := s.MergeParticipants(billID, ben, ana, "test")
+	if err != nil {
+		t.Fatalf("MergeParticipants: %v", err)
+	}
+	if merged.ID != ana || merged.Name != "Ana" {
+		t.Errorf("merged into %d %q, want Ana", merged.ID, merged.Name)
+	}
+	if merged.AmountPaid != 7 || merged.PaymentStatus != PaymentPartial {
+		t.Errorf("merged payment = %v %s, want 7 partial", merged.AmountPaid, merged.PaymentStatus)
+	}
+
+	want := map[[2]uint]bool{{noodles, ana}: true, {tea, ana}: true}
+	got := liveAssignments(t, db)
+	if len(got) != len(want) {
+		t.Errorf("assignments = %v, want %v", got, want)
+	}
+	for key := range want {
+		if !got[key] {
+			t.Errorf("assignment of item %d to participant %d is missing", key[0], key[1])
+		}
+	}
+
+	var remaining int64
+	db.Unscoped().Model(&models.Participants{}).Where("id = ?", ben).Count(&remaining)
+	if remaining != 0 {
+		t.Error("source participant still exists after the merge")
+	}
+
+	var event models.BillEvents
+	if err := db.Where("bill_id = ? AND action = ?", billID, EventParticipantsMerged).First(&event).Error; err != nil {
+		t.Fatalf("no merge event: %v", err)
+	}
+	if event.Payload["assignments_moved"] != float64(1) || event.Payload["assignments_skipped"] != float64(1) {
+		t.Errorf("merge event payload = %v, want 1 moved and 1 skipped", event.Payload)
+	}
+}
+
+func TestMergeParticipantsRejectsBadPairs(t *testing.T) {
+	s, _ := newTestBillService(t, nil)
+	billID, _, participants := seedAssignedBill(t, s)
+	_, _, others := seedAssignedBill(t, s)
+
+	if _, err := s.MergeParticipants(billID, participants[0], participants[0], "test"); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("merge into itself: err = %v, want ErrInvalidMerge", err)
+	}
+	if _, err := s.MergeParticipants(billID, others[0], participants[0], "test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("merge across bills: err = %v, want ErrNotFound", err)
+	}
+}
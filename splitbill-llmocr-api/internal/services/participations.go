@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxParticipations bounds how many bills a user's participation list computes shares for
+const maxParticipations = 100
+
+// ClaimParticipant links a participant of the bill to the user, so the bill shows up
+// under their account. A participant claimed through the share link can still be linked.
+// Claiming a participant the user already holds returns it as it is.
+// A participant claimed by someone else, or a second participant of the same bill, is
+// ErrAlreadyClaimed; a participant of another bill is ErrNotFound.
+func (s *BillService) ClaimParticipant(billID uuid.UUID, participantID, userID uint, actor string) (*models.ParticipantResponse, error) {
+	var participant models.Participants
+	claimed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(ScopeBill(billID)).
+			Where("id = ?", participantID).
+			First(&participant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find participant: %w", err)
+		}
+		if participant.UserID != nil {
+			if *participant.UserID != userID {
+				return fmt.Errorf("participant %d: %w", participantID, ErrAlreadyClaimed)
+			}
+			return nil
+		}
+
+		var other int64
+		if err := tx.Model(&models.Participants{}).
+			Where("bill_id = ? AND user_id = ? AND id <> ?", billID, userID, participantID).
+			Count(&other).Error; err != nil {
+			return fmt.Errorf("failed to check claimed participants: %w", err)
+		}
+		if other > 0 {
+			return fmt.Errorf("another participant of bill %s: %w", billID, ErrAlreadyClaimed)
+		}
+
+		updates := map[string]interface{}{"user_id": userID}
+		if participant.ClaimedAt == nil {
+			updates["claimed_at"] = time.Now()
+		}
+		if err := tx.Model(&participant).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to claim participant: %w", err)
+		}
+		claimed = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claimed {
+		s.recordEvent(billID, actor, EventParticipantClaimed, models.EventPayload{
+			"participant_id": participant.ID,
+			"name":           participant.Name,
+		})
+	}
+	response := participantResponse(&participant)
+	return &response, nil
+}
+
+// ListParticipations returns the bills on which the user claimed a participant, newest
+// first, with the share each participant owes. At most maxParticipations bills are listed.
+func (s *BillService) ListParticipations(userID uint) (*models.ParticipationsResponse, error) {
+	var participants []models.Participants
+	if err := s.db.Preload("Bill").
+		Joins("JOIN bills ON bills.id = participants.bill_id AND bills.deleted_at IS NULL").
+		Where("participants.user_id = ?", userID).
+		Order("bills.created_at DESC").
+		Limit(maxParticipations).
+		Find(&participants).Error; err != nil {
+		return nil, fmt.Errorf("failed to find participations: %w", err)
+	}
+
+	participations := make([]models.Participation, 0, len(participants))
+	for _, participant := range participants {
+		summary, err := s.GetBillSummary(participant.BillID)
+		if err != nil {
+			return nil, err
+		}
+		participation := models.Participation{
+			BillID:        participant.BillID,
+			BillName:      participant.Bill.Name,
+			BillStatus:    participant.Bill.Status,
+			Currency:      summary.Currency,
+			ParticipantID: participant.ID,
+			Name:          participant.Name,
+			PaymentStatus: participant.PaymentStatus,
+			AmountPaid:    participant.AmountPaid,
+			CreatedAt:     participant.Bill.CreatedAt,
+		}
+		for _, share := range summary.ParticipantShares {
+			if share.ParticipantID == participant.ID {
+				participation.Owed = share.Owed
+				break
+			}
+		}
+		participations = append(participations, participation)
+	}
+	return &models.ParticipationsResponse{Participations: participations, Total: len(participations)}, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/currency"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Payment statuses of a participant
+const (
+	PaymentUnpaid  = "unpaid"
+	PaymentPaid    = "paid"
+	PaymentPartial = "partial"
+)
+
+// ErrNoParticipants is returned when a bill has no participants to mark paid
+var ErrNoParticipants = errors.New("no participants to mark paid")
+
+// ErrInvalidPayment is returned when a recorded payment doesn't fit the status or exceeds
+// the participant's share
+var ErrInvalidPayment = errors.New("invalid payment")
+
+// paymentColumns returns the participant columns that record a payment with status. owed
+// is the participant's share of the bill in minor units of code. A paid participant
+// without an amount paid their whole share, a partial payment needs an amount, and an
+// unpaid participant clears the amount and paid_at.
+func paymentColumns(status string, amountPaid *float64, owed int64, code string, now time.Time) (map[string]interface{}, error) {
+	if status == PaymentUnpaid {
+		if amountPaid != nil && *amountPaid != 0 {
+			return nil, fmt.Errorf("%w: amount_paid must be 0 for an unpaid participant", ErrInvalidPayment)
+		}
+		return map[string]interface{}{"payment_status": status, "amount_paid": 0, "paid_at": nil}, nil
+	}
+
+	paid := owed
+	switch {
+	case amountPaid != nil:
+		paid = currency.ToMinor(*amountPaid, code)
+	case status == PaymentPartial:
+		return nil, fmt.Errorf("%w: amount_paid is required for a partial payment", ErrInvalidPayment)
+	}
+	if status == PaymentPartial && paid <= 0 {
+		return nil, fmt.Errorf("%w: amount_paid must be greater than 0 for a partial payment", ErrInvalidPayment)
+	}
+	if paid > owed {
+		return nil, fmt.Errorf("%w: amount_paid %.2f exceeds the participant's share of %.2f",
+			ErrInvalidPayment, currency.FromMinor(paid, code), currency.FromMinor(owed, code))
+	}
+	return map[string]interface{}{
+		"payment_status": status,
+		"amount_paid":    currency.FromMinor(paid, code),
+		"paid_at":        now,
+	}, nil
+}
+
+// participantOwed returns a participant's share of the bill in minor units, with the
+// bill's currency. A participant or bill that doesn't exist is ErrNotFound.
+func (s *BillService) participantOwed(db *gorm.DB, billID uuid.UUID, participantID uint) (int64, string, error) {
+	owed, code, err := s.billShares(db, billID)
+	if err != nil {
+		return 0, "", err
+	}
+	share, ok := owed[participantID]
+	if !ok {
+		return 0, code, fmt.Errorf("participant %d not found in bill %s: %w", participantID, billID, ErrNotFound)
+	}
+	return share, code, nil
+}
+
+// billShares returns every participant's share of the bill in minor units by participant
+// ID, with the bill's currency. A bill that doesn't exist is ErrNotFound.
+func (s *BillService) billShares(db *gorm.DB, billID uuid.UUID) (map[uint]int64, string, error) {
+	var bill models.Bills
+	if err := db.Preload("Items.ItemAssignments").Preload("Participants", ParticipantOrder).Preload("Payers").First(&bill, "id = ?", billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+		}
+		return nil, "", fmt.Errorf("failed to find bill: %w", err)
+	}
+
+	code := s.billCurrency(bill.Currency)
+	shares := billAllocation(&bill, code).Shares
+	owed := make(map[uint]int64, len(shares))
+	for _, share := range shares {
+		owed[share.ParticipantID] = share.Owed
+	}
+	return owed, code, nil
+}
+
+// MarkAllPaid marks every participant of the bill paid for their whole share, except those
+// in except, and returns all of the bill's participants in display order. A bill without
+// participants is ErrNoParticipants, as is one where every participant is excepted.
+// Participants that already paid are marked again with the current time.
+func (s *BillService) MarkAllPaid(billID uuid.UUID, except []uint, actor string) ([]models.Participants, error) {
+	skip := make(map[uint]bool, len(except))
+	for _, id := range except {
+		skip[id] = true
+	}
+
+	var participants []models.Participants
+	var marked []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(ScopeBill(billID), ParticipantOrder).
+			Find(&participants).Error; err != nil {
+			return fmt.Errorf("failed to find participants: %w", err)
+		}
+		if len(participants) == 0 {
+			return fmt.Errorf("bill %s: %w", billID, ErrNoParticipants)
+		}
+
+		owed, code, err := s.billShares(tx, billID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, participant := range participants {
+			if skip[participant.ID] {
+				continue
+			}
+			updates, err := paymentColumns(PaymentPaid, nil, owed[participant.ID], code, now)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Participants{}).Where("id = ?", participant.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to mark participant %d paid: %w", participant.ID, err)
+			}
+			marked = append(marked, participant.ID)
+		}
+		if len(marked) == 0 {
+			return fmt.Errorf("every participant of bill %s is excepted: %w", billID, ErrNoParticipants)
+		}
+
+		return tx.Scopes(ScopeBill(billID), ParticipantOrder).Find(&participants).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(billID, actor, EventPaymentsMarkedPaid, models.EventPayload{
+		"participant_ids": marked,
+		"count":           len(marked),
+	})
+	return participants, nil
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Where a defaulted bill field came from, as reported in BillResponse.Defaulted
+const (
+	DefaultFromPreferences = "preferences"
+	DefaultFromConfig      = "config"
+)
+
+// GetPreferences returns a user's preferences, or empty ones if they never saved any
+func (s *UserService) GetPreferences(userID uint) (*models.UserPreferences, error) {
+	prefs, err := loadPreferences(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = &models.UserPreferences{UserID: userID}
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences replaces a user's preferences. DefaultCurrency must already be a
+// normalized, supported code or empty.
+func (s *UserService) UpdatePreferences(userID uint, req *models.UserPreferencesRequest) (*models.UserPreferences, error) {
+	prefs := &models.UserPreferences{
+		UserID:            userID,
+		DefaultTipPercent: req.DefaultTipPercent,
+		DefaultTaxPercent: req.DefaultTaxPercent,
+		DefaultCurrency:   req.DefaultCurrency,
+		Locale:            req.Locale,
+		CleanupEmptyBills: req.CleanupEmptyBills,
+	}
+	if err := s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(prefs).Error; err != nil {
+		return nil, fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// loadPreferences returns a user's saved preferences, or nil if they have none
+func loadPreferences(db *gorm.DB, userID uint) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	if err := db.First(&prefs, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+// applyBillDefaults fills in the currency and suggested tip and tax percentages a new bill
+// was created without. An explicit value wins over the owner's preferences, which win over
+// the server config; anonymous bills only use the config. Explicit tip and tax amounts
+// leave nothing to suggest. It returns the fields it filled and where each came from.
+func (s *BillService) applyBillDefaults(bill *models.Bills, req *models.BillRequest, userID *uint) (map[string]string, error) {
+	var prefs *models.UserPreferences
+	if userID != nil {
+		var err error
+		if prefs, err = loadPreferences(s.db, *userID); err != nil {
+			return nil, err
+		}
+	}
+	if prefs == nil {
+		prefs = &models.UserPreferences{}
+	}
+
+	defaulted := make(map[string]string)
+	if req.Currency == "" {
+		if prefs.DefaultCurrency != "" {
+			bill.Currency = prefs.DefaultCurrency
+			defaulted["currency"] = DefaultFromPreferences
+		} else {
+			bill.Currency = s.defaultCurrency
+			defaulted["currency"] = DefaultFromConfig
+		}
+	}
+
+	percents := []struct {
+		field      string
+		explicit   *float64
+		preference *float64
+		config     *float64
+		dest       **float64
+	}{
+		{"tip_percent", req.TipAmount, prefs.DefaultTipPercent, s.defaultTipPercent, &bill.SuggestedTipPercent},
+		{"tax_percent", req.TaxAmount, prefs.DefaultTaxPercent, s.defaultTaxPercent, &bill.SuggestedTaxPercent},
+	}
+	for _, percent := range percents {
+		switch {
+		case percent.explicit != nil:
+		case percent.preference != nil:
+			*percent.dest = percent.preference
+			defaulted[percent.field] = DefaultFromPreferences
+		case percent.config != nil:
+			*percent.dest = percent.config
+			defaulted[percent.field] = DefaultFromConfig
+		}
+	}
+	return defaulted, nil
+}
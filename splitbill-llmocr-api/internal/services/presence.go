@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// ErrPresenceFull is returned when the presence store already tracks its maximum number of editors
+var ErrPresenceFull = errors.New("too many active editors")
+
+// maxTrackedEditors bounds the memory used by the presence store across all bills
+const maxTrackedEditors = 10000
+
+// presenceStore remembers who has a bill's editing screen open. Entries expire after ttl
+// without a heartbeat; expired entries are hidden immediately and removed by sweep.
+type presenceStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEditors int
+	count      int
+	bills      map[uuid.UUID]map[string]models.BillEditor
+}
+
+// newPresenceStore creates a presence store whose entries live for ttl
+func newPresenceStore(ttl time.Duration, maxEditors int) *presenceStore {
+	return &presenceStore{
+		ttl:        ttl,
+		maxEditors: maxEditors,
+		bills:      make(map[uuid.UUID]map[string]models.BillEditor),
+	}
+}
+
+// editorKey identifies an editor within a bill, whether a user or a participant
+func editorKey(editor models.BillEditor) string {
+	if editor.UserID != nil {
+		return fmt.Sprintf("user:%d", *editor.UserID)
+	}
+	return fmt.Sprintf("participant:%d", *editor.ParticipantID)
+}
+
+// touch records a heartbeat from editor at now
+func (p *presenceStore) touch(billID uuid.UUID, editor models.BillEditor, now time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	editors := p.bills[billID]
+	key := editorKey(editor)
+	if _, exists := editors[key]; !exists {
+		if p.count >= p.maxEditors {
+			// The sweep may drop this bill's map once its last editor expires
+			p.sweepLocked(now)
+			editors = p.bills[billID]
+		}
+		if p.count >= p.maxEditors {
+			return ErrPresenceFull
+		}
+		if editors == nil {
+			editors = make(map[string]models.BillEditor)
+			p.bills[billID] = editors
+		}
+		p.count++
+	}
+
+	editor.LastSeenAt = now
+	editor.ExpiresAt = now.Add(p.ttl)
+	editors[key] = editor
+	return nil
+}
+
+// list returns the bill's editors that have not expired, by name
+func (p *presenceStore) list(billID uuid.UUID, now time.Time) []models.BillEditor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	editors := make([]models.BillEditor, 0, len(p.bills[billID]))
+	for _, editor := range p.bills[billID] {
+		if now.Before(editor.ExpiresAt) {
+			editors = append(editors, editor)
+		}
+	}
+	sort.Slice(editors, func(i, j int) bool {
+		if editors[i].Name != editors[j].Name {
+			return editors[i].Name < editors[j].Name
+		}
+		return editors[i].LastSeenAt.Before(editors[j].LastSeenAt)
+	})
+	return editors
+}
+
+// remove forgets every editor of a bill
+func (p *presenceStore) remove(billID uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.count -= len(p.bills[billID])
+	delete(p.bills, billID)
+}
+
+// sweep drops expired editors and returns how many were removed
+func (p *presenceStore) sweep(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sweepLocked(now)
+}
+
+// sweepLocked is sweep for callers that already hold the lock
+func (p *presenceStore) sweepLocked(now time.Time) int {
+	removed := 0
+	for billID, editors := range p.bills {
+		for key, editor := range editors {
+			if !now.Before(editor.ExpiresAt) {
+				delete(editors, key)
+				removed++
+			}
+		}
+		if len(editors) == 0 {
+			delete(p.bills, billID)
+		}
+	}
+	p.count -= removed
+	return removed
+}
+
+// start sweeps expired editors every ttl until ctx is cancelled
+func (p *presenceStore) start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.ttl)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				p.sweep(now)
+			}
+		}
+	}()
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+// userEditor and guestEditor build the two kinds of editor a heartbeat records
+func userEditor(id uint, name string) models.BillEditor {
+	return models.BillEditor{UserID: &id, Name: name}
+}
+
+func guestEditor(id uint, name string) models.BillEditor {
+	return models.BillEditor{ParticipantID: &id, Name: name}
+}
+
+// editorNames lists the names of editors in order
+func editorNames(editors []models.BillEditor) []string {
+	names := make([]string, len(editors))
+	for i, editor := range editors {
+		names[i] = editor.Name
+	}
+	return names
+}
+
+func TestPresenceJoin(t *testing.T) {
+	p := newPresenceStore(30*time.Second, 10)
+	billID := uuid.New()
+	now := time.Unix(1700000000, 0)
+
+	if err := p.touch(billID, userEditor(1, "Budi"), now); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	if err := p.touch(billID, guestEditor(1, "Ana"), now.Add(time.Second)); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+	// User 1 and participant 1 are different editors; a repeated heartbeat is not a new one
+	if err := p.touch(billID, userEditor(1, "Budi"), now.Add(2*time.Second)); err != nil {
+		t.Fatalf("touch: %v", err)
+	}
+
+	editors := p.list(billID, now.Add(2*time.Second))
+	if names := editorNames(editors); len(names) != 2 || names[0] != "Ana" || names[1] != "Budi" {
+		t.Fatalf("editors = %v, want [Ana Budi]", names)
+	}
+	if budi := editors[1]; !budi.LastSeenAt.Equal(now.Add(2*time.Second)) || !budi.ExpiresAt.Equal(now.Add(32*time.Second)) {
+		t.Errorf("Budi seen %s until %s, want the latest heartbeat plus the ttl", budi.LastSeenAt, budi.ExpiresAt)
+	}
+	if p.count != 2 {
+		t.Errorf("count = %d, want 2", p.count)
+	}
+
+	// Other bills have their own editors
+	if editors := p.list(uuid.New(), now); len(editors) != 0 {
+		t.Errorf("another bill lists %v", editorNames(editors))
+	}
+}
+
+func TestPresenceExpiry(t *testing.T) {
+	const ttl = 30 * time.Second
+	p := newPresenceStore(ttl, 10)
+	billID := uuid.New()
+	now := time.Unix(1700000000, 0)
+
+	p.touch(billID, userEditor(1, "Budi"), now)
+	p.touch(billID, userEditor(2, "Citra"), now)
+	// Citra keeps sending heartbeats, Budi closed the screen
+	p.touch(billID, userEditor(2, "Citra"), now.Add(20*time.Second))
+
+	if names := editorNames(p.list(billID, now.Add(ttl-time.Nanosecond))); len(names) != 2 {
+		t.Errorf("just before the ttl: editors = %v, want both", names)
+	}
+	// Expired editors are hidden at once, before any sweep
+	if names := editorNames(p.list(billID, now.Add(ttl))); len(names) != 1 || names[0] != "Citra" {
+		t.Errorf("at the ttl: editors = %v, want [Citra]", names)
+	}
+	if p.count != 2 {
+		t.Errorf("count before the sweep = %d, want 2", p.count)
+	}
+
+	if removed := p.sweep(now.Add(ttl)); removed != 1 {
+		t.Errorf("sweep removed %d, want 1", removed)
+	}
+	if p.count != 1 || len(p.bills[billID]) != 1 {
+		t.Errorf("after the sweep: count %d, %d editors stored; want 1", p.count, len(p.bills[billID]))
+	}
+
+	// Once everyone has gone the bill itself is forgotten
+	p.sweep(now.Add(20*time.Second + ttl))
+	if _, tracked := p.bills[billID]; tracked || p.count != 0 {
+		t.Errorf("after everyone expired: bill tracked %v, count %d", tracked, p.count)
+	}
+}
+
+func TestPresenceBounded(t *testing.T) {
+	const ttl = 30 * time.Second
+	p := newPresenceStore(ttl, 2)
+	first, second := uuid.New(), uuid.New()
+	now := time.Unix(1700000000, 0)
+
+	p.touch(first, userEditor(1, "Budi"), now)
+	p.touch(second, userEditor(2, "Citra"), now.Add(10*time.Second))
+
+	// The limit counts editors across all bills
+	if err := p.touch(first, userEditor(3, "Dewi"), now.Add(time.Second)); !errors.Is(err, ErrPresenceFull) {
+		t.Errorf("third editor: err = %v, want ErrPresenceFull", err)
+	}
+	// Known editors still refresh at the limit
+	if err := p.touch(first, userEditor(1, "Budi"), now.Add(2*time.Second)); err != nil {
+		t.Errorf("heartbeat from a tracked editor at the limit: %v", err)
+	}
+
+	// A full store sweeps before refusing, so expired entries make room
+	if err := p.touch(first, userEditor(3, "Dewi"), now.Add(2*time.Second+ttl)); err != nil {
+		t.Fatalf("third editor after Budi expired: %v", err)
+	}
+	if names := editorNames(p.list(first, now.Add(2*time.Second+ttl))); len(names) != 1 || names[0] != "Dewi" {
+		t.Errorf("editors = %v, want [Dewi]", names)
+	}
+
+	// Removing a bill frees its slots and leaves the others
+	p.remove(first)
+	if p.count != 1 {
+		t.Errorf("count after removing the bill = %d, want 1", p.count)
+	}
+}
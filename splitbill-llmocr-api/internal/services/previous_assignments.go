@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSameBill is returned when a bill is asked to copy assignments from itself
+var ErrSameBill = errors.New("source bill is the same bill")
+
+// assignmentPriceTolerance is how far, as a fraction, an item's price may have moved since
+// the source bill and still count as the same item
+const assignmentPriceTolerance = 0.10
+
+// ApplyPreviousAssignments copies item assignments from sourceID onto billID. Items match
+// by name, ignoring case and spacing, when their prices are within 10% of each other; each
+// source item matches at most one item. A matched item gets the source item's participants
+// that are on this bill under the same name. Nothing is guessed for the rest, which is
+// reported instead. Assignments that already exist are kept, so applying twice changes
+// nothing the second time.
+func (s *BillService) ApplyPreviousAssignments(billID, sourceID uuid.UUID, actor string) (*models.ApplyAssignmentsReport, error) {
+	if billID == sourceID {
+		return nil, ErrSameBill
+	}
+
+	report := &models.ApplyAssignmentsReport{
+		SourceBillID:          sourceID,
+		Matched:               []models.MatchedItemAssignment{},
+		UnmatchedItems:        []models.UnmatchedItem{},
+		UnmatchedParticipants: []string{},
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+			Preload("Participants", ParticipantOrder).
+			First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		var source models.Bills
+		if err := tx.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+			Preload("Items.ItemAssignments.Participant").
+			First(&source, "id = ?", sourceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("source bill %s: %w", sourceID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find source bill: %w", err)
+		}
+
+		participants := make(map[string]models.Participants, len(bill.Participants))
+		for _, participant := range bill.Participants {
+			participants[matchKey(participant.Name)] = participant
+		}
+
+		used := make(map[uint]bool, len(source.Items))
+		missing := make(map[string]bool)
+		var assignments []models.ItemAssignments
+		for _, item := range bill.Items {
+			sourceItem, ok := matchSourceItem(item, source.Items, used)
+			if !ok {
+				report.UnmatchedItems = append(report.UnmatchedItems, models.UnmatchedItem{ItemID: item.ID, Name: item.Name})
+				continue
+			}
+			used[sourceItem.ID] = true
+
+			matched := models.MatchedItemAssignment{
+				ItemID:         item.ID,
+				SourceItemID:   sourceItem.ID,
+				Name:           item.Name,
+				ParticipantIDs: []uint{},
+				Participants:   []string{},
+			}
+			for _, assignment := range sourceItem.ItemAssignments {
+				name := assignment.Participant.Name
+				participant, ok := participants[matchKey(name)]
+				if !ok {
+					missing[name] = true
+					continue
+				}
+				matched.ParticipantIDs = append(matched.ParticipantIDs, participant.ID)
+				matched.Participants = append(matched.Participants, participant.Name)
+				assignments = append(assignments, models.ItemAssignments{ItemID: item.ID, ParticipantID: participant.ID, Weight: assignment.Weight})
+			}
+			report.Matched = append(report.Matched, matched)
+		}
+
+		for name := range missing {
+			report.UnmatchedParticipants = append(report.UnmatchedParticipants, name)
+		}
+		sort.Strings(report.UnmatchedParticipants)
+
+		if len(assignments) == 0 {
+			return nil
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&assignments)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create item assignments: %w", result.Error)
+		}
+		report.AssignmentsCreated = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if report.AssignmentsCreated > 0 {
+		s.recordEvent(billID, actor, EventAssignmentsCopied, models.EventPayload{
+			"source_bill_id":      sourceID,
+			"assignments_created": report.AssignmentsCreated,
+		})
+	}
+	return report, nil
+}
+
+// matchSourceItem finds the first unused source item with the same name as item and a
+// price within assignmentPriceTolerance
+func matchSourceItem(item models.Items, sourceItems []models.Items, used map[uint]bool) (models.Items, bool) {
+	name := matchKey(item.Name)
+	for _, candidate := range sourceItems {
+		if used[candidate.ID] || matchKey(candidate.Name) != name {
+			continue
+		}
+		if math.Abs(candidate.Price-item.Price) <= assignmentPriceTolerance*math.Max(candidate.Price, item.Price) {
+			return candidate, true
+		}
+	}
+	return models.Items{}, false
+}
+
+// matchKey is the form names are compared in: lower case with single spaces
+func matchKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,27 @@
+package services
+
+import "math"
+
+// quantityScale is the number of thousandths in one unit; quantities are stored to three
+// decimals, enough for weights in kilograms down to the gram
+const quantityScale = 1000
+
+// toThousandths converts a quantity to whole thousandths
+func toThousandths(quantity float64) int64 {
+	return int64(math.Round(quantity * quantityScale))
+}
+
+// fromThousandths converts whole thousandths back to a quantity
+func fromThousandths(thousandths int64) float64 {
+	return float64(thousandths) / quantityScale
+}
+
+// roundQuantity rounds a quantity to the three decimals that are stored
+func roundQuantity(quantity float64) float64 {
+	return fromThousandths(toThousandths(quantity))
+}
+
+// isWholeQuantity reports whether a quantity counts whole units rather than a weight
+func isWholeQuantity(quantity float64) bool {
+	return toThousandths(quantity)%quantityScale == 0
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookFrontendRevalidation names the frontend revalidation webhook in the delivery log
+const WebhookFrontendRevalidation = "frontend_revalidation"
+
+// Headers carrying the revalidation signature. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with FRONTEND_REVALIDATE_SECRET.
+const (
+	RevalidateTimestampHeader = "X-Splitbill-Timestamp"
+	RevalidateSignatureHeader = "X-Splitbill-Signature"
+)
+
+// RevalidationPayload is the body posted to the frontend revalidation URL. ShareSlug is nil
+// once the bill is deleted; RevokedShareSlugs lists share links that stopped working.
+type RevalidationPayload struct {
+	BillID            uuid.UUID `json:"bill_id"`
+	ShareSlug         *string   `json:"share_slug"`
+	RevokedShareSlugs []string  `json:"revoked_share_slugs"`
+}
+
+// revalidator tells the frontend to re-render a bill's public share page. Changes are
+// collected for one debounce window per bill so a burst of edits produces a single call.
+type revalidator struct {
+	db          *gorm.DB
+	url         string
+	secret      string
+	debounce    time.Duration
+	maxAttempts int
+	client      *http.Client
+
+	mu      sync.Mutex
+	pending map[uuid.UUID][]string
+}
+
+// newRevalidator creates a revalidator; it does nothing when url is empty
+func newRevalidator(db *gorm.DB, url, secret string, debounce time.Duration, maxAttempts int) *revalidator {
+	return &revalidator{
+		db:          db,
+		url:         url,
+		secret:      secret,
+		debounce:    debounce,
+		maxAttempts: maxAttempts,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		pending:     make(map[uuid.UUID][]string),
+	}
+}
+
+// schedule asks for billID's share page to be revalidated at the end of the current
+// debounce window, opening one if needed. revokedSlug, when not empty, is a share token
+// that no longer works.
+func (r *revalidator) schedule(billID uuid.UUID, revokedSlug string) {
+	if r.url == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revoked, open := r.pending[billID]
+	if revokedSlug != "" {
+		revoked = append(revoked, revokedSlug)
+	}
+	r.pending[billID] = revoked
+	if !open {
+		time.AfterFunc(r.debounce, func() { r.flush(billID) })
+	}
+}
+
+// flush closes billID's debounce window and delivers one revalidation for it
+func (r *revalidator) flush(billID uuid.UUID) {
+	r.mu.Lock()
+	revoked := r.pending[billID]
+	delete(r.pending, billID)
+	r.mu.Unlock()
+
+	payload := RevalidationPayload{BillID: billID, RevokedShareSlugs: []string{}}
+	if revoked != nil {
+		payload.RevokedShareSlugs = revoked
+	}
+
+	var bill models.Bills
+	err := r.db.Select("id", "share_token").Where("id = ?", billID).First(&bill).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Printf("Failed to load bill %s for revalidation: %v\n", billID, err)
+		return
+	}
+	if err == nil {
+		payload.ShareSlug = bill.ShareToken
+	}
+
+	// Nothing public to re-render
+	if payload.ShareSlug == nil && len(payload.RevokedShareSlugs) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Printf("Failed to encode revalidation for bill %s: %v\n", billID, err)
+		return
+	}
+	deliverWebhook(r.db, WebhookFrontendRevalidation, billID, r.maxAttempts, func() (int, error) {
+		return r.post(body, time.Now())
+	})
+}
+
+// post sends one signed revalidation request and returns the response status
+func (r *revalidator) post(body []byte, now time.Time) (int, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(RevalidateTimestampHeader, timestamp)
+	req.Header.Set(RevalidateSignatureHeader, "sha256="+SignRevalidation(r.secret, timestamp, body))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("frontend answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignRevalidation returns the hex HMAC-SHA256 signature the frontend checks
+func SignRevalidation(secret, timestamp string, body []byte) string {
+	return signHMAC(secret, []byte(timestamp), []byte("."), body)
+}
+
+// ListWebhookDeliveries returns one page of outgoing webhook attempts, newest first,
+// optionally for a single bill
+func (s *BillService) ListWebhookDeliveries(billID *uuid.UUID, page, pageSize int) (*models.WebhookDeliveryListResponse, error) {
+	deliveries := s.db.Model(&models.WebhookDeliveries{})
+	if billID != nil {
+		deliveries = deliveries.Where("bill_id = ?", *billID)
+	}
+	deliveries = deliveries.Session(&gorm.Session{})
+
+	var total int64
+	if err := deliveries.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	list := []models.WebhookDeliveries{}
+	if err := deliveries.
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return &models.WebhookDeliveryListResponse{
+		Deliveries: list,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+	}, nil
+}
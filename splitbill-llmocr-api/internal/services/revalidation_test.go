@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+const revalidateSecret = "revalidate-test-secret"
+
+// revalidation is one request the test receiver got
+type revalidation struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a frontend stand-in that answers each request with the next of
+// statuses, repeating the last one, and hands every request it gets to the returned channel
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan revalidation) {
+	t.Helper()
+
+	received := make(chan revalidation, 16)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- revalidation{header: r.Header.Clone(), body: body}
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// expectNone fails if the receiver gets anything within wait
+func expectNone(t *testing.T, received <-chan revalidation, wait time.Duration) {
+	t.Helper()
+	select {
+	case got := <-received:
+		t.Fatalf("unexpected revalidation %s", got.body)
+	case <-time.After(wait):
+	}
+}
+
+// expectOne waits for the next revalidation
+func expectOne(t *testing.T, received <-chan revalidation) revalidation {
+	t.Helper()
+	select {
+	case got := <-received:
+		return got
+	case <-time.After(5 * time.Second):
+		t.Fatal("no revalidation arrived")
+		return revalidation{}
+	}
+}
+
+func TestRevalidationSignature(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	r := newRevalidator(nil, server.URL, revalidateSecret, time.Second, 1)
+
+	body := []byte(`{"bill_id":"b"}`)
+	now := time.Unix(1700000000, 0)
+	if status, err := r.post(body, now); status != http.StatusOK || err != nil {
+		t.Fatalf("post = %d, %v", status, err)
+	}
+	got := expectOne(t, received)
+
+	timestamp := got.header.Get(RevalidateTimestampHeader)
+	if timestamp != strconv.FormatInt(now.Unix(), 10) {
+		t.Errorf("timestamp = %q, want %d", timestamp, now.Unix())
+	}
+	// The receiver's side of the check: HMAC-SHA256 of "<timestamp>.<body>"
+	mac := hmac.New(sha256.New, []byte(revalidateSecret))
+	mac.Write([]byte(timestamp + "." + string(got.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature := got.header.Get(RevalidateSignatureHeader); !hmac.Equal([]byte(signature), []byte(want)) {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+	if SignRevalidation("another secret", timestamp, got.body) == SignRevalidation(revalidateSecret, timestamp, got.body) {
+		t.Error("signature does not depend on the secret")
+	}
+
+	// Anything but a 2xx is a failure to retry
+	server, _ = newReceiver(t, http.StatusBadGateway)
+	r = newRevalidator(nil, server.URL, revalidateSecret, time.Second, 1)
+	if status, err := r.post(body, now); status != http.StatusBadGateway || err == nil {
+		t.Errorf("post to a failing frontend = %d, %v; want 502 and an error", status, err)
+	}
+}
+
+func TestRevalidationDebounce(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Shared dinner", nil)
+
+	const debounce = 100 * time.Millisecond
+	server, received := newReceiver(t, http.StatusOK)
+	r := newRevalidator(db, server.URL, revalidateSecret, debounce, 1)
+
+	// A burst of edits and a revoked link within one window make a single call
+	start := time.Now()
+	r.schedule(bill.ID, "")
+	r.schedule(bill.ID, "old-slug")
+	r.schedule(bill.ID, "")
+	got := expectOne(t, received)
+	if waited := time.Since(start); waited < debounce {
+		t.Errorf("revalidation sent after %s, before the %s window closed", waited, debounce)
+	}
+	expectNone(t, received, 3*debounce)
+
+	var payload RevalidationPayload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload.BillID != bill.ID || payload.ShareSlug == nil || *payload.ShareSlug != *bill.ShareToken {
+		t.Errorf("payload = %s, want bill %s with its share slug", got.body, bill.ID)
+	}
+	if len(payload.RevokedShareSlugs) != 1 || payload.RevokedShareSlugs[0] != "old-slug" {
+		t.Errorf("revoked slugs = %v, want [old-slug]", payload.RevokedShareSlugs)
+	}
+
+	// The next edit opens a new window
+	r.schedule(bill.ID, "")
+	expectOne(t, received)
+
+	var deliveries []models.WebhookDeliveries
+	db.Where("bill_id = ? AND target = ?", bill.ID, WebhookFrontendRevalidation).Find(&deliveries)
+	if len(deliveries) != 2 || !deliveries[0].Delivered || !deliveries[1].Delivered {
+		t.Errorf("deliveries = %+v, want two delivered", deliveries)
+	}
+}
+
+func TestRevalidationRetries(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Shared dinner", nil)
+
+	// The frontend fails once, then accepts the retry
+	server, received := newReceiver(t, http.StatusServiceUnavailable, http.StatusOK)
+	r := newRevalidator(db, server.URL, revalidateSecret, time.Millisecond, 3)
+	r.flush(bill.ID)
+	if len(received) != 2 {
+		t.Fatalf("%d requests, want 2", len(received))
+	}
+
+	var deliveries []models.WebhookDeliveries
+	db.Where("bill_id = ?", bill.ID).Order("attempt").Find(&deliveries)
+	if len(deliveries) != 2 {
+		t.Fatalf("%d deliveries logged, want 2", len(deliveries))
+	}
+	if deliveries[0].Delivered || deliveries[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("first attempt = %+v, want a logged 503", deliveries[0])
+	}
+	if !deliveries[1].Delivered || deliveries[1].Attempt != 2 {
+		t.Errorf("second attempt = %+v, want delivered on attempt 2", deliveries[1])
+	}
+}
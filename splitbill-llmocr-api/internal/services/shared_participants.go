@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNameMismatch is returned when the name confirming a claim isn't the participant's
+var ErrNameMismatch = errors.New("name does not match the participant")
+
+// ErrJoinLimited is returned when too many people joined through one share link lately
+var ErrJoinLimited = errors.New("too many joins through this share link")
+
+// maxTrackedShareLinks bounds the memory used by the join limiter across all share links
+const maxTrackedShareLinks = 10000
+
+// joinWindow counts the joins through one share link since start
+type joinWindow struct {
+	start time.Time
+	count int
+}
+
+// joinLimiter allows at most limit joins per share link in each window, so a leaked link
+// can't be used to flood a bill with participants. It is per instance.
+type joinLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*joinWindow
+}
+
+// newJoinLimiter creates a join limiter; a limit of zero refuses every join
+func newJoinLimiter(limit int, window time.Duration) *joinLimiter {
+	return &joinLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*joinWindow),
+	}
+}
+
+// allow counts a join through token at now and reports whether it is within the limit.
+// When every tracked link is still in its window, new links are refused until one ends.
+func (l *joinLimiter) allow(token string, now time.Time) bool {
+	if l.limit <= 0 {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, ok := l.windows[token]
+	if ok && now.Sub(current.start) >= l.window {
+		delete(l.windows, token)
+		ok = false
+	}
+	if !ok {
+		if len(l.windows) >= maxTrackedShareLinks {
+			l.sweepLocked(now)
+		}
+		if len(l.windows) >= maxTrackedShareLinks {
+			return false
+		}
+		current = &joinWindow{start: now}
+		l.windows[token] = current
+	}
+	if current.count >= l.limit {
+		return false
+	}
+	current.count++
+	return true
+}
+
+// sweepLocked forgets links whose window has ended. Callers must hold the lock.
+func (l *joinLimiter) sweepLocked(now time.Time) {
+	for token, current := range l.windows {
+		if now.Sub(current.start) >= l.window {
+			delete(l.windows, token)
+		}
+	}
+}
+
+// sharedBill resolves a share token to its bill's ID and status. Unknown and regenerated
+// tokens return ErrNotFound.
+func (s *BillService) sharedBill(token string) (*models.Bills, error) {
+	var bill models.Bills
+	if err := s.db.Select("id", "status").First(&bill, "share_token = ?", token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("share token: %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find bill: %w", err)
+	}
+	return &bill, nil
+}
+
+// ClaimSharedParticipant lets someone holding the share link say which participant they
+// are. name must repeat the participant's name, ignoring case and surrounding spaces, or
+// the claim is ErrNameMismatch. The claim is stamped with claimed_at and the optional
+// email. A participant claimed before, through a link or by an account, is
+// ErrAlreadyClaimed.
+func (s *BillService) ClaimSharedParticipant(token string, participantID uint, name, email string) (*models.ParticipantResponse, error) {
+	bill, err := s.sharedBill(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var participant models.Participants
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(ScopeBill(bill.ID)).
+			Where("id = ?", participantID).
+			First(&participant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("participant %d not found in bill %s: %w", participantID, bill.ID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find participant: %w", err)
+		}
+		if participant.ClaimedAt != nil || participant.UserID != nil {
+			return fmt.Errorf("participant %d: %w", participantID, ErrAlreadyClaimed)
+		}
+		if !strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(participant.Name)) {
+			return fmt.Errorf("participant %d: %w", participantID, ErrNameMismatch)
+		}
+
+		updates := map[string]interface{}{"claimed_at": time.Now()}
+		if email = strings.TrimSpace(email); email != "" {
+			updates["email"] = email
+		}
+		if err := tx.Model(&participant).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to claim participant: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(bill.ID, "guest", EventParticipantClaimed, models.EventPayload{
+		"participant_id": participant.ID,
+		"name":           participant.Name,
+	})
+	response := participantResponse(&participant)
+	return &response, nil
+}
+
+// JoinSharedBill adds someone missing from a bill through its share link, as a participant
+// already claimed by them. Joins are limited per link; past the limit the join is
+// ErrJoinLimited. Archived bills are ErrArchived.
+func (s *BillService) JoinSharedBill(token, name, email string) (*models.ParticipantResponse, error) {
+	bill, err := s.sharedBill(token)
+	if err != nil {
+		return nil, err
+	}
+	if BillStatus(bill.Status) == StatusArchived {
+		return nil, fmt.Errorf("bill %s: %w", bill.ID, ErrArchived)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name must not be blank", ErrInvalidUpdate)
+	}
+	now := time.Now()
+	if !s.joinLimiter.allow(token, now) {
+		return nil, ErrJoinLimited
+	}
+
+	participant := &models.Participants{
+		BillID:        bill.ID,
+		Name:          name,
+		Email:         strings.TrimSpace(email),
+		PaymentStatus: PaymentUnpaid,
+		ClaimedAt:     &now,
+	}
+	if err := s.insertParticipant(participant, "guest"); err != nil {
+		return nil, err
+	}
+	response := participantResponse(participant)
+	return &response, nil
+}
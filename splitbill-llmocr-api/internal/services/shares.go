@@ -0,0 +1,9 @@
+package services
+
+import "github.com/Aebroyx/splitbill-llmocr-api/internal/splitmath"
+
+// Rounding modes for splitting a bill total into whole minor units; see splitmath
+const (
+	RoundingLargestRemainder = splitmath.LargestRemainder
+	RoundingPayerAbsorbs     = splitmath.PayerAbsorbs
+)
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegenerateShareTokenRevokesOldLink(t *testing.T) {
+	s, _ := newTestBillService(t, nil)
+	bill := createTestBill(t, s, "Ramen", nil)
+
+	old, err := s.RegenerateShareToken(bill.ID)
+	if err != nil {
+		t.Fatalf("RegenerateShareToken: %v", err)
+	}
+	if shared, err := s.GetSharedBill(old); err != nil || shared.Bill.ID != bill.ID {
+		t.Fatalf("GetSharedBill with the first token = %v, %v", shared, err)
+	}
+
+	current, err := s.RegenerateShareToken(bill.ID)
+	if err != nil {
+		t.Fatalf("RegenerateShareToken: %v", err)
+	}
+	if current == old {
+		t.Fatal("the regenerated token equals the old one")
+	}
+	if _, err := s.GetSharedBill(old); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSharedBill with the old token err = %v, want ErrNotFound", err)
+	}
+	if shared, err := s.GetSharedBill(current); err != nil || shared.Bill.ID != bill.ID {
+		t.Errorf("GetSharedBill with the new token = %v, %v", shared, err)
+	}
+}
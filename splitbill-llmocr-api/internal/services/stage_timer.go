@@ -0,0 +1,77 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+)
+
+// Stages of the trip from an uploaded photo to items on screen, in pipeline order
+const (
+	StageValidation    = "validation"
+	StagePreprocessing = "preprocessing"
+	StageStorageWrite  = "storage_write"
+	StageQueueWait     = "queue_wait"
+	StageProviderCall  = "provider_call"
+	StageCallbackParse = "callback_parse"
+	StageDBPersist     = "db_persist"
+)
+
+// ExtractionStages lists every stage in pipeline order
+var ExtractionStages = []string{
+	StageValidation,
+	StagePreprocessing,
+	StageStorageWrite,
+	StageQueueWait,
+	StageProviderCall,
+	StageCallbackParse,
+	StageDBPersist,
+}
+
+// StageTimer times the consecutive stages of one extraction. Starting a stage ends the one
+// running before it, so the pipeline marks where each stage begins and never measures by
+// hand. A timer is handed from one step to the next and is not safe for concurrent use.
+type StageTimer struct {
+	now     func() time.Time
+	current string
+	since   time.Time
+	stages  map[string]time.Duration
+}
+
+// NewStageTimer returns a timer with no stage running
+func NewStageTimer() *StageTimer {
+	return &StageTimer{now: time.Now, stages: make(map[string]time.Duration)}
+}
+
+// Start ends the running stage, if any, and starts stage
+func (t *StageTimer) Start(stage string) {
+	now := t.now()
+	t.end(now)
+	t.current = stage
+	t.since = now
+}
+
+// Stop ends the running stage and returns its name, or "" when none was running
+func (t *StageTimer) Stop() string {
+	stage := t.current
+	t.end(t.now())
+	return stage
+}
+
+// Timings returns the time taken by every ended stage, in milliseconds
+func (t *StageTimer) Timings() models.StageTimings {
+	timings := make(models.StageTimings, len(t.stages))
+	for stage, took := range t.stages {
+		timings[stage] = took.Milliseconds()
+	}
+	return timings
+}
+
+// end adds the running stage's time up to now
+func (t *StageTimer) end(now time.Time) {
+	if t.current == "" {
+		return
+	}
+	t.stages[t.current] += now.Sub(t.since)
+	t.current = ""
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// statusSubscriberBuffer is how many unread status changes a subscriber may fall behind by
+// before the oldest is dropped; only the latest status matters to a stream
+const statusSubscriberBuffer = 4
+
+// statusBroker fans bill status changes out to in-process subscribers such as SSE streams
+type statusBroker struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan BillStatus]struct{}
+}
+
+// newStatusBroker creates a broker with no subscribers
+func newStatusBroker() *statusBroker {
+	return &statusBroker{subscribers: make(map[uuid.UUID]map[chan BillStatus]struct{})}
+}
+
+// subscribe registers for billID's status changes. The cancel function must be called when
+// the subscriber goes away; it is safe to call more than once.
+func (b *statusBroker) subscribe(billID uuid.UUID) (<-chan BillStatus, func()) {
+	ch := make(chan BillStatus, statusSubscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[billID] == nil {
+		b.subscribers[billID] = make(map[chan BillStatus]struct{})
+	}
+	b.subscribers[billID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[billID], ch)
+			if len(b.subscribers[billID]) == 0 {
+				delete(b.subscribers, billID)
+			}
+		})
+	}
+}
+
+// publish hands status to every subscriber of billID without blocking. A subscriber whose
+// buffer is full loses its oldest unread status instead.
+func (b *statusBroker) publish(billID uuid.UUID, status BillStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[billID] {
+		select {
+		case ch <- status:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- status
+		}
+	}
+}
+
+// SubscribeStatus streams billID's status changes until cancel is called. Subscribe before
+// reading the current status so no change is missed in between.
+func (s *BillService) SubscribeStatus(billID uuid.UUID) (<-chan BillStatus, func()) {
+	return s.statusBroker.subscribe(billID)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SweepStuckBills marks bills failed that have been processing since before now minus
+// timeout, because n8n accepted them but never called back. It returns how many bills
+// were marked failed.
+func (s *BillService) SweepStuckBills(now time.Time, timeout time.Duration) (int, error) {
+	cutoff := now.Add(-timeout)
+
+	var billIDs []uuid.UUID
+	if err := s.db.Model(&models.Bills{}).
+		Where("status = ? AND updated_at < ?", string(StatusProcessing), cutoff).
+		Pluck("id", &billIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find stuck bills: %w", err)
+	}
+
+	swept := 0
+	for _, billID := range billIDs {
+		// Re-check the status so a callback that lands meanwhile is not overwritten
+		result := s.db.Model(&models.Bills{}).
+			Where("id = ? AND status = ? AND updated_at < ?", billID, string(StatusProcessing), cutoff).
+			Update("status", string(StatusFailed))
+		if result.Error != nil {
+			return swept, fmt.Errorf("failed to mark bill %s failed: %w", billID, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			fmt.Printf("Bill %s was processing for more than %s, marked failed\n", billID, timeout)
+			s.extractionHealth.recordFailure()
+			s.recordStatusChange(billID, ActorSystem, StatusProcessing, StatusFailed)
+			// n8n accepted the image but never answered
+			s.abandonStageTimings(billID, StageProviderCall)
+			swept++
+		}
+	}
+	return swept, nil
+}
+
+// ListBillsByStatus returns up to limit bills that have been in status since before now
+// minus olderThan, the longest unchanged first. It is served by the status and updated_at
+// index.
+func (s *BillService) ListBillsByStatus(status BillStatus, olderThan time.Duration, now time.Time, limit int) (*models.AdminBillListResponse, error) {
+	bills := []models.AdminBillListItem{}
+	if err := s.db.Model(&models.Bills{}).
+		Select("id, status, image_path <> '' AS has_image, created_at, updated_at").
+		Where("status = ? AND updated_at <= ?", string(status), now.Add(-olderThan)).
+		Order("updated_at ASC").
+		Limit(limit).
+		Scan(&bills).Error; err != nil {
+		return nil, fmt.Errorf("failed to list bills: %w", err)
+	}
+	return &models.AdminBillListResponse{Bills: bills, Count: len(bills)}, nil
+}
+
+// ForceBillStatus moves a bill to status whatever its current status, for unsticking bills
+// by hand. The move is logged with actor like any other status change.
+func (s *BillService) ForceBillStatus(billID uuid.UUID, status BillStatus, actor string) (*models.BillResponse, error) {
+	var from BillStatus
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bills
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status").First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("bill %s: %w", billID, ErrNotFound)
+			}
+			return fmt.Errorf("failed to find bill: %w", err)
+		}
+
+		from = BillStatus(bill.Status)
+		if from == status {
+			return nil
+		}
+		if err := tx.Model(&models.Bills{}).Where("id = ?", billID).Update("status", string(status)).Error; err != nil {
+			return fmt.Errorf("failed to update bill status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if from != status {
+		s.recordStatusChange(billID, actor, from, status)
+	}
+
+	return s.GetBill(billID)
+}
+
+// StartStuckBillSweeper runs SweepStuckBills every interval until ctx is cancelled.
+// Nothing is started when timeout is zero or extraction is disabled.
+func (s *BillService) StartStuckBillSweeper(ctx context.Context, interval, timeout time.Duration) {
+	if timeout <= 0 || !s.features.Enabled(config.FeatureExtraction) {
+		fmt.Println("Stuck bill sweeper disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				swept, err := s.SweepStuckBills(now, timeout)
+				if err != nil {
+					fmt.Printf("Stuck bill sweep failed: %v\n", err)
+					continue
+				}
+				fmt.Printf("Stuck bill sweep: %d bill(s) marked failed\n", swept)
+			}
+		}
+	}()
+}
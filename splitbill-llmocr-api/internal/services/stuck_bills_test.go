@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+)
+
+func TestSweepStuckBills(t *testing.T) {
+	s, db := newTestBillService(t, nil)
+	now := time.Now()
+	timeout := 15 * time.Minute
+
+	tests := []struct {
+		name   string
+		status BillStatus
+		age    time.Duration
+		want   BillStatus
+	}{
+		{name: "stuck processing", status: StatusProcessing, age: time.Hour, want: StatusFailed},
+		{name: "fresh processing", status: StatusProcessing, age: time.Minute, want: StatusProcessing},
+		{name: "old queued", status: StatusQueued, age: time.Hour, want: StatusQueued},
+		{name: "old completed", status: StatusCompleted, age: time.Hour, want: StatusCompleted},
+	}
+
+	bills := make(map[string]uuid.UUID, len(tests))
+	for _, tt := range tests {
+		bill := createTestBill(t, s, tt.name, nil)
+		if err := db.Model(&models.Bills{}).Where("id = ?", bill.ID).UpdateColumns(map[string]interface{}{
+			"status":     string(tt.status),
+			"updated_at": now.Add(-tt.age),
+		}).Error; err != nil {
+			t.Fatalf("failed to age bill %s: %v", tt.name, err)
+		}
+		bills[tt.name] = bill.ID
+	}
+
+	swept, err := s.SweepStuckBills(now, timeout)
+	if err != nil {
+		t.Fatalf("SweepStuckBills: %v", err)
+	}
+	if swept != 1 {
+		t.Errorf("swept %d bills, want 1", swept)
+	}
+	for _, tt := range tests {
+		status, _, err := s.GetBillStatus(bills[tt.name])
+		if err != nil {
+			t.Fatalf("GetBillStatus %s: %v", tt.name, err)
+		}
+		if BillStatus(status) != tt.want {
+			t.Errorf("%s: status = %s, want %s", tt.name, status, tt.want)
+		}
+	}
+
+	// The move is logged like any other status change
+	var events int64
+	db.Model(&models.BillEvents{}).
+		Where("bill_id = ? AND action = ? AND actor = ?", bills["stuck processing"], EventStatusChanged, ActorSystem).
+		Count(&events)
+	if events != 1 {
+		t.Errorf("%d status change events for the stuck bill, want 1", events)
+	}
+
+	// A second sweep finds nothing left to do
+	if swept, err := s.SweepStuckBills(now, timeout); err != nil || swept != 0 {
+		t.Errorf("second sweep = %d, %v, want 0, nil", swept, err)
+	}
+}
@@ -2,8 +2,10 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/cache"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/config"
 	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,15 +14,21 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrCannotImpersonate is returned when an admin tries to impersonate another admin or a
+// disabled account
+var ErrCannotImpersonate = errors.New("user cannot be impersonated")
+
 type UserService struct {
-	db     *gorm.DB
-	config *config.Config
+	db        *gorm.DB
+	config    *config.Config
+	userCache cache.UserCache
 }
 
-func NewUserService(db *gorm.DB, config *config.Config) *UserService {
+func NewUserService(db *gorm.DB, config *config.Config, userCache cache.UserCache) *UserService {
 	return &UserService{
-		db:     db,
-		config: config,
+		db:        db,
+		config:    config,
+		userCache: userCache,
 	}
 }
 
@@ -115,14 +123,77 @@ func (s *UserService) Login(req *models.LoginRequest) (*models.LoginResponse, er
 	}, nil
 }
 
+// Impersonate issues a short-lived, read-only token that acts as userID for the admin
+// adminID, and records it in the audit log. Admins and disabled users cannot be impersonated.
+func (s *UserService) Impersonate(adminID, userID uint) (*models.ImpersonationResponse, error) {
+	var user models.Users
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.Role == "admin" || user.IsDeleted {
+		return nil, ErrCannotImpersonate
+	}
+
+	token, expiresAt, err := s.signToken(user, adminID, s.config.ImpersonationTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	RecordAudit(s.db, models.AuditLogs{
+		ActorID:   adminID,
+		SubjectID: user.ID,
+		Action:    AuditImpersonationStarted,
+	})
+
+	return &models.ImpersonationResponse{
+		User: models.RegisterResponse{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Name:     user.Name,
+			Role:     user.Role,
+		},
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// InvalidateUser drops any cached auth data for the user. Call it whenever
+// profile, password or role data changes, or the user's sessions are revoked.
+func (s *UserService) InvalidateUser(userID uint) {
+	s.userCache.Invalidate(userID)
+}
+
+// RevokeSessions signs the user out everywhere: every token issued so far, including
+// impersonation tokens for the user, stops working on its next request
+func (s *UserService) RevokeSessions(userID uint) error {
+	if err := s.db.Model(&models.Users{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	s.InvalidateUser(userID)
+	return nil
+}
+
 // generateToken generates a JWT token for the user
 func (s *UserService) generateToken(user models.Users, expiry time.Duration) (string, time.Time, error) {
+	return s.signToken(user, 0, expiry)
+}
+
+// signToken signs a JWT for the user; a non-zero impersonatorID marks a read-only
+// impersonation token
+func (s *UserService) signToken(user models.Users, impersonatorID uint, expiry time.Duration) (string, time.Time, error) {
 	expirationTime := time.Now().Add(expiry)
 	claims := &models.Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
+		UserID:         user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		Role:           user.Role,
+		ImpersonatorID: impersonatorID,
+		TokenVersion:   user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
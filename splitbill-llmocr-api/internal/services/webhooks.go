@@ -0,0 +1,73 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/domain/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxWebhookBackoff caps the wait between webhook delivery attempts
+const maxWebhookBackoff = time.Minute
+
+// webhookBackoff doubles the wait after each failed attempt, starting at one second
+func webhookBackoff(attempt int) time.Duration {
+	wait := time.Second << (attempt - 1)
+	if wait <= 0 || wait > maxWebhookBackoff {
+		return maxWebhookBackoff
+	}
+	return wait
+}
+
+// deliverWebhook calls send until it succeeds or attempts run out, backing off between
+// attempts and recording each one in the webhook delivery log. send returns the response
+// status, or zero when no response came back.
+func deliverWebhook(db *gorm.DB, target string, billID uuid.UUID, attempts int, send func() (int, error)) {
+	for attempt := 1; attempt <= attempts; attempt++ {
+		statusCode, err := send()
+
+		delivery := models.WebhookDeliveries{
+			BillID:     billID,
+			Target:     target,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Delivered:  err == nil,
+		}
+		if err != nil {
+			delivery.Error = truncate(err.Error(), 500)
+		}
+		if dbErr := db.Create(&delivery).Error; dbErr != nil {
+			fmt.Printf("Failed to record %s delivery for bill %s: %v\n", target, billID, dbErr)
+		}
+
+		if err == nil {
+			return
+		}
+		fmt.Printf("%s attempt %d/%d for bill %s failed: %v\n", target, attempt, attempts, billID, err)
+		if attempt < attempts {
+			time.Sleep(webhookBackoff(attempt))
+		}
+	}
+}
+
+// signHMAC returns the hex HMAC-SHA256 of the concatenated parts keyed with secret
+func signHMAC(secret string, parts ...[]byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncate shortens s to at most max bytes
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
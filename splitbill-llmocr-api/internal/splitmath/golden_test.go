@@ -0,0 +1,84 @@
+package splitmath
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the want of each golden fixture from Compute")
+
+// fixture is one golden bill scenario: Compute(Input) must return Want
+type fixture struct {
+	Description string
+	Input       Input
+	Want        Allocation
+}
+
+// TestComputeGolden runs every scenario in testdata. Changes to the split show up as
+// fixture diffs; regenerate them with go test ./internal/splitmath -update and review.
+func TestComputeGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no golden fixtures in testdata")
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f fixture
+			if err := json.Unmarshal(raw, &f); err != nil {
+				t.Fatalf("failed to decode fixture: %v", err)
+			}
+
+			got := Compute(f.Input)
+			if *update {
+				f.Want = got
+				out, err := json.MarshalIndent(f, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			gotJSON, _ := json.MarshalIndent(got, "", "  ")
+			wantJSON, _ := json.MarshalIndent(f.Want, "", "  ")
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("%s\ngot:\n%s\nwant:\n%s", f.Description, gotJSON, wantJSON)
+			}
+			checkInvariants(t, f.Input, got)
+		})
+	}
+}
+
+// checkInvariants holds for every non-manual split: the shares add up to the total, and
+// nobody absorbs more than the residual
+func checkInvariants(t *testing.T, in Input, alloc Allocation) {
+	t.Helper()
+	if in.Manual || len(alloc.Shares) == 0 {
+		return
+	}
+	var split, absorbed int64
+	for _, share := range alloc.Shares {
+		split += share.Split
+		absorbed += share.Absorbed
+	}
+	if split != alloc.Total {
+		t.Errorf("shares add up to %d, want the total %d", split, alloc.Total)
+	}
+	if absorbed != alloc.Residual {
+		t.Errorf("absorbed %d, want the residual %d", absorbed, alloc.Residual)
+	}
+}
@@ -0,0 +1,25 @@
+package splitmath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeRecordedPaymentsWin(t *testing.T) {
+	payer := uint(1)
+	alloc := Compute(Input{
+		Items:        []Item{{Line: 900}},
+		Participants: []Participant{{ID: 1}, {ID: 2}, {ID: 3}},
+		PayerID:      &payer,
+		Payments:     []Payment{{ParticipantID: 2, Amount: 600}, {ParticipantID: 3, Amount: 300}, {ParticipantID: 7, Amount: 50}},
+	})
+
+	wantPayments := []Payment{{ParticipantID: 2, Amount: 600}, {ParticipantID: 3, Amount: 300}}
+	if !reflect.DeepEqual(alloc.Payments, wantPayments) {
+		t.Errorf("payments = %+v, want %+v", alloc.Payments, wantPayments)
+	}
+	wantTransfers := []Transfer{{FromParticipantID: 1, ToParticipantID: 2, Amount: 300}}
+	if !reflect.DeepEqual(alloc.Transfers, wantTransfers) {
+		t.Errorf("transfers = %+v, want %+v", alloc.Transfers, wantTransfers)
+	}
+}
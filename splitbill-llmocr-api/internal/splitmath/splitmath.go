@@ -0,0 +1,379 @@
+// Package splitmath works out how a bill is split between its participants. It is pure:
+// every amount is in whole minor units of the bill's currency (cents for USD, rupiah for
+// IDR), and callers convert to and from stored amounts. Everything that shows a share, a
+// total or a settlement should come from Compute so the numbers never disagree.
+package splitmath
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Rounding modes for splitting a bill total into whole minor units
+const (
+	// LargestRemainder hands the leftover units out one each, in participant order
+	LargestRemainder = "largest_remainder"
+	// PayerAbsorbs gives all leftover units to the designated payer
+	PayerAbsorbs = "payer_absorbs"
+)
+
+// Charges are the bill-level amounts on top of the items. Tax and Tip already embedded
+// in item prices (PricesIncludeTax, PricesIncludeService) are informational only.
+type Charges struct {
+	Tax                  int64
+	Tip                  int64
+	ServiceCharge        int64
+	Discount             int64
+	PricesIncludeTax     bool
+	PricesIncludeService bool
+}
+
+// Participant is someone the bill is split between, in display order. Extra is their
+// share of common costs, owed on top of the split. Amount is what they owe on a manual
+// bill, where Extra does not apply. Weight is their part of items nobody is assigned to;
+// zero counts as one.
+type Participant struct {
+	ID     uint
+	Extra  int64
+	Amount int64
+	Weight int64
+}
+
+// Item is one line of the bill. Line is its price times quantity, less its discount.
+// An item with assignments is split between the assigned participants by weight; one
+// without, or whose assignees have all left the bill, is split between everyone.
+type Item struct {
+	Line        int64
+	Assignments []Assignment
+}
+
+// Assignment gives a participant a part of an item. Weight is their part relative to the
+// item's other assignees; zero counts as one.
+type Assignment struct {
+	ParticipantID uint
+	Weight        int64
+}
+
+// Payment is an amount a participant fronted to the merchant
+type Payment struct {
+	ParticipantID uint
+	Amount        int64
+}
+
+// Input is everything Compute needs. PayerID is the designated payer: they absorb leftover units under
+// PayerAbsorbs and are taken to have fronted the whole total when Payments is empty.
+// Manual bills skip the split: each participant owes their Amount and the total is the
+// sum of the amounts.
+type Input struct {
+	Items        []Item
+	Charges      Charges
+	Participants []Participant
+	Rounding     string
+	PayerID      *uint
+	Payments     []Payment
+	Manual       bool
+}
+
+// Share is one participant's part of the bill
+type Share struct {
+	ParticipantID uint
+	// Split is their part of the total, including any leftover units they absorbed
+	Split int64
+	// Absorbed is the leftover units included in Split
+	Absorbed int64
+	// Owed is Split plus their share of common costs
+	Owed int64
+	// Paid is what they fronted to the merchant
+	Paid int64
+}
+
+// Transfer is an amount one participant owes another to settle the bill
+type Transfer struct {
+	FromParticipantID uint
+	ToParticipantID   uint
+	Amount            int64
+}
+
+// Allocation is the full result of splitting a bill
+type Allocation struct {
+	Subtotal int64
+	Total    int64
+	// Rounding is the mode actually used; PayerAbsorbs falls back to LargestRemainder
+	// when the designated payer is not a participant
+	Rounding string
+	Residual int64
+	Shares   []Share
+	// Payments lists who fronted what: the recorded payments of participants, or the
+	// designated payer fronting the total. It is empty when nobody is known to have paid.
+	Payments  []Payment
+	Transfers []Transfer
+}
+
+// Totals returns the item subtotal and what the table actually pays: items plus the tax
+// and tip not already in item prices, plus the service charge, less the discount, and
+// never below zero
+func Totals(items []int64, charges Charges) (int64, int64) {
+	var subtotal int64
+	for _, item := range items {
+		subtotal += item
+	}
+
+	total := subtotal
+	if !charges.PricesIncludeTax {
+		total += charges.Tax
+	}
+	if !charges.PricesIncludeService {
+		total += charges.Tip
+	}
+	total += charges.ServiceCharge - charges.Discount
+	// Items edited down after a discount was set must not produce a negative bill
+	if total < 0 {
+		total = 0
+	}
+	return subtotal, total
+}
+
+// ManualTotal returns the total of a manual bill, the sum of its participants' amounts
+func ManualTotal(participants []Participant) int64 {
+	var total int64
+	for _, participant := range participants {
+		total += participant.Amount
+	}
+	return total
+}
+
+// Lines returns the line totals of items, in order
+func Lines(items []Item) []int64 {
+	lines := make([]int64, len(items))
+	for i, item := range items {
+		lines[i] = item.Line
+	}
+	return lines
+}
+
+// Compute splits the bill total between the participants, adds their share of common
+// costs, and nets what each owes against what they fronted. Each participant's part of
+// the total is their part of the items, with tax, tip, service charge and discount spread
+// in proportion; without assignments or weights that is an even split. Manual bills take
+// each participant's amount as is.
+func Compute(in Input) Allocation {
+	subtotal, total := Totals(Lines(in.Items), in.Charges)
+	if in.Manual {
+		total = ManualTotal(in.Participants)
+	}
+	alloc := Allocation{
+		Subtotal:  subtotal,
+		Total:     total,
+		Rounding:  LargestRemainder,
+		Shares:    make([]Share, len(in.Participants)),
+		Payments:  []Payment{},
+		Transfers: []Transfer{},
+	}
+
+	index := make(map[uint]int, len(in.Participants))
+	for i, participant := range in.Participants {
+		index[participant.ID] = i
+	}
+
+	// Payer absorbs only applies when the designated payer is still on the bill
+	payerIndex := -1
+	if in.PayerID != nil {
+		if i, ok := index[*in.PayerID]; ok && in.Rounding == PayerAbsorbs {
+			alloc.Rounding, payerIndex = PayerAbsorbs, i
+		}
+	}
+
+	split, absorbed := splitByWeight(total, itemWeights(in.Items, in.Participants, index), alloc.Rounding, payerIndex)
+	for i, participant := range in.Participants {
+		if in.Manual {
+			alloc.Shares[i] = Share{ParticipantID: participant.ID, Split: participant.Amount, Owed: participant.Amount}
+			continue
+		}
+		alloc.Shares[i] = Share{
+			ParticipantID: participant.ID,
+			Split:         split[i],
+			Absorbed:      absorbed[i],
+			Owed:          split[i] + participant.Extra,
+		}
+		alloc.Residual += absorbed[i]
+	}
+
+	// Recorded payments take precedence over the designated payer
+	for _, payment := range in.Payments {
+		if i, ok := index[payment.ParticipantID]; ok {
+			alloc.Shares[i].Paid += payment.Amount
+			alloc.Payments = append(alloc.Payments, payment)
+		}
+	}
+	if len(alloc.Payments) == 0 && in.PayerID != nil {
+		if i, ok := index[*in.PayerID]; ok {
+			alloc.Shares[i].Paid = total
+			alloc.Payments = append(alloc.Payments, Payment{ParticipantID: *in.PayerID, Amount: total})
+		}
+	}
+	if len(alloc.Payments) == 0 {
+		return alloc
+	}
+
+	balances := make([]int64, len(alloc.Shares))
+	for i, share := range alloc.Shares {
+		balances[i] = share.Paid - share.Owed
+	}
+	for _, t := range settle(balances) {
+		alloc.Transfers = append(alloc.Transfers, Transfer{
+			FromParticipantID: in.Participants[t.from].ID,
+			ToParticipantID:   in.Participants[t.to].ID,
+			Amount:            t.amount,
+		})
+	}
+	return alloc
+}
+
+// weight returns w, or one when w is not positive
+func weight(w int64) int64 {
+	if w <= 0 {
+		return 1
+	}
+	return w
+}
+
+// itemWeights returns each participant's exact part of the items, in participant order.
+// When the items add up to nothing, the parts are the participants' weights, so charges
+// on a bill without items are still split.
+func itemWeights(items []Item, participants []Participant, index map[uint]int) []*big.Rat {
+	parts := make([]*big.Rat, len(participants))
+	for i := range parts {
+		parts[i] = new(big.Rat)
+	}
+	if len(participants) == 0 {
+		return parts
+	}
+
+	var everyone int64
+	for _, participant := range participants {
+		everyone += weight(participant.Weight)
+	}
+
+	var itemized bool
+	for _, item := range items {
+		if item.Line <= 0 {
+			continue
+		}
+		itemized = true
+
+		var assigned int64
+		for _, assignment := range item.Assignments {
+			if _, ok := index[assignment.ParticipantID]; ok {
+				assigned += weight(assignment.Weight)
+			}
+		}
+		if assigned == 0 {
+			for i, participant := range participants {
+				parts[i].Add(parts[i], big.NewRat(item.Line*weight(participant.Weight), everyone))
+			}
+			continue
+		}
+		for _, assignment := range item.Assignments {
+			if i, ok := index[assignment.ParticipantID]; ok {
+				parts[i].Add(parts[i], big.NewRat(item.Line*weight(assignment.Weight), assigned))
+			}
+		}
+	}
+
+	if !itemized {
+		for i, participant := range participants {
+			parts[i].SetInt64(weight(participant.Weight))
+		}
+	}
+	return parts
+}
+
+// splitByWeight divides total across participants in proportion to parts. Each share is
+// rounded down, and the units left over are handed out one each by largest remainder
+// (ties go in participant order) for LargestRemainder, or all to payerIndex for
+// PayerAbsorbs. It returns the per-participant units and the extra units each absorbed.
+func splitByWeight(total int64, parts []*big.Rat, mode string, payerIndex int) ([]int64, []int64) {
+	count := len(parts)
+	shares := make([]int64, count)
+	absorbed := make([]int64, count)
+	if count == 0 {
+		return shares, absorbed
+	}
+
+	sum := new(big.Rat)
+	for _, part := range parts {
+		sum.Add(sum, part)
+	}
+
+	remainders := make([]*big.Rat, count)
+	residual := total
+	for i, part := range parts {
+		exact := new(big.Rat).Mul(big.NewRat(total, 1), part)
+		exact.Quo(exact, sum)
+		// Totals never go below zero, so truncating is rounding down
+		floor := new(big.Int).Quo(exact.Num(), exact.Denom())
+		shares[i] = floor.Int64()
+		remainders[i] = exact.Sub(exact, new(big.Rat).SetInt(floor))
+		residual -= shares[i]
+	}
+
+	if mode == PayerAbsorbs && payerIndex >= 0 && payerIndex < count {
+		shares[payerIndex] += residual
+		absorbed[payerIndex] = residual
+		return shares, absorbed
+	}
+
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]].Cmp(remainders[order[b]]) > 0 })
+	for i := int64(0); i < residual; i++ {
+		shares[order[i]]++
+		absorbed[order[i]] = 1
+	}
+	return shares, absorbed
+}
+
+// transfer moves amount minor units from one participant index to another
+type transfer struct {
+	from, to int
+	amount   int64
+}
+
+// settle turns balances (what each participant fronted minus what they owe) into the
+// transfers that zero them. The largest debtor pays the largest creditor first, so there
+// are at most one fewer transfers than participants with a balance.
+func settle(balances []int64) []transfer {
+	var debtors, creditors []int
+	remaining := make([]int64, len(balances))
+	copy(remaining, balances)
+	for i, balance := range balances {
+		switch {
+		case balance < 0:
+			debtors = append(debtors, i)
+		case balance > 0:
+			creditors = append(creditors, i)
+		}
+	}
+	// Stable sorts keep participant order between equal balances
+	sort.SliceStable(debtors, func(a, b int) bool { return remaining[debtors[a]] < remaining[debtors[b]] })
+	sort.SliceStable(creditors, func(a, b int) bool { return remaining[creditors[a]] > remaining[creditors[b]] })
+
+	var transfers []transfer
+	d, c := 0, 0
+	for d < len(debtors) && c < len(creditors) {
+		debtor, creditor := debtors[d], creditors[c]
+		amount := min(-remaining[debtor], remaining[creditor])
+		transfers = append(transfers, transfer{from: debtor, to: creditor, amount: amount})
+		remaining[debtor] += amount
+		remaining[creditor] -= amount
+		if remaining[debtor] == 0 {
+			d++
+		}
+		if remaining[creditor] == 0 {
+			c++
+		}
+	}
+	return transfers
+}
@@ -0,0 +1,114 @@
+package splitmath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTotals(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []int64
+		charges  Charges
+		subtotal int64
+		total    int64
+	}{
+		{name: "items only", items: []int64{1000, 250}, subtotal: 1250, total: 1250},
+		{name: "tax and tip", items: []int64{1000}, charges: Charges{Tax: 100, Tip: 150}, subtotal: 1000, total: 1250},
+		{name: "tax in prices", items: []int64{1000}, charges: Charges{Tax: 100, Tip: 150, PricesIncludeTax: true}, subtotal: 1000, total: 1150},
+		{name: "service in prices", items: []int64{1000}, charges: Charges{Tax: 100, Tip: 150, PricesIncludeService: true}, subtotal: 1000, total: 1100},
+		{name: "service charge and discount", items: []int64{1000}, charges: Charges{ServiceCharge: 50, Discount: 200}, subtotal: 1000, total: 850},
+		{name: "never negative", items: []int64{100}, charges: Charges{Discount: 500}, subtotal: 100, total: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subtotal, total := Totals(tt.items, tt.charges)
+			if subtotal != tt.subtotal || total != tt.total {
+				t.Errorf("Totals = %d, %d; want %d, %d", subtotal, total, tt.subtotal, tt.total)
+			}
+		})
+	}
+}
+
+func TestComputeLargestRemainder(t *testing.T) {
+	alloc := Compute(Input{
+		Items:        []Item{{Line: 1000}},
+		Participants: []Participant{{ID: 1}, {ID: 2}, {ID: 3, Extra: 50}},
+	})
+
+	if alloc.Total != 1000 || alloc.Residual != 1 || alloc.Rounding != LargestRemainder {
+		t.Fatalf("total %d, residual %d, rounding %s", alloc.Total, alloc.Residual, alloc.Rounding)
+	}
+	want := []Share{
+		{ParticipantID: 1, Split: 334, Absorbed: 1, Owed: 334},
+		{ParticipantID: 2, Split: 333, Owed: 333},
+		{ParticipantID: 3, Split: 333, Owed: 383},
+	}
+	if !reflect.DeepEqual(alloc.Shares, want) {
+		t.Errorf("shares = %+v, want %+v", alloc.Shares, want)
+	}
+	if len(alloc.Payments) != 0 || len(alloc.Transfers) != 0 {
+		t.Errorf("nobody paid, yet got payments %v and transfers %v", alloc.Payments, alloc.Transfers)
+	}
+}
+
+func TestComputePayerAbsorbsAndSettles(t *testing.T) {
+	payer := uint(2)
+	alloc := Compute(Input{
+		Items:        []Item{{Line: 1001}},
+		Participants: []Participant{{ID: 1}, {ID: 2}, {ID: 3}},
+		Rounding:     PayerAbsorbs,
+		PayerID:      &payer,
+	})
+
+	if alloc.Rounding != PayerAbsorbs {
+		t.Fatalf("rounding = %s, want %s", alloc.Rounding, PayerAbsorbs)
+	}
+	if got := alloc.Shares[1]; got.Split != 335 || got.Absorbed != 2 || got.Paid != 1001 {
+		t.Errorf("payer share = %+v", got)
+	}
+	wantTransfers := []Transfer{
+		{FromParticipantID: 1, ToParticipantID: 2, Amount: 333},
+		{FromParticipantID: 3, ToParticipantID: 2, Amount: 333},
+	}
+	if !reflect.DeepEqual(alloc.Transfers, wantTransfers) {
+		t.Errorf("transfers = %+v, want %+v", alloc.Transfers, wantTransfers)
+	}
+}
+
+func TestComputePayerAbsorbsFallsBackWithoutPayer(t *testing.T) {
+	gone := uint(9)
+	alloc := Compute(Input{
+		Items:        []Item{{Line: 100}},
+		Participants: []Participant{{ID: 1}, {ID: 2}, {ID: 3}},
+		Rounding:     PayerAbsorbs,
+		PayerID:      &gone,
+	})
+	if alloc.Rounding != LargestRemainder {
+		t.Errorf("rounding = %s, want %s", alloc.Rounding, LargestRemainder)
+	}
+	if len(alloc.Payments) != 0 {
+		t.Errorf("a payer who left the bill still paid: %v", alloc.Payments)
+	}
+}
+
+func TestComputeManual(t *testing.T) {
+	alloc := Compute(Input{
+		Items:        []Item{{Line: 5000}},
+		Participants: []Participant{{ID: 1, Amount: 700, Extra: 99}, {ID: 2, Amount: 300}},
+		Manual:       true,
+	})
+	if alloc.Total != 1000 || alloc.Subtotal != 5000 {
+		t.Errorf("total %d, subtotal %d; want 1000, 5000", alloc.Total, alloc.Subtotal)
+	}
+	if alloc.Shares[0].Owed != 700 || alloc.Shares[1].Owed != 300 {
+		t.Errorf("shares = %+v", alloc.Shares)
+	}
+}
+
+func TestComputeWithoutParticipants(t *testing.T) {
+	alloc := Compute(Input{Items: []Item{{Line: 100}}})
+	if len(alloc.Shares) != 0 || alloc.Total != 100 {
+		t.Errorf("alloc = %+v", alloc)
+	}
+}
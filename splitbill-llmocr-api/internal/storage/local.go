@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned when the uploads directory cannot be written
+var ErrUnavailable = errors.New("storage unavailable")
+
+// Status is a snapshot of the local backend's health
+type Status struct {
+	Available     bool       `json:"available"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// Local stores uploaded images in a directory on the local disk. A failed write marks
+// the backend unhealthy until a probe write succeeds again.
+type Local struct {
+	dir string
+
+	mu            sync.Mutex
+	healthy       bool
+	lastError     string
+	lastFailureAt *time.Time
+}
+
+// NewLocal creates a local backend rooted at dir
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir, healthy: true}
+}
+
+// Save writes data under name and returns the stored path
+func (l *Local) Save(name string, data []byte) (string, error) {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		l.markFailed(err)
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	path := filepath.Join(l.dir, filepath.Base(name))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		l.markFailed(err)
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	l.markHealthy()
+	return path, nil
+}
+
+// Exists reports whether a stored file is still on disk
+func (l *Local) Exists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// List returns the stored paths of files whose name starts with prefix
+func (l *Local) List(prefix string) ([]string, error) {
+	return filepath.Glob(filepath.Join(l.dir, filepath.Base(prefix)+"*"))
+}
+
+// Remove deletes a stored file. A file that is already gone is not an error.
+func (l *Local) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Status returns the current health of the backend
+func (l *Local) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Status{
+		Available:     l.healthy,
+		LastError:     l.lastError,
+		LastFailureAt: l.lastFailureAt,
+	}
+}
+
+// Available reports whether the last write or probe succeeded
+func (l *Local) Available() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.healthy
+}
+
+// Probe writes and removes a small file to check the directory is writable
+func (l *Local) Probe() error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		l.markFailed(err)
+		return err
+	}
+
+	path := filepath.Join(l.dir, ".probe")
+	if err := os.WriteFile(path, []byte("ok"), 0644); err != nil {
+		l.markFailed(err)
+		return err
+	}
+	os.Remove(path)
+
+	l.markHealthy()
+	return nil
+}
+
+// Watch probes the directory every interval while the backend is unhealthy,
+// re-enabling it automatically once writes succeed again
+func (l *Local) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if l.Available() {
+				continue
+			}
+			if err := l.Probe(); err != nil {
+				log.Printf("Storage probe failed, uploads still unavailable: %v", err)
+			}
+		}
+	}
+}
+
+// markFailed records a failed write and flips the backend to unhealthy
+func (l *Local) markFailed(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.healthy {
+		log.Printf("Storage at %s is unavailable: %v", l.dir, err)
+	}
+	now := time.Now().UTC()
+	l.healthy = false
+	l.lastError = err.Error()
+	l.lastFailureAt = &now
+}
+
+// markHealthy records a successful write
+func (l *Local) markHealthy() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.healthy {
+		log.Printf("Storage at %s is available again", l.dir)
+	}
+	l.healthy = true
+}
@@ -0,0 +1,73 @@
+// Package testdb opens a throwaway Postgres schema for tests that need a real database.
+package testdb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Aebroyx/splitbill-llmocr-api/internal/database"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// EnvURL names the variable holding the DSN of the database tests may use
+const EnvURL = "TEST_DATABASE_URL"
+
+// Open connects to the database in TEST_DATABASE_URL, creates a fresh schema, migrates
+// it and drops it again when the test ends. The test is skipped when the variable is
+// not set, except on CI, where it fails so database tests can't be skipped unnoticed.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv(EnvURL)
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("%s must be set on CI", EnvURL)
+		}
+		t.Skipf("%s not set; skipping database test", EnvURL)
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create test schema: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect to test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+	return db
+}
+
+// withSearchPath points every connection of dsn at schema, for both URL and keyword DSNs
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+	return fmt.Sprintf("%s search_path=%s", dsn, schema)
+}
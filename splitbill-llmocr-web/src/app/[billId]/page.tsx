@@ -355,7 +355,7 @@ export default function BillPage() {
 
     try {
       // Update the item via API
-      await billService.updateItem(itemId, {
+      await billService.updateItem(billId, itemId, {
         name: editItemData.name.trim(),
         price: editItemData.price,
         quantity: editItemData.quantity
@@ -248,9 +248,9 @@ export const billService = {
     }
   },
 
-  async updateItem(itemId: number, updates: UpdateItemPayload): Promise<BillItem> {
+  async updateItem(billId: string, itemId: number, updates: UpdateItemPayload): Promise<BillItem> {
     try {
-      const response = await axios.put(`${API_BASE_URL}/api/items/${itemId}`, updates, {
+      const response = await axios.put(`${API_BASE_URL}/api/bills/${billId}/items/${itemId}`, updates, {
         headers: {
           'Content-Type': 'application/json',
         },